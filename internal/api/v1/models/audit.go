@@ -0,0 +1,114 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Actions of the audit log
+const (
+	AuditActionSignIn              = "auth.sign_in"
+	AuditActionSignInFailed        = "auth.sign_in_failed"
+	AuditActionFingerprintMismatch = "auth.fingerprint_mismatch"
+	AuditActionPasswordChange      = "auth.password.change"
+	AuditActionPasswordReset       = "auth.password.reset"
+	AuditActionTwoFactorEnable     = "auth.two_factor.enable"
+	AuditActionTwoFactorDisable    = "auth.two_factor.disable"
+	AuditActionPersonalTokenCreate = "personal_token.create"
+	AuditActionPersonalTokenRevoke = "personal_token.revoke"
+	AuditActionAppRegister         = "app.register"
+	AuditActionAppDelete           = "app.delete"
+	AuditActionAppUpdate           = "app.update"
+	AuditActionGroupCreate         = "group.create"
+	AuditActionGroupUpdate         = "group.update"
+	AuditActionGroupDelete         = "group.delete"
+	AuditActionGroupRollover       = "group.rollover"
+	AuditActionGroupArchive        = "group.archive"
+	AuditActionGroupUnarchive      = "group.unarchive"
+	AuditActionGroupRestore        = "group.restore"
+	AuditActionGroupDepartment     = "group.department"
+	AuditActionGroupMemberJoin     = "group.member.join"
+	AuditActionGroupMemberLeave    = "group.member.leave"
+	AuditActionGroupMemberRemove   = "group.member.remove"
+	AuditActionGroupMemberImport   = "group.member.import"
+	AuditActionGroupTransfer       = "group.transfer"
+	AuditActionGroupInviteCreate   = "group.invite.create"
+	AuditActionGroupInviteRevoke   = "group.invite.revoke"
+	AuditActionJoinRequestApprove  = "group.join_request.approve"
+	AuditActionJoinRequestReject   = "group.join_request.reject"
+	AuditActionTaskCreate          = "task.create"
+	AuditActionSubjectDepartment   = "subject.department"
+	AuditActionFacultyCreate       = "faculty.create"
+	AuditActionFacultyUpdate       = "faculty.update"
+	AuditActionFacultyDelete       = "faculty.delete"
+	AuditActionDepartmentCreate    = "department.create"
+	AuditActionDepartmentUpdate    = "department.update"
+	AuditActionDepartmentDelete    = "department.delete"
+	AuditActionRoleAssign          = "role.assign"
+	AuditActionRoleRevoke          = "role.revoke"
+	AuditActionFacultyRoleAssign   = "faculty.role.assign"
+	AuditActionFacultyRoleRevoke   = "faculty.role.revoke"
+	AuditActionImpersonationStart  = "impersonation.start"
+	AuditActionImpersonationWrite  = "impersonation.write"
+)
+
+// Targets of the audit log entries
+const (
+	AuditTargetUser  = "user"
+	AuditTargetApp   = "app"
+	AuditTargetGroup = "group"
+	AuditTargetTask  = "task"
+	// AuditTargetPersonalToken is the personal access token of the user
+	AuditTargetPersonalToken = "personal_token"
+	// AuditTargetUniversity is the target of the actions with all groups of the university
+	AuditTargetUniversity = "university"
+	AuditTargetFaculty    = "faculty"
+	AuditTargetDepartment = "department"
+	AuditTargetSubject    = "subject"
+)
+
+const (
+	AuditDefaultLimit = 50
+	AuditMaxLimit     = 200
+)
+
+// AuditEntry is the record of the audit log. The entries are never updated, they are deleted only by the retention.
+//	The actor is the user or the app the request was authenticated with, ImpersonatorID is set if the user was impersonated.
+//	Before and After are the JSON states of the target, Details is the JSON of other data of the action.
+type AuditEntry struct {
+	ID             int64           `json:"id" db:"id"`
+	ActorUserID    *int            `json:"actor_user_id,omitempty" db:"actor_user_id"`
+	ActorAppID     *uuid.UUID      `json:"actor_app_id,omitempty" db:"actor_app_id"`
+	ImpersonatorID *int            `json:"impersonator_id,omitempty" db:"impersonator_id"`
+	Action         string          `json:"action" db:"action"`
+	TargetType     string          `json:"target_type,omitempty" db:"target_type"`
+	TargetID       string          `json:"target_id,omitempty" db:"target_id"`
+	RequestID      string          `json:"request_id,omitempty" db:"request_id"`
+	IP             string          `json:"ip,omitempty" db:"ip"`
+	Before         json.RawMessage `json:"before,omitempty" db:"before"`
+	After          json.RawMessage `json:"after,omitempty" db:"after"`
+	Details        json.RawMessage `json:"details,omitempty" db:"details"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AuditFilter of the audit log. Empty fields aren't filtered.
+//	The entries are returned the newest first, the page starts after the Cursor entry.
+type AuditFilter struct {
+	ActorUserID int
+	ActorAppID  *uuid.UUID
+	Action      string
+	TargetType  string
+	TargetID    string
+	RequestID   string
+	From        time.Time
+	To          time.Time
+	Cursor      int64
+	Limit       int
+}
+
+// AuditPage is the page of the audit log. NextCursor is empty on the last page
+type AuditPage struct {
+	Entries    []AuditEntry `json:"entries"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
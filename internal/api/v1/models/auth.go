@@ -2,26 +2,49 @@ package models
 
 import (
 	"github.com/dgrijalva/jwt-go"
+	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/google/uuid"
+	"strings"
 	"time"
 )
 
+// App scopes limit what the app token can be used for
+const (
+	// AppScopeAuth allows registration and sign-in of users
+	AppScopeAuth = "auth"
+	// AppScopeAPI allows requests on behalf of signed-in users
+	AppScopeAPI = "api"
+)
+
+// AppScopes are all scopes an app can be granted
+var AppScopes = []string{AppScopeAuth, AppScopeAPI}
+
+// ParseScope splits the space-delimited scope (RFC 6749, section 3.3) into the list of scopes
+func ParseScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
 type AppToken struct {
 	AppID               uuid.UUID `json:"client_id" db:"app_id"`
 	AppToken            string    `json:"access_token" db:"app_token"`
 	IssueTokenTimestamp time.Time `json:"-" db:"issue_token_timestamp"`
 	StartTimestamp      time.Time `json:"-" db:"start_timestamp"`
 	ExpirationTimestamp time.Time `json:"expiration_timestamp" db:"expiration_timestamp"`
+	// Scope is the space-delimited list of scopes granted to the token
+	Scope string `json:"scope" db:"scope"`
 }
 
 func (t *AppToken) Valid() bool {
 	now := time.Now()
-	if now.After(t.IssueTokenTimestamp) ||
-		now.After(t.StartTimestamp) ||
-		now.Before(t.ExpirationTimestamp) {
-		return true
-	}
+	return !now.Before(t.StartTimestamp) && now.Before(t.ExpirationTimestamp)
+}
 
+func (t *AppToken) HasScope(scope string) bool {
+	for _, s := range ParseScope(t.Scope) {
+		if s == scope {
+			return true
+		}
+	}
 	return false
 }
 
@@ -32,45 +55,78 @@ func (t *AppToken) CheckIdentity(requestToken string) bool {
 	return false
 }
 
+// RegisteredApp is the OAuth2 client.
+//	AppSecret is known only at the registration, only its hash is stored.
 type RegisteredApp struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	AppName   string    `json:"app_name" db:"app_name"`
-	AppSecret string    `json:"app_secret" db:"app_secret"`
+	ID         uuid.UUID `json:"id" db:"id"`
+	AppName    string    `json:"app_name" db:"app_name"`
+	AppSecret  string    `json:"app_secret,omitempty" db:"-"`
+	SecretHash string    `json:"-" db:"app_secret_hash"`
+	// Scope is the space-delimited list of scopes the app can request
+	Scope string `json:"scope" db:"scope"`
+	// FingerprintPolicy decides what happens to the user access tokens used from another device, see Fingerprint
+	FingerprintPolicy string `json:"fingerprint_policy" db:"fingerprint_policy"`
 }
 
-func (a *RegisteredApp) IsSecretValid(appSecret string) bool {
-	if appSecret == a.AppSecret {
-		return true
+// HasScopes reports whether all the scopes are allowed to the app
+func (a *RegisteredApp) HasScopes(scopes []string) bool {
+	allowed := ParseScope(a.Scope)
+	for _, scope := range scopes {
+		found := false
+		for _, s := range allowed {
+			if s == scope {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
 	}
-
-	return false
+	return true
 }
 //	----	----	----	----	----	----	----	----
 
+// UserToken is the access-refresh token pair.
+//	FamilyID is shared by all pairs issued by rotation from the same sign-in,
+//	ParentID is the pair that was exchanged for this one (0 for the first pair of the family).
 type UserToken struct {
-	ID                  int       `db:"id"`
-	UserID              int       `db:"user_id"`
-	AccessToken         string    `db:"access_token"`
-	RefreshToken        string    `db:"refresh_token"`
-	IssueTokenTimestamp time.Time `db:"issue_token_timestamp"`
-	StartTimestamp      time.Time `db:"start_timestamp"`
-	ExpirationTimestamp time.Time `db:"expiration_timestamp"`
-	LogoutTimestamp     time.Time `db:"exit_timestamp"`
+	ID                  int        `db:"id"`
+	UserID              int        `db:"user_id"`
+	SessionID           int        `db:"session_id"`
+	FamilyID            uuid.UUID  `db:"family_id"`
+	ParentID            int        `db:"parent_id"`
+	AccessToken         string     `db:"access_token"`
+	RefreshToken        string     `db:"refresh_token"`
+	IssueTokenTimestamp time.Time  `db:"issue_token_timestamp"`
+	StartTimestamp      time.Time  `db:"start_timestamp"`
+	ExpirationTimestamp time.Time  `db:"expiration_timestamp"`
+	LogoutTimestamp     time.Time  `db:"exit_timestamp"`
+	UsedAt              *time.Time `db:"used_at"`
 }
 
 func (t *UserToken) Valid() bool {
 	now := time.Now()
-	if now.After(t.IssueTokenTimestamp) ||
-		now.After(t.StartTimestamp) ||
-		now.Before(t.ExpirationTimestamp) {
-		return true
+	if now.Before(t.StartTimestamp) ||
+		now.After(t.ExpirationTimestamp) {
+		return false
 	}
 
-	return false
+	return true
+}
+
+// IsUsed returns true if the refresh token was already exchanged for a new pair
+func (t *UserToken) IsUsed() bool {
+	return t.UsedAt != nil
 }
 
 type UAccessTokenClaims struct {
-	UserID int    `json:"user_id"`
+	UserID    int `json:"user_id"`
+	SessionID int `json:"sid,omitempty"`
+	// ImpersonatorID is the administrator that acts as the user, see Impersonation
+	ImpersonatorID int `json:"impersonator_id,omitempty"`
+	// Fingerprint is the hash of the fingerprint of the session, see Fingerprint.Hash
+	Fingerprint string `json:"fgp,omitempty"`
 	//Exp    int64  `json:"exp"`
 	jwt.StandardClaims
 }
@@ -84,4 +140,260 @@ func (t *UAccessTokenClaims) Validate() error {
 		return ErrAccessTokenIsExpired
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+//	----	----	----	----	----	----	----	----
+
+// UserSession is a sign-in of the user from one device.
+//	Every session has its own chain of access-refresh tokens,
+//	so the user can be logged out from a single device.
+type UserSession struct {
+	ID         int        `json:"id" db:"id"`
+	UserID     int        `json:"user_id" db:"user_id"`
+	DeviceName string     `json:"device_name" db:"device_name"`
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+	IP         string     `json:"ip" db:"ip"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	// ImpersonatorID is set for the sessions opened by the administrator on behalf of the user
+	ImpersonatorID *int `json:"impersonator_id,omitempty" db:"impersonator_id"`
+	// ExpiresAt is set for the impersonation sessions, they end together with their access token
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+}
+
+func (s *UserSession) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && (s.ExpiresAt == nil || now.Before(*s.ExpiresAt))
+}
+
+func (s *UserSession) IsImpersonated() bool {
+	return s.ImpersonatorID != nil
+}
+
+// Impersonation is the short-lived access token the administrator uses to see the service as the user.
+//	The token has no refresh token and works only for reading, unless the writes are allowed by the config.
+type Impersonation struct {
+	AccessToken    string    `json:"access_token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	UserID         int       `json:"user_id"`
+	ImpersonatorID int       `json:"impersonator_id"`
+	SessionID      int       `json:"session_id"`
+}
+
+//	----	----	----	----	----	----	----	----
+
+// PersonalTokenPrefix starts all personal access tokens, so they aren't confused with JWT access tokens
+const PersonalTokenPrefix = "utp_"
+
+// Scopes of personal access tokens
+const (
+	TokenScopeTasksRead   = "tasks:read"
+	TokenScopeTasksWrite  = "tasks:write"
+	TokenScopeGroupsRead  = "groups:read"
+	TokenScopeGroupsWrite = "groups:write"
+	TokenScopeUsersRead   = "users:read"
+)
+
+// TokenScopes are all scopes a personal access token can be granted
+var TokenScopes = []string{TokenScopeTasksRead, TokenScopeTasksWrite, TokenScopeGroupsRead, TokenScopeGroupsWrite, TokenScopeUsersRead}
+
+// PersonalToken is the long-lived access token the user creates for scripts and bots.
+//	The token works only for the requests allowed by its scope. Only the hash of the token is stored.
+type PersonalToken struct {
+	ID         int        `json:"id" db:"id"`
+	UserID     int        `json:"-" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	Scope      string     `json:"scope" db:"scope"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"-" db:"revoked_at"`
+}
+
+func (t *PersonalToken) Valid(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+func (t *PersonalToken) HasScope(scope string) bool {
+	for _, s := range ParseScope(t.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *PersonalToken) Validate() error {
+	return validation.ValidateStruct(
+		t,
+		validation.Field(&t.Name, validation.Required, validation.RuneLength(1, 64)),
+		validation.Field(&t.Scope, validation.Required),
+	)
+}
+
+//	----	----	----	----	----	----	----	----
+
+// SigningKey is a key of the access token key ring.
+//	The newest key without RetiredAt signs new tokens, the retired keys
+//	only verify the tokens issued before the rotation until RetiredAt.
+type SigningKey struct {
+	ID         string     `db:"kid"`
+	Algorithm  string     `db:"algorithm"`
+	PrivateKey string     `db:"private_key"` //PEM encoded PKCS #8 key
+	CreatedAt  time.Time  `db:"created_at"`
+	RetiredAt  *time.Time `db:"retired_at"`
+}
+
+func (k *SigningKey) IsActive() bool {
+	return k.RetiredAt == nil
+}
+
+// CanVerify returns true if the tokens signed by the key are still accepted
+func (k *SigningKey) CanVerify(now time.Time) bool {
+	return k.RetiredAt == nil || k.RetiredAt.After(now)
+}
+
+// JSONWebKey is the public part of the signing key as defined in RFC 7517
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	//RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	//Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+//	----	----	----	----	----	----	----	----
+
+const (
+	VerificationEmailConfirmation = "email_confirmation"
+	VerificationPasswordReset     = "password_reset"
+	VerificationMFAChallenge      = "mfa_challenge"
+)
+
+// VerificationToken is a single-use token sent to the user by email.
+//	Only the SHA-256 hash of the token is stored.
+type VerificationToken struct {
+	ID        int        `db:"id"`
+	UserID    int        `db:"user_id"`
+	Purpose   string     `db:"purpose"`
+	TokenHash string     `db:"token_hash"`
+	Email     string     `db:"email"`
+	CreatedAt time.Time  `db:"created_at"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	Attempts  int        `db:"attempts"`
+}
+
+func (t *VerificationToken) Valid(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
+
+//	----	----	----	----	----	----	----	----
+
+// UserTOTP is the TOTP secret of the user.
+//	Two-factor authentication is enabled only after the first code is verified.
+//	LastUsedStep prevents the reuse of the same code.
+type UserTOTP struct {
+	UserID       int        `db:"user_id"`
+	Secret       string     `db:"secret"`
+	CreatedAt    time.Time  `db:"created_at"`
+	ConfirmedAt  *time.Time `db:"confirmed_at"`
+	LastUsedStep int64      `db:"last_used_step"`
+}
+
+func (t *UserTOTP) IsEnabled() bool {
+	return t.ConfirmedAt != nil
+}
+
+// TwoFactorEnrollment is shown to the user once, when the 2FA is being enabled
+type TwoFactorEnrollment struct {
+	Secret        string   `json:"secret"`
+	URI           string   `json:"otpauth_uri"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAChallenge is returned by the sign-in instead of the token pair, if the user has 2FA enabled.
+//	The token is exchanged for the token pair together with the TOTP or recovery code.
+type MFAChallenge struct {
+	Token     string    `json:"challenge_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type MFASignIn struct {
+	ChallengeToken string
+	Code           string
+	DeviceName     string
+	UserAgent      string
+	IP             string
+}
+
+//	----	----	----	----	----	----	----	----
+
+// LoginAttempts counts failed sign-in attempts of the account or of the IP address.
+//	Key is "user:<id>" or "ip:<address>".
+type LoginAttempts struct {
+	Key           string     `db:"key"`
+	Failures      int        `db:"failures"`
+	LastFailureAt time.Time  `db:"last_failure_at"`
+	LockedUntil   *time.Time `db:"locked_until"`
+}
+
+func (a *LoginAttempts) IsLocked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
+
+//	----	----	----	----	----	----	----	----
+
+// ExternalIdentity links the subject of the OpenID Connect provider to the user.
+//	Subjects are unique only within the issuer.
+type ExternalIdentity struct {
+	ID          int        `json:"id" db:"id"`
+	UserID      int        `json:"-" db:"user_id"`
+	Issuer      string     `json:"issuer" db:"issuer"`
+	Subject     string     `json:"subject" db:"subject"`
+	Email       string     `json:"email" db:"email"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at" db:"last_login_at"`
+}
+
+// OIDCLoginState is kept between the redirect to the provider and the callback.
+//	Only the hash of the state is stored, the code verifier and the nonce never leave the server.
+type OIDCLoginState struct {
+	ID           int        `db:"id"`
+	StateHash    string     `db:"state_hash"`
+	CodeVerifier string     `db:"code_verifier"`
+	Nonce        string     `db:"nonce"`
+	CreatedAt    time.Time  `db:"created_at"`
+	ExpiresAt    time.Time  `db:"expires_at"`
+	UsedAt       *time.Time `db:"used_at"`
+}
+
+func (s *OIDCLoginState) Valid(now time.Time) bool {
+	return s.UsedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// OIDCAuthorization is the provider URL the client redirects the user to
+type OIDCAuthorization struct {
+	URL       string    `json:"authorization_url"`
+	State     string    `json:"state"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// OIDCSignIn is the code and the state the provider redirected back with
+type OIDCSignIn struct {
+	Code       string
+	State      string
+	DeviceName string
+	UserAgent  string
+	IP         string
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation"
+	"time"
+)
+
+// Nodes of the org tree of the university: university -> faculty -> department.
+//	The groups and the subjects are attached to the departments
+const (
+	OrgNodeUniversity = "university"
+	OrgNodeFaculty    = "faculty"
+	OrgNodeDepartment = "department"
+)
+
+// OrgNode is the node of the org tree of the university, e.g. to list the groups of all departments of the faculty
+type OrgNode struct {
+	Type string
+	ID   int
+}
+
+type Faculty struct {
+	ID           int       `json:"id" db:"id"`
+	UniversityID int       `json:"university_id" db:"university_id"`
+	Name         string    `json:"name" db:"name"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+func (f *Faculty) Validate() error {
+	return validation.ValidateStruct(f,
+		validation.Field(&f.Name, validation.Required, validation.RuneLength(2, 128)))
+}
+
+type Department struct {
+	ID        int       `json:"id" db:"id"`
+	FacultyID int       `json:"faculty_id" db:"faculty_id"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+func (d *Department) Validate() error {
+	return validation.ValidateStruct(d,
+		validation.Field(&d.Name, validation.Required, validation.RuneLength(2, 128)))
+}
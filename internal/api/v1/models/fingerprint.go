@@ -0,0 +1,79 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Policies of the registered apps for the user access tokens used from another device
+const (
+	// FingerprintPolicyOff doesn't check the fingerprint
+	FingerprintPolicyOff = "off"
+	// FingerprintPolicyWarn accepts the token and writes the warning to the log
+	FingerprintPolicyWarn = "warn"
+	// FingerprintPolicyEnforce rejects the token
+	FingerprintPolicyEnforce = "enforce"
+)
+
+var FingerprintPolicies = []string{FingerprintPolicyOff, FingerprintPolicyWarn, FingerprintPolicyEnforce}
+
+// Device types of the fingerprint
+const (
+	DeviceTypeMobile  = "mobile"
+	DeviceTypeTablet  = "tablet"
+	DeviceTypePC      = "pc"
+	DeviceTypeUnknown = "unknown"
+)
+
+// Fingerprint is the coarse description of the device the request came from.
+//	Network is the IP address masked to the prefix, so it doesn't change in the same provider's network.
+//	The access token carries the hash of the fingerprint of the session it was issued for.
+type Fingerprint struct {
+	Network    string
+	DeviceType string
+}
+
+// NewFingerprint returns the fingerprint of the IP and the User-Agent.
+//	The IPv4 and IPv6 addresses are masked to the prefixes of the given length.
+func NewFingerprint(ip, userAgent string, ipv4Prefix, ipv6Prefix int) Fingerprint {
+	return Fingerprint{
+		Network:    maskIP(ip, ipv4Prefix, ipv6Prefix),
+		DeviceType: DeviceType(userAgent),
+	}
+}
+
+// Hash binds the fingerprint to the session
+func (f Fingerprint) Hash(sessionID int) string {
+	sum := sha256.Sum256([]byte(strconv.Itoa(sessionID) + "|" + f.Network + "|" + f.DeviceType))
+	return hex.EncodeToString(sum[:])
+}
+
+// DeviceType detects the type of the device by the User-Agent
+func DeviceType(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case ua == "":
+		return DeviceTypeUnknown
+	case strings.Contains(ua, "ipad") || strings.Contains(ua, "tablet"):
+		return DeviceTypeTablet
+	case strings.Contains(ua, "mobile") || strings.Contains(ua, "android") || strings.Contains(ua, "iphone"):
+		return DeviceTypeMobile
+	default:
+		return DeviceTypePC
+	}
+}
+
+func maskIP(ip string, ipv4Prefix, ipv6Prefix int) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(ipv4Prefix, 8*net.IPv4len)).String()
+	}
+	return parsed.Mask(net.CIDRMask(ipv6Prefix, 8*net.IPv6len)).String()
+}
@@ -0,0 +1,30 @@
+package models_test
+
+import (
+	"backend/internal/api/v1/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewFingerprint(t *testing.T) {
+	f := models.NewFingerprint("192.168.1.10", "Mozilla/5.0 (Linux; Android 11) Mobile Safari/537.36", 16, 48)
+	assert.Equal(t, "192.168.0.0", f.Network)
+	assert.Equal(t, models.DeviceTypeMobile, f.DeviceType)
+
+	f = models.NewFingerprint("2001:db8:1234:5678::1", "Mozilla/5.0 (iPad; CPU OS 14_7 like Mac OS X)", 16, 48)
+	assert.Equal(t, "2001:db8:1234::", f.Network)
+	assert.Equal(t, models.DeviceTypeTablet, f.DeviceType)
+
+	f = models.NewFingerprint("not an ip", "", 16, 48)
+	assert.Equal(t, "", f.Network)
+	assert.Equal(t, models.DeviceTypeUnknown, f.DeviceType)
+
+	assert.Equal(t, models.DeviceTypePC, models.DeviceType("Mozilla/5.0 (Windows NT 10.0; Win64; x64)"))
+}
+
+func TestFingerprint_Hash(t *testing.T) {
+	f := models.NewFingerprint("192.168.1.10", "curl/7.68.0", 16, 48)
+	assert.Equal(t, f.Hash(1), models.NewFingerprint("192.168.200.1", "curl/7.68.0", 16, 48).Hash(1))
+	assert.NotEqual(t, f.Hash(1), f.Hash(2))
+	assert.NotEqual(t, f.Hash(1), models.NewFingerprint("10.0.0.1", "curl/7.68.0", 16, 48).Hash(1))
+}
@@ -4,9 +4,22 @@ import (
 	"errors"
 	"fmt"
 	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
 	"time"
 )
 
+// Modes of joining the group
+const (
+	// GroupJoinModeOpen lets the users with an invite join the group at once
+	GroupJoinModeOpen = "open"
+	// GroupJoinModeApproval lets the users request to join the group, the request is approved by the owner or the headman
+	GroupJoinModeApproval = "approval"
+	// GroupJoinModeClosed doesn't let the users join the group with invites or requests
+	GroupJoinModeClosed = "closed"
+)
+
+var GroupJoinModes = []interface{}{GroupJoinModeOpen, GroupJoinModeApproval, GroupJoinModeClosed}
+
 type Group struct {
 	ID           int    `json:"id" db:"id"`
 	UniversityID int    `json:"university_id" db:"university_id"`
@@ -19,13 +32,43 @@ type Group struct {
 	StartYear          string    `json:"start_year" db:"start_year"`
 	CourseNumber       int       `json:"course_number" db:"course_number"`
 	GroupNumber        string    `json:"-" db:"group_number"`
+	JoinMode           string    `json:"join_mode" db:"join_mode"`
 	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+	// ArchivedAt is set, when the group graduates on the academic year rollover or is archived by the owner.
+	//The archived groups are read-only
+	ArchivedAt *time.Time `json:"archived_at,omitempty" db:"archived_at"`
+	// DeletedAt is set, when the group is deleted. The deleted group is restored by an administrator
+	//or purged after the retention
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	// DepartmentID is the department of the university the group is attached to
+	DepartmentID *int `json:"department_id,omitempty" db:"department_id"`
+}
+
+// ArchivedGroupPermissions are the permissions the members have in the archived group.
+//	The archived group is read, but not changed, the owner unarchives or deletes it.
+var ArchivedGroupPermissions = []string{
+	PermissionGroupRead,
+	PermissionGroupMembersRead,
+	PermissionTaskRead,
+	PermissionGroupDelete,
+}
+
+// IsArchivedGroupPermission checks whether the permission is granted in the archived group
+func IsArchivedGroupPermission(permission string) bool {
+	for _, p := range ArchivedGroupPermissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (g *Group) Validate() error {
 	return validation.ValidateStruct(
 		g,
 		validation.Field(&g.SpecializationName, validation.Required, validation.RuneLength(2, 16)),
+		validation.Field(&g.JoinMode, validation.In(GroupJoinModes...)),
 	)
 
 }
@@ -37,16 +80,63 @@ func (g *Group) CompileFullGroupNameAndCompareCustom() {
 	}
 }
 
+var ErrEmptyUpdate = errors.New("update structure has no values")
+
+// MaxCourseNumber is the last course of the longest study programme
+const MaxCourseNumber = 6
+
+// UpdateGroup is the partial update of the group, the nil fields are left unchanged
 type UpdateGroup struct {
-	UniversityID *int
-	Name         *string
+	UniversityID       *int    `json:"university_id"`
+	CustomName         *string `json:"custom_name"`
+	SpecializationName *string `json:"specialization_name"`
+	StartYear          *string `json:"start_year"`
+	CourseNumber       *int    `json:"course_number"`
+	GroupNumber        *string `json:"group_number"`
+	JoinMode           *string `json:"join_mode"`
 }
 
 func (up *UpdateGroup) Validate() error {
-	if up.UniversityID == nil && up.Name == nil {
-		return errors.New("update structure has no values")
+	if up.UniversityID == nil && up.CustomName == nil && up.SpecializationName == nil &&
+		up.StartYear == nil && up.CourseNumber == nil && up.GroupNumber == nil && up.JoinMode == nil {
+		return ErrEmptyUpdate
 	}
-	return nil
+	return validation.ValidateStruct(
+		up,
+		validation.Field(&up.UniversityID, validation.NilOrNotEmpty),
+		validation.Field(&up.CustomName, validation.RuneLength(0, 64)),
+		validation.Field(&up.SpecializationName, validation.NilOrNotEmpty, validation.RuneLength(2, 16)),
+		validation.Field(&up.StartYear, validation.NilOrNotEmpty, is.Digit, validation.RuneLength(4, 4)),
+		validation.Field(&up.CourseNumber, validation.NilOrNotEmpty, validation.Min(1), validation.Max(MaxCourseNumber)),
+		validation.Field(&up.GroupNumber, validation.RuneLength(0, 8)),
+		validation.Field(&up.JoinMode, validation.NilOrNotEmpty, validation.In(GroupJoinModes...)),
+	)
+}
+
+// Apply sets the changed fields of the group and compiles its full name
+func (up *UpdateGroup) Apply(g *Group) {
+	if up.UniversityID != nil {
+		g.UniversityID = *up.UniversityID
+	}
+	if up.CustomName != nil {
+		g.CustomName = *up.CustomName
+	}
+	if up.SpecializationName != nil {
+		g.SpecializationName = *up.SpecializationName
+	}
+	if up.StartYear != nil {
+		g.StartYear = *up.StartYear
+	}
+	if up.CourseNumber != nil {
+		g.CourseNumber = *up.CourseNumber
+	}
+	if up.GroupNumber != nil {
+		g.GroupNumber = *up.GroupNumber
+	}
+	if up.JoinMode != nil {
+		g.JoinMode = *up.JoinMode
+	}
+	g.CompileFullGroupNameAndCompareCustom()
 }
 
 type University struct {
@@ -57,19 +147,162 @@ type University struct {
 	AddedAt  time.Time `json:"added_at" db:"added_at"`
 }
 
+func (u *University) Validate() error {
+	return validation.ValidateStruct(u,
+		validation.Field(&u.Name, validation.Required, validation.RuneLength(2, 256)),
+		validation.Field(&u.Site, is.URL))
+}
+
 type GroupMember struct {
 	ID      int
 	UserID  int
 	GroupID int
 }
 
+// GroupInvite is the link the users join the group with.
+//	The invite isn't limited in uses, if MaxUses is zero.
+//	The users joined with the invite get DefaultRole, or the member role, if it's empty.
 type GroupInvite struct {
-	GroupID    int       `json:"group_id" db:"group_id"`
-	InviterID  int       `json:"inviter_id" db:"inviter_id"`
-	InviteHash string    `json:"invite_hash" db:"hash"`
-	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	ID          int        `json:"id" db:"id"`
+	GroupID     int        `json:"group_id" db:"group_id"`
+	InviterID   int        `json:"inviter_id" db:"inviter_id"`
+	Name        string     `json:"name" db:"name"`
+	InviteHash  string     `json:"invite_hash" db:"hash"`
+	MaxUses     int        `json:"max_uses" db:"max_uses"`
+	Uses        int        `json:"uses" db:"uses"`
+	DefaultRole string     `json:"default_role,omitempty" db:"default_role"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// Valid reports whether the users can join with the invite
+func (gi *GroupInvite) Valid(now time.Time) bool {
+	return gi.RevokedAt == nil && now.Before(gi.ExpiresAt) && (gi.MaxUses == 0 || gi.Uses < gi.MaxUses)
 }
 
 func (gi *GroupInvite) Validate() error {
-	return errors.New("not implemented")
+	return validation.ValidateStruct(
+		gi,
+		validation.Field(&gi.Name, validation.RuneLength(0, 64)),
+		validation.Field(&gi.MaxUses, validation.Min(0)),
+	)
+}
+
+// Statuses of the join requests
+const (
+	JoinRequestStatusPending  = "pending"
+	JoinRequestStatusApproved = "approved"
+	JoinRequestStatusRejected = "rejected"
+)
+
+var JoinRequestStatuses = []string{JoinRequestStatusPending, JoinRequestStatusApproved, JoinRequestStatusRejected}
+
+// GroupJoinRequest is the request of the user to join the group in the approval mode.
+//	DecidedByID and DecidedAt are set, when the request is approved or rejected.
+type GroupJoinRequest struct {
+	ID          int        `json:"id" db:"id"`
+	GroupID     int        `json:"group_id" db:"group_id"`
+	UserID      int        `json:"user_id" db:"user_id"`
+	Message     string     `json:"message" db:"message"`
+	Status      string     `json:"status" db:"status"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	DecidedByID *int       `json:"decided_by_id,omitempty" db:"decided_by_id"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty" db:"decided_at"`
+}
+
+func (jr *GroupJoinRequest) Validate() error {
+	return validation.ValidateStruct(
+		jr,
+		validation.Field(&jr.Message, validation.RuneLength(0, 500)),
+	)
+}
+
+// Statuses of the rows of the member import
+const (
+	// MemberImportRowAdded is the row of the user that is added to the group
+	MemberImportRowAdded = "added"
+	// MemberImportRowSkipped is the row of the user that is already a member or is repeated in the file
+	MemberImportRowSkipped = "skipped"
+	// MemberImportRowFailed is the row that can't be imported, Reason tells why
+	MemberImportRowFailed = "failed"
+)
+
+// MemberImportRow is the result of the import of one row of the CSV file, Row is counted from 1 without the header.
+//	Created is true, if the placeholder account is created for the row.
+type MemberImportRow struct {
+	Row      int    `json:"row"`
+	Login    string `json:"login,omitempty"`
+	Email    string `json:"email"`
+	FullName string `json:"full_name,omitempty"`
+	Status   string `json:"status"`
+	UserID   int    `json:"user_id,omitempty"`
+	Created  bool   `json:"created,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// MemberImportReport is the per-row report of the member import. Nothing is written, if DryRun is true.
+type MemberImportReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Added   int               `json:"added"`
+	Created int               `json:"created"`
+	Skipped int               `json:"skipped"`
+	Failed  int               `json:"failed"`
+	Rows    []MemberImportRow `json:"rows"`
+}
+
+// AddRow appends the row to the report and counts it by its status
+func (r *MemberImportReport) AddRow(row MemberImportRow) {
+	switch row.Status {
+	case MemberImportRowAdded:
+		r.Added++
+		if row.Created {
+			r.Created++
+		}
+	case MemberImportRowSkipped:
+		r.Skipped++
+	case MemberImportRowFailed:
+		r.Failed++
+	}
+
+	r.Rows = append(r.Rows, row)
+}
+
+// GroupRollover is the move of the groups of the university to the next course at the start of the academic year.
+//	AcademicYear is the year the academic year starts in, the groups of the university are rolled over once a year.
+//	RolledByID is nil, if the rollover was done by the scheduler.
+type GroupRollover struct {
+	ID           int       `json:"id" db:"id"`
+	UniversityID int       `json:"university_id" db:"university_id"`
+	AcademicYear int       `json:"academic_year" db:"academic_year"`
+	RolledByID   *int      `json:"rolled_by_id,omitempty" db:"rolled_by_id"`
+	Advanced     int       `json:"advanced" db:"advanced"`
+	Archived     int       `json:"archived" db:"archived"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// GroupNameHistory is the name the group had before the rollover to the AcademicYear
+type GroupNameHistory struct {
+	ID           int       `json:"id" db:"id"`
+	GroupID      int       `json:"group_id" db:"group_id"`
+	FullName     string    `json:"full_name" db:"full_name"`
+	CustomName   string    `json:"custom_name,omitempty" db:"custom_name"`
+	CourseNumber int       `json:"course_number" db:"course_number"`
+	AcademicYear int       `json:"academic_year" db:"academic_year"`
+	ChangedAt    time.Time `json:"changed_at" db:"changed_at"`
+}
+
+// AcademicYear returns the year the academic year of the moment starts in.
+//	The academic year starts on the day of the month, e.g. on the 1st of September.
+func AcademicYear(t time.Time, month time.Month, day int) int {
+	if t.Before(AcademicYearStart(t.Year(), month, day, t.Location())) {
+		return t.Year() - 1
+	}
+
+	return t.Year()
+}
+
+// AcademicYearStart returns the first moment of the academic year
+func AcademicYearStart(year int, month time.Month, day int, loc *time.Location) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
 }
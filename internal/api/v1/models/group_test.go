@@ -0,0 +1,14 @@
+package models_test
+
+import (
+	"backend/internal/api/v1/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAcademicYear(t *testing.T) {
+	assert.Equal(t, 2025, models.AcademicYear(time.Date(2026, time.August, 31, 23, 0, 0, 0, time.UTC), time.September, 1))
+	assert.Equal(t, 2026, models.AcademicYear(time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC), time.September, 1))
+	assert.Equal(t, 2026, models.AcademicYear(time.Date(2027, time.January, 15, 0, 0, 0, 0, time.UTC), time.September, 1))
+}
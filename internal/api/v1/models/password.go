@@ -0,0 +1,18 @@
+package models
+
+// Rules of the password policy
+const (
+	PasswordRuleMinLength    = "min_length"
+	PasswordRuleLowercase    = "lowercase"
+	PasswordRuleUppercase    = "uppercase"
+	PasswordRuleDigit        = "digit"
+	PasswordRuleSymbol       = "symbol"
+	PasswordRulePersonalInfo = "personal_info"
+	PasswordRuleBreached     = "breached"
+)
+
+// PasswordPolicyViolation is the rule of the password policy the password doesn't satisfy
+type PasswordPolicyViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
@@ -1,8 +1,58 @@
 package models
 
+const (
+	// RoleScopeGlobal roles are granted to the user in the whole service
+	RoleScopeGlobal = "global"
+	// RoleScopeGroup roles are granted to the member of the group and work only in the group
+	RoleScopeGroup = "group"
+	// RoleScopeFaculty roles are granted to the user in the faculty and work in it and in the groups of its departments
+	RoleScopeFaculty = "faculty"
+
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleOwner     = "owner"
+	RoleHeadman   = "headman"
+	RoleMember    = "member"
+	RoleViewer    = "viewer"
+	// RoleFacultyAdmin manages the departments of the faculty and their groups and subjects
+	RoleFacultyAdmin = "faculty_admin"
+)
+
+// Permissions checked by the service. The list of permissions of the roles is in the service data file
+const (
+	PermissionAdminPanel       = "admin.panel"
+	PermissionAdminUsersManage = "admin.users.manage"
+	PermissionAdminRolesManage = "admin.roles.manage"
+	PermissionAdminLogsRead    = "admin.logs.read"
+	// PermissionAdminUsersImpersonate allows to sign in as another user, see AuthService.Impersonate
+	PermissionAdminUsersImpersonate = "admin.users.impersonate"
+	// PermissionAdminGroupsManage allows to manage the groups of all universities, e.g. to roll them over to the next academic year
+	PermissionAdminGroupsManage = "admin.groups.manage"
+	// PermissionAdminUniversitiesManage allows to create the universities and the faculties and to delegate the faculties
+	PermissionAdminUniversitiesManage = "admin.universities.manage"
+
+	// PermissionFacultyManage allows to manage the departments of the faculty and to attach the groups and the subjects to them
+	PermissionFacultyManage = "faculty.manage"
+
+	PermissionGroupRead          = "group.read"
+	PermissionGroupUpdate        = "group.update"
+	PermissionGroupDelete        = "group.delete"
+	PermissionGroupInvite        = "group.invite"
+	PermissionGroupMembersRead   = "group.members.read"
+	PermissionGroupMembersManage = "group.members.manage"
+	PermissionGroupRolesManage   = "group.roles.manage"
+
+	PermissionTaskCreate = "task.create"
+	PermissionTaskRead   = "task.read"
+	PermissionTaskUpdate = "task.update"
+	PermissionTaskDelete = "task.delete"
+)
+
 type Permission struct {
-	ID   int
-	Name string
+	ID   int    `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
+	// Type is RoleScopeGlobal, RoleScopeGroup or RoleScopeFaculty
+	Type string `json:"type" db:"type"`
 }
 
 type RolePermissions struct {
@@ -15,5 +65,6 @@ type Role struct {
 	ID          int               `db:"id"`
 	Name        string            `db:"name"`
 	Description string            `db:"description"`
+	Scope       string            `db:"scope"`
 	Permissions []RolePermissions `db:"-"`
 }
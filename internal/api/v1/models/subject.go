@@ -7,6 +7,8 @@ import (
 type Subject struct {
 	ID int	`json:"id" db:"id"`
 	Name string `json:"name" db:"name"`
+	// DepartmentID is the department of the university the subject is attached to
+	DepartmentID *int `json:"department_id,omitempty" db:"department_id"`
 }
 
 func (s *Subject) Validate() error {
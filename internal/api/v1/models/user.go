@@ -8,13 +8,14 @@ import (
 )
 
 type User struct {
-	ID                int       `json:"id"`
-	Login             string    `json:"login" binding:"required" db:"login"`
-	FullName          string    `json:"full_name" binding:"required" db:"full_name"`
-	Email             string    `json:"email" binding:"required" db:"email"`
-	Password          string    `json:"password,omitempty"`
-	EncryptedPassword string    `json:"-" db:"encrypted_password"`
-	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+	ID                int        `json:"id"`
+	Login             string     `json:"login" binding:"required" db:"login"`
+	FullName          string     `json:"full_name" binding:"required" db:"full_name"`
+	Email             string     `json:"email" binding:"required" db:"email"`
+	Password          string     `json:"password,omitempty"`
+	EncryptedPassword string     `json:"-" db:"encrypted_password"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	EmailConfirmedAt  *time.Time `json:"email_confirmed_at,omitempty" db:"email_confirmed_at"`
 }
 
 func (u *User) Validate() error {
@@ -26,6 +27,13 @@ func (u *User) Validate() error {
 	)
 }
 
+// ValidatePassword checks the new password of the existing user
+func ValidatePassword(password string) error {
+	return validation.Errors{
+		"password": validation.Validate(password, validation.Required, validation.RuneLength(6, 100)),
+	}.Filter()
+}
+
 func (u *User) BeforeCreate() error {
 	if len(u.Password) > 0 {
 		enc, err := EncryptPassword(u.Password)
@@ -39,6 +47,10 @@ func (u *User) BeforeCreate() error {
 	return nil
 }
 
+func (u *User) IsEmailConfirmed() bool {
+	return u.EmailConfirmedAt != nil
+}
+
 func (u *User) Sanitize() {
 	u.Password = ""
 }
@@ -56,10 +68,12 @@ func EncryptPassword(s string) (string, error) {
 	return string(b), nil
 }
 
-/*// ?
-type UserRegistry struct {
-	PasswordHash [256]string
-}
+/*
+// ?
+
+	type UserRegistry struct {
+		PasswordHash [256]string
+	}
 */
 type UserSignUp struct {
 }
@@ -69,6 +83,7 @@ type UserSignIn struct {
 	Email             string `json:"email"`
 	Password          string `json:"password"`
 	EncryptedPassword string `json:"encrypted_password"`
+	DeviceName        string `json:"device_name"`
 	UserAgent         string `json:"user_agent"`
 	IP                string `json:"ip"`
 }
@@ -1,8 +1,14 @@
 package apiserver
 
 import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -15,8 +21,12 @@ func (s *server) authorizeAdministrator(next http.Handler) http.Handler {
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
 		}
-		// Temporarily: No roles added yet
-		if reqUser.Login != "admin" {
+		allowed, err := s.services.Role().HasPermission(reqUser.ID, models.PermissionAdminPanel)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		if !allowed {
 			s.respondHTML(w, r, http.StatusForbidden, errForbiddenHTML)
 			return
 		}
@@ -25,6 +35,62 @@ func (s *server) authorizeAdministrator(next http.Handler) http.Handler {
 	})
 }
 
+//requirePermission is the middleware.
+//For methods that require the permission. Permissions of group roles are checked in the group from the {id} URL variable
+func (s *server) requirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqUser, err := s.getUserFromContext(r.Context())
+			if err != nil {
+				s.error(w, r, http.StatusInternalServerError, err)
+				return
+			}
+
+			isGroupPermission, err := s.services.Role().IsGroupPermission(permission)
+			if err != nil {
+				s.error(w, r, http.StatusInternalServerError, err)
+				return
+			}
+
+			var allowed bool
+			if isGroupPermission {
+				groupID, err := strconv.Atoi(mux.Vars(r)["id"])
+				if err != nil {
+					s.error(w, r, http.StatusBadRequest, err)
+					return
+				}
+
+				group, err := s.services.Group().Find(groupID)
+				if err == service.ErrGroupNotFound {
+					s.error(w, r, http.StatusNotFound, err)
+					return
+				} else if err != nil {
+					s.error(w, r, http.StatusInternalServerError, err)
+					return
+				}
+				if group.ArchivedAt != nil && !models.IsArchivedGroupPermission(permission) {
+					s.error(w, r, http.StatusConflict, service.ErrGroupIsArchived)
+					return
+				}
+
+				allowed, err = s.services.Role().HasGroupPermission(reqUser.ID, groupID, permission)
+			} else {
+				allowed, err = s.services.Role().HasPermission(reqUser.ID, permission)
+			}
+			if err != nil {
+				s.error(w, r, http.StatusInternalServerError, err)
+				return
+			}
+			if !allowed {
+				s.error(w, r, http.StatusForbidden, service.ErrPermissionDenied)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (s *server) sendStdoutHandler() http.HandlerFunc {
 	type logItem struct {
 		Level   log.Level  `json:"level"`
@@ -50,3 +116,225 @@ func (s *server) sendStdoutHandler() http.HandlerFunc {
 		s.respond(w, r, http.StatusOK, res)
 	}
 }
+
+// handleUnlockUser removes the sign-in lock of the user after too many failed attempts
+func (s *server) handleUnlockUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		err = s.services.Auth().UnlockUser(userID)
+		if err == service.ErrUserNotFound {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// handleAssignUserRole grants the global role to the user
+func (s *server) handleAssignUserRole() http.HandlerFunc {
+	type request struct {
+		Role string `json:"role"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		s.respondRoleChange(w, r, s.services.Role().AssignRole(r.Context(), userID, req.Role))
+	}
+}
+
+// handleRevokeUserRole revokes the global role of the user
+func (s *server) handleRevokeUserRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		URLVars := mux.Vars(r)
+		userID, err := strconv.Atoi(URLVars["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		s.respondRoleChange(w, r, s.services.Role().RevokeRole(r.Context(), userID, URLVars["role"]))
+	}
+}
+
+// handleSetAppFingerprintPolicy sets the policy of the app for the user access tokens used from another device
+func (s *server) handleSetAppFingerprintPolicy() http.HandlerFunc {
+	type request struct {
+		FingerprintPolicy string `json:"fingerprint_policy"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		appID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		err = s.services.Auth().SetAppFingerprintPolicy(r.Context(), appID, req.FingerprintPolicy)
+		if err == service.ErrInvalidFingerprintPolicy {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		} else if err == service.ErrAppNotFound {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+func (s *server) respondRoleChange(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case nil:
+		s.respond(w, r, http.StatusNoContent, nil)
+	case service.ErrRoleNotFound, service.ErrInvalidRoleScope:
+		s.error(w, r, http.StatusBadRequest, err)
+	case service.ErrUserNotFound, service.ErrFacultyNotFound:
+		s.error(w, r, http.StatusNotFound, err)
+	default:
+		s.error(w, r, http.StatusInternalServerError, err)
+	}
+}
+
+// handleUniversityRollover rolls the groups of the university over to the academic year.
+//	The current academic year is rolled over, if academic_year isn't set
+func (s *server) handleUniversityRollover() http.HandlerFunc {
+	type request struct {
+		AcademicYear int `json:"academic_year"`
+	}
+	type response struct {
+		Rollover models.GroupRollover `json:"rollover"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		universityID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil && err != errJSONEOF {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		rollover, err := s.services.Group().Rollover(r.Context(), universityID, req.AcademicYear, user.ID)
+		switch err {
+		case nil:
+			s.respond(w, r, http.StatusOK, response{*rollover})
+		case service.ErrInvalidAcademicYear:
+			s.error(w, r, http.StatusBadRequest, err)
+		case service.ErrUniversityNotFound:
+			s.error(w, r, http.StatusNotFound, err)
+		case service.ErrGroupRolloverIsDone:
+			s.error(w, r, http.StatusConflict, err)
+		default:
+			s.error(w, r, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// handleUniversityRollovers returns the rollovers of the groups of the university, the latest academic year first
+func (s *server) handleUniversityRollovers() http.HandlerFunc {
+	type response struct {
+		Rollovers []models.GroupRollover `json:"rollovers"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		universityID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		rollovers, err := s.services.Group().GetRollovers(universityID)
+		if err == service.ErrUniversityNotFound {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{rollovers})
+	}
+}
+
+// handleDeletedGroups returns the deleted groups, which aren't purged yet
+func (s *server) handleDeletedGroups() http.HandlerFunc {
+	type response struct {
+		Groups []models.Group `json:"groups"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		groups, err := s.services.Group().GetDeletedGroups()
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{groups})
+	}
+}
+
+// handleGroupRestore restores the deleted group within the retention
+func (s *server) handleGroupRestore() http.HandlerFunc {
+	type response struct {
+		Group models.Group `json:"group"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		group, err := s.services.Group().Restore(r.Context(), groupID, user.ID)
+		switch err {
+		case nil:
+			s.respond(w, r, http.StatusOK, response{*group})
+		case service.ErrGroupNotFound:
+			s.error(w, r, http.StatusNotFound, err)
+		case service.ErrGroupIsNotDeleted, service.ErrGroupRestoreExpired:
+			s.error(w, r, http.StatusConflict, err)
+		default:
+			s.error(w, r, http.StatusInternalServerError, err)
+		}
+	}
+}
@@ -1,13 +1,14 @@
 package apiserver
 
 import (
+	"backend/internal/api/v1/models"
 	"errors"
 	"github.com/gorilla/handlers"
 	"net/http"
 )
 
 func (s *server) configureUnauthorizedHandlers() {
-
+	s.router.HandleFunc("/.well-known/jwks.json", s.handleJWKS()).Methods("GET")
 }
 
 func (s *server) configureRouterAPIv1() {
@@ -22,7 +23,9 @@ func (s *server) configureRouterAPIv1() {
 
 	// //=		[__ 	without app authentication		__]
 	s.router.Path("/api/v1/auth/app/register").Handler(s.handleAppRegister()).Methods("POST")
-	s.router.Path("/api/v1/auth/app/token").Handler(s.handleAppAuthorization()).Methods("GET")
+	s.router.Path("/api/v1/auth/app/token").Handler(s.handleAppAuthorization()).Methods("GET") //	deprecated
+	s.router.Path("/oauth/token").Handler(s.handleOAuthToken()).Methods("POST")
+	s.router.Path("/api/v1/account/emailconfimation").Handler(s.handleConfirmEmail()).Methods("GET")
 
 	//<domain>/api
 	api := s.router.PathPrefix("/api").Subrouter()
@@ -36,27 +39,48 @@ func (s *server) configureRouterAPIv1() {
 		// //=		[__ 	app authentication required		__]
 		auth := api.PathPrefix("/v1/auth").Subrouter()
 		{
+			auth.Use(s.requireAppScope(models.AppScopeAuth))
 			auth.HandleFunc("/app/delete", s.handleAppDelete()).Methods("DELETE")
 			auth.HandleFunc("/register", s.handleUserRegister()).Methods("POST")
 			auth.HandleFunc("/login", s.handleUserSignIn()).Methods("POST")
+			auth.HandleFunc("/login/mfa", s.handleMFASignIn()).Methods("POST")
+			auth.HandleFunc("/oidc/authorize", s.handleOIDCAuthorize()).Methods("GET")
+			auth.HandleFunc("/oidc/callback", s.handleOIDCCallback()).Methods("POST")
 			auth.HandleFunc("/token", s.handleUserToken()).Methods("POST")
+			auth.HandleFunc("/password/forgot", s.handlePasswordForgot()).Methods("POST")
+			auth.HandleFunc("/password/reset", s.handlePasswordReset()).Methods("POST")
 		}
 
 		// //=		[__		user authentication required	__]
 		//<domain>/api/v1
 		v1 := api.PathPrefix("/v1").Subrouter()
 		{
+			v1.Use(s.requireAppScope(models.AppScopeAPI))
 			v1.Use(s.authenticateUser)
+			v1.Use(s.restrictImpersonation)
 
 			authAuthenticated := v1.PathPrefix("/auth").Subrouter()
 			{
+				authAuthenticated.Use(s.rejectPersonalToken)
 				authAuthenticated.HandleFunc("/me", s.handleWhoami()).Methods("GET")
 				authAuthenticated.HandleFunc("/logout", s.handleUserLogout()).Methods("GET")
+				authAuthenticated.HandleFunc("/sessions", s.handleUserSessions()).Methods("GET")
+				authAuthenticated.HandleFunc("/sessions/others", s.handleCloseOtherUserSessions()).Methods("DELETE")
+				authAuthenticated.HandleFunc("/sessions/{id:[0-9]+}", s.handleCloseUserSession()).Methods("DELETE")
 			}
 
 			account := v1.PathPrefix("/account").Subrouter()
 			{
-				account.HandleFunc("/emailconfimation", s.handleNotImplemented()).Methods("GET")
+				account.Use(s.rejectPersonalToken)
+				account.HandleFunc("/emailconfimation", s.handleSendEmailConfirmation()).Methods("POST")
+				account.HandleFunc("/password", s.handleChangePassword()).Methods("PUT")
+				account.HandleFunc("/2fa", s.handleEnrollTwoFactor()).Methods("POST")
+				account.HandleFunc("/2fa", s.handleDisableTwoFactor()).Methods("DELETE")
+				account.HandleFunc("/2fa/verify", s.handleVerifyTwoFactor()).Methods("POST")
+				account.HandleFunc("/tokens", s.handlePersonalTokens()).Methods("GET")
+				account.HandleFunc("/tokens", s.handleCreatePersonalToken()).Methods("POST")
+				account.HandleFunc("/tokens/{id:[0-9]+}", s.handleRevokePersonalToken()).Methods("DELETE")
+				account.HandleFunc("/identities", s.handleExternalIdentities()).Methods("GET")
 			}
 
 			////= == == == == == == == == == == == == == == ==//
@@ -65,12 +89,31 @@ func (s *server) configureRouterAPIv1() {
 
 			admin := v1.PathPrefix("/admin").Subrouter()
 			{
+				admin.Use(s.rejectPersonalToken)
 				admin.Use(s.authorizeAdministrator)
 				admin.HandleFunc("/users", s.handleUsers()).Methods("GET")
 				admin.HandleFunc("/groups", s.handleGroups()).Methods("GET")
 				admin.HandleFunc("/tasks", s.handleTasks()).Methods("GET")
 				admin.HandleFunc("/tasksV2", s.handleTasksV2()).Methods("GET")
 				admin.HandleFunc("/logs", s.sendStdoutHandler()).Methods("GET")
+				admin.Handle("/audit",
+					s.requirePermission(models.PermissionAdminLogsRead)(s.handleAuditLog())).Methods("GET")
+				admin.HandleFunc("/users/{id:[0-9]+}/unlock", s.handleUnlockUser()).Methods("POST")
+				admin.HandleFunc("/apps/{id}/fingerprint-policy", s.handleSetAppFingerprintPolicy()).Methods("PUT")
+				admin.Handle("/users/{id:[0-9]+}/impersonate",
+					s.requirePermission(models.PermissionAdminUsersImpersonate)(s.handleImpersonateUser())).Methods("POST")
+				admin.Handle("/users/{id:[0-9]+}/roles",
+					s.requirePermission(models.PermissionAdminRolesManage)(s.handleAssignUserRole())).Methods("POST")
+				admin.Handle("/users/{id:[0-9]+}/roles/{role}",
+					s.requirePermission(models.PermissionAdminRolesManage)(s.handleRevokeUserRole())).Methods("DELETE")
+				admin.Handle("/universities/{id:[0-9]+}/rollover",
+					s.requirePermission(models.PermissionAdminGroupsManage)(s.handleUniversityRollover())).Methods("POST")
+				admin.Handle("/universities/{id:[0-9]+}/rollovers",
+					s.requirePermission(models.PermissionAdminGroupsManage)(s.handleUniversityRollovers())).Methods("GET")
+				admin.Handle("/groups/deleted",
+					s.requirePermission(models.PermissionAdminGroupsManage)(s.handleDeletedGroups())).Methods("GET")
+				admin.Handle("/groups/{id:[0-9]+}/restore",
+					s.requirePermission(models.PermissionAdminGroupsManage)(s.handleGroupRestore())).Methods("POST")
 			}
 
 			////= == == == == == == == == == == == == == == ==//
@@ -78,6 +121,7 @@ func (s *server) configureRouterAPIv1() {
 			////= == == == == == == == == == == == == == == ==//
 			users := v1.PathPrefix("/users").Subrouter()
 			{
+				users.Use(s.requireTokenScope(models.TokenScopeUsersRead))
 				users.HandleFunc("/{id:[0-9]+}", s.handleUser()).Methods("GET")
 			}
 
@@ -85,24 +129,106 @@ func (s *server) configureRouterAPIv1() {
 			//					   GROUPS
 			////= == == == == == == == == == == == == == == ==//
 
+			// Tasks of the group require the task scopes of personal access tokens instead of the group ones
+			groupTasks := v1.PathPrefix("/groups/{id:[0-9]+}/tasks").Subrouter()
+			{
+				groupTasks.Use(s.requireTokenScopeByMethod(models.TokenScopeTasksRead, models.TokenScopeTasksWrite))
+				groupTasks.Handle("/create",
+					s.requirePermission(models.PermissionTaskCreate)(s.handleCreateGroupTask())).Methods("POST")
+				groupTasks.Handle("",
+					s.requirePermission(models.PermissionTaskRead)(s.handleGetGroupTasks())).Methods("GET")
+				groupTasks.Handle("/{taskId:[0-9]+}",
+					s.requirePermission(models.PermissionTaskRead)(s.handleGetGroupTask())).Methods("GET")
+			}
+
 			groups := v1.PathPrefix("/groups").Subrouter()
 			{
+				groups.Use(s.requireTokenScopeByMethod(models.TokenScopeGroupsRead, models.TokenScopeGroupsWrite))
 				//groups.HandleFunc("", s.handleGroups()).Methods("GET")
 				groups.HandleFunc("/{id:[0-9]+}", s.handleGroup()).Methods("GET")
 				groups.HandleFunc("/create", s.handleGroupCreate()).Methods("POST")
-				groups.HandleFunc("/update", s.handleNotImplemented()).Methods("PUT")
-				//	Requires: The user must be a member of the group
-				groups.HandleFunc("/{id:[0-9]+}/delete", s.handleGroupDelete()).Methods("DELETE")
-				groups.HandleFunc("/{id:[0-9]+}/tasks/create", s.handleCreateGroupTask()).Methods("POST")
-				//	Requires: The user must be a member of the group
-				groups.HandleFunc("/{id:[0-9]+}/tasks", s.handleGetGroupTasks()).Methods("GET")
-				groups.HandleFunc("/{id:[0-9]+}/tasks/{taskId:[0-9]+}", s.handleGetGroupTask()).Methods("GET")
-				groups.HandleFunc("/{id:[0-9]+}/members", s.handleGetGroupMembers()).Methods("GET")
-				// Require: The user must be a member of the group
-				groups.HandleFunc("/{id:[0-9]+}/invite/create", s.handleGroupCreateInvitation()).Methods("GET")
+				groups.Handle("/{id:[0-9]+}",
+					s.requirePermission(models.PermissionGroupUpdate)(s.handleGroupUpdate())).Methods("PATCH")
+				groups.Handle("/{id:[0-9]+}/delete",
+					s.requirePermission(models.PermissionGroupDelete)(s.handleGroupDelete())).Methods("DELETE")
+				groups.Handle("/{id:[0-9]+}/archive",
+					s.requirePermission(models.PermissionGroupDelete)(s.handleGroupArchive(true))).Methods("POST")
+				groups.Handle("/{id:[0-9]+}/unarchive",
+					s.requirePermission(models.PermissionGroupDelete)(s.handleGroupArchive(false))).Methods("POST")
+				groups.Handle("/{id:[0-9]+}/members",
+					s.requirePermission(models.PermissionGroupMembersRead)(s.handleGetGroupMembers())).Methods("GET")
+				groups.Handle("/{id:[0-9]+}/members/{userId:[0-9]+}",
+					s.requirePermission(models.PermissionGroupMembersManage)(s.handleGroupMemberRemove())).Methods("DELETE")
+				groups.Handle("/{id:[0-9]+}/members/import",
+					s.requirePermission(models.PermissionGroupMembersManage)(s.handleGroupMembersImport())).Methods("POST")
+				groups.HandleFunc("/{id:[0-9]+}/department", s.handleGroupDepartment()).Methods("PUT")
+				groups.Handle("/{id:[0-9]+}/names",
+					s.requirePermission(models.PermissionGroupRead)(s.handleGroupNameHistory())).Methods("GET")
+				groups.HandleFunc("/{id:[0-9]+}/leave", s.handleGroupLeave()).Methods("POST")
+				groups.Handle("/{id:[0-9]+}/transfer",
+					s.requirePermission(models.PermissionGroupRolesManage)(s.handleGroupTransfer())).Methods("POST")
+				groups.Handle("/{id:[0-9]+}/members/{userId:[0-9]+}/role",
+					s.requirePermission(models.PermissionGroupRolesManage)(s.handleSetGroupMemberRole())).Methods("PUT")
+				groups.Handle("/{id:[0-9]+}/invites",
+					s.requirePermission(models.PermissionGroupInvite)(s.handleGroupInvites())).Methods("GET")
+				groups.Handle("/{id:[0-9]+}/invites",
+					s.requirePermission(models.PermissionGroupInvite)(s.handleGroupInviteCreate())).Methods("POST")
+				groups.Handle("/{id:[0-9]+}/invites/{inviteId:[0-9]+}",
+					s.requirePermission(models.PermissionGroupInvite)(s.handleGroupInviteRevoke())).Methods("DELETE")
 				groups.HandleFunc("/member", s.handleGroupWhereUserIsMember()).Methods("GET")
+				groups.HandleFunc("/join-requests", s.handleUserJoinRequests()).Methods("GET")
+				groups.HandleFunc("/{id:[0-9]+}/join-requests", s.handleGroupJoinRequestCreate()).Methods("POST")
+				groups.Handle("/{id:[0-9]+}/join-requests",
+					s.requirePermission(models.PermissionGroupMembersManage)(s.handleGroupJoinRequests())).Methods("GET")
+				groups.Handle("/{id:[0-9]+}/join-requests/{requestId:[0-9]+}/approve",
+					s.requirePermission(models.PermissionGroupMembersManage)(s.handleGroupJoinRequestDecide(true))).Methods("POST")
+				groups.Handle("/{id:[0-9]+}/join-requests/{requestId:[0-9]+}/reject",
+					s.requirePermission(models.PermissionGroupMembersManage)(s.handleGroupJoinRequestDecide(false))).Methods("POST")
+
+				v1.Handle("/invite/{hash}",
+					s.requireTokenScope(models.TokenScopeGroupsWrite)(s.handleJoinToGroupWithInvite())).Methods("GET")
+			}
 
-				v1.HandleFunc("/invite/{hash}", s.handleJoinToGroupWithInvite()).Methods("GET")
+			////= == == == == == == == == == == == == == == ==//
+			//					   UNIVERSITIES
+			////= == == == == == == == == == == == == == == ==//
+
+			universities := v1.PathPrefix("/universities").Subrouter()
+			{
+				universities.Use(s.requireTokenScopeByMethod(models.TokenScopeGroupsRead, models.TokenScopeGroupsWrite))
+				universities.HandleFunc("", s.handleUniversities()).Methods("GET")
+				universities.Handle("",
+					s.requirePermission(models.PermissionAdminUniversitiesManage)(s.handleUniversityCreate())).Methods("POST")
+				universities.HandleFunc("/{id:[0-9]+}", s.handleUniversity()).Methods("GET")
+				universities.HandleFunc("/{id:[0-9]+}/faculties", s.handleFaculties()).Methods("GET")
+				universities.HandleFunc("/{id:[0-9]+}/faculties", s.handleFacultyCreate()).Methods("POST")
+				universities.HandleFunc("/{id:[0-9]+}/groups", s.handleOrgNodeGroups(models.OrgNodeUniversity)).Methods("GET")
+				universities.HandleFunc("/{id:[0-9]+}/subjects", s.handleOrgNodeSubjects(models.OrgNodeUniversity)).Methods("GET")
+			}
+
+			faculties := v1.PathPrefix("/faculties").Subrouter()
+			{
+				faculties.Use(s.requireTokenScopeByMethod(models.TokenScopeGroupsRead, models.TokenScopeGroupsWrite))
+				faculties.HandleFunc("/{id:[0-9]+}", s.handleFaculty()).Methods("GET")
+				faculties.HandleFunc("/{id:[0-9]+}", s.handleFacultyUpdate()).Methods("PATCH")
+				faculties.HandleFunc("/{id:[0-9]+}", s.handleFacultyDelete()).Methods("DELETE")
+				faculties.HandleFunc("/{id:[0-9]+}/departments", s.handleDepartmentCreate()).Methods("POST")
+				faculties.HandleFunc("/{id:[0-9]+}/groups", s.handleOrgNodeGroups(models.OrgNodeFaculty)).Methods("GET")
+				faculties.HandleFunc("/{id:[0-9]+}/subjects", s.handleOrgNodeSubjects(models.OrgNodeFaculty)).Methods("GET")
+				faculties.Handle("/{id:[0-9]+}/users/{userId:[0-9]+}/roles",
+					s.requirePermission(models.PermissionAdminUniversitiesManage)(s.handleAssignFacultyRole())).Methods("POST")
+				faculties.Handle("/{id:[0-9]+}/users/{userId:[0-9]+}/roles/{role}",
+					s.requirePermission(models.PermissionAdminUniversitiesManage)(s.handleRevokeFacultyRole())).Methods("DELETE")
+			}
+
+			departments := v1.PathPrefix("/departments").Subrouter()
+			{
+				departments.Use(s.requireTokenScopeByMethod(models.TokenScopeGroupsRead, models.TokenScopeGroupsWrite))
+				departments.HandleFunc("/{id:[0-9]+}", s.handleDepartment()).Methods("GET")
+				departments.HandleFunc("/{id:[0-9]+}", s.handleDepartmentUpdate()).Methods("PATCH")
+				departments.HandleFunc("/{id:[0-9]+}", s.handleDepartmentDelete()).Methods("DELETE")
+				departments.HandleFunc("/{id:[0-9]+}/groups", s.handleOrgNodeGroups(models.OrgNodeDepartment)).Methods("GET")
+				departments.HandleFunc("/{id:[0-9]+}/subjects", s.handleOrgNodeSubjects(models.OrgNodeDepartment)).Methods("GET")
 			}
 
 			////= == == == == == == == == == == == == == == ==//
@@ -111,10 +237,12 @@ func (s *server) configureRouterAPIv1() {
 
 			subjects := v1.PathPrefix("/subjects").Subrouter()
 			{
+				subjects.Use(s.requireTokenScopeByMethod(models.TokenScopeTasksRead, models.TokenScopeTasksWrite))
 				subjects.HandleFunc("", s.handleSubjects()).Methods("GET")
 				subjects.HandleFunc("/{id:[0-9]+}", s.handleSubject()).Methods("GET")
 				subjects.HandleFunc("/create", s.handleSubjectCreate()).Methods("POST")
 				subjects.HandleFunc("/delete/{id:[0-9]+}", s.handleDeleteSubject()).Methods("DELETE")
+				subjects.HandleFunc("/{id:[0-9]+}/department", s.handleSubjectDepartment()).Methods("PUT")
 			}
 
 			////= == == == == == == == == == == == == == == ==//
@@ -123,6 +251,7 @@ func (s *server) configureRouterAPIv1() {
 
 			tasks := v1.PathPrefix("/tasks").Subrouter()
 			{
+				tasks.Use(s.requireTokenScopeByMethod(models.TokenScopeTasksRead, models.TokenScopeTasksWrite))
 				tasks.HandleFunc("", s.handleGetAllUserTasks()).Methods("GET")
 				tasks.HandleFunc("/{id:[0-9]+}", s.handleGetTask()).Methods("GET")
 				tasks.HandleFunc("/personal", s.handleGetUserTasks()).Methods("GET")
@@ -165,37 +294,97 @@ func (s *server) handleHealth() http.HandlerFunc {
 
 	[__ without app authentication		__]
 
+	/.well-known/jwks.json GET
+	/oauth/token POST		//grant_type=client_credentials, scope "auth api"
 	/api/v1/auth/app/register
-	/api/v1/auth/app/token?app_uuid=$ & app_secret
+	/api/v1/auth/app/token?app_uuid=$ & app_secret	//deprecated, see auth.oauth.legacyAppTokenEnabled
 	/api/v1/auth/app/delete
+	/api/v1/account/emailconfimation?token=$ GET
 
-	[__ app authentication required		__]
+	[__ app authentication required		__]	//X-App-Token, the "auth" scope
 
 	/api/v1/auth/token POST		//if authenticated
 	/api/vi/auth/refresh_token POST
 	/api/v1/auth/register
-	/api/v1/auth/login
+	/api/v1/auth/login				//returns the challenge instead of tokens, if 2FA is enabled
+	/api/v1/auth/login/mfa POST
+	/api/v1/auth/oidc/authorize GET		//the provider URL and the state, see auth.oidc
+	/api/v1/auth/oidc/callback POST		//code and state from the provider redirect, returns tokens as /login
+	/api/v1/auth/password/forgot POST
+	/api/v1/auth/password/reset POST
 
-	[__	user authentication required	__]
+	/api/v1/auth/sessions GET
+	/api/v1/auth/sessions/{id} DELETE
+	/api/v1/auth/sessions/others DELETE
+
+	[__	user authentication required	__]	//the "api" scope of the app token
 
 	/api/v1/users
 
 	/api/v1/account
-	/api/v1/account/emailconfirmation
+	/api/v1/account/emailconfimation POST	//sends the link again
+	/api/v1/account/password PUT
+	/api/v1/account/2fa POST		//enrolment: secret, otpauth URI, recovery codes
+	/api/v1/account/2fa/verify POST
+	/api/v1/account/2fa DELETE
+	/api/v1/account/tokens GET POST		//personal access tokens, not accepted by /account, /auth and /admin
+	/api/v1/account/tokens/{id} DELETE
+	/api/v1/account/identities GET		//linked identity provider accounts
+
+	/api/v1/admin/users/{id}/unlock POST	//removes the sign-in lock
+	/api/v1/admin/users/{id}/impersonate POST	//the short-lived read-only access token of the user, see auth.impersonation
+	/api/v1/admin/users/{id}/roles POST		//grants the global role
+	/api/v1/admin/users/{id}/roles/{role} DELETE
+	/api/v1/admin/apps/{id}/fingerprint-policy PUT	//off, warn or enforce for the access tokens used from another device
+	/api/v1/admin/audit GET		//the audit log, filters and the cursor in the query, see audit.retention
+	/api/v1/admin/universities/{id}/rollover POST	//moves the groups to the next course once per academic year, see groups.rollover
+	/api/v1/admin/universities/{id}/rollovers GET
+	/api/v1/admin/groups/deleted GET	//the deleted groups, which aren't purged yet, see groups.deletion
+	/api/v1/admin/groups/{id}/restore POST	//restores the deleted group within the retention
 
 	/api/v1/groups
 	/api/v1/group
 	/api/v1/group/{id}
 	/api/v1/group/create
-	/api/v1/group/update
+	/api/v1/groups/{id} PATCH	//owner, headman; join_mode: open, approval or closed
 	/api/v1/group/delete
+	/api/v1/groups/{id}/archive POST	//owner; the archived group is read-only
+	/api/v1/groups/{id}/unarchive POST
 	/api/v1/group/tasks
+	/api/v1/groups/{id}/members/{userId}/role PUT	//owner only
+	/api/v1/groups/{id}/members/{userId} DELETE	//owner, headman
+	/api/v1/groups/{id}/members/import POST	//owner, headman; CSV with login, email, full_name; ?create_accounts=true&dry_run=true
+	/api/v1/groups/member GET	//the groups of the user, ?archived=true includes the archived ones
+	/api/v1/groups/{id}/names GET	//the names of the group before the academic year rollovers
+	/api/v1/groups/{id}/department PUT	//faculty admin; attaches the group to the department, null detaches it
+	/api/v1/groups/{id}/leave POST
+	/api/v1/groups/{id}/transfer POST	//owner only
+	/api/v1/groups/{id}/invites GET POST	//owner, headman; max uses, expiry and the default role, see groups.invites
+	/api/v1/groups/{id}/invites/{inviteId} DELETE	//revokes the invite
+	/api/v1/invite/{hash} GET	//joins the group in the open mode
+	/api/v1/groups/join-requests GET	//the join requests of the user with their statuses
+	/api/v1/groups/{id}/join-requests POST	//requests to join the group in the approval mode
+	/api/v1/groups/{id}/join-requests GET	//owner, headman; ?status=pending|approved|rejected
+	/api/v1/groups/{id}/join-requests/{requestId}/approve POST	//owner, headman
+	/api/v1/groups/{id}/join-requests/{requestId}/reject POST	//owner, headman
 	/api/v1/group/task/{id}
 
+	/api/v1/universities GET POST	//POST by admin
+	/api/v1/universities/{id} GET
+	/api/v1/universities/{id}/faculties GET POST	//POST by admin
+	/api/v1/faculties/{id} GET PATCH DELETE	//PATCH by faculty admin, DELETE by admin
+	/api/v1/faculties/{id}/departments POST	//faculty admin
+	/api/v1/faculties/{id}/users/{userId}/roles POST	//admin; delegates the faculty with the faculty role, e.g. faculty_admin
+	/api/v1/faculties/{id}/users/{userId}/roles/{role} DELETE
+	/api/v1/departments/{id} GET PATCH DELETE	//PATCH and DELETE by faculty admin
+	/api/v1/{universities|faculties|departments}/{id}/groups GET	//the groups of the departments of the node
+	/api/v1/{universities|faculties|departments}/{id}/subjects GET
+
 	/api/v1/subjects
 	/api/v1/subject/{id}
 	/api/v1/subject/create
 	/api/v1/subject/delete/{id}
+	/api/v1/subjects/{id}/department PUT	//faculty admin, admin for the unattached subject; attaches the subject to the department, null detaches it
 
 	/api/v1/tasks
 	/api/v1/task/{id}
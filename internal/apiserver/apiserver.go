@@ -92,6 +92,8 @@ func Run(configPath string) {
 	} else {
 		log.Printf("HTTP server shutdowned")
 	}
+
+	srv.services.Close()
 }
 
 //newDB deprecated
@@ -0,0 +1,78 @@
+package apiserver
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"github.com/google/uuid"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handleAuditLog returns the page of the audit log.
+//	Filters: actor_user_id, actor_app_id, action, target_type, target_id, request_id, from and to (RFC 3339).
+//	The next page is requested with the cursor from the previous one
+func (s *server) handleAuditLog() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseAuditFilter(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		page, err := s.services.Audit().Find(filter, r.URL.Query().Get("cursor"))
+		if err == service.ErrInvalidAuditCursor {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		} else if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, page)
+	}
+}
+
+func parseAuditFilter(r *http.Request) (*models.AuditFilter, error) {
+	query := r.URL.Query()
+	filter := &models.AuditFilter{
+		Action:     query.Get("action"),
+		TargetType: query.Get("target_type"),
+		TargetID:   query.Get("target_id"),
+		RequestID:  query.Get("request_id"),
+	}
+
+	var err error
+	if v := query.Get("actor_user_id"); v != "" {
+		if filter.ActorUserID, err = strconv.Atoi(v); err != nil {
+			return nil, err
+		}
+	}
+	if v := query.Get("actor_app_id"); v != "" {
+		appID, err := uuid.Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		filter.ActorAppID = &appID
+	}
+	if v := query.Get("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, err
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, err
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, err
+		}
+		if filter.Limit < 0 {
+			return nil, models.ErrLimitLessThanZero
+		}
+	}
+
+	return filter, nil
+}
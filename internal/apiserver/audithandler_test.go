@@ -0,0 +1,48 @@
+package apiserver
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/config"
+	"backend/internal/store/teststore"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServer_HandleAuditLog(t *testing.T) {
+	s := newServer(teststore.New(), config.NewConfig())
+	defer s.services.Close()
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, s.services.Audit().Record(context.Background(), &models.AuditEntry{Action: models.AuditActionTaskCreate}))
+	}
+	assert.NoError(t, s.services.Audit().Record(context.Background(), &models.AuditEntry{Action: models.AuditActionGroupCreate}))
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/admin/audit?action=task.create&limit=2", nil)
+	s.handleAuditLog().ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	page := &models.AuditPage{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(page))
+	assert.Len(t, page.Entries, 2)
+	assert.NotEmpty(t, page.NextCursor)
+
+	rec = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/admin/audit?action=task.create&cursor="+page.NextCursor, nil)
+	s.handleAuditLog().ServeHTTP(rec, req)
+	page = &models.AuditPage{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(page))
+	assert.Len(t, page.Entries, 1)
+	assert.Empty(t, page.NextCursor)
+
+	for _, query := range []string{"from=yesterday", "actor_user_id=x", "actor_app_id=x", "cursor=x", "limit=-1"} {
+		rec = httptest.NewRecorder()
+		req, _ = http.NewRequest(http.MethodGet, "/api/v1/admin/audit?"+query, nil)
+		s.handleAuditLog().ServeHTTP(rec, req)
+		assert.NotContains(t, rec.Body.String(), `"entries"`, query)
+	}
+}
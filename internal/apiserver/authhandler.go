@@ -3,25 +3,39 @@ package apiserver
 import (
 	"backend/internal/api/v1/models"
 	"backend/internal/service"
+	"backend/internal/service/services"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
+	"math"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
 //	----	----	----	middleware handles	----	----	----
 
+// setRequestID is the middleware. The request ID and the IP address are also set for the services, see services.CtxKeyRequestID
 func (s *server) setRequestID(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		id := uuid.New().String()
-		w.Header().Set("X-Request-ID", id)
-		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), CtxKeyRequestID, id)))
+		id := uuid.New()
+		w.Header().Set("X-Request-ID", id.String())
+
+		ctx := context.WithValue(r.Context(), CtxKeyRequestID, id)
+		ctx = context.WithValue(ctx, services.CtxKeyRequestID, id)
+		if ip, err := s.clientIP(r); err == nil {
+			ctx = context.WithValue(ctx, services.CtxKeyIP, ip)
+		}
+		ctx = context.WithValue(ctx, services.CtxKeyUserAgent, r.UserAgent())
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
@@ -62,6 +76,12 @@ func (s *server) authenticateApp(next http.Handler) http.Handler {
 			return
 		}
 
+		tokenInfo, err := s.services.Auth().GetTokenInfo(appToken)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
 		app, err := s.services.Auth().GetAppInfoByToken(appToken)
 		if err != nil {
 			s.error(w, r, http.StatusInternalServerError, err)
@@ -74,10 +94,33 @@ func (s *server) authenticateApp(next http.Handler) http.Handler {
 			"app_token":   appToken,
 		}).Debug("the app authenticated successfuly")
 
-		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), CtxKeyAppID, app.ID)))
+		ctx := context.WithValue(r.Context(), CtxKeyAppID, app.ID)
+		ctx = context.WithValue(ctx, services.CtxKeyAppID, app.ID)
+		ctx = context.WithValue(ctx, services.CtxKeyApp, app)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, CtxKeyAppToken, tokenInfo)))
 	})
 }
 
+// requireAppScope is the middleware. The app token must have the scope
+func (s *server) requireAppScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := s.getAppTokenFromContext(r.Context())
+			if err != nil {
+				s.error(w, r, http.StatusUnauthorized, service.ErrInvalidAppToken)
+				return
+			}
+
+			if !token.HasScope(scope) {
+				s.error(w, r, http.StatusForbidden, service.ErrInsufficientAppScope)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (s *server) authenticateUser(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var accessToken string
@@ -95,7 +138,22 @@ func (s *server) authenticateUser(next http.Handler) http.Handler {
 			return
 		}
 
-		user, err := s.services.Auth().AuthenticateUser(accessToken)
+		// Personal access tokens are accepted next to JWT access tokens
+		if strings.HasPrefix(accessToken, models.PersonalTokenPrefix) {
+			user, token, err := s.services.Auth().AuthenticatePersonalToken(accessToken)
+			if err != nil {
+				s.errorV2(w, r, http.StatusUnauthorized, models.New(err, http.StatusUnauthorized, "invalid_access_token"))
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), CtxKeyUser, user)
+			ctx = context.WithValue(ctx, services.CtxKeyUser, user)
+			ctx = context.WithValue(ctx, CtxKeyPersonalToken, token)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		user, session, err := s.services.Auth().AuthenticateUserSession(r.Context(), accessToken)
 		if err != nil {
 			s.errorV2(w, r, http.StatusUnauthorized, models.New(err, http.StatusUnauthorized, "invalid_access_token"))
 			return
@@ -108,7 +166,11 @@ func (s *server) authenticateUser(next http.Handler) http.Handler {
 				user.Password)
 		}
 
-		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), CtxKeyUser, user)))
+		ctx := context.WithValue(r.Context(), CtxKeyUser, user)
+		ctx = context.WithValue(ctx, CtxKeySession, session)
+		ctx = context.WithValue(ctx, services.CtxKeyUser, user)
+		ctx = context.WithValue(ctx, services.CtxKeySession, session)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
@@ -117,11 +179,17 @@ func (s *server) authenticateUser(next http.Handler) http.Handler {
 func (s *server) handleAppRegister() http.HandlerFunc {
 	type req struct {
 		AppName string `json:"app_name"`
+		// Scope is space-delimited, all scopes are granted if it's empty
+		Scope string `json:"scope"`
+		// FingerprintPolicy is off, warn or enforce. The default one is set if it's empty
+		FingerprintPolicy string `json:"fingerprint_policy"`
 	}
 	type res struct {
-		AppID     string `json:"client_id"`
-		AppName   string `json:"client_name"`
-		AppSecret string `json:"client_secret"`
+		AppID             string `json:"client_id"`
+		AppName           string `json:"client_name"`
+		AppSecret         string `json:"client_secret"`
+		Scope             string `json:"scope"`
+		FingerprintPolicy string `json:"fingerprint_policy"`
 	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		req := &req{}
@@ -138,18 +206,25 @@ func (s *server) handleAppRegister() http.HandlerFunc {
 		}
 
 		app := &models.RegisteredApp{
-			AppName: req.AppName,
+			AppName:           req.AppName,
+			Scope:             req.Scope,
+			FingerprintPolicy: req.FingerprintPolicy,
 		}
-		err = s.services.Auth().RegisterApp(app)
-		if err != nil {
+		err = s.services.Auth().RegisterApp(r.Context(), app)
+		if err == service.ErrInvalidAppScope || err == service.ErrInvalidFingerprintPolicy {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		} else if err != nil {
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
 		}
 
 		res := &res{
-			AppID:     app.ID.String(),
-			AppName:   app.AppName,
-			AppSecret: app.AppSecret,
+			AppID:             app.ID.String(),
+			AppName:           app.AppName,
+			AppSecret:         app.AppSecret,
+			Scope:             app.Scope,
+			FingerprintPolicy: app.FingerprintPolicy,
 		}
 
 		s.respond(w, r, http.StatusOK, res)
@@ -157,8 +232,16 @@ func (s *server) handleAppRegister() http.HandlerFunc {
 }
 
 //Авторизация приложения
+//	Deprecated: use POST /oauth/token. The endpoint works only while Auth.OAuth.LegacyAppTokenEnabled is set
 func (s *server) handleAppAuthorization() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.config.Auth.OAuth.LegacyAppTokenEnabled {
+			s.error(w, r, http.StatusGone, errLegacyAppTokenDisabled)
+			return
+		}
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", `</oauth/token>; rel="successor-version"`)
+
 		appToken := r.Header.Get("X-App-Token")
 		//	Если приложение уже авторизовано
 		if appToken != "" {
@@ -232,7 +315,7 @@ func (s *server) handleAppDelete() http.HandlerFunc {
 
 		accessToken := r.Header.Get("X-App-Token")
 
-		err = s.services.Auth().DeleteApp(req.AppID, req.AppSecret, accessToken)
+		err = s.services.Auth().DeleteApp(r.Context(), req.AppID, req.AppSecret, accessToken)
 		if err != nil {
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
@@ -272,7 +355,11 @@ func (s *server) handleUserRegister() http.HandlerFunc {
 		}
 
 		err := s.services.Auth().RegisterUser(u)
-		if err != nil {
+		var policyErr *service.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			s.respondPasswordPolicy(w, r, policyErr)
+			return
+		} else if err != nil {
 			if err != service.ErrEmailIsAlreadyOccupied || err != service.ErrLoginIsAlreadyOccupied {
 				s.error(w, r, http.StatusInternalServerError, err)
 				return
@@ -289,10 +376,152 @@ func (s *server) handleUserRegister() http.HandlerFunc {
 
 func (s *server) handleUserSignIn() http.HandlerFunc {
 	type signInRequest struct {
-		Login    string `json:"login"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Login      string `json:"login"`
+		Email      string `json:"email"`
+		Password   string `json:"password"`
+		DeviceName string `json:"device_name"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &signInRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		_, port, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+		// The IP address of the client is throttled, not the one of the load balancer
+		ip, err := s.clientIP(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+		s.logger.WithFields(logrus.Fields{
+			"user_ip":   ip,
+			"user_port": port,
+		}).Debug("user was signed in")
+		if ip == "" || port == "" {
+			s.error(w, r, http.StatusBadRequest, fmt.Errorf("debuging: ip or port not found in request: %s:%s", ip, port))
+			return
+		}
+
+		userToken, challenge, err := s.services.Auth().UserSignIn(r.Context(), &models.UserSignIn{
+			Login:      req.Login,
+			Email:      req.Email,
+			Password:   req.Password,
+			DeviceName: req.DeviceName,
+			UserAgent:  r.UserAgent(),
+			IP:         ip,
+		})
+		var throttled *service.ThrottledError
+		if errors.As(err, &throttled) {
+			s.respondThrottled(w, r, throttled)
+			return
+		} else if err == service.ErrEmailIsNotConfirmed {
+			s.error(w, r, http.StatusForbidden, err)
+			return
+		} else if err != nil {
+			s.error(w, r, http.StatusOK, err)
+			return
+		}
+
+		if challenge != nil {
+			s.respondMFAChallenge(w, r, challenge)
+			return
+		}
+
+		s.respondUserToken(w, r, userToken)
+	}
+}
+
+// respondMFAChallenge responds with the challenge, that is exchanged for the token pair by /login/mfa
+func (s *server) respondMFAChallenge(w http.ResponseWriter, r *http.Request, challenge *models.MFAChallenge) {
+	type response struct {
+		MFARequired bool `json:"mfa_required"`
+		models.MFAChallenge
+	}
+
+	s.respond(w, r, http.StatusOK, response{MFARequired: true, MFAChallenge: *challenge})
+}
+
+// respondThrottled responds with 423 if the account is locked and with 429 if the attempt is too early.
+//	Retry-After tells the client when to try again.
+func (s *server) respondThrottled(w http.ResponseWriter, r *http.Request, err *service.ThrottledError) {
+	retryAfter := int(math.Ceil(time.Until(err.RetryAfter).Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+
+	if errors.Is(err, service.ErrAccountIsLocked) {
+		s.error(w, r, http.StatusLocked, err)
+		return
+	}
+	s.error(w, r, http.StatusTooManyRequests, err)
+}
+
+// respondPasswordPolicy responds with 400 and the list of the violated rules of the password policy
+func (s *server) respondPasswordPolicy(w http.ResponseWriter, r *http.Request, err *service.PasswordPolicyError) {
+	type response struct {
+		Error      string                           `json:"error"`
+		Violations []models.PasswordPolicyViolation `json:"violations"`
 	}
+
+	s.logger.WithFields(logrus.Fields{
+		"request_id": r.Context().Value(CtxKeyRequestID),
+		"violations": len(err.Violations),
+	}).Info("The password doesn't satisfy the password policy")
+	s.respond(w, r, http.StatusBadRequest, response{
+		Error:      service.ErrPasswordPolicy.Error(),
+		Violations: err.Violations,
+	})
+}
+
+// handleMFASignIn completes the sign-in of the user with 2FA enabled.
+//	The challenge token from /login is exchanged for the token pair with the TOTP or recovery code.
+func (s *server) handleMFASignIn() http.HandlerFunc {
+	type request struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+		DeviceName     string `json:"device_name"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		ip, err := s.clientIP(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		userToken, err := s.services.Auth().CompleteMFASignIn(r.Context(), &models.MFASignIn{
+			ChallengeToken: req.ChallengeToken,
+			Code:           req.Code,
+			DeviceName:     req.DeviceName,
+			UserAgent:      r.UserAgent(),
+			IP:             ip,
+		})
+		if err == service.ErrInvalidMFAChallenge || err == service.ErrIncorrectTwoFactorCode {
+			s.error(w, r, http.StatusUnauthorized, err)
+			return
+		} else if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respondUserToken(w, r, userToken)
+	}
+}
+
+// respondUserToken responds with the first token pair of the new session
+func (s *server) respondUserToken(w http.ResponseWriter, r *http.Request, userToken *models.UserToken) {
 	type userInfo struct {
 		UserID    int       `json:"user_id"`
 		FullName  string    `json:"full_name"`
@@ -301,80 +530,398 @@ func (s *server) handleUserSignIn() http.HandlerFunc {
 	}
 	type response struct {
 		User         userInfo `json:"user"`
+		SessionID    int      `json:"session_id"`
 		AccessToken  string   `json:"access_token"`
 		RefreshToken string   `json:"refresh_token"`
 		Expires      int32    `json:"expires"`
 	}
+
+	user, err := s.services.User().Find(userToken.UserID)
+	if err != nil {
+		s.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	user.Sanitize()
+
+	res := &response{
+		User: userInfo{
+			UserID:    user.ID,
+			FullName:  user.FullName,
+			Login:     user.Login,
+			CreatedAt: user.CreatedAt,
+		},
+		SessionID:    userToken.SessionID,
+		AccessToken:  userToken.AccessToken,
+		RefreshToken: userToken.RefreshToken,
+		Expires:      int32(userToken.ExpirationTimestamp.Sub(time.Now()).Seconds()),
+	}
+	s.respond(w, r, http.StatusOK, res)
+}
+
+func (s *server) handleUserLogout() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		req := &signInRequest{}
-		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		session, err := s.getSessionFromContext(r.Context())
+		if err != nil {
 			s.error(w, r, http.StatusBadRequest, err)
 			return
 		}
 
-		ip, port, err := net.SplitHostPort(r.RemoteAddr)
+		err = s.services.Auth().UserLogout(user.ID, session.ID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		s.respond(w, r, http.StatusOK, nil)
+	}
+}
+
+func (s *server) handleUserSessions() http.HandlerFunc {
+	type sessionInfo struct {
+		models.UserSession
+		Current bool `json:"current"`
+	}
+	type response struct {
+		Total    int           `json:"total"`
+		Sessions []sessionInfo `json:"sessions"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		currentSessionID := 0
+		if session, err := s.getSessionFromContext(r.Context()); err == nil {
+			currentSessionID = session.ID
+		}
+
+		sessions, err := s.services.Auth().GetUserSessions(user.ID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		res := response{
+			Total: len(sessions),
+		}
+		for _, session := range sessions {
+			res.Sessions = append(res.Sessions, sessionInfo{
+				UserSession: session,
+				Current:     session.ID == currentSessionID,
+			})
+		}
+
+		s.respond(w, r, http.StatusOK, res)
+	}
+}
+
+func (s *server) handleCloseUserSession() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		URLVars := mux.Vars(r)
+		sessionID, err := strconv.Atoi(URLVars["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, errors.New("invalid session id type"))
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		err = s.services.Auth().CloseSession(user.ID, sessionID)
+		if err == service.ErrSessionNotFound {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+func (s *server) handleCloseOtherUserSessions() http.HandlerFunc {
+	type response struct {
+		Closed int `json:"closed"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		session, err := s.getSessionFromContext(r.Context())
 		if err != nil {
 			s.error(w, r, http.StatusBadRequest, err)
 			return
 		}
-		s.logger.WithFields(logrus.Fields{
-			"user_ip":   ip,
-			"user_port": port,
-		}).Debug("user was signed in")
-		if ip == "" || port == "" {
-			s.error(w, r, http.StatusBadRequest, fmt.Errorf("debuging: ip or port not found in request: %s:%s", ip, port))
+
+		closed, err := s.services.Auth().CloseOtherSessions(user.ID, session.ID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
 			return
 		}
 
-		userToken, err := s.services.Auth().UserSignIn(&models.UserSignIn{
-			Login:    req.Login,
-			Email:    req.Email,
-			Password: req.Password,
-			IP:       ip,
+		s.respond(w, r, http.StatusOK, response{Closed: closed})
+	}
+}
+
+// handleConfirmEmail confirms the email by the link from the confirmation email.
+//The link is opened in a browser, so neither app nor user authentication is required
+func (s *server) handleConfirmEmail() http.HandlerFunc {
+	type response struct {
+		UserID           int        `json:"user_id"`
+		Email            string     `json:"email"`
+		EmailConfirmedAt *time.Time `json:"email_confirmed_at"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.services.Auth().ConfirmEmail(r.URL.Query().Get("token"))
+		if err == service.ErrInvalidVerificationToken {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		} else if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{
+			UserID:           user.ID,
+			Email:            user.Email,
+			EmailConfirmedAt: user.EmailConfirmedAt,
 		})
+	}
+}
+
+func (s *server) handleSendEmailConfirmation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.getUserFromContext(r.Context())
 		if err != nil {
-			s.error(w, r, http.StatusOK, err)
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		err = s.services.Auth().SendEmailConfirmation(user.ID)
+		if err == service.ErrEmailIsAlreadyConfirmed {
+			s.error(w, r, http.StatusConflict, err)
+			return
+		} else if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+func (s *server) handlePasswordForgot() http.HandlerFunc {
+	type request struct {
+		Email string `json:"email"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := validation.Validate(req.Email, validation.Required, is.Email); err != nil {
+			s.error(w, r, http.StatusBadRequest, service.ErrInvalidUserEmail)
+			return
+		}
+
+		// The response is the same whether the user exists or not
+		if err := s.services.Auth().RequestPasswordReset(req.Email); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusAccepted, nil)
+	}
+}
+
+func (s *server) handlePasswordReset() http.HandlerFunc {
+	type request struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		err := s.services.Auth().ResetPassword(r.Context(), req.Token, req.Password)
+		var policyErr *service.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			s.respondPasswordPolicy(w, r, policyErr)
+			return
+		} else if err != nil {
+			if _, ok := err.(validation.Errors); ok || err == service.ErrInvalidVerificationToken {
+				s.error(w, r, http.StatusBadRequest, err)
+				return
+			}
+			s.error(w, r, http.StatusInternalServerError, err)
 			return
 		}
 
-		user, err := s.services.User().Find(userToken.UserID)
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// handleChangePassword changes the password of the current user.
+//All sessions, including the current one, are closed after the change
+func (s *server) handleChangePassword() http.HandlerFunc {
+	type request struct {
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
 		if err != nil {
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
 		}
 
-		user.Sanitize()
+		err = s.services.Auth().ChangePassword(r.Context(), user.ID, req.OldPassword, req.NewPassword)
+		var policyErr *service.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			s.respondPasswordPolicy(w, r, policyErr)
+			return
+		} else if err != nil {
+			if _, ok := err.(validation.Errors); ok {
+				s.error(w, r, http.StatusBadRequest, err)
+				return
+			} else if err == service.ErrIncorrectPassword {
+				s.error(w, r, http.StatusForbidden, err)
+				return
+			}
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
 
-		res := &response{
-			User: userInfo{
-				UserID:    user.ID,
-				FullName:  user.FullName,
-				Login:     user.Login,
-				CreatedAt: user.CreatedAt,
-			},
-			AccessToken:  userToken.AccessToken,
-			RefreshToken: userToken.RefreshToken,
-			Expires:      int32(userToken.ExpirationTimestamp.Sub(time.Now()).Seconds()),
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// handleEnrollTwoFactor generates the TOTP secret and recovery codes of the current user.
+//	2FA is enabled after the first code is sent to /account/2fa/verify
+func (s *server) handleEnrollTwoFactor() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		enrollment, err := s.services.Auth().EnrollTOTP(user.ID)
+		if err == service.ErrTwoFactorIsAlreadyEnabled {
+			s.error(w, r, http.StatusConflict, err)
+			return
+		} else if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
 		}
-		s.respond(w, r, http.StatusOK, res)
 
+		s.respond(w, r, http.StatusCreated, enrollment)
 	}
 }
 
-func (s *server) handleUserLogout() http.HandlerFunc {
+func (s *server) handleVerifyTwoFactor() http.HandlerFunc {
+	type request struct {
+		Code string `json:"code"`
+	}
 	return func(w http.ResponseWriter, r *http.Request) {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
 		user, err := s.getUserFromContext(r.Context())
 		if err != nil {
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
 		}
 
-		err = s.services.Auth().UserLogout(user.ID)
+		err = s.services.Auth().ConfirmTOTP(r.Context(), user.ID, req.Code)
+		if err != nil {
+			switch err {
+			case service.ErrIncorrectTwoFactorCode:
+				s.error(w, r, http.StatusForbidden, err)
+			case service.ErrTwoFactorIsNotEnrolled:
+				s.error(w, r, http.StatusNotFound, err)
+			case service.ErrTwoFactorIsAlreadyEnabled:
+				s.error(w, r, http.StatusConflict, err)
+			default:
+				s.error(w, r, http.StatusInternalServerError, err)
+			}
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+func (s *server) handleDisableTwoFactor() http.HandlerFunc {
+	type request struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
 		if err != nil {
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
 		}
-		s.respond(w, r, http.StatusOK, nil)
+
+		err = s.services.Auth().DisableTOTP(r.Context(), user.ID, req.Password, req.Code)
+		if err != nil {
+			switch err {
+			case service.ErrIncorrectPassword, service.ErrIncorrectTwoFactorCode:
+				s.error(w, r, http.StatusForbidden, err)
+			case service.ErrTwoFactorIsNotEnabled:
+				s.error(w, r, http.StatusNotFound, err)
+			default:
+				s.error(w, r, http.StatusInternalServerError, err)
+			}
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// handleJWKS publishes the public keys of the access tokens,
+//so other services can verify them without the shared secret
+func (s *server) handleJWKS() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		s.respond(w, r, http.StatusOK, s.services.Auth().GetJWKS())
 	}
 }
 
@@ -442,6 +989,9 @@ func (s *server) handleUserToken() http.HandlerFunc {
 		case service.ErrInvalidTokenPair, service.ErrAccessTokenRefreshRateExceeded:
 			s.error(w, r, http.StatusTooManyRequests, err)
 			return
+		case service.ErrRefreshTokenReused, service.ErrInvalidRefreshToken, service.ErrSessionIsRevoked:
+			s.error(w, r, http.StatusUnauthorized, err)
+			return
 		case nil:
 			break
 		default:
@@ -481,9 +1031,7 @@ func (s *server) authenticateUser(next http.Handler) http.Handler {
 
 		tokenClaims := &claims{}
 
-		token, err := jwt.ParseWithClaims(accessToken, &claims{}, func(*jwt.Token) (interface{}, error) {
-			return []byte(s.services.Auth().GetSigningKey()), nil
-		})
+		token, err := jwt.ParseWithClaims(accessToken, &claims{}, s.services.Auth().KeyFunc)
 		if err != nil {
 			s.error(w, r, http.StatusOK, err)
 			return
@@ -0,0 +1,160 @@
+package apiserver
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/config"
+	"backend/internal/store/teststore"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestServer_HandleUserRegister_PasswordPolicy(t *testing.T) {
+	s := newServer(teststore.New(), config.NewConfig())
+	defer s.services.Close()
+
+	b := &bytes.Buffer{}
+	_ = json.NewEncoder(b).Encode(map[string]string{
+		"login":     "Student",
+		"email":     "student@example.org",
+		"full_name": "Student Name",
+		"password":  "student",
+	})
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/auth/register", b)
+	s.handleUserRegister().ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	res := struct {
+		Error      string                           `json:"error"`
+		Violations []models.PasswordPolicyViolation `json:"violations"`
+	}{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	assert.NotEmpty(t, res.Error)
+	if assert.Len(t, res.Violations, 2) {
+		assert.Equal(t, models.PasswordRuleMinLength, res.Violations[0].Rule)
+		assert.Equal(t, models.PasswordRulePersonalInfo, res.Violations[1].Rule)
+	}
+}
+
+func TestServer_HandleCloseUserSession(t *testing.T) {
+	s := newServer(teststore.New(), config.NewConfig())
+	defer s.services.Close()
+
+	u := models.TestUser(t)
+	assert.NoError(t, s.services.Auth().RegisterUser(u))
+	other := &models.User{Login: "other", FullName: "Other", Email: "other@example.org", Password: "password"}
+	assert.NoError(t, s.services.Auth().RegisterUser(other))
+
+	signIn := func(login string) *models.UserToken {
+		token, _, err := s.services.Auth().UserSignIn(context.Background(), &models.UserSignIn{
+			Login:    login,
+			Password: "password",
+			IP:       "127.0.0.1",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	userToken := signIn(u.Login)
+	otherToken := signIn(other.Login)
+
+	router := mux.NewRouter()
+	router.Handle("/sessions/{id:[0-9]+}", s.authenticateUser(s.handleCloseUserSession())).Methods(http.MethodDelete)
+
+	testCases := []struct {
+		name         string
+		sessionID    int
+		expectedCode int
+	}{
+		{name: "session of another user", sessionID: otherToken.SessionID, expectedCode: http.StatusNotFound},
+		{name: "unknown session", sessionID: 100, expectedCode: http.StatusNotFound},
+		{name: "own session", sessionID: userToken.SessionID, expectedCode: http.StatusNoContent},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodDelete, "/sessions/"+strconv.Itoa(tc.sessionID), nil)
+			req.RemoteAddr = "127.0.0.1:1234"
+			req.Header.Set("Authorization", "Bearer "+userToken.AccessToken)
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+
+	// The session of another user is still active
+	_, err := s.services.Auth().AuthenticateUser(otherToken.AccessToken)
+	assert.NoError(t, err)
+}
+
+func TestServer_ClientIP(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.HTTP.TrustedProxies = []string{"10.0.0.0/8", "192.0.2.1"}
+	s := newServer(teststore.New(), cfg)
+	defer s.services.Close()
+
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{
+			name:       "direct",
+			remoteAddr: "203.0.113.5:1234",
+			expected:   "203.0.113.5",
+		},
+		{
+			name:       "untrusted forwarded",
+			remoteAddr: "203.0.113.5:1234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.7"},
+			expected:   "203.0.113.5",
+		},
+		{
+			name:       "trusted forwarded",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.7"},
+			expected:   "198.51.100.7",
+		},
+		{
+			name:       "proxy chain",
+			remoteAddr: "192.0.2.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.9, 198.51.100.7, 10.0.0.2"},
+			expected:   "198.51.100.7",
+		},
+		{
+			name:       "real ip",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.7"},
+			expected:   "198.51.100.7",
+		},
+		{
+			name:       "invalid forwarded",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string]string{"X-Forwarded-For": "unknown"},
+			expected:   "10.1.2.3",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+
+			ip, err := s.clientIP(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, ip)
+		})
+	}
+}
@@ -11,7 +11,8 @@ var (
 	errInvalidRequest           = errors.New("invalid request")
 	errUserNotFoundInContext    = errors.New("user not found in context")
 
-	errAppTokenNotFound = errors.New("app token wasn't found in header")
+	errAppTokenNotFound       = errors.New("app token wasn't found in header")
+	errLegacyAppTokenDisabled = errors.New("the endpoint was removed, use POST /oauth/token with the client_credentials grant")
 
 	errJSONEOF            = errors.New("EOF")
 	errJSONParseEOF       = errors.New("unexpected EOF when parsing JSON")
@@ -5,10 +5,14 @@ import (
 	"backend/internal/service"
 	"encoding/json"
 	"errors"
+	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/gorilla/mux"
+	"io"
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func (s *server) handleGroups() http.HandlerFunc {
@@ -86,6 +90,7 @@ func (s *server) handleGroupCreate() http.HandlerFunc {
 		StartYear          string `json:"start_year"`
 		CourseNumber       int    `json:"course_number"`
 		GroupNumber        string `json:"group_number"`
+		JoinMode           string `json:"join_mode"`
 	}
 	type response struct {
 		Group models.Group `json:"group"`
@@ -115,9 +120,10 @@ func (s *server) handleGroupCreate() http.HandlerFunc {
 			StartYear:          req.StartYear,
 			CourseNumber:       req.CourseNumber,
 			GroupNumber:        req.GroupNumber,
+			JoinMode:           req.JoinMode,
 		}
 
-		err = s.services.Group().Create(group, creator)
+		err = s.services.Group().Create(r.Context(), group, creator)
 		if err != nil {
 			s.error(w, r, http.StatusOK, err)
 			return
@@ -127,7 +133,54 @@ func (s *server) handleGroupCreate() http.HandlerFunc {
 	}
 }
 
-//	Requires: The user must be a member of the group
+//	Requires: The group.update permission
+func (s *server) handleGroupUpdate() http.HandlerFunc {
+	type response struct {
+		Group models.Group `json:"group"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		URLVars := mux.Vars(r)
+		groupID, _ := strconv.Atoi(URLVars["id"])
+
+		req := &models.UpdateGroup{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			if err == errJSONEOF {
+				s.error(w, r, http.StatusBadRequest, errJSONParseEOF)
+				return
+			}
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		group, err := s.services.Group().Update(r.Context(), groupID, user.ID, req)
+		if err != nil {
+			_, isValidationErr := err.(validation.Errors)
+			switch {
+			case isValidationErr, err == models.ErrEmptyUpdate, err == service.ErrUniversityNotFound:
+				s.error(w, r, http.StatusBadRequest, err)
+			case err == service.ErrPermissionDenied:
+				s.error(w, r, http.StatusForbidden, err)
+			case err == service.ErrGroupNotFound:
+				s.error(w, r, http.StatusNotFound, err)
+			case err == service.ErrGroupNameIsAlreadyOccupied, err == service.ErrGroupIsAttachedToDepartment:
+				s.error(w, r, http.StatusConflict, err)
+			default:
+				s.error(w, r, http.StatusInternalServerError, err)
+			}
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{*group})
+	}
+}
+
+//	Requires: The group.delete permission
 func (s *server) handleGroupDelete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		URLVars := mux.Vars(r)
@@ -139,10 +192,13 @@ func (s *server) handleGroupDelete() http.HandlerFunc {
 			return
 		}
 
-		err = s.services.Group().Delete(groupID, user.ID)
-		if err == service.ErrUserIsNotGroupMember {
+		err = s.services.Group().Delete(r.Context(), groupID, user.ID)
+		if err == service.ErrPermissionDenied {
 			s.error(w, r, http.StatusForbidden, err)
 			return
+		} else if err == service.ErrGroupNotFound {
+			s.error(w, r, http.StatusNotFound, err)
+			return
 		} else if err != nil {
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
@@ -152,66 +208,145 @@ func (s *server) handleGroupDelete() http.HandlerFunc {
 	}
 }
 
-func (s *server) handleGroupCreateInvitation() http.HandlerFunc {
-	type Inviter struct {
-		ID       int    `json:"id"`
-		Username string `json:"username"`
-		//TODO: Avatar
+//	Requires: The group.delete permission
+func (s *server) handleGroupArchive(archive bool) http.HandlerFunc {
+	type response struct {
+		Group models.Group `json:"group"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		URLVars := mux.Vars(r)
+		groupID, _ := strconv.Atoi(URLVars["id"])
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		group, err := s.services.Group().Archive(r.Context(), groupID, user.ID, archive)
+		if err == service.ErrPermissionDenied {
+			s.error(w, r, http.StatusForbidden, err)
+			return
+		} else if err == service.ErrGroupNotFound {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{*group})
 	}
-	type Group struct {
-		ID             int    `json:"id"`
-		Name           string `json:"name"`
-		UniversityName string `json:"university_name"`
+}
+
+//	Requires: The group.invite permission, and the group.roles.manage one to grant roles above the member
+func (s *server) handleGroupInviteCreate() http.HandlerFunc {
+	type request struct {
+		Name        string    `json:"name"`
+		MaxUses     int       `json:"max_uses"`
+		DefaultRole string    `json:"default_role"`
+		ExpiresAt   time.Time `json:"expires_at"`
 	}
 	type response struct {
-		InviteHash string  `json:"invite"`
-		Group      Group   `json:"group"`
-		Inviter    Inviter `json:"inviter"`
+		Invite models.GroupInvite `json:"invite"`
 	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		URLVars := mux.Vars(r)
-		groupID, err := strconv.Atoi(URLVars["id"])
-		if err != nil {
-			s.errorV2(w, r, 500, models.New(err, 500, "invalid_group_id"))
+		groupID, _ := strconv.Atoi(URLVars["id"])
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil && err != errJSONEOF {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
 		}
 
-		inviterID, err := s.getUserFromContext(r.Context())
+		inviter, err := s.getUserFromContext(r.Context())
 		if err != nil {
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
 		}
-		invite, err := s.services.Group().GetOrCreateInviteLink(groupID, inviterID.ID)
+
+		invite := &models.GroupInvite{
+			GroupID:     groupID,
+			InviterID:   inviter.ID,
+			Name:        req.Name,
+			MaxUses:     req.MaxUses,
+			DefaultRole: req.DefaultRole,
+			ExpiresAt:   req.ExpiresAt,
+		}
+		err = s.services.Group().CreateInvite(r.Context(), invite)
 		if err != nil {
-			s.errorV2(w, r, 500, models.New(err, 500, "invalid_group_id?"))
+			if _, ok := err.(validation.Errors); ok {
+				s.error(w, r, http.StatusBadRequest, err)
+				return
+			}
+			switch err {
+			case service.ErrInvalidInviteExpiry, service.ErrInvalidInviteRole, service.ErrRoleNotFound, service.ErrInvalidRoleScope:
+				s.error(w, r, http.StatusBadRequest, err)
+			case service.ErrPermissionDenied:
+				s.error(w, r, http.StatusForbidden, err)
+			case service.ErrGroupNotFound:
+				s.error(w, r, http.StatusNotFound, err)
+			case service.ErrTooManyGroupInvites:
+				s.error(w, r, http.StatusConflict, err)
+			default:
+				s.error(w, r, http.StatusInternalServerError, err)
+			}
 			return
 		}
 
-		group, err := s.services.Group().Find(groupID)
+		s.respond(w, r, http.StatusCreated, response{*invite})
+	}
+}
+
+//	Requires: The group.invite permission
+func (s *server) handleGroupInvites() http.HandlerFunc {
+	type response struct {
+		Total   int                  `json:"total"`
+		Invites []models.GroupInvite `json:"invites"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		URLVars := mux.Vars(r)
+		groupID, _ := strconv.Atoi(URLVars["id"])
+
+		invites, err := s.services.Group().GetInvites(groupID)
 		if err != nil {
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
 		}
 
-		university, err := s.services.University().Find(group.UniversityID)
+		s.respond(w, r, http.StatusOK, response{Total: len(invites), Invites: invites})
+	}
+}
+
+//	Requires: The group.invite permission
+func (s *server) handleGroupInviteRevoke() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		URLVars := mux.Vars(r)
+		groupID, _ := strconv.Atoi(URLVars["id"])
+		inviteID, err := strconv.Atoi(URLVars["inviteId"])
 		if err != nil {
-			s.error(w, r, http.StatusInternalServerError, err)
+			s.error(w, r, http.StatusBadRequest, err)
 			return
 		}
 
-		res := &response{
-			InviteHash: invite.InviteHash,
-			Inviter: Inviter{
-				ID:       inviterID.ID,
-				Username: inviterID.Login,
-			},
-			Group: Group{
-				ID:             groupID,
-				Name:           group.CustomName,
-				UniversityName: university.Name,
-			},
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
 		}
 
-		s.respond(w, r, 200, res)
+		err = s.services.Group().RevokeInvite(r.Context(), groupID, inviteID, user.ID)
+		switch err {
+		case nil:
+			s.respond(w, r, http.StatusNoContent, nil)
+		case service.ErrPermissionDenied:
+			s.error(w, r, http.StatusForbidden, err)
+		case service.ErrGroupInviteNotFound:
+			s.error(w, r, http.StatusNotFound, err)
+		default:
+			s.error(w, r, http.StatusInternalServerError, err)
+		}
 	}
 }
 
@@ -226,8 +361,20 @@ func (s *server) handleJoinToGroupWithInvite() http.HandlerFunc {
 			return
 		}
 
-		err = s.services.Group().AddUserToGroupByInvite(user.ID, invite)
-		if err != nil {
+		err = s.services.Group().AddUserToGroupByInvite(r.Context(), user.ID, invite)
+		if err == service.ErrEmailIsNotConfirmed {
+			s.error(w, r, http.StatusForbidden, err)
+			return
+		} else if err == service.ErrGroupIsClosed || err == service.ErrGroupJoinRequiresApproval {
+			s.error(w, r, http.StatusForbidden, err)
+			return
+		} else if err == service.ErrInvalidInvite {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		} else if err == service.ErrUserIsAlreadyGroupMember {
+			s.error(w, r, http.StatusConflict, err)
+			return
+		} else if err != nil {
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
 		}
@@ -249,8 +396,16 @@ func (s *server) handleGroupWhereUserIsMember() http.HandlerFunc {
 			return
 		}
 
+		var includeArchived bool
+		if v := r.URL.Query().Get("archived"); v != "" {
+			if includeArchived, err = strconv.ParseBool(v); err != nil {
+				s.error(w, r, http.StatusBadRequest, err)
+				return
+			}
+		}
+
 		res := response{}
-		groups, err := s.services.Group().GetGroupsUserMemberOf(user.ID)
+		groups, err := s.services.Group().GetGroupsUserMemberOf(user.ID, includeArchived)
 		if err == service.ErrUserNotMemberOfAnyGroups {
 
 		} else if err != nil {
@@ -294,3 +449,338 @@ func (s *server) handleGetGroupMembers() http.HandlerFunc {
 		s.respond(w, r, http.StatusOK, res)
 	}
 }
+
+// handleGroupNameHistory returns the names the group had before the academic year rollovers
+//	Requires: The group.read permission
+func (s *server) handleGroupNameHistory() http.HandlerFunc {
+	type response struct {
+		Names []models.GroupNameHistory `json:"names"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		names, err := s.services.Group().GetNameHistory(groupID)
+		if err == service.ErrGroupNotFound {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{names})
+	}
+}
+
+// handleSetGroupMemberRole replaces the role of the group member
+func (s *server) handleSetGroupMemberRole() http.HandlerFunc {
+	type request struct {
+		Role string `json:"role"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		URLVars := mux.Vars(r)
+		groupID, _ := strconv.Atoi(URLVars["id"])
+		userID, err := strconv.Atoi(URLVars["userId"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		err = s.services.Role().SetGroupRole(userID, groupID, req.Role)
+		switch err {
+		case nil:
+			s.respond(w, r, http.StatusNoContent, nil)
+		case service.ErrRoleNotFound, service.ErrInvalidRoleScope:
+			s.error(w, r, http.StatusBadRequest, err)
+		case service.ErrUserIsNotGroupMember:
+			s.error(w, r, http.StatusNotFound, err)
+		case service.ErrLastGroupOwner:
+			s.error(w, r, http.StatusConflict, err)
+		default:
+			s.error(w, r, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// handleGroupLeave removes the user from the group
+func (s *server) handleGroupLeave() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		URLVars := mux.Vars(r)
+		groupID, _ := strconv.Atoi(URLVars["id"])
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		err = s.services.Group().Leave(r.Context(), groupID, user.ID)
+		s.respondGroupMembership(w, r, err)
+	}
+}
+
+//	Requires: The group.members.manage permission, and the group.roles.manage one to remove an owner
+func (s *server) handleGroupMemberRemove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		URLVars := mux.Vars(r)
+		groupID, _ := strconv.Atoi(URLVars["id"])
+		memberID, err := strconv.Atoi(URLVars["userId"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		err = s.services.Group().RemoveMember(r.Context(), groupID, memberID, user.ID)
+		s.respondGroupMembership(w, r, err)
+	}
+}
+
+//	Requires: The group.roles.manage permission. Only an owner of the group transfers its ownership
+func (s *server) handleGroupTransfer() http.HandlerFunc {
+	type request struct {
+		UserID int `json:"user_id"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		URLVars := mux.Vars(r)
+		groupID, _ := strconv.Atoi(URLVars["id"])
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		err = s.services.Group().TransferOwnership(r.Context(), groupID, user.ID, req.UserID)
+		s.respondGroupMembership(w, r, err)
+	}
+}
+
+// respondGroupMembership responds to the changes of the group members
+// maxMemberImportFileSize limits the CSV file of the member import
+const maxMemberImportFileSize = 5 << 20
+
+// handleGroupMembersImport adds the users from the CSV file to the group.
+//	The file is the body of the request or the "file" field of the multipart form.
+//	?create_accounts=true creates the placeholder accounts for the unknown emails, ?dry_run=true only returns the report
+//	Requires: The group.members.manage permission
+func (s *server) handleGroupMembersImport() http.HandlerFunc {
+	type response struct {
+		Report models.MemberImportReport `json:"report"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		URLVars := mux.Vars(r)
+		groupID, _ := strconv.Atoi(URLVars["id"])
+
+		query := r.URL.Query()
+		var createAccounts, dryRun bool
+		var err error
+		if v := query.Get("create_accounts"); v != "" {
+			if createAccounts, err = strconv.ParseBool(v); err != nil {
+				s.error(w, r, http.StatusBadRequest, err)
+				return
+			}
+		}
+		if v := query.Get("dry_run"); v != "" {
+			if dryRun, err = strconv.ParseBool(v); err != nil {
+				s.error(w, r, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxMemberImportFileSize)
+		var file io.Reader = r.Body
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			f, _, err := r.FormFile("file")
+			if err != nil {
+				s.error(w, r, http.StatusBadRequest, err)
+				return
+			}
+			defer f.Close()
+			file = f
+		}
+
+		report, err := s.services.Group().ImportMembers(r.Context(), groupID, user.ID, file, createAccounts, dryRun)
+		switch err {
+		case nil:
+			s.respond(w, r, http.StatusOK, response{*report})
+		case service.ErrInvalidImportFile, service.ErrTooManyImportRows:
+			s.error(w, r, http.StatusBadRequest, err)
+		case service.ErrPermissionDenied:
+			s.error(w, r, http.StatusForbidden, err)
+		case service.ErrGroupNotFound:
+			s.error(w, r, http.StatusNotFound, err)
+		default:
+			s.error(w, r, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (s *server) respondGroupMembership(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case nil:
+		s.respond(w, r, http.StatusNoContent, nil)
+	case service.ErrTransferToSelf:
+		s.error(w, r, http.StatusBadRequest, err)
+	case service.ErrPermissionDenied:
+		s.error(w, r, http.StatusForbidden, err)
+	case service.ErrGroupNotFound, service.ErrUserIsNotGroupMember:
+		s.error(w, r, http.StatusNotFound, err)
+	case service.ErrLastGroupOwner:
+		s.error(w, r, http.StatusConflict, err)
+	default:
+		s.error(w, r, http.StatusInternalServerError, err)
+	}
+}
+
+// handleGroupJoinRequestCreate requests to join the group in the approval mode
+func (s *server) handleGroupJoinRequestCreate() http.HandlerFunc {
+	type request struct {
+		Message string `json:"message"`
+	}
+	type response struct {
+		Request models.GroupJoinRequest `json:"request"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		URLVars := mux.Vars(r)
+		groupID, _ := strconv.Atoi(URLVars["id"])
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil && err != errJSONEOF {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		joinRequest, err := s.services.Group().RequestToJoin(r.Context(), groupID, user.ID, req.Message)
+		if err != nil {
+			if _, ok := err.(validation.Errors); ok {
+				s.error(w, r, http.StatusBadRequest, err)
+				return
+			}
+			switch err {
+			case service.ErrGroupIsClosed, service.ErrJoinRequestsNotAccepted, service.ErrEmailIsNotConfirmed:
+				s.error(w, r, http.StatusForbidden, err)
+			case service.ErrGroupNotFound:
+				s.error(w, r, http.StatusNotFound, err)
+			case service.ErrUserIsAlreadyGroupMember, service.ErrJoinRequestIsPending:
+				s.error(w, r, http.StatusConflict, err)
+			default:
+				s.error(w, r, http.StatusInternalServerError, err)
+			}
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, response{*joinRequest})
+	}
+}
+
+//	Requires: The group.members.manage permission
+func (s *server) handleGroupJoinRequests() http.HandlerFunc {
+	type response struct {
+		Total    int                       `json:"total"`
+		Requests []models.GroupJoinRequest `json:"requests"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		URLVars := mux.Vars(r)
+		groupID, _ := strconv.Atoi(URLVars["id"])
+
+		requests, err := s.services.Group().GetJoinRequests(groupID, r.URL.Query().Get("status"))
+		if err == service.ErrInvalidJoinRequestStatus {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		} else if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{Total: len(requests), Requests: requests})
+	}
+}
+
+//	Requires: The group.members.manage permission
+func (s *server) handleGroupJoinRequestDecide(approve bool) http.HandlerFunc {
+	type response struct {
+		Request models.GroupJoinRequest `json:"request"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		URLVars := mux.Vars(r)
+		groupID, _ := strconv.Atoi(URLVars["id"])
+		requestID, err := strconv.Atoi(URLVars["requestId"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		joinRequest, err := s.services.Group().DecideJoinRequest(r.Context(), groupID, requestID, user.ID, approve)
+		switch err {
+		case nil:
+			s.respond(w, r, http.StatusOK, response{*joinRequest})
+		case service.ErrPermissionDenied:
+			s.error(w, r, http.StatusForbidden, err)
+		case service.ErrJoinRequestNotFound:
+			s.error(w, r, http.StatusNotFound, err)
+		case service.ErrJoinRequestIsDecided, service.ErrUserIsAlreadyGroupMember:
+			s.error(w, r, http.StatusConflict, err)
+		default:
+			s.error(w, r, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// handleUserJoinRequests returns the join requests of the user with their statuses
+func (s *server) handleUserJoinRequests() http.HandlerFunc {
+	type response struct {
+		Total    int                       `json:"total"`
+		Requests []models.GroupJoinRequest `json:"requests"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		requests, err := s.services.Group().GetUserJoinRequests(user.ID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{Total: len(requests), Requests: requests})
+	}
+}
@@ -0,0 +1,68 @@
+package apiserver
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/config"
+	"backend/internal/service"
+	"backend/internal/store/teststore"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestServer_HandleGroupUpdate_AttachedGroup(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.Roles.DataFile = "../../configs/service_roles.json"
+	s := newServer(teststore.New(), cfg)
+	defer s.services.Close()
+	ctx := context.Background()
+
+	admin := models.TestUser(t)
+	assert.NoError(t, s.services.Auth().RegisterUser(admin))
+	assert.NoError(t, s.services.Role().AssignRole(ctx, admin.ID, models.RoleAdmin))
+	owner := &models.User{Login: "owner", FullName: "Owner", Email: "owner@example.org", Password: "password"}
+	assert.NoError(t, s.services.Auth().RegisterUser(owner))
+
+	university := &models.University{Name: "Test university"}
+	assert.NoError(t, s.services.University().Create(university))
+	other := &models.University{Name: "Other university"}
+	assert.NoError(t, s.services.University().Create(other))
+	faculty := &models.Faculty{UniversityID: university.ID, Name: "Physics"}
+	assert.NoError(t, s.services.Faculty().Create(ctx, admin.ID, faculty))
+	department := &models.Department{FacultyID: faculty.ID, Name: "Optics"}
+	assert.NoError(t, s.services.Faculty().CreateDepartment(ctx, admin.ID, department))
+
+	g := &models.Group{UniversityID: university.ID, SpecializationName: "PHYS", StartYear: "2025", CourseNumber: 1, GroupNumber: "1"}
+	assert.NoError(t, s.services.Group().Create(ctx, g, owner))
+	_, err := s.services.Faculty().SetGroupDepartment(ctx, g.ID, admin.ID, &department.ID)
+	assert.NoError(t, err)
+
+	token, _, err := s.services.Auth().UserSignIn(ctx, &models.UserSignIn{Login: owner.Login, Password: "password", IP: "127.0.0.1"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	router := mux.NewRouter()
+	router.Handle("/groups/{id:[0-9]+}", s.authenticateUser(s.handleGroupUpdate())).Methods(http.MethodPatch)
+
+	// The attached group is detached before it moves to another university
+	b := &bytes.Buffer{}
+	_ = json.NewEncoder(b).Encode(map[string]int{"university_id": other.ID})
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPatch, "/groups/"+strconv.Itoa(g.ID), b)
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), service.ErrGroupIsAttachedToDepartment.Error())
+
+	group, err := s.services.Group().Find(g.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, university.ID, group.UniversityID)
+}
@@ -30,11 +30,10 @@ type Group struct {
 }
 
 type FullGroupInfo struct {
-	Group             Group               `json:"group"`
-	GroupMembersCount int                 `json:"group_members_count"`
-	Members           []UserResponseInfo  `json:"members"`
-	GroupHaveInvite   bool                `json:"group_have_invite"`
-	Invite            *models.GroupInvite `json:"invite"`
+	Group             Group              `json:"group"`
+	GroupMembersCount int                `json:"group_members_count"`
+	Members           []UserResponseInfo `json:"members"`
+	GroupHaveInvite   bool               `json:"group_have_invite"`
 }
 
 type ShortTaskInfo struct {
@@ -102,15 +101,17 @@ func (s *server) GetFullInfoOfGroup(group models.Group) (FullGroupInfo, error) {
 	}
 	newGroupInfo.GroupMembersCount = len(newGroupInfo.Members)
 
-	invite, err := s.services.Group().GetInviteLink(group.ID)
-	if err != nil && err != service.ErrGroupHaveNotInvitation {
+	// The invites themselves are listed only to the users with the group.invite permission
+	invites, err := s.services.Group().GetInvites(group.ID)
+	if err != nil {
 		return newGroupInfo, err
 	}
-	if invite == nil {
-		newGroupInfo.GroupHaveInvite = false
-	} else {
-		newGroupInfo.GroupHaveInvite = true
-		newGroupInfo.Invite = invite
+	now := time.Now()
+	for i := range invites {
+		if invites[i].Valid(now) {
+			newGroupInfo.GroupHaveInvite = true
+			break
+		}
 	}
 
 	return newGroupInfo, nil
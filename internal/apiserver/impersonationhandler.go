@@ -0,0 +1,118 @@
+package apiserver
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// impersonationReadOnlyPrefixes are the paths where the impersonation token is read-only even if the writes are allowed
+var impersonationReadOnlyPrefixes = []string{"/api/v1/account", "/api/v1/auth"}
+
+// restrictImpersonation is the middleware.
+//For requests with the impersonation token the writes are rejected, or allowed if the config allows them.
+//Every write is written to the audit log. Reading requests aren't limited
+func (s *server) restrictImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, err := s.getSessionFromContext(r.Context())
+		if err != nil || !session.IsImpersonated() ||
+			r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed := s.config.Auth.Impersonation.AllowWrites
+		for _, prefix := range impersonationReadOnlyPrefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				allowed = false
+			}
+		}
+		details, err := json.Marshal(map[string]interface{}{
+			"session_id": session.ID,
+			"method":     r.Method,
+			"path":       r.URL.Path,
+			"allowed":    allowed,
+		})
+		if err == nil {
+			err = s.services.Audit().Record(r.Context(), &models.AuditEntry{
+				Action:     models.AuditActionImpersonationWrite,
+				TargetType: models.AuditTargetUser,
+				TargetID:   strconv.Itoa(session.UserID),
+				Details:    details,
+			})
+		}
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.logger.WithFields(logrus.Fields{
+			"admin_id":   *session.ImpersonatorID,
+			"user_id":    session.UserID,
+			"request_id": r.Context().Value(CtxKeyRequestID),
+			"allowed":    allowed,
+		}).Warn("The write with the impersonation token")
+
+		if !allowed {
+			s.error(w, r, http.StatusForbidden, service.ErrImpersonationIsReadOnly)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleImpersonateUser issues the short-lived access token of the user for the administrator
+func (s *server) handleImpersonateUser() http.HandlerFunc {
+	type request struct {
+		Reason string `json:"reason"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		// The reason is optional, so the empty body is accepted
+		req := &request{}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+				s.error(w, r, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		admin, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		ip, err := s.clientIP(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		impersonation, err := s.services.Auth().Impersonate(r.Context(), admin, userID, req.Reason, ip, r.UserAgent())
+		if err == service.ErrUserNotFound {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		} else if err == service.ErrImpersonationNotAllowed {
+			s.error(w, r, http.StatusForbidden, err)
+			return
+		} else if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		s.respond(w, r, http.StatusCreated, impersonation)
+	}
+}
@@ -0,0 +1,59 @@
+package apiserver
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/config"
+	"backend/internal/store/teststore"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServer_RestrictImpersonation(t *testing.T) {
+	s := newServer(teststore.New(), config.NewConfig())
+	defer s.services.Close()
+
+	admin := models.TestUser(t)
+	assert.NoError(t, s.services.Auth().RegisterUser(admin))
+	u := models.TestUser(t)
+	u.Login, u.Email = "Student", "student@example.org"
+	assert.NoError(t, s.services.Auth().RegisterUser(u))
+
+	impersonation, err := s.services.Auth().Impersonate(context.Background(), admin, u.ID, "", "127.0.0.1", "test")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := s.authenticateUser(s.restrictImpersonation(ok))
+
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		allowWrites  bool
+		expectedCode int
+	}{
+		{name: "read", method: http.MethodGet, path: "/api/v1/tasks", expectedCode: http.StatusOK},
+		{name: "write", method: http.MethodPost, path: "/api/v1/tasks/local/create", expectedCode: http.StatusForbidden},
+		{name: "allowed write", method: http.MethodPost, path: "/api/v1/tasks/local/create", allowWrites: true, expectedCode: http.StatusOK},
+		{name: "account", method: http.MethodPut, path: "/api/v1/account/password", allowWrites: true, expectedCode: http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s.config.Auth.Impersonation.AllowWrites = tc.allowWrites
+
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, tc.path, nil)
+			req.RemoteAddr = "127.0.0.1:1234"
+			req.Header.Set("Authorization", "Bearer "+impersonation.AccessToken)
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+}
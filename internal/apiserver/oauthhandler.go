@@ -0,0 +1,112 @@
+package apiserver
+
+import (
+	"backend/internal/service"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// OAuth2 error codes (RFC 6749, section 5.2)
+const (
+	oauthErrInvalidRequest       = "invalid_request"
+	oauthErrInvalidClient        = "invalid_client"
+	oauthErrInvalidScope         = "invalid_scope"
+	oauthErrUnsupportedGrantType = "unsupported_grant_type"
+	oauthErrServerError          = "server_error"
+
+	oauthGrantTypeClientCredentials = "client_credentials"
+)
+
+type oauthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// handleOAuthToken issues app tokens by the OAuth2 client credentials grant (RFC 6749, section 4.4).
+//	The app authenticates with HTTP Basic or with client_id and client_secret in the form body.
+//	Credentials in the query string aren't accepted.
+func (s *server) handleOAuthToken() http.HandlerFunc {
+	type response struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+		Scope       string `json:"scope"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+
+		if err := r.ParseForm(); err != nil {
+			s.oauthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, err.Error())
+			return
+		}
+
+		grantType := r.PostForm.Get("grant_type")
+		if grantType == "" {
+			s.oauthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "the grant_type parameter is missing")
+			return
+		} else if grantType != oauthGrantTypeClientCredentials {
+			s.oauthError(w, r, http.StatusBadRequest, oauthErrUnsupportedGrantType, "")
+			return
+		}
+
+		clientID, clientSecret, basicAuth := r.BasicAuth()
+		if basicAuth {
+			// The credentials are form-urlencoded before they're put in the header (RFC 6749, section 2.3.1)
+			var err error
+			if clientID, err = url.QueryUnescape(clientID); err != nil {
+				s.oauthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "invalid client_id encoding")
+				return
+			}
+			if clientSecret, err = url.QueryUnescape(clientSecret); err != nil {
+				s.oauthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "invalid client_secret encoding")
+				return
+			}
+		} else {
+			clientID = r.PostForm.Get("client_id")
+			clientSecret = r.PostForm.Get("client_secret")
+		}
+		if clientID == "" || clientSecret == "" {
+			s.oauthError(w, r, http.StatusUnauthorized, oauthErrInvalidClient, "the client credentials are missing")
+			return
+		}
+
+		token, err := s.services.Auth().IssueClientCredentialsToken(clientID, clientSecret, r.PostForm.Get("scope"))
+		switch err {
+		case nil:
+		case service.ErrAppAuthorization:
+			if basicAuth {
+				w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+			}
+			s.oauthError(w, r, http.StatusUnauthorized, oauthErrInvalidClient, err.Error())
+			return
+		case service.ErrInvalidAppScope:
+			s.oauthError(w, r, http.StatusBadRequest, oauthErrInvalidScope, err.Error())
+			return
+		default:
+			s.logger.Error("Unable to issue the app token: ", err)
+			s.oauthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{
+			AccessToken: token.AppToken,
+			TokenType:   "Bearer",
+			ExpiresIn:   int64(token.ExpirationTimestamp.Sub(token.StartTimestamp) / time.Second),
+			Scope:       token.Scope,
+		})
+	}
+}
+
+// oauthError responds with the error in the format of RFC 6749
+func (s *server) oauthError(w http.ResponseWriter, r *http.Request, code int, errorCode, description string) {
+	s.logger.WithFields(logrus.Fields{
+		"request_id":  r.Context().Value(CtxKeyRequestID),
+		"request_uri": r.RequestURI,
+		"error":       errorCode,
+	}).Debug("the app token wasn't issued: ", description)
+
+	s.respond(w, r, code, oauthErrorResponse{Error: errorCode, ErrorDescription: description})
+}
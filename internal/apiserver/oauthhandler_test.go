@@ -0,0 +1,116 @@
+package apiserver
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/config"
+	"backend/internal/store/teststore"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestServer_HandleOAuthToken(t *testing.T) {
+	cfg := config.NewConfig()
+	s := newServer(teststore.New(), cfg)
+	defer s.services.Close()
+
+	app := &models.RegisteredApp{AppName: "test app", Scope: models.AppScopeAuth}
+	assert.NoError(t, s.services.Auth().RegisterApp(context.Background(), app))
+
+	testCases := []struct {
+		name          string
+		form          url.Values
+		basicAuth     bool
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name:         "valid",
+			form:         url.Values{"grant_type": {"client_credentials"}, "client_id": {app.ID.String()}, "client_secret": {app.AppSecret}},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "valid basic auth",
+			form:         url.Values{"grant_type": {"client_credentials"}, "scope": {"auth"}},
+			basicAuth:    true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:          "missing grant type",
+			form:          url.Values{"client_id": {app.ID.String()}, "client_secret": {app.AppSecret}},
+			expectedCode:  http.StatusBadRequest,
+			expectedError: oauthErrInvalidRequest,
+		},
+		{
+			name:          "unsupported grant type",
+			form:          url.Values{"grant_type": {"password"}, "client_id": {app.ID.String()}, "client_secret": {app.AppSecret}},
+			expectedCode:  http.StatusBadRequest,
+			expectedError: oauthErrUnsupportedGrantType,
+		},
+		{
+			name:          "invalid secret",
+			form:          url.Values{"grant_type": {"client_credentials"}, "client_id": {app.ID.String()}, "client_secret": {"invalid"}},
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: oauthErrInvalidClient,
+		},
+		{
+			name:          "invalid scope",
+			form:          url.Values{"grant_type": {"client_credentials"}, "scope": {"api"}},
+			basicAuth:     true,
+			expectedCode:  http.StatusBadRequest,
+			expectedError: oauthErrInvalidScope,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(tc.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tc.basicAuth {
+				req.SetBasicAuth(url.QueryEscape(app.ID.String()), url.QueryEscape(app.AppSecret))
+			}
+			s.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+			res := map[string]interface{}{}
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+			if tc.expectedError != "" {
+				assert.Equal(t, tc.expectedError, res["error"])
+			} else {
+				assert.NotEmpty(t, res["access_token"])
+				assert.Equal(t, "Bearer", res["token_type"])
+				assert.Equal(t, models.AppScopeAuth, res["scope"])
+			}
+		})
+	}
+}
+
+func TestServer_HandleAppAuthorization_Deprecated(t *testing.T) {
+	cfg := config.NewConfig()
+	s := newServer(teststore.New(), cfg)
+	defer s.services.Close()
+
+	app := &models.RegisteredApp{AppName: "test app"}
+	assert.NoError(t, s.services.Auth().RegisterApp(context.Background(), app))
+	query := url.Values{"client_id": {app.ID.String()}, "client_secret": {app.AppSecret}}
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/auth/app/token?"+query.Encode(), nil)
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+	assert.NotEmpty(t, rec.Header().Get("X-App-Token"))
+
+	cfg.Auth.OAuth.LegacyAppTokenEnabled = false
+	rec = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/auth/app/token?"+query.Encode(), nil)
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusGone, rec.Code)
+}
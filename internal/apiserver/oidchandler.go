@@ -0,0 +1,101 @@
+package apiserver
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"encoding/json"
+	"net/http"
+)
+
+// handleOIDCAuthorize starts the sign-in with the OpenID Connect provider.
+//	The client redirects the user to authorization_url and keeps the state to compare it with the one in the redirect.
+func (s *server) handleOIDCAuthorize() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorization, err := s.services.Auth().OIDCAuthorization()
+		if err == service.ErrOIDCIsDisabled {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			s.error(w, r, http.StatusBadGateway, err)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		s.respond(w, r, http.StatusOK, authorization)
+	}
+}
+
+// handleOIDCCallback completes the sign-in with the code and the state the provider redirected the user back with.
+//	Responds with the token pair or the MFA challenge as /login does.
+func (s *server) handleOIDCCallback() http.HandlerFunc {
+	type request struct {
+		Code       string `json:"code"`
+		State      string `json:"state"`
+		DeviceName string `json:"device_name"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		ip, err := s.clientIP(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		userToken, challenge, err := s.services.Auth().OIDCSignIn(r.Context(), &models.OIDCSignIn{
+			Code:       req.Code,
+			State:      req.State,
+			DeviceName: req.DeviceName,
+			UserAgent:  r.UserAgent(),
+			IP:         ip,
+		})
+		switch err {
+		case nil:
+		case service.ErrOIDCIsDisabled:
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		case service.ErrInvalidOIDCState, service.ErrOIDCSignInFailed:
+			s.error(w, r, http.StatusUnauthorized, err)
+			return
+		case service.ErrExternalAccountIsNotLinked, service.ErrEmailIsNotConfirmed:
+			s.error(w, r, http.StatusForbidden, err)
+			return
+		case service.ErrEmailIsAlreadyOccupied:
+			s.error(w, r, http.StatusConflict, err)
+			return
+		default:
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		if challenge != nil {
+			s.respondMFAChallenge(w, r, challenge)
+			return
+		}
+
+		s.respondUserToken(w, r, userToken)
+	}
+}
+
+// handleExternalIdentities returns the identity provider accounts linked to the user
+func (s *server) handleExternalIdentities() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		identities, err := s.services.Auth().GetExternalIdentities(user.ID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, identities)
+	}
+}
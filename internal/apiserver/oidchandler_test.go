@@ -0,0 +1,76 @@
+package apiserver
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/config"
+	"backend/internal/store/teststore"
+	"backend/pkg/oidc/oidctest"
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServer_HandleOIDC(t *testing.T) {
+	p, err := oidctest.NewProvider("unitask", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	cfg := config.NewConfig()
+	cfg.Auth.OIDC.Enabled = true
+	cfg.Auth.OIDC.Issuer = p.Issuer()
+	cfg.Auth.OIDC.ClientID = "unitask"
+	cfg.Auth.OIDC.RedirectURL = "http://localhost:3000/login/oidc"
+	s := newServer(teststore.New(), cfg)
+	defer s.services.Close()
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/auth/oidc/authorize", nil)
+	s.handleOIDCAuthorize().ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	authorization := &models.OIDCAuthorization{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(authorization))
+	code, state, err := p.Authorize(authorization.URL, oidctest.Identity{
+		Subject:       "s-1",
+		Email:         "student@university.example",
+		EmailVerified: true,
+	})
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		payload      map[string]string
+		expectedCode int
+	}{
+		{
+			name:         "valid",
+			payload:      map[string]string{"code": code, "state": state, "device_name": "browser"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "used state",
+			payload:      map[string]string{"code": code, "state": state},
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			assert.NoError(t, json.NewEncoder(b).Encode(tc.payload))
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, "/api/v1/auth/oidc/callback", b)
+			req.RemoteAddr = "127.0.0.1:12345"
+			s.handleOIDCCallback().ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			if tc.expectedCode == http.StatusOK {
+				assert.Contains(t, rec.Body.String(), "access_token")
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package apiserver
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"context"
+	"encoding/json"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// requireTokenScope is the middleware.
+//For requests authenticated with the personal access token the token must have the scope.
+//Requests authenticated with the JWT access token aren't limited
+func (s *server) requireTokenScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token, err := s.getPersonalTokenFromContext(r.Context()); err == nil && !token.HasScope(scope) {
+				s.error(w, r, http.StatusForbidden, service.ErrInsufficientTokenScope)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireTokenScopeByMethod is the middleware. It works as requireTokenScope
+//with readScope for GET and HEAD requests and with writeScope for others
+func (s *server) requireTokenScopeByMethod(readScope, writeScope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		read := s.requireTokenScope(readScope)(next)
+		write := s.requireTokenScope(writeScope)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				read.ServeHTTP(w, r)
+				return
+			}
+			write.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rejectPersonalToken is the middleware.
+//For methods that can't be used with personal access tokens: account management, sessions and administration
+func (s *server) rejectPersonalToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := s.getPersonalTokenFromContext(r.Context()); err == nil {
+			s.error(w, r, http.StatusForbidden, service.ErrPersonalTokenNotAccepted)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// getPersonalTokenFromContext returns the personal access token the request was authenticated with
+func (s *server) getPersonalTokenFromContext(ctx context.Context) (*models.PersonalToken, error) {
+	token, ok := ctx.Value(CtxKeyPersonalToken).(*models.PersonalToken)
+	if !ok || token == nil {
+		return nil, service.ErrInvalidPersonalToken
+	}
+	return token, nil
+}
+
+// handleCreatePersonalToken creates the personal access token.
+//	The raw token is returned only once in the response
+func (s *server) handleCreatePersonalToken() http.HandlerFunc {
+	type request struct {
+		Name string `json:"name"`
+		// Scope is space-delimited, e.g. "tasks:read tasks:write"
+		Scope     string    `json:"scope"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	type response struct {
+		Token         string               `json:"token"`
+		PersonalToken models.PersonalToken `json:"personal_token"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		t, token, err := s.services.Auth().CreatePersonalToken(r.Context(), user.ID, req.Name, req.Scope, req.ExpiresAt)
+		if err != nil {
+			if _, ok := err.(validation.Errors); ok {
+				s.error(w, r, http.StatusBadRequest, err)
+				return
+			}
+			switch err {
+			case service.ErrInvalidTokenScope, service.ErrInvalidTokenExpiry:
+				s.error(w, r, http.StatusBadRequest, err)
+			case service.ErrTooManyPersonalTokens:
+				s.error(w, r, http.StatusConflict, err)
+			default:
+				s.error(w, r, http.StatusInternalServerError, err)
+			}
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, response{Token: token, PersonalToken: *t})
+	}
+}
+
+func (s *server) handlePersonalTokens() http.HandlerFunc {
+	type response struct {
+		Total  int                    `json:"total"`
+		Tokens []models.PersonalToken `json:"tokens"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		tokens, err := s.services.Auth().GetPersonalTokens(user.ID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{Total: len(tokens), Tokens: tokens})
+	}
+}
+
+func (s *server) handleRevokePersonalToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		err = s.services.Auth().RevokePersonalToken(r.Context(), user.ID, tokenID)
+		if err == service.ErrPersonalTokenNotFound {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
@@ -0,0 +1,79 @@
+package apiserver
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/config"
+	"backend/internal/store/teststore"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServer_PersonalTokenScopes(t *testing.T) {
+	s := newServer(teststore.New(), config.NewConfig())
+	defer s.services.Close()
+
+	u := models.TestUser(t)
+	assert.NoError(t, s.services.Auth().RegisterUser(u))
+	_, token, err := s.services.Auth().CreatePersonalToken(context.Background(), u.ID, "bot", models.TokenScopeTasksRead, time.Time{})
+	assert.NoError(t, err)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := s.authenticateUser(s.requireTokenScopeByMethod(models.TokenScopeTasksRead, models.TokenScopeTasksWrite)(ok))
+	account := s.authenticateUser(s.rejectPersonalToken(ok))
+
+	testCases := []struct {
+		name         string
+		handler      http.Handler
+		method       string
+		token        string
+		expectedCode int
+	}{
+		{
+			name:         "read with scope",
+			handler:      handler,
+			method:       http.MethodGet,
+			token:        token,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "write without scope",
+			handler:      handler,
+			method:       http.MethodPost,
+			token:        token,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "account management",
+			handler:      account,
+			method:       http.MethodGet,
+			token:        token,
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			tc.handler.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+
+	// Revoked tokens aren't accepted
+	tokens, err := s.services.Auth().GetPersonalTokens(u.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, s.services.Auth().RevokePersonalToken(context.Background(), u.ID, tokens[0].ID))
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	handler.ServeHTTP(rec, req)
+	assert.Contains(t, rec.Body.String(), "invalid_access_token")
+}
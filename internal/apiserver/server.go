@@ -6,15 +6,21 @@ import (
 	"backend/internal/service"
 	"backend/internal/service/services"
 	"backend/internal/store"
+	"backend/pkg/ServiceData"
 	"backend/pkg/hooks"
+	"backend/pkg/mailer"
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 )
 
 type ctxKey int8
@@ -23,6 +29,9 @@ const (
 	CtxKeyUser = ctxKey(iota)
 	CtxKeyRequestID
 	CtxKeyAppID
+	CtxKeySession
+	CtxKeyAppToken
+	CtxKeyPersonalToken
 )
 
 type server struct {
@@ -32,6 +41,8 @@ type server struct {
 	config     *config.Config
 	logrusHook *hooks.LogsStoreHook
 	remoteHook *hooks.LogsRemoteControllerHook
+	// trustedProxies are the networks of the load balancers, see config.HTTPConfig
+	trustedProxies []*net.IPNet
 }
 
 func newServer(store store.Store, config *config.Config) *server {
@@ -51,9 +62,132 @@ func newServer(store store.Store, config *config.Config) *server {
 
 	s.services.AddLogger(s.logger)
 
+	s.trustedProxies, err = parseTrustedProxies(config.HTTP.TrustedProxies)
+	if err != nil {
+		s.logger.Error("cannot parse trusted proxies: ", err)
+	}
+
+	if err := s.seedRoles(config.Roles); err != nil {
+		s.logger.Error("cannot seed roles: ", err)
+	}
+
+	m, err := newMailer(config.Mailer, s.logger)
+	if err != nil {
+		s.logger.Error("cannot configure mailer: ", err)
+	} else {
+		s.services.AddMailer(m)
+	}
+
+	// The group service runs the scheduler of the academic year rollover
+	s.services.Group()
+
 	return s
 }
 
+// seedRoles writes roles and permissions from the service data file to the store
+func (s *server) seedRoles(cfg config.RolesConfig) error {
+	data, err := ServiceData.NewServiceData(filepath.Dir(cfg.DataFile)).
+		GetDataFromFile(filepath.Base(cfg.DataFile), s.logger)
+	if err != nil {
+		return err
+	}
+
+	return s.services.Role().SeedRoles(data)
+}
+
+// parseTrustedProxies parses the IP addresses and CIDR ranges of the trusted proxies
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			proxy = fmt.Sprintf("%s/%d", proxy, bits)
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// clientIP returns the IP address of the client. X-Forwarded-For and X-Real-IP are read only
+//if the request came from a trusted proxy, the addresses of other trusted proxies in the chain are skipped.
+func (s *server) clientIP(r *http.Request) (string, error) {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "", err
+	}
+	if !s.isTrustedProxy(ip) {
+		return ip, nil
+	}
+
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			ip = hop
+			if !s.isTrustedProxy(hop) {
+				break
+			}
+		}
+		return ip, nil
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP, nil
+	}
+
+	return ip, nil
+}
+
+func (s *server) isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, network := range s.trustedProxies {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newMailer returns the mailer of the configured driver
+func newMailer(cfg config.MailerConfig, logger *logrus.Logger) (mailer.Mailer, error) {
+	switch cfg.Driver {
+	case config.MailerDriverSMTP:
+		return mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     cfg.Host,
+			Port:     cfg.Port,
+			Username: cfg.Username,
+			Password: cfg.Password,
+		}), nil
+	case config.MailerDriverFile:
+		return mailer.NewFileMailer(cfg.Dir)
+	case config.MailerDriverLog, "":
+		return mailer.NewLogMailer(logger), nil
+	}
+
+	return nil, fmt.Errorf("unknown mailer driver %q", cfg.Driver)
+}
+
 func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.router.ServeHTTP(w, r)
 }
@@ -242,6 +376,16 @@ func (s *server) getUserFromContext(ctx context.Context) (*models.User, error) {
 	return user, nil
 }
 
+// getSessionFromContext returns the session of the access token.
+//	Tokens issued before sessions were introduced have no session.
+func (s *server) getSessionFromContext(ctx context.Context) (*models.UserSession, error) {
+	session, ok := ctx.Value(CtxKeySession).(*models.UserSession)
+	if !ok || session == nil {
+		return nil, service.ErrSessionNotFoundInContext
+	}
+	return session, nil
+}
+
 func (s *server) getRequestIDFromContext(ctx context.Context) (uuid.UUID, error) {
 	uid, ok := ctx.Value(CtxKeyRequestID).(uuid.UUID)
 	if !ok {
@@ -251,6 +395,15 @@ func (s *server) getRequestIDFromContext(ctx context.Context) (uuid.UUID, error)
 	return uid, nil
 }
 
+// getAppTokenFromContext returns the token the app was authenticated with
+func (s *server) getAppTokenFromContext(ctx context.Context) (*models.AppToken, error) {
+	token, ok := ctx.Value(CtxKeyAppToken).(*models.AppToken)
+	if !ok || token == nil {
+		return nil, service.ErrAppIDNotFoundInContext
+	}
+	return token, nil
+}
+
 func (s *server) getAppIDFromContext(ctx context.Context) (uuid.UUID, error) {
 	uid, ok := ctx.Value(CtxKeyAppID).(uuid.UUID)
 	if !ok {
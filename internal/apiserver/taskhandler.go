@@ -322,6 +322,9 @@ func (s *server) handleCreateGroupTask() http.HandlerFunc {
 			if err == models.ErrTaskCannotPointToItself {
 				s.error(w, r, http.StatusBadRequest, err)
 				return
+			} else if err == service.ErrPermissionDenied {
+				s.error(w, r, http.StatusForbidden, err)
+				return
 			}
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
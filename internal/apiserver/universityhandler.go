@@ -0,0 +1,525 @@
+package apiserver
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"encoding/json"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+func (s *server) handleUniversities() http.HandlerFunc {
+	type response struct {
+		Universities []models.University `json:"universities"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		universities, err := s.services.University().GetAll()
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{universities})
+	}
+}
+
+func (s *server) handleUniversity() http.HandlerFunc {
+	type response struct {
+		University models.University `json:"university"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		universityID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		university, err := s.services.University().Find(universityID)
+		if err != nil {
+			s.orgTreeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{*university})
+	}
+}
+
+//	Requires: The admin.universities.manage permission
+func (s *server) handleUniversityCreate() http.HandlerFunc {
+	type response struct {
+		University models.University `json:"university"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		university := &models.University{}
+		if err := json.NewDecoder(r.Body).Decode(university); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.services.University().Create(university); err != nil {
+			s.orgTreeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, response{*university})
+	}
+}
+
+func (s *server) handleFaculties() http.HandlerFunc {
+	type response struct {
+		Faculties []models.Faculty `json:"faculties"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		universityID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		faculties, err := s.services.Faculty().GetFaculties(universityID)
+		if err != nil {
+			s.orgTreeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{faculties})
+	}
+}
+
+// handleFacultyCreate creates the faculty of the university from the {id} URL variable.
+//	Requires: The admin.universities.manage permission
+func (s *server) handleFacultyCreate() http.HandlerFunc {
+	type request struct {
+		Name string `json:"name"`
+	}
+	type response struct {
+		Faculty models.Faculty `json:"faculty"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		universityID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		faculty := &models.Faculty{UniversityID: universityID, Name: req.Name}
+		if err := s.services.Faculty().Create(r.Context(), user.ID, faculty); err != nil {
+			s.orgTreeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, response{*faculty})
+	}
+}
+
+func (s *server) handleFaculty() http.HandlerFunc {
+	type response struct {
+		Faculty     models.Faculty      `json:"faculty"`
+		Departments []models.Department `json:"departments"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		facultyID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		faculty, err := s.services.Faculty().Find(facultyID)
+		if err != nil {
+			s.orgTreeError(w, r, err)
+			return
+		}
+
+		departments, err := s.services.Faculty().GetDepartments(facultyID)
+		if err != nil {
+			s.orgTreeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{*faculty, departments})
+	}
+}
+
+//	Requires: The faculty.manage permission in the faculty
+func (s *server) handleFacultyUpdate() http.HandlerFunc {
+	type request struct {
+		Name string `json:"name"`
+	}
+	type response struct {
+		Faculty models.Faculty `json:"faculty"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		facultyID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		faculty, err := s.services.Faculty().Update(r.Context(), facultyID, user.ID, req.Name)
+		if err != nil {
+			s.orgTreeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{*faculty})
+	}
+}
+
+//	Requires: The admin.universities.manage permission
+func (s *server) handleFacultyDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		facultyID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		if err := s.services.Faculty().Delete(r.Context(), facultyID, user.ID); err != nil {
+			s.orgTreeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// handleAssignFacultyRole grants the faculty role to the user in the faculty, e.g. the faculty_admin one.
+//	Requires: The admin.universities.manage permission
+func (s *server) handleAssignFacultyRole() http.HandlerFunc {
+	type request struct {
+		Role string `json:"role"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		URLVars := mux.Vars(r)
+		facultyID, err := strconv.Atoi(URLVars["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+		userID, err := strconv.Atoi(URLVars["userId"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		s.respondRoleChange(w, r, s.services.Role().AssignFacultyRole(r.Context(), userID, facultyID, req.Role))
+	}
+}
+
+//	Requires: The admin.universities.manage permission
+func (s *server) handleRevokeFacultyRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		URLVars := mux.Vars(r)
+		facultyID, err := strconv.Atoi(URLVars["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+		userID, err := strconv.Atoi(URLVars["userId"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		s.respondRoleChange(w, r, s.services.Role().RevokeFacultyRole(r.Context(), userID, facultyID, URLVars["role"]))
+	}
+}
+
+// handleDepartmentCreate creates the department of the faculty from the {id} URL variable.
+//	Requires: The faculty.manage permission in the faculty
+func (s *server) handleDepartmentCreate() http.HandlerFunc {
+	type request struct {
+		Name string `json:"name"`
+	}
+	type response struct {
+		Department models.Department `json:"department"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		facultyID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		department := &models.Department{FacultyID: facultyID, Name: req.Name}
+		if err := s.services.Faculty().CreateDepartment(r.Context(), user.ID, department); err != nil {
+			s.orgTreeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, response{*department})
+	}
+}
+
+func (s *server) handleDepartment() http.HandlerFunc {
+	type response struct {
+		Department models.Department `json:"department"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		departmentID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		department, err := s.services.Faculty().FindDepartment(departmentID)
+		if err != nil {
+			s.orgTreeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{*department})
+	}
+}
+
+//	Requires: The faculty.manage permission in the faculty of the department
+func (s *server) handleDepartmentUpdate() http.HandlerFunc {
+	type request struct {
+		Name string `json:"name"`
+	}
+	type response struct {
+		Department models.Department `json:"department"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		departmentID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		department, err := s.services.Faculty().UpdateDepartment(r.Context(), departmentID, user.ID, req.Name)
+		if err != nil {
+			s.orgTreeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{*department})
+	}
+}
+
+//	Requires: The faculty.manage permission in the faculty of the department
+func (s *server) handleDepartmentDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		departmentID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		if err := s.services.Faculty().DeleteDepartment(r.Context(), departmentID, user.ID); err != nil {
+			s.orgTreeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// handleOrgNodeGroups returns the groups of the departments of the node of the org tree from the {id} URL variable
+func (s *server) handleOrgNodeGroups(nodeType string) http.HandlerFunc {
+	type response struct {
+		Total  int            `json:"total"`
+		Groups []models.Group `json:"groups"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		nodeID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		groups, err := s.services.Faculty().GetGroups(models.OrgNode{Type: nodeType, ID: nodeID})
+		if err != nil {
+			s.orgTreeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{len(groups), groups})
+	}
+}
+
+// handleOrgNodeSubjects returns the subjects of the departments of the node of the org tree from the {id} URL variable
+func (s *server) handleOrgNodeSubjects(nodeType string) http.HandlerFunc {
+	type response struct {
+		Total    int              `json:"total"`
+		Subjects []models.Subject `json:"subjects"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		nodeID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		subjects, err := s.services.Faculty().GetSubjects(models.OrgNode{Type: nodeType, ID: nodeID})
+		if err != nil {
+			s.orgTreeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{len(subjects), subjects})
+	}
+}
+
+// handleGroupDepartment attaches the group to the department, the null department_id detaches it.
+//	Requires: The faculty.manage permission in the faculties of the current and the new departments
+func (s *server) handleGroupDepartment() http.HandlerFunc {
+	type request struct {
+		DepartmentID *int `json:"department_id"`
+	}
+	type response struct {
+		Group models.Group `json:"group"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		group, err := s.services.Faculty().SetGroupDepartment(r.Context(), groupID, user.ID, req.DepartmentID)
+		if err != nil {
+			s.orgTreeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{*group})
+	}
+}
+
+// handleSubjectDepartment attaches the subject to the department, the null department_id detaches it.
+//	Requires: The faculty.manage permission in the faculties of the current and the new departments
+func (s *server) handleSubjectDepartment() http.HandlerFunc {
+	type request struct {
+		DepartmentID *int `json:"department_id"`
+	}
+	type response struct {
+		Subject models.Subject `json:"subject"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		subjectID, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.getUserFromContext(r.Context())
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		subject, err := s.services.Faculty().SetSubjectDepartment(r.Context(), subjectID, user.ID, req.DepartmentID)
+		if err != nil {
+			s.orgTreeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, response{*subject})
+	}
+}
+
+func (s *server) orgTreeError(w http.ResponseWriter, r *http.Request, err error) {
+	if _, ok := err.(validation.Errors); ok {
+		s.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	switch err {
+	case service.ErrPermissionDenied:
+		s.error(w, r, http.StatusForbidden, err)
+	case service.ErrUniversityNotFound, service.ErrFacultyNotFound, service.ErrDepartmentNotFound,
+		service.ErrGroupNotFound, service.ErrSubjectNotFound:
+		s.error(w, r, http.StatusNotFound, err)
+	case service.ErrDepartmentOfAnotherUniversity:
+		s.error(w, r, http.StatusConflict, err)
+	default:
+		s.error(w, r, http.StatusInternalServerError, err)
+	}
+}
@@ -13,12 +13,97 @@ const (
 	defaultAccessTokenTTL  = 15 * time.Minute
 	defaultRefreshTokenTTL = 24 * time.Hour * 30
 	defaultLimiterRPS      = 200 //TODO: Add limiter
+	defaultSigningKey      = "startKey"
+
+	defaultSigningAlgorithm    = SigningAlgorithmHS256
+	defaultKeyRotationInterval = 24 * time.Hour * 7
+
+	defaultBlacklistStorage         = BlacklistStoragePostgres
+	defaultBlacklistCleanupInterval = 10 * time.Minute
+
+	defaultEmailConfirmationTTL = 24 * time.Hour
+	defaultEmailConfirmationURL = "http://localhost:8000/api/v1/account/emailconfimation"
+
+	defaultPasswordResetTTL = 1 * time.Hour
+	defaultPasswordResetURL = "http://localhost:8000/password/reset"
+
+	defaultPasswordMinLength = 8
+
+	defaultTwoFactorIssuer       = "Unitask"
+	defaultTwoFactorChallengeTTL = 5 * time.Minute
+
+	defaultLoginThrottleFreeAttempts     = 3
+	defaultLoginThrottleBaseDelay        = 1 * time.Second
+	defaultLoginThrottleMaxDelay         = 5 * time.Minute
+	defaultLoginThrottleFailureWindow    = 1 * time.Hour
+	defaultLoginThrottleAccountThreshold = 10
+	defaultLoginThrottleIPThreshold      = 100
+	defaultLoginThrottleLockoutDuration  = 15 * time.Minute
+
+	defaultOAuthTokenTTL = 1 * time.Hour
+
+	defaultPersonalTokenTTL        = 24 * time.Hour * 90
+	defaultPersonalTokenMaxTTL     = 24 * time.Hour * 365
+	defaultPersonalTokenMaxPerUser = 20
+
+	defaultOIDCStateTTL = 10 * time.Minute
+
+	defaultImpersonationTokenTTL = 15 * time.Minute
+
+	defaultFingerprintPolicy     = "off"
+	defaultFingerprintIPv4Prefix = 16
+	defaultFingerprintIPv6Prefix = 48
+
+	defaultLDAPTimeout           = 10 * time.Second
+	defaultLDAPUserFilter        = "(&(objectClass=person)(|(uid={username})(mail={username})))"
+	defaultLDAPLoginAttribute    = "uid"
+	defaultLDAPFullNameAttribute = "cn"
+	defaultLDAPEmailAttribute    = "mail"
+	defaultLDAPGroupAttribute    = "memberOf"
+
+	defaultGroupInviteTTL         = 24 * time.Hour * 15
+	defaultGroupInviteMaxTTL      = 24 * time.Hour * 365
+	defaultGroupInviteMaxPerGroup = 50
+	defaultGroupImportMaxRows     = 500
+	defaultGroupImportInviteTTL   = 24 * time.Hour * 7
+	defaultGroupRolloverMonth     = 9
+	defaultGroupRolloverDay       = 1
+	defaultGroupFinalCourse       = 4
+	defaultGroupDeletionRetention = 24 * time.Hour * 30
+	defaultGroupPurgeInterval     = 1 * time.Hour
+
+	defaultAuditRetention     = 24 * time.Hour * 365
+	defaultAuditPruneInterval = 1 * time.Hour
+
+	defaultMailerDriver = MailerDriverLog
+	defaultMailerFrom   = "Unitask <noreply@unitask.local>"
+	defaultMailerDir    = "./mail"
+	defaultSMTPPort     = 587
+
+	defaultRolesDataFile = "./configs/service_roles.json"
 
 	defaultLogrusLevel = "trace"
 
 	EnvLocal = "local"
 	EnvProd  = "prod"
 	EnvDev   = "dev"
+
+	// SigningAlgorithmHS256 signs access tokens with the shared SigningKey. Keys aren't rotated and published
+	SigningAlgorithmHS256 = "HS256"
+	// SigningAlgorithmRS256 and SigningAlgorithmEdDSA sign access tokens with the rotated key ring.
+	//Public keys are published at /.well-known/jwks.json
+	SigningAlgorithmRS256 = "RS256"
+	SigningAlgorithmEdDSA = "EdDSA"
+
+	// MailerDriverSMTP sends emails through the SMTP server, the other drivers are for local development
+	MailerDriverSMTP = "smtp"
+	MailerDriverFile = "file"
+	MailerDriverLog  = "log"
+
+	// BlacklistStorageMemory keeps revoked tokens in the process. Suitable only for a single API server instance
+	BlacklistStorageMemory = "memory"
+	// BlacklistStoragePostgres keeps revoked tokens in the database, shared by all API server replicas
+	BlacklistStoragePostgres = "postgres"
 )
 
 type (
@@ -27,6 +112,10 @@ type (
 		Postgres    PostgresConfig
 		HTTP        HTTPConfig
 		Auth        AuthConfig
+		Mailer      MailerConfig
+		Groups      GroupsConfig
+		Audit       AuditConfig
+		Roles       RolesConfig
 		Logrus      LogrusConfig
 	}
 
@@ -37,21 +126,247 @@ type (
 		DBName   string
 	}
 
+	// HTTPConfig. TrustedProxies are the IP addresses or CIDR ranges of the load balancers.
+	//	The client address is read from X-Forwarded-For or X-Real-IP only if the request came from one of them.
 	HTTPConfig struct {
-		Host         string
-		Port         string
-		ReadTimeout  time.Duration
-		WriteTimeout time.Duration
+		Host           string
+		Port           string
+		ReadTimeout    time.Duration
+		WriteTimeout   time.Duration
+		TrustedProxies []string `mapstructure:"trustedProxies"`
 	}
 
 	AuthConfig struct {
-		JWT JWTConfig
+		JWT               JWTConfig
+		Blacklist         BlacklistConfig
+		EmailConfirmation EmailConfirmationConfig
+		PasswordReset     PasswordResetConfig
+		PasswordPolicy    PasswordPolicyConfig
+		TwoFactor         TwoFactorConfig
+		LoginThrottle     LoginThrottleConfig
+		OAuth             OAuthConfig
+		PersonalTokens    PersonalTokensConfig
+		OIDC              OIDCConfig
+		LDAP              LDAPConfig
+		Impersonation     ImpersonationConfig
+		Fingerprint       FingerprintConfig
 	}
 
 	JWTConfig struct {
 		AccessTokenTTL  time.Duration `mapstructure:"accessTokenTTL"`
 		RefreshTokenTTL time.Duration `mapstructure:"refreshTokenTTL"`
 		SigningKey      string        `mapstructure:"signingKey"`
+
+		SigningAlgorithm    string        `mapstructure:"signingAlgorithm"`
+		KeyRotationInterval time.Duration `mapstructure:"keyRotationInterval"`
+		// KeyOverlap is how long a retired key still verifies tokens. It can't be less than AccessTokenTTL
+		KeyOverlap time.Duration `mapstructure:"keyOverlap"`
+	}
+
+	BlacklistConfig struct {
+		Storage         string        `mapstructure:"storage"`
+		CleanupInterval time.Duration `mapstructure:"cleanupInterval"`
+	}
+
+	// EmailConfirmationConfig decides what users without a confirmed email are allowed to do.
+	//	URL is the link sent in the email, the token is added as the "token" query parameter.
+	EmailConfirmationConfig struct {
+		TokenTTL            time.Duration `mapstructure:"tokenTTL"`
+		URL                 string        `mapstructure:"url"`
+		RequiredToSignIn    bool          `mapstructure:"requiredToSignIn"`
+		RequiredToJoinGroup bool          `mapstructure:"requiredToJoinGroup"`
+	}
+
+	// PasswordResetConfig. URL is the page of the client that sends the new password
+	//	with the token from the "token" query parameter to /api/v1/auth/password/reset.
+	PasswordResetConfig struct {
+		TokenTTL time.Duration `mapstructure:"tokenTTL"`
+		URL      string        `mapstructure:"url"`
+	}
+
+	// TwoFactorConfig. Issuer is shown in authenticator apps next to the account.
+	//	ChallengeTTL is how long the user has to enter the code after the password was accepted.
+	TwoFactorConfig struct {
+		Issuer       string        `mapstructure:"issuer"`
+		ChallengeTTL time.Duration `mapstructure:"challengeTTL"`
+	}
+
+	// LoginThrottleConfig. After FreeAttempts failed sign-ins of the account or from the IP address
+	//	the next attempt is accepted only after the delay, that starts at BaseDelay and doubles up to MaxDelay.
+	//	The account or the IP address is locked for LockoutDuration after the threshold of failures.
+	//	Failures older than FailureWindow are forgotten.
+	//	Behind a load balancer it must be listed in HTTP.TrustedProxies, otherwise all clients share the IP address
+	//	of the balancer and are locked together after IPLockoutThreshold failures.
+	LoginThrottleConfig struct {
+		Enabled                 bool          `mapstructure:"enabled"`
+		FreeAttempts            int           `mapstructure:"freeAttempts"`
+		BaseDelay               time.Duration `mapstructure:"baseDelay"`
+		MaxDelay                time.Duration `mapstructure:"maxDelay"`
+		FailureWindow           time.Duration `mapstructure:"failureWindow"`
+		AccountLockoutThreshold int           `mapstructure:"accountLockoutThreshold"`
+		IPLockoutThreshold      int           `mapstructure:"ipLockoutThreshold"`
+		LockoutDuration         time.Duration `mapstructure:"lockoutDuration"`
+	}
+
+	// OAuthConfig. Access tokens issued to apps by /oauth/token expire after TokenTTL.
+	//	LegacyAppTokenEnabled keeps the deprecated /api/v1/auth/app/token endpoint working until clients migrate.
+	// PasswordPolicyConfig is checked when the password is registered, changed or reset.
+	//	DisallowPersonalInfo rejects passwords containing the login, the email or a word of the full name.
+	//	BreachedPasswordsFile is the list of SHA-1 hashes of known-breached passwords sorted by the hash,
+	//	one "HASH[:COUNT]" per line. The check is disabled, if it's empty.
+	PasswordPolicyConfig struct {
+		MinLength             int    `mapstructure:"minLength"`
+		RequireLowercase      bool   `mapstructure:"requireLowercase"`
+		RequireUppercase      bool   `mapstructure:"requireUppercase"`
+		RequireDigit          bool   `mapstructure:"requireDigit"`
+		RequireSymbol         bool   `mapstructure:"requireSymbol"`
+		DisallowPersonalInfo  bool   `mapstructure:"disallowPersonalInfo"`
+		BreachedPasswordsFile string `mapstructure:"breachedPasswordsFile"`
+	}
+
+	OAuthConfig struct {
+		TokenTTL              time.Duration `mapstructure:"tokenTTL"`
+		LegacyAppTokenEnabled bool          `mapstructure:"legacyAppTokenEnabled"`
+	}
+
+	// PersonalTokensConfig. Personal access tokens without the expiry expire after DefaultTTL,
+	//	the expiry can't be later than MaxTTL. MaxPerUser limits active tokens of the user.
+	PersonalTokensConfig struct {
+		DefaultTTL time.Duration `mapstructure:"defaultTTL"`
+		MaxTTL     time.Duration `mapstructure:"maxTTL"`
+		MaxPerUser int           `mapstructure:"maxPerUser"`
+	}
+
+	// ImpersonationConfig. The administrator's access token of the user expires after TokenTTL.
+	//	The writes with the token are rejected, if AllowWrites is set they are allowed and written to the audit log.
+	ImpersonationConfig struct {
+		TokenTTL    time.Duration `mapstructure:"tokenTTL"`
+		AllowWrites bool          `mapstructure:"allowWrites"`
+	}
+
+	// OIDCConfig is the OpenID Connect provider users can sign in with instead of the password.
+	//	The provider endpoints are discovered from Issuer/.well-known/openid-configuration.
+	//	RedirectURL is the client page that receives the code and posts it to /api/v1/auth/oidc/callback.
+	//	On the first sign-in the subject is linked to the user with the same verified email if LinkByEmail is set,
+	//	otherwise the account is created if AutoProvision is set.
+	OIDCConfig struct {
+		Enabled       bool          `mapstructure:"enabled"`
+		Issuer        string        `mapstructure:"issuer"`
+		ClientID      string        `mapstructure:"clientID"`
+		ClientSecret  string        `mapstructure:"clientSecret"`
+		RedirectURL   string        `mapstructure:"redirectURL"`
+		Scopes        []string      `mapstructure:"scopes"`
+		StateTTL      time.Duration `mapstructure:"stateTTL"`
+		AutoProvision bool          `mapstructure:"autoProvision"`
+		LinkByEmail   bool          `mapstructure:"linkByEmail"`
+	}
+
+	// LDAPConfig is the directory users can sign in with by the login and the password.
+	//	The local password is checked first, then the user is searched with UserFilter ({username} is replaced
+	//	with the login or the email) and bound with its DN. IDAttribute is the stable id of the entry, the DN if empty.
+	//	The directory users are linked and provisioned as the OIDC users are.
+	LDAPConfig struct {
+		Enabled            bool                `mapstructure:"enabled"`
+		URL                string              `mapstructure:"url"`
+		StartTLS           bool                `mapstructure:"startTLS"`
+		InsecureSkipVerify bool                `mapstructure:"insecureSkipVerify"`
+		Timeout            time.Duration       `mapstructure:"timeout"`
+		BindDN             string              `mapstructure:"bindDN"`
+		BindPassword       string              `mapstructure:"bindPassword"`
+		BaseDN             string              `mapstructure:"baseDN"`
+		UserFilter         string              `mapstructure:"userFilter"`
+		IDAttribute        string              `mapstructure:"idAttribute"`
+		LoginAttribute     string              `mapstructure:"loginAttribute"`
+		FullNameAttribute  string              `mapstructure:"fullNameAttribute"`
+		EmailAttribute     string              `mapstructure:"emailAttribute"`
+		AutoProvision      bool                `mapstructure:"autoProvision"`
+		LinkByEmail        bool                `mapstructure:"linkByEmail"`
+		GroupSync          LDAPGroupSyncConfig `mapstructure:"groupSync"`
+	}
+
+	// LDAPGroupSyncConfig. On every LDAP sign-in the user is added to the Unitask groups
+	//	mapped to the directory groups the user is a member of (the GroupAttribute values).
+	LDAPGroupSyncConfig struct {
+		Enabled        bool               `mapstructure:"enabled"`
+		GroupAttribute string             `mapstructure:"groupAttribute"`
+		Groups         []LDAPGroupMapping `mapstructure:"groups"`
+	}
+
+	LDAPGroupMapping struct {
+		DN      string `mapstructure:"dn"`
+		GroupID int    `mapstructure:"groupID"`
+	}
+
+	// RolesConfig. Roles and permissions are seeded from the service data file on start.
+	//	Users with the Administrators logins are granted the admin role.
+	RolesConfig struct {
+		DataFile       string   `mapstructure:"dataFile"`
+		Administrators []string `mapstructure:"administrators"`
+	}
+
+	MailerConfig struct {
+		Driver   string `mapstructure:"driver"`
+		From     string `mapstructure:"from"`
+		Dir      string `mapstructure:"dir"`
+		Host     string `mapstructure:"host"`
+		Port     int    `mapstructure:"port"`
+		Username string `mapstructure:"username"`
+		Password string `mapstructure:"password"`
+	}
+
+	GroupsConfig struct {
+		Invites  GroupInvitesConfig  `mapstructure:"invites"`
+		Import   GroupImportConfig   `mapstructure:"import"`
+		Rollover GroupRolloverConfig `mapstructure:"rollover"`
+		Deletion GroupDeletionConfig `mapstructure:"deletion"`
+	}
+
+	// GroupInvitesConfig. Invites expire after DefaultTTL, if the expiry isn't set, and can't live longer than MaxTTL.
+	//	MaxTTL and MaxPerGroup aren't limited, if they are zero.
+	GroupInvitesConfig struct {
+		DefaultTTL  time.Duration `mapstructure:"defaultTTL"`
+		MaxTTL      time.Duration `mapstructure:"maxTTL"`
+		MaxPerGroup int           `mapstructure:"maxPerGroup"`
+	}
+
+	// GroupImportConfig. MaxRows limits the rows of one imported CSV file, it isn't limited, if it is zero.
+	//	The placeholder accounts get an invite email with a password reset link that lives for InviteTTL.
+	GroupImportConfig struct {
+		MaxRows   int           `mapstructure:"maxRows"`
+		InviteTTL time.Duration `mapstructure:"inviteTTL"`
+	}
+
+	// GroupRolloverConfig. The academic year starts on the Day of the Month, then the groups move to the next course,
+	//	and the groups of the FinalCourse graduate and are archived. The scheduler checks every Interval,
+	//	whether the groups of the universities are rolled over to the current academic year. It's disabled, if Interval is zero.
+	GroupRolloverConfig struct {
+		Month       int           `mapstructure:"month"`
+		Day         int           `mapstructure:"day"`
+		FinalCourse int           `mapstructure:"finalCourse"`
+		Interval    time.Duration `mapstructure:"interval"`
+	}
+
+	// GroupDeletionConfig. The deleted groups are restored by an administrator within Retention,
+	//	then they are purged every PurgeInterval. The deleted groups are kept forever, if Retention is zero.
+	GroupDeletionConfig struct {
+		Retention     time.Duration `mapstructure:"retention"`
+		PurgeInterval time.Duration `mapstructure:"purgeInterval"`
+	}
+
+	// AuditConfig. The entries of the audit log older than Retention are deleted every PruneInterval.
+	//	The entries are kept forever, if Retention is zero.
+	AuditConfig struct {
+		Retention     time.Duration `mapstructure:"retention"`
+		PruneInterval time.Duration `mapstructure:"pruneInterval"`
+	}
+
+	// FingerprintConfig. The user access tokens carry the fingerprint of the session: the IP network and the device type.
+	//	The IP addresses are masked to IPv4Prefix and IPv6Prefix, so the token used from the same network matches.
+	//	DefaultPolicy (off, warn or enforce) is set to the apps registered without the policy.
+	FingerprintConfig struct {
+		DefaultPolicy string `mapstructure:"defaultPolicy"`
+		IPv4Prefix    int    `mapstructure:"ipv4Prefix"`
+		IPv6Prefix    int    `mapstructure:"ipv6Prefix"`
 	}
 
 	LogrusConfig struct {
@@ -59,15 +374,202 @@ type (
 	}
 )
 
+// NewConfig returns the config with default values. Used for testing
+func NewConfig() *Config {
+	return &Config{
+		Environment: EnvLocal,
+		HTTP: HTTPConfig{
+			Port:         defaultHttpPort,
+			ReadTimeout:  defaultHttpRWTimeout,
+			WriteTimeout: defaultHttpRWTimeout,
+		},
+		Auth: AuthConfig{
+			JWT: JWTConfig{
+				AccessTokenTTL:  defaultAccessTokenTTL,
+				RefreshTokenTTL: defaultRefreshTokenTTL,
+				SigningKey:      defaultSigningKey,
+
+				SigningAlgorithm:    defaultSigningAlgorithm,
+				KeyRotationInterval: defaultKeyRotationInterval,
+				KeyOverlap:          defaultAccessTokenTTL,
+			},
+			Blacklist: BlacklistConfig{
+				Storage:         defaultBlacklistStorage,
+				CleanupInterval: defaultBlacklistCleanupInterval,
+			},
+			EmailConfirmation: EmailConfirmationConfig{
+				TokenTTL:            defaultEmailConfirmationTTL,
+				URL:                 defaultEmailConfirmationURL,
+				RequiredToJoinGroup: true,
+			},
+			PasswordReset: PasswordResetConfig{
+				TokenTTL: defaultPasswordResetTTL,
+				URL:      defaultPasswordResetURL,
+			},
+			PasswordPolicy: PasswordPolicyConfig{
+				MinLength:            defaultPasswordMinLength,
+				DisallowPersonalInfo: true,
+			},
+			TwoFactor: TwoFactorConfig{
+				Issuer:       defaultTwoFactorIssuer,
+				ChallengeTTL: defaultTwoFactorChallengeTTL,
+			},
+			LoginThrottle: LoginThrottleConfig{
+				Enabled:                 true,
+				FreeAttempts:            defaultLoginThrottleFreeAttempts,
+				BaseDelay:               defaultLoginThrottleBaseDelay,
+				MaxDelay:                defaultLoginThrottleMaxDelay,
+				FailureWindow:           defaultLoginThrottleFailureWindow,
+				AccountLockoutThreshold: defaultLoginThrottleAccountThreshold,
+				IPLockoutThreshold:      defaultLoginThrottleIPThreshold,
+				LockoutDuration:         defaultLoginThrottleLockoutDuration,
+			},
+			OAuth: OAuthConfig{
+				TokenTTL:              defaultOAuthTokenTTL,
+				LegacyAppTokenEnabled: true,
+			},
+			PersonalTokens: PersonalTokensConfig{
+				DefaultTTL: defaultPersonalTokenTTL,
+				MaxTTL:     defaultPersonalTokenMaxTTL,
+				MaxPerUser: defaultPersonalTokenMaxPerUser,
+			},
+			OIDC: OIDCConfig{
+				Scopes:        []string{"openid", "profile", "email"},
+				StateTTL:      defaultOIDCStateTTL,
+				AutoProvision: true,
+			},
+			Impersonation: ImpersonationConfig{
+				TokenTTL: defaultImpersonationTokenTTL,
+			},
+			Fingerprint: FingerprintConfig{
+				DefaultPolicy: defaultFingerprintPolicy,
+				IPv4Prefix:    defaultFingerprintIPv4Prefix,
+				IPv6Prefix:    defaultFingerprintIPv6Prefix,
+			},
+			LDAP: LDAPConfig{
+				Timeout:           defaultLDAPTimeout,
+				UserFilter:        defaultLDAPUserFilter,
+				LoginAttribute:    defaultLDAPLoginAttribute,
+				FullNameAttribute: defaultLDAPFullNameAttribute,
+				EmailAttribute:    defaultLDAPEmailAttribute,
+				AutoProvision:     true,
+				GroupSync: LDAPGroupSyncConfig{
+					GroupAttribute: defaultLDAPGroupAttribute,
+				},
+			},
+		},
+		Mailer: MailerConfig{
+			Driver: defaultMailerDriver,
+			From:   defaultMailerFrom,
+			Dir:    defaultMailerDir,
+			Port:   defaultSMTPPort,
+		},
+		Groups: GroupsConfig{
+			Invites: GroupInvitesConfig{
+				DefaultTTL:  defaultGroupInviteTTL,
+				MaxTTL:      defaultGroupInviteMaxTTL,
+				MaxPerGroup: defaultGroupInviteMaxPerGroup,
+			},
+			Import: GroupImportConfig{
+				MaxRows:   defaultGroupImportMaxRows,
+				InviteTTL: defaultGroupImportInviteTTL,
+			},
+			Rollover: GroupRolloverConfig{
+				Month:       defaultGroupRolloverMonth,
+				Day:         defaultGroupRolloverDay,
+				FinalCourse: defaultGroupFinalCourse,
+			},
+			Deletion: GroupDeletionConfig{
+				Retention:     defaultGroupDeletionRetention,
+				PurgeInterval: defaultGroupPurgeInterval,
+			},
+		},
+		Audit: AuditConfig{
+			Retention:     defaultAuditRetention,
+			PruneInterval: defaultAuditPruneInterval,
+		},
+		Roles: RolesConfig{
+			DataFile:       defaultRolesDataFile,
+			Administrators: []string{"admin"},
+		},
+		Logrus: LogrusConfig{
+			Level: defaultLogrusLevel,
+		},
+	}
+}
+
 func PrintConfig(cfg Config) {
 	fmt.Printf("\tHTTP:\tHOST: %s\n", cfg.HTTP.Host)
 	fmt.Printf("\tHTTP:\tPORT: %s\n", cfg.HTTP.Port)
 	fmt.Printf("\tHTTP:\tR_TIMEOUT: %s\n", cfg.HTTP.ReadTimeout)
-	fmt.Printf("\tHTTP:\tW_TIMEOUT: %s\n\n", cfg.HTTP.WriteTimeout)
+	fmt.Printf("\tHTTP:\tW_TIMEOUT: %s\n", cfg.HTTP.WriteTimeout)
+	fmt.Printf("\tHTTP:\tTRUSTED_PROXIES: %v\n\n", cfg.HTTP.TrustedProxies)
 
 	fmt.Printf("\tAUTH:\tJWT:\tAccess TTL: %s\n", cfg.Auth.JWT.AccessTokenTTL)
 	fmt.Printf("\tAUTH:\tJWT:\tRefresh TTL: %s\n", cfg.Auth.JWT.RefreshTokenTTL)
-	fmt.Printf("\tAUTH:\tJWT:\tSigningKey: %s\n\n", cfg.Auth.JWT.SigningKey)
+	fmt.Printf("\tAUTH:\tJWT:\tSigningKey: %s\n", cfg.Auth.JWT.SigningKey)
+	fmt.Printf("\tAUTH:\tJWT:\tSigning algorithm: %s\n", cfg.Auth.JWT.SigningAlgorithm)
+	fmt.Printf("\tAUTH:\tJWT:\tKey rotation interval: %s\n", cfg.Auth.JWT.KeyRotationInterval)
+	fmt.Printf("\tAUTH:\tJWT:\tKey overlap: %s\n\n", cfg.Auth.JWT.KeyOverlap)
+
+	fmt.Printf("\tAUTH:\tBlacklist:\tStorage: %s\n", cfg.Auth.Blacklist.Storage)
+	fmt.Printf("\tAUTH:\tBlacklist:\tCleanup interval: %s\n\n", cfg.Auth.Blacklist.CleanupInterval)
+
+	fmt.Printf("\tAUTH:\tEmail confirmation:\tTTL: %s\n", cfg.Auth.EmailConfirmation.TokenTTL)
+	fmt.Printf("\tAUTH:\tEmail confirmation:\tRequired to sign in: %t\n", cfg.Auth.EmailConfirmation.RequiredToSignIn)
+	fmt.Printf("\tAUTH:\tEmail confirmation:\tRequired to join group: %t\n\n", cfg.Auth.EmailConfirmation.RequiredToJoinGroup)
+
+	fmt.Printf("\tAUTH:\tPassword policy:\tMin length: %d\n", cfg.Auth.PasswordPolicy.MinLength)
+	fmt.Printf("\tAUTH:\tPassword policy:\tLowercase: %t, uppercase: %t, digit: %t, symbol: %t\n", cfg.Auth.PasswordPolicy.RequireLowercase, cfg.Auth.PasswordPolicy.RequireUppercase, cfg.Auth.PasswordPolicy.RequireDigit, cfg.Auth.PasswordPolicy.RequireSymbol)
+	fmt.Printf("\tAUTH:\tPassword policy:\tDisallow personal info: %t\n", cfg.Auth.PasswordPolicy.DisallowPersonalInfo)
+	fmt.Printf("\tAUTH:\tPassword policy:\tBreached passwords file: %s\n\n", cfg.Auth.PasswordPolicy.BreachedPasswordsFile)
+
+	fmt.Printf("\tAUTH:\t2FA:\tIssuer: %s\n", cfg.Auth.TwoFactor.Issuer)
+	fmt.Printf("\tAUTH:\t2FA:\tChallenge TTL: %s\n\n", cfg.Auth.TwoFactor.ChallengeTTL)
+
+	fmt.Printf("\tAUTH:\tLogin throttle:\tEnabled: %t\n", cfg.Auth.LoginThrottle.Enabled)
+	fmt.Printf("\tAUTH:\tLogin throttle:\tDelay: %s - %s after %d failures\n", cfg.Auth.LoginThrottle.BaseDelay, cfg.Auth.LoginThrottle.MaxDelay, cfg.Auth.LoginThrottle.FreeAttempts)
+	fmt.Printf("\tAUTH:\tLogin throttle:\tLockout: %s after %d (account) / %d (IP) failures\n\n", cfg.Auth.LoginThrottle.LockoutDuration, cfg.Auth.LoginThrottle.AccountLockoutThreshold, cfg.Auth.LoginThrottle.IPLockoutThreshold)
+
+	fmt.Printf("\tAUTH:\tOAuth:\tToken TTL: %s\n", cfg.Auth.OAuth.TokenTTL)
+	fmt.Printf("\tAUTH:\tOAuth:\tLegacy app token endpoint: %t\n\n", cfg.Auth.OAuth.LegacyAppTokenEnabled)
+
+	fmt.Printf("\tAUTH:\tPersonal tokens:\tTTL: %s (max %s)\n", cfg.Auth.PersonalTokens.DefaultTTL, cfg.Auth.PersonalTokens.MaxTTL)
+	fmt.Printf("\tAUTH:\tPersonal tokens:\tMax per user: %d\n\n", cfg.Auth.PersonalTokens.MaxPerUser)
+
+	fmt.Printf("\tAUTH:\tImpersonation:\tTTL: %s\n", cfg.Auth.Impersonation.TokenTTL)
+	fmt.Printf("\tAUTH:\tImpersonation:\tAllow writes: %t\n\n", cfg.Auth.Impersonation.AllowWrites)
+
+	fmt.Printf("\tAUTH:\tFingerprint:\tDefault policy: %s\n", cfg.Auth.Fingerprint.DefaultPolicy)
+	fmt.Printf("\tAUTH:\tFingerprint:\tNetwork prefixes: /%d (IPv4), /%d (IPv6)\n\n", cfg.Auth.Fingerprint.IPv4Prefix, cfg.Auth.Fingerprint.IPv6Prefix)
+
+	fmt.Printf("\tAUTH:\tOIDC:\tEnabled: %t\n", cfg.Auth.OIDC.Enabled)
+	fmt.Printf("\tAUTH:\tOIDC:\tIssuer: %s\n", cfg.Auth.OIDC.Issuer)
+	fmt.Printf("\tAUTH:\tOIDC:\tClient ID: %s\n", cfg.Auth.OIDC.ClientID)
+	fmt.Printf("\tAUTH:\tOIDC:\tAuto provision: %t, link by email: %t\n\n", cfg.Auth.OIDC.AutoProvision, cfg.Auth.OIDC.LinkByEmail)
+
+	fmt.Printf("\tAUTH:\tLDAP:\tEnabled: %t\n", cfg.Auth.LDAP.Enabled)
+	fmt.Printf("\tAUTH:\tLDAP:\tURL: %s (StartTLS: %t)\n", cfg.Auth.LDAP.URL, cfg.Auth.LDAP.StartTLS)
+	fmt.Printf("\tAUTH:\tLDAP:\tBase DN: %s\n", cfg.Auth.LDAP.BaseDN)
+	fmt.Printf("\tAUTH:\tLDAP:\tAuto provision: %t, link by email: %t\n", cfg.Auth.LDAP.AutoProvision, cfg.Auth.LDAP.LinkByEmail)
+	fmt.Printf("\tAUTH:\tLDAP:\tGroup sync: %t (%d groups)\n\n", cfg.Auth.LDAP.GroupSync.Enabled, len(cfg.Auth.LDAP.GroupSync.Groups))
+
+	fmt.Printf("\tMAILER:\tDriver: %s\n", cfg.Mailer.Driver)
+	fmt.Printf("\tMAILER:\tHost: %s:%d\n\n", cfg.Mailer.Host, cfg.Mailer.Port)
+
+	fmt.Printf("\tGROUPS:\tInvites:\tTTL: %s (max %s), max per group: %d\n",
+		cfg.Groups.Invites.DefaultTTL, cfg.Groups.Invites.MaxTTL, cfg.Groups.Invites.MaxPerGroup)
+	fmt.Printf("\tGROUPS:\tImport:\tMax rows: %d, invite TTL: %s\n",
+		cfg.Groups.Import.MaxRows, cfg.Groups.Import.InviteTTL)
+	fmt.Printf("\tGROUPS:\tRollover:\tStarts: %02d.%02d, final course: %d, interval: %s\n",
+		cfg.Groups.Rollover.Day, cfg.Groups.Rollover.Month, cfg.Groups.Rollover.FinalCourse, cfg.Groups.Rollover.Interval)
+	fmt.Printf("\tGROUPS:\tDeletion:\tRetention: %s (purge every %s)\n\n",
+		cfg.Groups.Deletion.Retention, cfg.Groups.Deletion.PurgeInterval)
+
+	fmt.Printf("\tAUDIT:\tRetention: %s (prune every %s)\n\n", cfg.Audit.Retention, cfg.Audit.PruneInterval)
+
+	fmt.Printf("\tROLES:\tData file: %s\n", cfg.Roles.DataFile)
+	fmt.Printf("\tROLES:\tAdministrators: %v\n\n", cfg.Roles.Administrators)
 }
 
 func Init(configsDir string) (*Config, error) {
@@ -101,6 +603,64 @@ func populateDefaults() {
 	viper.SetDefault("http.timeouts.write", defaultHttpRWTimeout)
 	viper.SetDefault("auth.accessTokenTTL", defaultAccessTokenTTL)
 	viper.SetDefault("auth.refreshTokenTTL", defaultRefreshTokenTTL)
+	viper.SetDefault("auth.signingAlgorithm", defaultSigningAlgorithm)
+	viper.SetDefault("auth.keyRotationInterval", defaultKeyRotationInterval)
+	viper.SetDefault("auth.blacklist.storage", defaultBlacklistStorage)
+	viper.SetDefault("auth.blacklist.cleanupInterval", defaultBlacklistCleanupInterval)
+	viper.SetDefault("auth.emailConfirmation.tokenTTL", defaultEmailConfirmationTTL)
+	viper.SetDefault("auth.emailConfirmation.url", defaultEmailConfirmationURL)
+	viper.SetDefault("auth.emailConfirmation.requiredToJoinGroup", true)
+	viper.SetDefault("auth.passwordReset.tokenTTL", defaultPasswordResetTTL)
+	viper.SetDefault("auth.passwordReset.url", defaultPasswordResetURL)
+	viper.SetDefault("auth.passwordPolicy.minLength", defaultPasswordMinLength)
+	viper.SetDefault("auth.passwordPolicy.disallowPersonalInfo", true)
+	viper.SetDefault("auth.twoFactor.issuer", defaultTwoFactorIssuer)
+	viper.SetDefault("auth.twoFactor.challengeTTL", defaultTwoFactorChallengeTTL)
+	viper.SetDefault("auth.loginThrottle.enabled", true)
+	viper.SetDefault("auth.loginThrottle.freeAttempts", defaultLoginThrottleFreeAttempts)
+	viper.SetDefault("auth.loginThrottle.baseDelay", defaultLoginThrottleBaseDelay)
+	viper.SetDefault("auth.loginThrottle.maxDelay", defaultLoginThrottleMaxDelay)
+	viper.SetDefault("auth.loginThrottle.failureWindow", defaultLoginThrottleFailureWindow)
+	viper.SetDefault("auth.loginThrottle.accountLockoutThreshold", defaultLoginThrottleAccountThreshold)
+	viper.SetDefault("auth.loginThrottle.ipLockoutThreshold", defaultLoginThrottleIPThreshold)
+	viper.SetDefault("auth.loginThrottle.lockoutDuration", defaultLoginThrottleLockoutDuration)
+	viper.SetDefault("auth.oauth.tokenTTL", defaultOAuthTokenTTL)
+	viper.SetDefault("auth.oauth.legacyAppTokenEnabled", true)
+	viper.SetDefault("auth.personalTokens.defaultTTL", defaultPersonalTokenTTL)
+	viper.SetDefault("auth.personalTokens.maxTTL", defaultPersonalTokenMaxTTL)
+	viper.SetDefault("auth.personalTokens.maxPerUser", defaultPersonalTokenMaxPerUser)
+	viper.SetDefault("auth.impersonation.tokenTTL", defaultImpersonationTokenTTL)
+	viper.SetDefault("auth.fingerprint.defaultPolicy", defaultFingerprintPolicy)
+	viper.SetDefault("auth.fingerprint.ipv4Prefix", defaultFingerprintIPv4Prefix)
+	viper.SetDefault("auth.fingerprint.ipv6Prefix", defaultFingerprintIPv6Prefix)
+	viper.SetDefault("auth.oidc.scopes", []string{"openid", "profile", "email"})
+	viper.SetDefault("auth.oidc.stateTTL", defaultOIDCStateTTL)
+	viper.SetDefault("auth.oidc.autoProvision", true)
+	viper.SetDefault("auth.ldap.timeout", defaultLDAPTimeout)
+	viper.SetDefault("auth.ldap.userFilter", defaultLDAPUserFilter)
+	viper.SetDefault("auth.ldap.loginAttribute", defaultLDAPLoginAttribute)
+	viper.SetDefault("auth.ldap.fullNameAttribute", defaultLDAPFullNameAttribute)
+	viper.SetDefault("auth.ldap.emailAttribute", defaultLDAPEmailAttribute)
+	viper.SetDefault("auth.ldap.autoProvision", true)
+	viper.SetDefault("auth.ldap.groupSync.groupAttribute", defaultLDAPGroupAttribute)
+	viper.SetDefault("mailer.driver", defaultMailerDriver)
+	viper.SetDefault("roles.dataFile", defaultRolesDataFile)
+	viper.SetDefault("roles.administrators", []string{"admin"})
+	viper.SetDefault("mailer.from", defaultMailerFrom)
+	viper.SetDefault("mailer.dir", defaultMailerDir)
+	viper.SetDefault("mailer.port", defaultSMTPPort)
+	viper.SetDefault("groups.invites.defaultTTL", defaultGroupInviteTTL)
+	viper.SetDefault("groups.invites.maxTTL", defaultGroupInviteMaxTTL)
+	viper.SetDefault("groups.invites.maxPerGroup", defaultGroupInviteMaxPerGroup)
+	viper.SetDefault("groups.import.maxRows", defaultGroupImportMaxRows)
+	viper.SetDefault("groups.import.inviteTTL", defaultGroupImportInviteTTL)
+	viper.SetDefault("groups.rollover.month", defaultGroupRolloverMonth)
+	viper.SetDefault("groups.rollover.day", defaultGroupRolloverDay)
+	viper.SetDefault("groups.rollover.finalCourse", defaultGroupFinalCourse)
+	viper.SetDefault("groups.deletion.retention", defaultGroupDeletionRetention)
+	viper.SetDefault("groups.deletion.purgeInterval", defaultGroupPurgeInterval)
+	viper.SetDefault("audit.retention", defaultAuditRetention)
+	viper.SetDefault("audit.pruneInterval", defaultAuditPruneInterval)
 	viper.SetDefault("limiter.rps", defaultLimiterRPS)
 	viper.SetDefault("logrus.level", defaultLogrusLevel)
 }
@@ -127,6 +687,27 @@ func setFromEnv(cfg *Config) {
 	if input, is = os.LookupEnv("JWT_SIGNING_KEY"); is {
 		cfg.Auth.JWT.SigningKey = input
 	}
+	if input, is = os.LookupEnv("JWT_SIGNING_ALGORITHM"); is {
+		cfg.Auth.JWT.SigningAlgorithm = input
+	}
+	if input, is = os.LookupEnv("TOKEN_BLACKLIST_STORAGE"); is {
+		cfg.Auth.Blacklist.Storage = input
+	}
+	if input, is = os.LookupEnv("OIDC_CLIENT_SECRET"); is {
+		cfg.Auth.OIDC.ClientSecret = input
+	}
+	if input, is = os.LookupEnv("LDAP_BIND_PASSWORD"); is {
+		cfg.Auth.LDAP.BindPassword = input
+	}
+	if input, is = os.LookupEnv("SMTP_HOST"); is {
+		cfg.Mailer.Host = input
+	}
+	if input, is = os.LookupEnv("SMTP_USER"); is {
+		cfg.Mailer.Username = input
+	}
+	if input, is = os.LookupEnv("SMTP_PASSWORD"); is {
+		cfg.Mailer.Password = input
+	}
 	if input, is = os.LookupEnv("HTTP_HOST"); is {
 		cfg.HTTP.Host = input
 	}
@@ -166,6 +747,70 @@ func unmarshal(cfg *Config) error {
 		return err
 	}
 
+	if err := viper.UnmarshalKey("auth.blacklist", &cfg.Auth.Blacklist); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("auth.emailConfirmation", &cfg.Auth.EmailConfirmation); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("auth.passwordReset", &cfg.Auth.PasswordReset); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("auth.passwordPolicy", &cfg.Auth.PasswordPolicy); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("auth.twoFactor", &cfg.Auth.TwoFactor); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("auth.loginThrottle", &cfg.Auth.LoginThrottle); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("auth.oauth", &cfg.Auth.OAuth); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("auth.personalTokens", &cfg.Auth.PersonalTokens); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("auth.impersonation", &cfg.Auth.Impersonation); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("auth.fingerprint", &cfg.Auth.Fingerprint); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("auth.oidc", &cfg.Auth.OIDC); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("auth.ldap", &cfg.Auth.LDAP); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("mailer", &cfg.Mailer); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("groups", &cfg.Groups); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("audit", &cfg.Audit); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("roles", &cfg.Roles); err != nil {
+		return err
+	}
+
 	if err := viper.UnmarshalKey("logrus", &cfg.Logrus); err != nil {
 		return err
 	}
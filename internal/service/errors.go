@@ -1,7 +1,10 @@
 package service
 
 import (
+	"backend/internal/api/v1/models"
 	"errors"
+	"strings"
+	"time"
 )
 
 var (
@@ -10,6 +13,8 @@ var (
 	ErrAppAuthorization         = errors.New("invalid authorization data")
 	ErrInvalidAppToken          = errors.New("invalid app token")
 	ErrInvalidAppID             = errors.New("invalid app id")
+	ErrInvalidAppScope          = errors.New("the requested scope is invalid or exceeds the scope of the app")
+	ErrInsufficientAppScope     = errors.New("the app token doesn't have the required scope")
 
 	//	Auth/registry
 	ErrInvalidUserEmail       = errors.New("invalid user email")
@@ -17,19 +22,73 @@ var (
 	ErrMailLoginAlreadyUsing  = errors.New("this login or email address is already in use")
 	ErrEmailIsAlreadyOccupied = errors.New("this email is already occupied")
 	ErrLoginIsAlreadyOccupied = errors.New("this login is already occupied")
+
+	//	Auth/password policy
+	ErrPasswordPolicy = errors.New("the password doesn't satisfy the password policy")
+
+	//	Auth/fingerprint
+	ErrFingerprintMismatch      = errors.New("the access token is used from another device")
+	ErrInvalidFingerprintPolicy = errors.New("the fingerprint policy must be off, warn or enforce")
+
+	//	Auth/email confirmation
+	ErrEmailIsNotConfirmed      = errors.New("the email address is not confirmed")
+	ErrEmailIsAlreadyConfirmed  = errors.New("the email address is already confirmed")
+	ErrInvalidVerificationToken = errors.New("the token is invalid or has expired")
 	//ErrPasswordIsTooLight	  = errors.New("this password is too light")
 
 	//	Auth/user/login
 	ErrIncorrectLoginOrPassword = errors.New("incorrect login or password")
+	ErrIncorrectPassword        = errors.New("incorrect password")
+	ErrAccountIsLocked          = errors.New("the account is temporarily locked because of too many failed sign-in attempts")
+	ErrTooManySignInAttempts    = errors.New("too many failed sign-in attempts, try again later")
 	//ErrIncorrectEmailOrPassword = errors.New("incorrect email or password")
 
+	//	Auth/2FA
+	ErrTwoFactorIsAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorIsNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorIsNotEnrolled    = errors.New("two-factor authentication enrolment wasn't started")
+	ErrIncorrectTwoFactorCode    = errors.New("incorrect two-factor authentication code")
+	ErrInvalidMFAChallenge       = errors.New("the sign-in challenge is invalid or has expired")
+
+	//	Roles
+	ErrPermissionDenied   = errors.New("permission denied")
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrPermissionNotFound = errors.New("permission not found")
+	ErrInvalidRoleScope   = errors.New("the role can't be granted in this scope")
+
+	//	Auth/personal tokens
+	ErrPersonalTokenNotFound    = errors.New("personal access token not found")
+	ErrInvalidTokenScope        = errors.New("the token scope has unknown scopes")
+	ErrInvalidTokenExpiry       = errors.New("the token expiry must be in the future and within the maximum lifetime")
+	ErrTooManyPersonalTokens    = errors.New("too many personal access tokens, revoke unused ones")
+	ErrInvalidPersonalToken     = errors.New("the personal access token is invalid, expired or revoked")
+	ErrInsufficientTokenScope   = errors.New("the personal access token doesn't have the required scope")
+	ErrPersonalTokenNotAccepted = errors.New("personal access tokens can't be used for this request")
+
+	//	Auth/OpenID Connect
+	ErrOIDCIsDisabled             = errors.New("sign-in with the identity provider is disabled")
+	ErrInvalidOIDCState           = errors.New("the sign-in state is invalid, expired or already used")
+	ErrOIDCSignInFailed           = errors.New("the identity provider didn't confirm the sign-in")
+	ErrExternalAccountIsNotLinked = errors.New("the identity provider account isn't linked to any user")
+
+	//	Auth/impersonation
+	ErrImpersonationNotAllowed = errors.New("the user can't be impersonated")
+	ErrImpersonationIsReadOnly = errors.New("the impersonation token can't be used for changes")
+
+	//	Audit
+	ErrInvalidAuditCursor = errors.New("the audit log cursor is invalid")
+
 	//	Auth/user/authorization
 	ErrInvalidUserToken               = errors.New("invalid user token")
 	ErrInvalidTokenPair               = errors.New("invalid access-refresh token pair")
 	ErrInvalidRefreshToken            = errors.New("invalid refresh token")
+	ErrRefreshTokenReused             = errors.New("the refresh token was already used, all tokens of the session are revoked")
 	ErrAccessTokenExpired             = errors.New("the access token has expired")
 	ErrAccessTokenRefreshRateExceeded = errors.New("token refresh rate exceeded")
 	ErrAccessTokenIsBlacklisted       = errors.New("the access token is blacklisted")
+	ErrSessionIsRevoked               = errors.New("the session was closed")
+	ErrSigningKeyNotFound             = errors.New("the signing key of the token is unknown or retired")
+	ErrInvalidSigningMethod           = errors.New("the signing method doesn't match the signing key")
 	//ErrInvalidAccessToken	 = errors.New("invalid access token")
 
 	// Object not found
@@ -39,15 +98,48 @@ var (
 	ErrUserNotFoundInContext      = errors.New("user not found in context")
 	ErrAppIDNotFoundInContext     = errors.New("app id not found in context")
 	ErrRequestIDNotFoundInContext = errors.New("request id not found in context")
+	ErrSessionNotFound            = errors.New("session not found")
+	ErrSessionNotFoundInContext   = errors.New("session not found in context")
 	ErrTaskNotFound               = errors.New("task not found")
 	ErrSubjectNotFound            = errors.New("subject not found")
 	ErrUniversityNotFound         = errors.New("university not found")
+	ErrFacultyNotFound            = errors.New("faculty not found")
+	ErrDepartmentNotFound         = errors.New("department not found")
 	ErrGroupHaveNoMembers         = errors.New("group have no members")
 
 	ErrGroupNotFound            = errors.New("group not found")
-	ErrGroupHaveNotInvitation   = errors.New("group doesn't have an invitation")
 	ErrUserIsNotGroupMember     = errors.New("the user in not a member of the group")
 	ErrUserNotMemberOfAnyGroups = errors.New("the user is not a member of any groups")
+	ErrLastGroupOwner           = errors.New("the last owner of the group can't leave it or lose the role, transfer the ownership first")
+	ErrTransferToSelf           = errors.New("the ownership can't be transferred to the owner")
+	ErrUserIsAlreadyGroupMember = errors.New("the user is already a member of the group")
+
+	ErrGroupInviteNotFound = errors.New("invite not found")
+	ErrInvalidInvite       = errors.New("the invite is invalid, expired or used up")
+	ErrInvalidInviteExpiry = errors.New("the expiry of the invite is in the past or too far in the future")
+	ErrInvalidInviteRole   = errors.New("the invite can't grant the owner role")
+	ErrTooManyGroupInvites = errors.New("the group has too many invites")
+
+	ErrGroupIsClosed             = errors.New("the group is closed for joining")
+	ErrGroupJoinRequiresApproval = errors.New("the group is joined only by the approved join requests")
+	ErrJoinRequestsNotAccepted   = errors.New("the group doesn't accept join requests")
+	ErrJoinRequestNotFound       = errors.New("join request not found")
+	ErrJoinRequestIsPending      = errors.New("the user already has a pending request to join the group")
+	ErrJoinRequestIsDecided      = errors.New("the join request is already approved or rejected")
+	ErrInvalidJoinRequestStatus  = errors.New("invalid join request status")
+
+	ErrInvalidImportFile = errors.New("the CSV file is invalid or has no email column")
+	ErrTooManyImportRows = errors.New("the CSV file has too many rows")
+
+	ErrGroupIsArchived     = errors.New("the group is archived and read-only")
+	ErrGroupIsNotDeleted   = errors.New("the group isn't deleted")
+	ErrGroupRestoreExpired = errors.New("the retention of the deleted group is over, it can't be restored")
+
+	ErrGroupRolloverIsDone = errors.New("the groups of the university are already rolled over to the academic year")
+	ErrInvalidAcademicYear = errors.New("the academic year hasn't started yet or is before the last rollover")
+
+	ErrDepartmentOfAnotherUniversity = errors.New("the department belongs to another university")
+	ErrGroupIsAttachedToDepartment   = errors.New("the group is attached to the department, detach it before moving to another university")
 
 	ErrInvalidLimitOrPage = errors.New("the limit of page or page number can't be less than zero")
 
@@ -57,3 +149,37 @@ var (
 var (
 //ErrorInvalidAccessToken = models.New(ErrInvalidAccess)
 )
+
+// ThrottledError wraps ErrAccountIsLocked and ErrTooManySignInAttempts.
+//	RetryAfter is the time the next sign-in attempt is accepted.
+type ThrottledError struct {
+	Err        error
+	RetryAfter time.Time
+}
+
+func (e *ThrottledError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return e.Err
+}
+
+// PasswordPolicyError wraps ErrPasswordPolicy.
+//	Violations are all rules of the password policy the password doesn't satisfy.
+type PasswordPolicyError struct {
+	Violations []models.PasswordPolicyViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+
+	return ErrPasswordPolicy.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrPasswordPolicy
+}
@@ -2,10 +2,13 @@ package service
 
 import (
 	"backend/internal/api/v1/models"
+	"backend/pkg/ServiceData"
+	"backend/pkg/mailer"
 	"context"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"io"
 	"time"
 )
 
@@ -14,14 +17,50 @@ type Service interface {
 	User() UserService
 	Task() TaskService
 	University() UniversityService
+	Faculty() FacultyService
 	Group() GroupService
 	Subject() SubjectService
+	Role() RoleService
+	Audit() AuditService
 
 	AddLogger(logger *logrus.Logger)
+	AddMailer(mailer mailer.Mailer)
+	// Close stops background jobs of the services
+	Close()
+}
+
+// TokenBlacklist keeps revoked access tokens until their expiration.
+//Implementations must be safe for concurrent use.
+type TokenBlacklist interface {
+	Add(token string, expiresAt time.Time) error
+	IsBlacklisted(token string) (bool, error)
+	// Prune removes expired tokens and returns the number of removed ones
+	Prune() (int, error)
+	// Close stops the background janitor
+	Close()
 }
 
 type AuthService interface {
-	GetSigningKey() string
+	// SendEmailConfirmation sends the new confirmation link to the user's email.
+	//	Previous links of the user stop working.
+	SendEmailConfirmation(userID int) error
+	// ConfirmEmail confirms the email of the user the token was sent to. The token can be used only once
+	ConfirmEmail(token string) (*models.User, error)
+
+	// RequestPasswordReset sends the password reset link to the email.
+	//	Nothing is returned if there is no user with the email, so the method can't be used to find out registered emails.
+	RequestPasswordReset(email string) error
+	// ResetPassword sets the new password by the token from the password reset email.
+	//	The new passwords are checked by the password policy, *service.PasswordPolicyError is returned if it's violated.
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	// ChangePassword sets the new password, if the old one is correct.
+	//	Returns service.ErrIncorrectPassword otherwise.
+	ChangePassword(ctx context.Context, userID int, oldPassword, newPassword string) error
+
+	// KeyFunc returns the key that verifies the access token. It's used as jwt.Keyfunc
+	KeyFunc(token *jwt.Token) (interface{}, error)
+	// GetJWKS returns the public keys that verify access tokens
+	GetJWKS() models.JSONWebKeySet
 
 	// GenerateJWTToken Not used. This method generates a JWT token.
 	//Returns a jwt token and an error, if any.
@@ -36,19 +75,90 @@ type AuthService interface {
 	// CheckRefreshToken Check refreshToken and returns valid=true, if it is valid
 	CheckRefreshToken(refreshToken string, userID int) (*models.UserToken, error)
 
-	RegisterApp(app *models.RegisteredApp) error
+	// RegisterApp generates the ID and the secret of the app. Only the hash of the secret is stored.
+	//	Returns service.ErrInvalidAppScope, if the scope of the app has unknown scopes.
+	RegisterApp(ctx context.Context, app *models.RegisteredApp) error
 
-	DeleteApp(appID, appSecret, appToken string) error
+	// IssueClientCredentialsToken issues the expiring app token by the OAuth2 client credentials grant
+	IssueClientCredentialsToken(appID, appSecret, scope string) (*models.AppToken, error)
+
+	DeleteApp(ctx context.Context, appID, appSecret, appToken string) error
+	// SetAppFingerprintPolicy sets the policy of the app for the access tokens used from another device
+	SetAppFingerprintPolicy(ctx context.Context, appID uuid.UUID, policy string) error
 
 	// RegisterUser Register new user. Receive *model.User. Return ErrMailLoginAlreadyUsing or nil
 	RegisterUser(*models.User) error
 
-	UserSignIn(userSignIn *models.UserSignIn) (*models.UserToken, error)
+	// UserSignIn opens a new session for the device described in userSignIn
+	//and returns the first token pair of the session.
+	//	If the user has 2FA enabled, only the MFA challenge is returned. The challenge is exchanged
+	//	for the token pair by CompleteMFASignIn.
+	UserSignIn(ctx context.Context, userSignIn *models.UserSignIn) (*models.UserToken, *models.MFAChallenge, error)
+
+	// CompleteMFASignIn checks the TOTP or recovery code for the challenge and opens the session.
+	//	The challenge is revoked after several incorrect codes.
+	CompleteMFASignIn(ctx context.Context, signIn *models.MFASignIn) (*models.UserToken, error)
 
-	UserLogout(userID int) error
+	// EnrollTOTP generates the new TOTP secret and recovery codes of the user.
+	//	2FA isn't enabled until the first code is confirmed by ConfirmTOTP.
+	EnrollTOTP(userID int) (*models.TwoFactorEnrollment, error)
+	// ConfirmTOTP enables 2FA, if the code matches the enrolled secret
+	ConfirmTOTP(ctx context.Context, userID int, code string) error
+	// DisableTOTP disables 2FA. The password and a TOTP or recovery code are required
+	DisableTOTP(ctx context.Context, userID int, password, code string) error
+
+	// UnlockUser removes the sign-in lock of the user after too many failed attempts
+	UnlockUser(userID int) error
+
+	// UserLogout closes the session of the user. Other sessions of the user stay active
+	UserLogout(userID, sessionID int) error
 
 	AuthenticateUser(accessToken string) (*models.User, error)
 
+	// AuthenticateUserSession works as AuthenticateUser and also returns the session of the access token.
+	//	Returns service.ErrSessionIsRevoked if the session was closed.
+	//	The fingerprint of the token is checked against the request from the context by the policy of the app,
+	//	service.ErrFingerprintMismatch is returned if the app enforces it.
+	AuthenticateUserSession(ctx context.Context, accessToken string) (*models.User, *models.UserSession, error)
+
+	// CreatePersonalToken creates the personal access token with the space-delimited scope.
+	//	The token expires after the default TTL, if expiresAt is zero. Returns the raw token, only its hash is stored.
+	CreatePersonalToken(ctx context.Context, userID int, name, scope string, expiresAt time.Time) (*models.PersonalToken, string, error)
+	// GetPersonalTokens returns not revoked personal access tokens of the user
+	GetPersonalTokens(userID int) ([]models.PersonalToken, error)
+	// RevokePersonalToken returns service.ErrPersonalTokenNotFound, if the user has no active token with the id
+	RevokePersonalToken(ctx context.Context, userID, tokenID int) error
+	// AuthenticatePersonalToken returns the owner of the token.
+	//	Returns service.ErrInvalidPersonalToken, if the token is unknown, expired or revoked.
+	AuthenticatePersonalToken(token string) (*models.User, *models.PersonalToken, error)
+
+	// OIDCAuthorization starts the sign-in with the OpenID Connect provider.
+	//	Returns service.ErrOIDCIsDisabled, if the provider isn't configured.
+	OIDCAuthorization() (*models.OIDCAuthorization, error)
+	// OIDCSignIn exchanges the code the provider redirected back with for the token pair.
+	//	The user is found by the linked subject, on the first sign-in the user is linked or created.
+	//	If the user has 2FA enabled, only the MFA challenge is returned as by UserSignIn.
+	//	Returns service.ErrInvalidOIDCState, service.ErrOIDCSignInFailed or service.ErrExternalAccountIsNotLinked.
+	OIDCSignIn(ctx context.Context, signIn *models.OIDCSignIn) (*models.UserToken, *models.MFAChallenge, error)
+	// GetExternalIdentities returns the provider accounts linked to the user
+	GetExternalIdentities(userID int) ([]models.ExternalIdentity, error)
+
+	// Impersonate opens the session of the user for the administrator and returns its short-lived access token.
+	//	The administrator's id is in the token and the session. The start is written to the audit log.
+	//	Returns service.ErrImpersonationNotAllowed for the administrator itself and for users with the admin panel permission.
+	Impersonate(ctx context.Context, admin *models.User, userID int, reason, ip, userAgent string) (*models.Impersonation, error)
+
+	// GetUserSessions returns active sessions of the user
+	GetUserSessions(userID int) ([]models.UserSession, error)
+
+	// CloseSession closes the session of the user.
+	//	Returns service.ErrSessionNotFound if the session doesn't exist or belongs to another user.
+	CloseSession(userID, sessionID int) error
+
+	// CloseOtherSessions closes all sessions of the user except the current one.
+	//	Returns the number of closed sessions.
+	CloseOtherSessions(userID, currentSessionID int) (int, error)
+
 	// IsAppTokenValid App token validation. Returns true, if valid
 	IsAppTokenValid(appToken string) (bool, error)
 
@@ -70,7 +180,8 @@ type AuthService interface {
 
 	GenerateAccessToken(user *models.User, startTimestamp time.Time) (string, error)
 
-	GenerateRefreshToken() string
+	// GenerateRefreshToken returns the unguessable refresh token
+	GenerateRefreshToken() (string, error)
 }
 
 type UserService interface {
@@ -113,10 +224,49 @@ type TaskService interface {
 type UniversityService interface {
 	Create(university *models.University) error
 	Find(universityID int) (*models.University, error)
+	GetAll() ([]models.University, error)
+}
+
+// FacultyService manages the org tree of the universities: university -> faculty -> department.
+//	The faculties are created by the administrators and delegated to the users with the faculty roles,
+//	the faculty.manage permission in the faculty allows to manage its departments and their groups and subjects.
+type FacultyService interface {
+	// Create creates the faculty of the university, the user must have the admin.universities.manage permission
+	Create(ctx context.Context, userID int, faculty *models.Faculty) error
+	Find(facultyID int) (*models.Faculty, error)
+	// Update renames the faculty, the user must have the faculty.manage permission in it
+	Update(ctx context.Context, facultyID, userID int, name string) (*models.Faculty, error)
+	// Delete deletes the faculty with its departments, their groups and subjects are detached.
+	//	The user must have the admin.universities.manage permission
+	Delete(ctx context.Context, facultyID, userID int) error
+	GetFaculties(universityID int) ([]models.Faculty, error)
+
+	// CreateDepartment creates the department of the faculty, the user must have the faculty.manage permission in it
+	CreateDepartment(ctx context.Context, userID int, department *models.Department) error
+	FindDepartment(departmentID int) (*models.Department, error)
+	UpdateDepartment(ctx context.Context, departmentID, userID int, name string) (*models.Department, error)
+	// DeleteDepartment deletes the department, its groups and subjects are detached
+	DeleteDepartment(ctx context.Context, departmentID, userID int) error
+	GetDepartments(facultyID int) ([]models.Department, error)
+
+	// SetGroupDepartment attaches the group to the department or detaches it, if departmentID is nil.
+	//	The user must have the faculty.manage permission in the faculties of the current and the new departments,
+	//	the group, which isn't attached, is attached by the user with the group.update permission in it.
+	//	Returns service.ErrDepartmentOfAnotherUniversity, if the group is of another university
+	SetGroupDepartment(ctx context.Context, groupID, userID int, departmentID *int) (*models.Group, error)
+	// SetSubjectDepartment attaches the subject to the department or detaches it, if departmentID is nil.
+	//	The user must have the faculty.manage permission in the faculties of the current and the new departments,
+	//	the subject, which isn't attached, is attached by the user with the admin.universities.manage permission.
+	SetSubjectDepartment(ctx context.Context, subjectID, userID int, departmentID *int) (*models.Subject, error)
+
+	// GetGroups returns the groups of the departments of the node of the org tree
+	GetGroups(node models.OrgNode) ([]models.Group, error)
+	// GetSubjects returns the subjects of the departments of the node of the org tree
+	GetSubjects(node models.OrgNode) ([]models.Subject, error)
 }
 
 type GroupService interface {
-	Create(group *models.Group, user *models.User) error
+	Create(ctx context.Context, group *models.Group, user *models.User) error
 	GetAllGroups(limit, offset int) ([]models.Group, error)
 
 	// Find returns *models.Group by groupID or nil if group not found.
@@ -128,17 +278,76 @@ type GroupService interface {
 	//	If group was not found, method returns service.ErrGroupNotFound.
 	//	If an error occurs during the execution of the method, the method returns error.
 	FindByName(name string) (*models.Group, error)
-	Update(groupID int, ud *models.UpdateGroup) error
-	Delete(groupID int, userID int) error
+	// Update changes the given fields of the group and returns the updated group with the compiled full name.
+	//	The user must have the group.update permission in the group.
+	//	Returns service.ErrGroupIsAttachedToDepartment, if the attached group is moved to another university.
+	Update(ctx context.Context, groupID, userID int, ud *models.UpdateGroup) (*models.Group, error)
+	// Delete marks the group deleted, an administrator restores it within the retention, then it's purged
+	Delete(ctx context.Context, groupID int, userID int) error
+	// Archive archives the group or unarchives it, if archive is false. The members only read the archived group,
+	//	the user must have the group.delete permission
+	Archive(ctx context.Context, groupID, userID int, archive bool) (*models.Group, error)
+	// Restore restores the deleted group. Returns service.ErrGroupRestoreExpired, if the retention is over
+	Restore(ctx context.Context, groupID, userID int) (*models.Group, error)
+	GetDeletedGroups() ([]models.Group, error)
+	// PurgeDeleted deletes forever the groups deleted before the retention and returns the number of them
+	PurgeDeleted() (int, error)
+
+	// Leave removes the user from the group with its assignments to the tasks of the group.
+	//	Returns service.ErrLastGroupOwner for the last owner of the group.
+	Leave(ctx context.Context, groupID, userID int) error
+	// RemoveMember removes the member from the group with its assignments to the tasks of the group.
+	//	The user must have the group.members.manage permission, and the group.roles.manage one to remove an owner.
+	//	Returns service.ErrLastGroupOwner for the last owner of the group.
+	RemoveMember(ctx context.Context, groupID, memberID, userID int) error
+	// TransferOwnership makes the member the owner of the group, the previous owner becomes the headman.
+	//	Only an owner of the group transfers its ownership.
+	TransferOwnership(ctx context.Context, groupID, ownerID, newOwnerID int) error
+	// ImportMembers adds the users from the CSV file with the login, email and full_name columns to the group
+	//	as members in one transaction. The users are matched by the email, the placeholder accounts are created
+	//	for the unknown emails and get the invite emails, if createAccounts is true.
+	//	Nothing is written, if dryRun is true. The user must have the group.members.manage permission.
+	ImportMembers(ctx context.Context, groupID, userID int, file io.Reader, createAccounts, dryRun bool) (*models.MemberImportReport, error)
+
+	// Rollover moves the groups of the university created before the academic year to the next course
+	//	and archives the graduated groups of the final course, the previous names of the groups are kept in the history.
+	//	The academic year is the year it starts in, the current one is rolled over, if it's zero.
+	//	Returns service.ErrGroupRolloverIsDone, if the groups are already rolled over to the academic year.
+	Rollover(ctx context.Context, universityID, academicYear, userID int) (*models.GroupRollover, error)
+	// GetRollovers returns the rollovers of the university, the latest academic year first
+	GetRollovers(universityID int) ([]models.GroupRollover, error)
+	// GetNameHistory returns the names the group had before the rollovers
+	GetNameHistory(groupID int) ([]models.GroupNameHistory, error)
 
 	GetGroupMembers(groupID int) ([]models.User, error)
-	GetGroupsUserMemberOf(userID int) ([]models.Group, error)
+	// GetGroupsUserMemberOf returns the groups of the user, the archived ones only if includeArchived
+	GetGroupsUserMemberOf(userID int, includeArchived bool) ([]models.Group, error)
 	IsUserGroupMember(userID, groupID int) (bool, error)
-	GetUserPermissions(userID, groupID int) error
-
-	AddUserToGroupByInvite(userID int, invite string) error
-	GetInviteLink(groupID int) (*models.GroupInvite, error)
-	GetOrCreateInviteLink(groupID int, inviterID int) (*models.GroupInvite, error)
+	// GetUserPermissions returns names of the permissions the user has in the group
+	GetUserPermissions(userID, groupID int) ([]string, error)
+
+	// CreateInvite creates the invite of the group by the inviter with the random hash.
+	//	The invite expires after the default TTL, if ExpiresAt is zero.
+	//	Only the users with the group.roles.manage permission create the invites granting the roles above the member,
+	//	the owner role isn't granted by the invites.
+	CreateInvite(ctx context.Context, invite *models.GroupInvite) error
+	// GetInvites returns not revoked invites of the group. Expired and used up invites are returned too
+	GetInvites(groupID int) ([]models.GroupInvite, error)
+	RevokeInvite(ctx context.Context, groupID, inviteID, userID int) error
+	// AddUserToGroupByInvite adds the user to the group of the invite with the default role of the invite.
+	//	Returns service.ErrInvalidInvite, if the invite is revoked, expired or used up,
+	//	and service.ErrGroupJoinRequiresApproval or service.ErrGroupIsClosed, if the group isn't in the open mode.
+	AddUserToGroupByInvite(ctx context.Context, userID int, invite string) error
+
+	// RequestToJoin creates the pending request of the user to join the group in the approval mode
+	RequestToJoin(ctx context.Context, groupID, userID int, message string) (*models.GroupJoinRequest, error)
+	// GetJoinRequests returns the join requests of the group with the status, or all of them, if the status is empty
+	GetJoinRequests(groupID int, status string) ([]models.GroupJoinRequest, error)
+	// GetUserJoinRequests returns the join requests of the user, the newest first
+	GetUserJoinRequests(userID int) ([]models.GroupJoinRequest, error)
+	// DecideJoinRequest approves or rejects the pending join request, the approved requester becomes a member.
+	//	The user must have the group.members.manage permission.
+	DecideJoinRequest(ctx context.Context, groupID, requestID, userID int, approve bool) (*models.GroupJoinRequest, error)
 }
 
 type SubjectService interface {
@@ -147,3 +356,56 @@ type SubjectService interface {
 	Find(subjectID int) (*models.Subject, error)
 	Delete(subjectID int) (*models.Subject, error)
 }
+
+// RoleService checks permissions of users.
+//	Global roles (admin, moderator) work in the whole service,
+//	group roles (owner, headman, member, viewer) work only in the group the user is a member of,
+//	faculty roles (faculty_admin) work in the faculty and in the groups of its departments.
+type RoleService interface {
+	// SeedRoles writes roles and permissions from the service data to the store
+	//and grants the admin role to the administrators from the config
+	SeedRoles(data ServiceData.Data) error
+
+	// HasPermission checks the permission in the global roles of the user
+	HasPermission(userID int, permission string) (bool, error)
+	// HasGroupPermission checks the permission in the roles of the user in the group, in the faculty of the group
+	//	and in the global roles. Members without any role in the group have the member role.
+	HasGroupPermission(userID, groupID int, permission string) (bool, error)
+	// HasFacultyPermission checks the permission in the roles of the user in the faculty and in the global roles
+	HasFacultyPermission(userID, facultyID int, permission string) (bool, error)
+	// IsGroupPermission reports whether the permission is granted by group roles.
+	//	Returns service.ErrPermissionNotFound for unknown permissions.
+	IsGroupPermission(permission string) (bool, error)
+	// IsGroupOwner reports whether the member has the owner role in the group
+	IsGroupOwner(userID, groupID int) (bool, error)
+	// FindGroupRole returns the role granted in the groups.
+	//	Returns service.ErrRoleNotFound for unknown roles and service.ErrInvalidRoleScope for the roles of other scopes.
+	FindGroupRole(roleName string) (*models.Role, error)
+
+	GetUserPermissions(userID int) ([]string, error)
+	GetMemberPermissions(userID, groupID int) ([]string, error)
+	GetFacultyPermissions(userID, facultyID int) ([]string, error)
+
+	// AssignRole grants the global role to the user
+	AssignRole(ctx context.Context, userID int, roleName string) error
+	// RevokeRole revokes the global role of the user
+	RevokeRole(ctx context.Context, userID int, roleName string) error
+	// SetGroupRole replaces the role of the group member.
+	//	Returns service.ErrLastGroupOwner, if the last owner of the group loses the role.
+	SetGroupRole(userID, groupID int, roleName string) error
+	// AssignFacultyRole grants the faculty role to the user in the faculty
+	AssignFacultyRole(ctx context.Context, userID, facultyID int, roleName string) error
+	RevokeFacultyRole(ctx context.Context, userID, facultyID int, roleName string) error
+}
+
+// AuditService writes the append-only audit log of the security-sensitive actions
+type AuditService interface {
+	// Record writes the entry. The actor user and app, the impersonator, the request ID and the IP address
+	//	are taken from the context, if they aren't set in the entry. The time is set, if it's zero.
+	Record(ctx context.Context, entry *models.AuditEntry) error
+	// Find returns the page of the entries matching the filter, the newest first. The cursor is the one of the previous page.
+	//	Returns service.ErrInvalidAuditCursor, if the cursor can't be decoded.
+	Find(filter *models.AuditFilter, cursor string) (*models.AuditPage, error)
+	// Prune deletes the entries older than the retention and returns the number of deleted ones
+	Prune() (int, error)
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+)
+
+type AuditService struct {
+	service *Service
+	janitor *periodicJob
+}
+
+func NewAuditService(service *Service) *AuditService {
+	s := &AuditService{
+		service: service,
+	}
+
+	if service.config.Audit.Retention > 0 {
+		s.janitor = startPeriodicJob(service.config.Audit.PruneInterval, func() {
+			if _, err := s.Prune(); err != nil && service.logger != nil {
+				service.logger.Error("Unable to prune the audit log: ", err)
+			}
+		})
+	}
+
+	return s
+}
+
+func (s *AuditService) Record(ctx context.Context, entry *models.AuditEntry) error {
+	if entry.ActorUserID == nil {
+		if user, err := s.service.getUserFromContext(ctx); err == nil {
+			entry.ActorUserID = &user.ID
+		}
+	}
+	if entry.ActorAppID == nil {
+		if appID, err := s.service.getAppIDFromContext(ctx); err == nil {
+			entry.ActorAppID = &appID
+		}
+	}
+	if entry.ImpersonatorID == nil {
+		if session, err := s.service.getSessionFromContext(ctx); err == nil {
+			entry.ImpersonatorID = session.ImpersonatorID
+		}
+	}
+	if entry.RequestID == "" {
+		if requestID, err := s.service.getRequestIDFromContext(ctx); err == nil {
+			entry.RequestID = requestID.String()
+		}
+	}
+	if entry.IP == "" {
+		entry.IP, _ = ctx.Value(CtxKeyIP).(string)
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	return s.service.store.Audit().Create(entry)
+}
+
+func (s *AuditService) Find(filter *models.AuditFilter, cursor string) (*models.AuditPage, error) {
+	if cursor != "" {
+		id, err := decodeAuditCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.Cursor = id
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = models.AuditDefaultLimit
+	} else if filter.Limit > models.AuditMaxLimit {
+		filter.Limit = models.AuditMaxLimit
+	}
+
+	// One more entry is requested to find out if there is the next page
+	limit := filter.Limit
+	filter.Limit++
+	entries, err := s.service.store.Audit().Find(filter)
+	filter.Limit = limit
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+
+	page := &models.AuditPage{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.NextCursor = encodeAuditCursor(page.Entries[limit-1].ID)
+	}
+
+	return page, nil
+}
+
+func (s *AuditService) Prune() (int, error) {
+	if s.service.config.Audit.Retention <= 0 {
+		return 0, nil
+	}
+
+	return s.service.store.Audit().DeleteCreatedBefore(time.Now().Add(-s.service.config.Audit.Retention))
+}
+
+// Close stops the retention janitor
+func (s *AuditService) Close() {
+	s.janitor.Stop()
+}
+
+func encodeAuditCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeAuditCursor(cursor string) (int64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, service.ErrInvalidAuditCursor
+	}
+	id, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, service.ErrInvalidAuditCursor
+	}
+
+	return id, nil
+}
+
+// audit writes the entry with the JSON states of the target before and after the action.
+//	The failures are logged and don't fail the action, that is already done.
+func (s *Service) audit(ctx context.Context, entry *models.AuditEntry, before, after interface{}) {
+	var err error
+	if entry.Before, err = auditJSON(before); err == nil {
+		entry.After, err = auditJSON(after)
+	}
+	if err == nil {
+		err = s.Audit().Record(ctx, entry)
+	}
+
+	if err != nil && s.logger != nil {
+		s.logger.WithFields(logrus.Fields{
+			"action":      entry.Action,
+			"target_type": entry.TargetType,
+			"target_id":   entry.TargetID,
+		}).Error("Unable to write the audit log: ", err)
+	}
+}
+
+// auditJSON returns nil for nil values, so they aren't stored
+func auditJSON(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(v)
+}
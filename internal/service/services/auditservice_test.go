@@ -0,0 +1,140 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAuditService_Record(t *testing.T) {
+	s := newTestService(t)
+
+	u := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(u))
+	requestID := uuid.New()
+	appID := uuid.New()
+
+	ctx := context.WithValue(context.Background(), CtxKeyUser, u)
+	ctx = context.WithValue(ctx, CtxKeyRequestID, requestID)
+	ctx = context.WithValue(ctx, CtxKeyAppID, appID)
+	ctx = context.WithValue(ctx, CtxKeyIP, "127.0.0.1")
+
+	assert.NoError(t, s.Audit().Record(ctx, &models.AuditEntry{Action: models.AuditActionGroupCreate}))
+
+	page, err := s.Audit().Find(&models.AuditFilter{RequestID: requestID.String()}, "")
+	assert.NoError(t, err)
+	if assert.Len(t, page.Entries, 1) {
+		entry := page.Entries[0]
+		assert.Equal(t, u.ID, *entry.ActorUserID)
+		assert.Equal(t, appID, *entry.ActorAppID)
+		assert.Equal(t, "127.0.0.1", entry.IP)
+		assert.False(t, entry.CreatedAt.IsZero())
+	}
+}
+
+func TestAuditService_Find(t *testing.T) {
+	s := newTestService(t)
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, s.Audit().Record(context.Background(), &models.AuditEntry{Action: models.AuditActionTaskCreate}))
+	}
+	assert.NoError(t, s.Audit().Record(context.Background(), &models.AuditEntry{Action: models.AuditActionGroupCreate}))
+
+	var ids []int64
+	cursor := ""
+	for {
+		page, err := s.Audit().Find(&models.AuditFilter{Action: models.AuditActionTaskCreate, Limit: 2}, cursor)
+		if !assert.NoError(t, err) {
+			return
+		}
+		for _, entry := range page.Entries {
+			ids = append(ids, entry.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	assert.Equal(t, []int64{5, 4, 3, 2, 1}, ids)
+
+	_, err := s.Audit().Find(&models.AuditFilter{}, "not a cursor")
+	assert.Equal(t, service.ErrInvalidAuditCursor, err)
+}
+
+func TestAuditService_Prune(t *testing.T) {
+	s := newTestService(t)
+	s.config.Audit.Retention = time.Hour
+
+	assert.NoError(t, s.Audit().Record(context.Background(), &models.AuditEntry{
+		Action:    models.AuditActionTaskCreate,
+		CreatedAt: time.Now().Add(-2 * time.Hour),
+	}))
+	assert.NoError(t, s.Audit().Record(context.Background(), &models.AuditEntry{Action: models.AuditActionGroupCreate}))
+
+	deleted, err := s.Audit().Prune()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	page, err := s.Audit().Find(&models.AuditFilter{}, "")
+	assert.NoError(t, err)
+	if assert.Len(t, page.Entries, 1) {
+		assert.Equal(t, models.AuditActionGroupCreate, page.Entries[0].Action)
+	}
+}
+
+func TestAuthService_UserSignIn_Audit(t *testing.T) {
+	s := newTestService(t)
+
+	u := models.TestUser(t)
+	password := u.Password
+	assert.NoError(t, s.Auth().RegisterUser(u))
+
+	_, _, err := s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: u.Login, Password: "wrong", IP: "127.0.0.1"})
+	assert.Equal(t, service.ErrIncorrectLoginOrPassword, err)
+	_, _, err = s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: u.Login, Password: password, IP: "127.0.0.1"})
+	assert.NoError(t, err)
+
+	page, err := s.Audit().Find(&models.AuditFilter{TargetType: models.AuditTargetUser, TargetID: strconv.Itoa(u.ID)}, "")
+	assert.NoError(t, err)
+	if assert.Len(t, page.Entries, 2) {
+		assert.Equal(t, models.AuditActionSignIn, page.Entries[0].Action)
+		assert.Equal(t, models.AuditActionSignInFailed, page.Entries[1].Action)
+	}
+}
+
+func TestAuthService_AccountSecurity_Audit(t *testing.T) {
+	s := newTestService(t)
+	m := &testMailer{}
+	s.AddMailer(m)
+	ctx := context.Background()
+
+	u := models.TestUser(t)
+	password := u.Password
+	assert.NoError(t, s.Auth().RegisterUser(u))
+
+	assert.NoError(t, s.Auth().ChangePassword(ctx, u.ID, password, "new password"))
+	assert.NoError(t, s.Auth().RequestPasswordReset(u.Email))
+	assert.NoError(t, s.Auth().ResetPassword(ctx, m.lastToken(t), "another password"))
+
+	pt, _, err := s.Auth().CreatePersonalToken(ctx, u.ID, "bot", models.TokenScopeTasksRead, time.Time{})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Auth().RevokePersonalToken(ctx, u.ID, pt.ID))
+
+	page, err := s.Audit().Find(&models.AuditFilter{ActorUserID: u.ID}, "")
+	assert.NoError(t, err)
+	actions := make([]string, len(page.Entries))
+	for i, e := range page.Entries {
+		actions[i] = e.Action
+	}
+	assert.Equal(t, []string{
+		models.AuditActionPersonalTokenRevoke,
+		models.AuditActionPersonalTokenCreate,
+		models.AuditActionPasswordReset,
+		models.AuditActionPasswordChange,
+	}, actions)
+}
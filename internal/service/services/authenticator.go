@@ -0,0 +1,96 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"backend/internal/store"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Authenticator checks the login (or the email) and the password of the sign-in.
+//	The local user is nil, if the login or the email isn't registered.
+//	Returns service.ErrIncorrectLoginOrPassword, if the credentials are rejected.
+type Authenticator interface {
+	Name() string
+	Authenticate(signIn *models.UserSignIn, local *models.User) (*models.User, error)
+}
+
+// newAuthenticators returns the enabled authenticators in the order they are tried
+func newAuthenticators(s *AuthService) []Authenticator {
+	authenticators := []Authenticator{&localAuthenticator{}}
+	if s.service.config.Auth.LDAP.Enabled {
+		authenticators = append(authenticators, newLDAPAuthenticator(s))
+	}
+
+	return authenticators
+}
+
+// localAuthenticator checks the bcrypt hash of the password
+type localAuthenticator struct{}
+
+func (a *localAuthenticator) Name() string {
+	return "local"
+}
+
+func (a *localAuthenticator) Authenticate(signIn *models.UserSignIn, local *models.User) (*models.User, error) {
+	if local == nil {
+		return nil, service.ErrIncorrectLoginOrPassword
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(local.EncryptedPassword), []byte(signIn.Password)); err != nil {
+		return nil, service.ErrIncorrectLoginOrPassword
+	}
+
+	return local, nil
+}
+
+// findSignInUser returns the local user by the login or the email of the sign-in, nil if it isn't registered
+func (s *AuthService) findSignInUser(signIn *models.UserSignIn) (*models.User, error) {
+	var user *models.User
+	var err error
+
+	if signIn.Login != "" && signIn.Email == "" {
+		user, err = s.service.store.User().FindByLogin(signIn.Login)
+	} else if signIn.Email != "" && signIn.Login == "" {
+		user, err = s.service.store.User().FindByEmail(signIn.Email)
+	} else {
+		return nil, nil
+	}
+
+	if err == store.ErrRecordNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// authenticate tries the authenticators in order, the first one that accepts the credentials wins.
+//	The failures other than the rejected credentials (e.g. the directory is unavailable) are logged.
+//	If no authenticator accepts the credentials, service.ErrIncorrectLoginOrPassword is returned,
+//	unless every authenticator has failed, then the last failure is returned.
+func (s *AuthService) authenticate(signIn *models.UserSignIn, local *models.User) (*models.User, error) {
+	var lastErr error
+	rejected := false
+	for _, a := range s.authenticators {
+		user, err := a.Authenticate(signIn, local)
+		if err == nil {
+			return user, nil
+		}
+		if err == service.ErrIncorrectLoginOrPassword {
+			rejected = true
+			continue
+		}
+
+		s.service.logger.WithFields(logrus.Fields{
+			"authenticator": a.Name(),
+		}).Warn("Unable to authenticate the user: ", err)
+		lastErr = err
+	}
+
+	if !rejected && lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, service.ErrIncorrectLoginOrPassword
+}
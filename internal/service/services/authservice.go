@@ -4,28 +4,33 @@ import (
 	"backend/internal/api/v1/models"
 	"backend/internal/service"
 	"backend/internal/store"
-	"crypto/md5"
-	"crypto/sha1"
-	"encoding/hex"
+	"backend/pkg/mailer"
+	"backend/pkg/oidc"
+	"context"
+	"crypto/rand"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
-	"math/rand"
+	"github.com/sirupsen/logrus"
+	"math/big"
 	"strconv"
+	"strings"
 	"time"
 )
 
 const (
-	refreshTokenLength = 64
+	// refreshTokenBytes of the crypto/rand, the token is 64 characters of the base64url
+	refreshTokenBytes = 48
 )
 
 var (
-	TokenTTL         = 12 * time.Hour      //Deprecated.
-	appTokenTTL      = 24 * time.Hour * 30 //Deprecated.
-	signingKeyLength = 64                  //Deprecated.
+	TokenTTL    = 12 * time.Hour      //Deprecated.
+	appTokenTTL = 24 * time.Hour * 30 //Deprecated.
 
 	minimumAccessTokenRefreshRate = 5 * time.Second
+	sessionLastSeenUpdateRate     = 1 * time.Minute
 )
 
 type tokenClaims struct {
@@ -36,10 +41,14 @@ type tokenClaims struct {
 type AuthService struct {
 	service *Service
 
-	tokenBlacklistMgr *BlacklistManager
-	signingKey        string
-	accessTTL         time.Duration
-	refreshTTL        time.Duration
+	tokenBlacklist service.TokenBlacklist
+	keys           *keyRing
+	throttle       *loginThrottle
+	passwordPolicy *passwordPolicy
+	oidc           *oidc.Client
+	authenticators []Authenticator
+	accessTTL      time.Duration
+	refreshTTL     time.Duration
 }
 
 func NewAuthService(service *Service) *AuthService {
@@ -49,18 +58,46 @@ func NewAuthService(service *Service) *AuthService {
 		accessTTL:  service.config.Auth.JWT.AccessTokenTTL,
 	}
 
-	s.signingKey = service.config.Auth.JWT.SigningKey
-
-	s.tokenBlacklistMgr = New()
-	_ = service.store.Auth().ClearUserTokens()
+	keys, err := newKeyRing(service.store.SigningKey(), service.config.Auth.JWT, service.logger)
+	if err != nil && service.logger != nil {
+		service.logger.Error("Unable to load the signing keys: ", err)
+	}
+	s.keys = keys
+
+	s.tokenBlacklist = newTokenBlacklist(service)
+	s.throttle = newLoginThrottle(service.store.LoginAttempt(), service.config.Auth.LoginThrottle, service.logger)
+	s.passwordPolicy = newPasswordPolicy(service.config.Auth.PasswordPolicy, service.logger)
+
+	if cfg := service.config.Auth.OIDC; cfg.Enabled {
+		s.oidc = oidc.New(oidc.Config{
+			Issuer:       cfg.Issuer,
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       cfg.Scopes,
+		}, nil)
+	}
+	s.authenticators = newAuthenticators(s)
 	return s
 }
 
-func (s *AuthService) GetSigningKey() string {
-	return s.signingKey
+func (s *AuthService) KeyFunc(token *jwt.Token) (interface{}, error) {
+	if s.keys == nil {
+		return nil, service.ErrSigningKeyNotFound
+	}
+
+	return s.keys.Keyfunc(token)
+}
+
+func (s *AuthService) GetJWKS() models.JSONWebKeySet {
+	if s.keys == nil {
+		return models.JSONWebKeySet{Keys: []models.JSONWebKey{}}
+	}
+
+	return s.keys.JWKS()
 }
 
-func (s *AuthService) RegisterApp(app *models.RegisteredApp) error {
+func (s *AuthService) RegisterApp(ctx context.Context, app *models.RegisteredApp) error {
 	_, err := s.service.store.Auth().GetAppByName(app.AppName)
 	if err != nil && err != store.ErrRecordNotFound {
 		return err
@@ -68,19 +105,42 @@ func (s *AuthService) RegisterApp(app *models.RegisteredApp) error {
 		return service.ErrAppNameIsAlreadyOccupied
 	}
 
+	scope, err := normalizeAppScope(app.Scope, strings.Join(models.AppScopes, " "))
+	if err != nil {
+		return err
+	}
+	app.Scope = scope
+
+	app.FingerprintPolicy, err = s.normalizeFingerprintPolicy(app.FingerprintPolicy)
+	if err != nil {
+		return err
+	}
+
 	uuid, err := uuid.NewUUID()
 	if err != nil {
 		return err
 	}
 	app.ID = uuid
-	letters := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890")
-	app.AppSecret = s.GetToken(32, letters)
-	err = s.service.store.Auth().RegisterApp(app)
+	app.AppSecret, err = generateAppSecret()
+	if err != nil {
+		return err
+	}
+	app.SecretHash = hashToken(app.AppSecret)
+	if err := s.service.store.Auth().RegisterApp(app); err != nil {
+		return err
+	}
 
-	return err
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorAppID: &app.ID,
+		Action:     models.AuditActionAppRegister,
+		TargetType: models.AuditTargetApp,
+		TargetID:   app.ID.String(),
+	}, nil, auditApp(app))
+
+	return nil
 }
 
-func (s *AuthService) DeleteApp(appID, appSecret, appToken string) error {
+func (s *AuthService) DeleteApp(ctx context.Context, appID, appSecret, appToken string) error {
 	appUUID, err := uuid.Parse(appID)
 	if err != nil {
 		return service.ErrInvalidAppID
@@ -96,7 +156,7 @@ func (s *AuthService) DeleteApp(appID, appSecret, appToken string) error {
 		return service.ErrAppAuthorization
 	}
 
-	if regApp.AppSecret != appSecret || regAppToken.AppToken != appToken {
+	if !isAppSecretValid(regApp, appSecret) || regAppToken.AppToken != appToken {
 		return service.ErrAppAuthorization
 	}
 
@@ -110,6 +170,13 @@ func (s *AuthService) DeleteApp(appID, appSecret, appToken string) error {
 		return err
 	}
 
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorAppID: &appUUID,
+		Action:     models.AuditActionAppDelete,
+		TargetType: models.AuditTargetApp,
+		TargetID:   appUUID.String(),
+	}, auditApp(regApp), nil)
+
 	return nil
 }
 
@@ -119,6 +186,9 @@ func (s *AuthService) RegisterUser(user *models.User) error {
 	if err := user.Validate(); err != nil {
 		return err
 	}
+	if err := s.passwordPolicy.check(user.Password, user); err != nil {
+		return err
+	}
 
 	_, err := s.service.User().FindByEmail(user.Email)
 	if err != nil && err != service.ErrUserNotFound {
@@ -143,104 +213,542 @@ func (s *AuthService) RegisterUser(user *models.User) error {
 		return err
 	}
 
+	// The user can request the link again, so the registration doesn't fail because of the mailer
+	if err := s.sendEmailConfirmation(user); err != nil {
+		s.service.logger.WithFields(logrus.Fields{
+			"user_id": user.ID,
+		}).Error("Unable to send the email confirmation: ", err)
+	}
+
 	return nil
 }
 
-func (s *AuthService) UserSignIn(userSignIn *models.UserSignIn) (*models.UserToken, error) {
-	user := &models.User{}
-	var err error
+func (s *AuthService) SendEmailConfirmation(userID int) error {
+	user, err := s.service.User().Find(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.IsEmailConfirmed() {
+		return service.ErrEmailIsAlreadyConfirmed
+	}
+
+	return s.sendEmailConfirmation(user)
+}
+
+func (s *AuthService) sendEmailConfirmation(user *models.User) error {
+	cfg := s.service.config.Auth.EmailConfirmation
 
-	if userSignIn.Login != "" && userSignIn.Email == "" {
-		user, err = s.service.store.User().FindByLogin(userSignIn.Login)
-		if err == store.ErrRecordNotFound {
-			return nil, service.ErrIncorrectLoginOrPassword
-		} else if err != nil {
+	token, err := s.service.issueVerificationToken(user, models.VerificationEmailConfirmation, cfg.TokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link, err := addTokenToURL(cfg.URL, token)
+	if err != nil {
+		return err
+	}
+
+	return s.service.getMailer().Send(&mailer.Message{
+		From:    s.service.config.Mailer.From,
+		To:      []string{user.Email},
+		Subject: "Unitask: confirm your email address",
+		Body: fmt.Sprintf("Hello, %s!\n\n"+
+			"To confirm your email address open the link:\n%s\n\n"+
+			"The link is valid for %s. If you didn't register at Unitask, ignore this email.\n",
+			user.Login, link, cfg.TokenTTL),
+	})
+}
+
+func (s *AuthService) ConfirmEmail(token string) (*models.User, error) {
+	t, err := s.service.useVerificationToken(models.VerificationEmailConfirmation, token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.service.User().Find(t.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The link confirms only the address it was sent to
+	if user.Email != t.Email {
+		return nil, service.ErrInvalidVerificationToken
+	}
+
+	if !user.IsEmailConfirmed() {
+		confirmedAt := time.Now()
+		if err := s.service.store.User().ConfirmEmail(user.ID, confirmedAt); err != nil {
 			return nil, err
 		}
-	} else if userSignIn.Email != "" && userSignIn.Login == "" {
+		user.EmailConfirmedAt = &confirmedAt
+	}
 
-		user, err = s.service.store.User().FindByEmail(userSignIn.Email)
-		if err == store.ErrRecordNotFound {
-			return nil, service.ErrIncorrectLoginOrPassword
-		} else if err != nil {
-			return nil, err
+	return user, nil
+}
+
+func (s *AuthService) RequestPasswordReset(email string) error {
+	user, err := s.service.store.User().FindByEmail(email)
+	if err == store.ErrRecordNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	cfg := s.service.config.Auth.PasswordReset
+
+	token, err := s.service.issueVerificationToken(user, models.VerificationPasswordReset, cfg.TokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link, err := addTokenToURL(cfg.URL, token)
+	if err != nil {
+		return err
+	}
+
+	return s.service.getMailer().Send(&mailer.Message{
+		From:    s.service.config.Mailer.From,
+		To:      []string{user.Email},
+		Subject: "Unitask: password reset",
+		Body: fmt.Sprintf("Hello, %s!\n\n"+
+			"To set a new password open the link:\n%s\n\n"+
+			"The link is valid for %s. If you didn't request the password reset, ignore this email.\n",
+			user.Login, link, cfg.TokenTTL),
+	})
+}
+
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if err := models.ValidatePassword(newPassword); err != nil {
+		return err
+	}
+
+	// The token is used only after the policy check, so the rejected password doesn't spend the link
+	t, err := s.service.findVerificationToken(models.VerificationPasswordReset, token)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.service.User().Find(t.UserID)
+	if err != nil {
+		return err
+	}
+
+	if user.Email != t.Email {
+		return service.ErrInvalidVerificationToken
+	}
+
+	if err := s.passwordPolicy.check(newPassword, user); err != nil {
+		return err
+	}
+
+	if err := s.service.markVerificationTokenUsed(t); err != nil {
+		return err
+	}
+
+	if err := s.setPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &user.ID,
+		Action:      models.AuditActionPasswordReset,
+		TargetType:  models.AuditTargetUser,
+		TargetID:    strconv.Itoa(user.ID),
+	}, nil, nil)
+
+	// The link from the email proves the ownership of the address
+	if !user.IsEmailConfirmed() {
+		return s.service.store.User().ConfirmEmail(user.ID, time.Now())
+	}
+
+	return nil
+}
+
+func (s *AuthService) ChangePassword(ctx context.Context, userID int, oldPassword, newPassword string) error {
+	if err := models.ValidatePassword(newPassword); err != nil {
+		return err
+	}
+
+	user, err := s.service.User().Find(userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.ComparePassword(oldPassword) {
+		return service.ErrIncorrectPassword
+	}
+
+	if err := s.passwordPolicy.check(newPassword, user); err != nil {
+		return err
+	}
+
+	if err := s.setPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &user.ID,
+		Action:      models.AuditActionPasswordChange,
+		TargetType:  models.AuditTargetUser,
+		TargetID:    strconv.Itoa(user.ID),
+	}, nil, nil)
+
+	return nil
+}
+
+// setPassword saves the new password and signs the user out of all devices
+func (s *AuthService) setPassword(user *models.User, newPassword string) error {
+	enc, err := models.EncryptPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.service.store.User().UpdatePassword(user.ID, enc); err != nil {
+		return err
+	}
+	user.EncryptedPassword = enc
+
+	return s.revokeAllUserTokens(user.ID)
+}
+
+// revokeAllUserTokens closes all sessions of the user, invalidates the refresh tokens,
+//blacklists the access tokens that haven't expired yet and revokes the personal access tokens
+func (s *AuthService) revokeAllUserTokens(userID int) error {
+	now := time.Now()
+
+	tokens, err := s.service.store.Auth().GetUserTokensIssuedAfter(userID, now.Add(-s.accessTTL))
+	if err != nil {
+		return err
+	}
+	for _, t := range tokens {
+		if err := s.addAccessTokenToBlacklist(t.AccessToken, t.IssueTokenTimestamp.Add(s.accessTTL)); err != nil {
+			return err
+		}
+	}
+
+	if err := s.service.store.Auth().SetUserTokenInvalidByUserID(userID); err != nil {
+		return err
+	}
+
+	if err := s.service.store.PersonalToken().RevokeByUser(userID, now); err != nil {
+		return err
+	}
+
+	sessions, err := s.service.store.Session().FindByUser(userID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if err := s.service.store.Session().Revoke(session.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UserSignIn checks the credentials and opens a new session.
+//	If the user has 2FA enabled, the session isn't opened, the challenge is returned instead
+//	and the sign-in is completed by CompleteMFASignIn.
+//	Failed attempts are throttled per account and per IP, *service.ThrottledError is returned then.
+func (s *AuthService) UserSignIn(ctx context.Context, userSignIn *models.UserSignIn) (*models.UserToken, *models.MFAChallenge, error) {
+	if userSignIn.IP != "" {
+		if err := s.throttle.check(loginAttemptsIPKey(userSignIn.IP)); err != nil {
+			return nil, nil, err
 		}
 	}
 
-	if err = bcrypt.CompareHashAndPassword([]byte(user.EncryptedPassword), []byte(userSignIn.Password)); err != nil {
-		return nil, service.ErrIncorrectLoginOrPassword
+	local, err := s.findSignInUser(userSignIn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The account is checked before the authenticators, so the throttled attempts don't cost the CPU time
+	if local != nil {
+		if err := s.throttle.check(loginAttemptsAccountKey(local.ID)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Any attempt, that isn't accepted, is counted, even if the directory is unavailable
+	user, err := s.authenticate(userSignIn, local)
+	if err != nil {
+		s.failSignIn(local, userSignIn.IP)
+		s.auditFailedSignIn(ctx, local, userSignIn.IP, map[string]string{
+			"login": userSignIn.Login,
+			"email": userSignIn.Email,
+		})
+		return nil, nil, err
+	}
+
+	return s.completeSignIn(ctx, user, userSignIn.DeviceName, userSignIn.UserAgent, userSignIn.IP)
+}
+
+// completeSignIn opens the session of the user whose first factor was accepted.
+//	The user without the confirmed email is rejected, if the confirmation is required to sign in.
+//	If the user has 2FA enabled, the challenge is returned instead of the session.
+func (s *AuthService) completeSignIn(ctx context.Context, user *models.User, deviceName, userAgent, ip string) (*models.UserToken, *models.MFAChallenge, error) {
+	if s.service.config.Auth.EmailConfirmation.RequiredToSignIn && !user.IsEmailConfirmed() {
+		return nil, nil, service.ErrEmailIsNotConfirmed
 	}
 
-	accessToken, err := s.service.Auth().GenerateAccessToken(user, time.Now())
+	enabled, err := s.isTwoFactorEnabled(user.ID)
 	if err != nil {
+		return nil, nil, err
+	}
+	// With 2FA the failures are forgotten only after the second factor, so new challenges don't reset the code guessing
+	if enabled {
+		challenge, err := s.issueMFAChallenge(user)
+		return nil, challenge, err
+	}
+
+	if err := s.throttle.reset(loginAttemptsAccountKey(user.ID)); err != nil {
+		return nil, nil, err
+	}
+
+	userToken, err := s.openSession(ctx, user, deviceName, userAgent, ip)
+	return userToken, nil, err
+}
+
+// openSession creates the session and its first token pair. The sign-in is written to the audit log
+func (s *AuthService) openSession(ctx context.Context, user *models.User, deviceName, userAgent, ip string) (*models.UserToken, error) {
+	session := &models.UserSession{
+		UserID:     user.ID,
+		DeviceName: deviceName,
+		UserAgent:  userAgent,
+		IP:         ip,
+	}
+	if err := s.service.store.Session().Create(session); err != nil {
 		return nil, err
 	}
-	refreshToken := s.service.Auth().GenerateRefreshToken()
-	userToken := &models.UserToken{
-		AccessToken:         accessToken,
-		RefreshToken:        refreshToken,
-		IssueTokenTimestamp: time.Now(),
-		ExpirationTimestamp: time.Now().Add(s.refreshTTL),
-		StartTimestamp:      time.Now(),
-		UserID:              user.ID,
+
+	userToken, err := s.GenerateTokenPair(user, session.ID)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := s.service.store.Auth().AddUserToken(userToken); err != nil {
 		return nil, err
 	}
 
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &user.ID,
+		Action:      models.AuditActionSignIn,
+		TargetType:  models.AuditTargetUser,
+		TargetID:    strconv.Itoa(user.ID),
+		IP:          ip,
+	}, nil, nil)
+
 	return userToken, nil
 }
 
-func (s *AuthService) UserLogout(userID int) error {
-	//TODO Выходит сразу со всех устройств и приложений. Доработать
-	token, err := s.service.store.Auth().GetUserToken(userID)
-	if err != nil {
+// auditFailedSignIn writes the failed sign-in to the audit log. The user is nil, if the login isn't registered
+func (s *AuthService) auditFailedSignIn(ctx context.Context, user *models.User, ip string, details interface{}) {
+	entry := &models.AuditEntry{
+		Action:     models.AuditActionSignInFailed,
+		TargetType: models.AuditTargetUser,
+		IP:         ip,
+	}
+	if user != nil {
+		entry.TargetID = strconv.Itoa(user.ID)
+	}
+	if data, err := json.Marshal(details); err == nil {
+		entry.Details = data
+	}
+
+	s.service.audit(ctx, entry, nil, nil)
+}
+
+// auditApp is the state of the app in the audit log, without the secret
+func auditApp(app *models.RegisteredApp) interface{} {
+	return map[string]string{
+		"id":                 app.ID.String(),
+		"app_name":           app.AppName,
+		"scope":              app.Scope,
+		"fingerprint_policy": app.FingerprintPolicy,
+	}
+}
+
+func (s *AuthService) UserLogout(userID, sessionID int) error {
+	return s.CloseSession(userID, sessionID)
+}
+
+func (s *AuthService) GetUserSessions(userID int) ([]models.UserSession, error) {
+	return s.service.store.Session().FindByUser(userID)
+}
+
+func (s *AuthService) CloseSession(userID, sessionID int) error {
+	session, err := s.service.store.Session().Find(sessionID)
+	if err == store.ErrRecordNotFound {
+		return service.ErrSessionNotFound
+	} else if err != nil {
 		return err
 	}
+	if session.UserID != userID {
+		return service.ErrSessionNotFound
+	}
 
-	s.addAccessTokenToBlacklist(token.AccessToken, token.ExpirationTimestamp)
+	return s.revokeSession(session)
+}
 
-	err = s.service.store.Auth().SetUserTokenInvalidByToken(token.AccessToken)
+func (s *AuthService) CloseOtherSessions(userID, currentSessionID int) (int, error) {
+	sessions, err := s.service.store.Session().FindByUser(userID)
 	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for i := range sessions {
+		if sessions[i].ID == currentSessionID {
+			continue
+		}
+		if err := s.revokeSession(&sessions[i]); err != nil {
+			return closed, err
+		}
+		closed++
+	}
+
+	return closed, nil
+}
+
+// revokeSession closes the session, invalidates its refresh tokens
+//and blacklists the last access token of the session
+func (s *AuthService) revokeSession(session *models.UserSession) error {
+	token, err := s.service.store.Auth().GetSessionToken(session.ID)
+	if err != nil && err != store.ErrRecordNotFound {
 		return err
+	} else if err == nil {
+		if err := s.addAccessTokenToBlacklist(token.AccessToken, time.Now().Add(s.accessTTL)); err != nil {
+			return err
+		}
 	}
 
-	_, err = s.service.store.Auth().RemoveUserTokens(userID)
-	if err != nil {
+	if err := s.service.store.Auth().SetUserTokenInvalidBySessionID(session.ID); err != nil {
 		return err
 	}
 
-	return nil
+	return s.service.store.Session().Revoke(session.ID)
 }
 
 func (s *AuthService) AuthenticateUser(accessToken string) (*models.User, error) {
+	u, _, err := s.AuthenticateUserSession(context.Background(), accessToken)
+	return u, err
+}
+
+func (s *AuthService) AuthenticateUserSession(ctx context.Context, accessToken string) (*models.User, *models.UserSession, error) {
 	tokenClaims, err := s.CheckAccessToken(accessToken)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if err := tokenClaims.Valid(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if s.tokenBlacklistMgr.IsTokenBlacklisted(accessToken) {
-		return nil, service.ErrAccessTokenIsBlacklisted
+	isBlacklisted, err := s.tokenBlacklist.IsBlacklisted(accessToken)
+	if err != nil {
+		return nil, nil, err
+	} else if isBlacklisted {
+		return nil, nil, service.ErrAccessTokenIsBlacklisted
+	}
+
+	session, err := s.getActiveSession(tokenClaims.SessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkImpersonation(tokenClaims, session); err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkFingerprint(ctx, tokenClaims, session); err != nil {
+		return nil, nil, err
 	}
 
 	u, err := s.service.User().Find(tokenClaims.UserID)
 	if err != nil {
+		return nil, nil, err
+	}
+
+	return u, session, nil
+}
+
+// getActiveSession returns the session and updates the time of its last use.
+//	Tokens issued before sessions were introduced have no session, in this case nil is returned.
+func (s *AuthService) getActiveSession(sessionID int) (*models.UserSession, error) {
+	if sessionID == 0 {
+		return nil, nil
+	}
+
+	session, err := s.service.store.Session().Find(sessionID)
+	if err == store.ErrRecordNotFound {
+		return nil, service.ErrSessionNotFound
+	} else if err != nil {
 		return nil, err
 	}
+	now := time.Now()
+	if !session.IsActive(now) {
+		return nil, service.ErrSessionIsRevoked
+	}
 
-	return u, nil
+	if now.Sub(session.LastSeenAt) > sessionLastSeenUpdateRate {
+		if err := s.service.store.Session().UpdateLastSeen(session.ID, now); err != nil {
+			return nil, err
+		}
+		session.LastSeenAt = now
+	}
 
+	return session, nil
+}
+
+// revokeTokenFamily invalidates all refresh tokens of the family, blacklists
+//its live access tokens and closes the session the family belongs to
+func (s *AuthService) revokeTokenFamily(token *models.UserToken) error {
+	s.service.logger.WithFields(logrus.Fields{
+		"user_id":    token.UserID,
+		"session_id": token.SessionID,
+		"family_id":  token.FamilyID,
+	}).Warn("refresh token reuse detected, the token family is revoked")
+
+	// The token issued before the sessions has no family, only itself is revoked
+	if token.FamilyID == uuid.Nil {
+		return s.service.store.Auth().SetUserTokenInvalidByToken(token.RefreshToken)
+	}
+
+	family, err := s.service.store.Auth().GetUserTokenFamily(token.FamilyID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, t := range family {
+		accessExpiresAt := t.IssueTokenTimestamp.Add(s.accessTTL)
+		if accessExpiresAt.After(now) {
+			if err := s.addAccessTokenToBlacklist(t.AccessToken, accessExpiresAt); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := s.service.store.Auth().SetUserTokenInvalidByFamily(token.FamilyID); err != nil {
+		return err
+	}
+
+	if token.SessionID != 0 {
+		return s.service.store.Session().Revoke(token.SessionID)
+	}
+	return nil
 }
 
 func (s *AuthService) RefreshPairAccessRefreshToken(userID int, accessToken, refreshToken string) (*models.UserToken, error) {
 	// Проверить наличие и валидность refresh-токена
 	oldToken, err := s.CheckRefreshToken(refreshToken, userID)
-	if err != nil {
+	if err == service.ErrRefreshTokenReused {
+		// Токен уже был обменян: вероятно, он украден. Отозвать всё семейство токенов
+		if err := s.revokeTokenFamily(oldToken); err != nil {
+			return nil, err
+		}
+		return nil, service.ErrRefreshTokenReused
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -264,8 +772,15 @@ func (s *AuthService) RefreshPairAccessRefreshToken(userID int, accessToken, ref
 		return nil, service.ErrAccessTokenRefreshRateExceeded
 	}
 
-	// Выдать время окончания жизни токена сейчас
-	if err := s.service.store.Auth().SetUserTokenInvalidByToken(oldToken.RefreshToken); err != nil {
+	// Пометить refresh-токен использованным. Повторное предъявление приведёт к отзыву семейства.
+	//	Из одновременных обменов одного токена пару получает только первый, остальные считаются повторным использованием
+	err = s.service.store.Auth().MarkUserTokenUsed(oldToken.ID)
+	if err == store.ErrRecordNotFound {
+		if err := s.revokeTokenFamily(oldToken); err != nil {
+			return nil, err
+		}
+		return nil, service.ErrRefreshTokenReused
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -275,11 +790,13 @@ func (s *AuthService) RefreshPairAccessRefreshToken(userID int, accessToken, ref
 		return nil, err
 	}
 
-	//Выдать новую пару access-refresh
-	newToken, err := s.GenerateTokenPair(user)
+	//Выдать новую пару access-refresh в той же сессии и том же семействе
+	newToken, err := s.GenerateTokenPair(user, oldToken.SessionID)
 	if err != nil {
 		return nil, err
 	}
+	newToken.FamilyID = oldToken.FamilyID
+	newToken.ParentID = oldToken.ID
 
 	// Добавить новую пару токенов в бд
 	if err := s.service.store.Auth().AddUserToken(newToken); err != nil {
@@ -314,9 +831,7 @@ func (s *AuthService) RestoreJWTToken(userID int, refreshToken string) (*models.
 func (s *AuthService) CheckAccessToken(accessToken string) (*models.UAccessTokenClaims, error) {
 	tokenClaims := &models.UAccessTokenClaims{}
 
-	token, err := jwt.ParseWithClaims(accessToken, &models.UAccessTokenClaims{}, func(*jwt.Token) (interface{}, error) {
-		return []byte(s.signingKey), nil
-	})
+	token, err := jwt.ParseWithClaims(accessToken, &models.UAccessTokenClaims{}, s.KeyFunc)
 	if token == nil {
 		return nil, err
 	}
@@ -330,23 +845,33 @@ func (s *AuthService) CheckAccessToken(accessToken string) (*models.UAccessToken
 }
 
 func (s *AuthService) CheckRefreshToken(refreshToken string, userID int) (*models.UserToken, error) {
-	userToken, err := s.service.store.Auth().GetUserToken(userID)
-	if err != nil && err != store.ErrRecordNotFound {
-		return nil, service.ErrInvalidUserToken
+	userToken, err := s.service.store.Auth().GetUserTokenByRefreshToken(refreshToken)
+	if err == store.ErrRecordNotFound {
+		return nil, service.ErrInvalidRefreshToken
 	} else if err != nil {
 		return nil, err
 	}
 
-	//Проверка на соответствие refresh токена
-	if userToken.RefreshToken != refreshToken {
+	//Проверка на принадлежность refresh токена пользователю
+	if userToken.UserID != userID {
 		return nil, service.ErrInvalidRefreshToken
 	}
 
+	//Проверка на повторное использование. Токен возвращается для отзыва семейства
+	if userToken.IsUsed() {
+		return userToken, service.ErrRefreshTokenReused
+	}
+
 	//	Проверить валидность токена
 	if !userToken.Valid() {
 		return nil, service.ErrInvalidUserToken
 	}
 
+	//	Проверить, что сессия не закрыта
+	if _, err := s.getActiveSession(userToken.SessionID); err != nil {
+		return nil, err
+	}
+
 	return userToken, nil
 }
 
@@ -364,7 +889,7 @@ func (s *AuthService) IsAppSecretValid(appID, appSecret string) (bool, error) {
 		return false, err
 	}
 
-	if !appData.IsSecretValid(appSecret) {
+	if !isAppSecretValid(appData, appSecret) {
 		return false, service.ErrAppAuthorization
 	}
 
@@ -409,6 +934,7 @@ func (s *AuthService) GetAppToken(appID uuid.UUID) (*models.AppToken, error) {
 			ExpirationTimestamp: time.Now().Add(appTokenTTL),
 			StartTimestamp:      time.Now(),
 			AppID:               appID,
+			Scope:               app.Scope,
 		}
 
 		newAppToken.AppToken, err = s.GenerateAppToken(app, newAppToken.StartTimestamp, newAppToken.ExpirationTimestamp)
@@ -457,16 +983,29 @@ func (s *AuthService) GetTokenInfo(token string) (*models.AppToken, error) {
 	return tokenInfo, nil
 }
 
-func (s *AuthService) addAccessTokenToBlacklist(token string, expirationTimestamp time.Time) {
-	s.tokenBlacklistMgr.AddTokenToBlacklist(token, expirationTimestamp)
+func (s *AuthService) addAccessTokenToBlacklist(token string, expirationTimestamp time.Time) error {
+	return s.tokenBlacklist.Add(token, expirationTimestamp)
 }
 
-func (s *AuthService) GenerateTokenPair(user *models.User) (*models.UserToken, error) {
-	accessToken, err := s.service.Auth().GenerateAccessToken(user, time.Now())
+// Close stops the janitor of the token blacklist and the rotation of signing keys
+func (s *AuthService) Close() {
+	s.tokenBlacklist.Close()
+	s.throttle.Close()
+	s.passwordPolicy.Close()
+	if s.keys != nil {
+		s.keys.Close()
+	}
+}
+
+func (s *AuthService) GenerateTokenPair(user *models.User, sessionID int) (*models.UserToken, error) {
+	accessToken, err := s.generateSessionAccessToken(user, sessionID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := s.service.Auth().GenerateRefreshToken()
 	if err != nil {
 		return nil, err
 	}
-	refreshToken := s.service.Auth().GenerateRefreshToken()
 	token := &models.UserToken{
 		AccessToken:         accessToken,
 		RefreshToken:        refreshToken,
@@ -474,66 +1013,67 @@ func (s *AuthService) GenerateTokenPair(user *models.User) (*models.UserToken, e
 		ExpirationTimestamp: time.Now().Add(s.refreshTTL),
 		StartTimestamp:      time.Now(),
 		UserID:              user.ID,
+		SessionID:           sessionID,
+		FamilyID:            uuid.New(),
 	}
 	return token, nil
 }
 
+// GenerateAppToken returns the random app token. The token doesn't depend on the app and timestamps
 func (s *AuthService) GenerateAppToken(app *models.RegisteredApp, startTimestamp time.Time, expirationTimestamp time.Time) (string, error) {
-	hashTime := startTimestamp.Nanosecond() + expirationTimestamp.Nanosecond()
-
-	s1 := sha1.New()
-	s1.Write([]byte(app.AppSecret))
-	s1.Write([]byte(strconv.Itoa(hashTime)))
-
-	m := md5.New()
-	m.Write(s1.Sum(nil))
-	m.Write([]byte(app.AppName))
-	token := hex.EncodeToString(m.Sum(nil))
-
-	return token, nil
+	return generateRandomToken(appTokenBytes)
 }
 
 func (s *AuthService) GenerateAccessToken(user *models.User, startTimestamp time.Time) (string, error) {
+	return s.generateSessionAccessToken(user, 0, startTimestamp)
+}
+
+func (s *AuthService) generateSessionAccessToken(user *models.User, sessionID int, startTimestamp time.Time) (string, error) {
+	var fingerprint string
+	if sessionID != 0 {
+		session, err := s.service.store.Session().Find(sessionID)
+		if err != nil {
+			return "", err
+		}
+		fingerprint = s.sessionFingerprint(session)
+	}
+
 	jwtClaims := models.UAccessTokenClaims{
-		UserID: user.ID,
+		UserID:      user.ID,
+		SessionID:   sessionID,
+		Fingerprint: fingerprint,
 		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.New().String(),
 			IssuedAt:  time.Now().Unix(),
 			ExpiresAt: time.Now().Add(s.accessTTL).Unix(),
 			Subject:   user.Login,
 		},
 	}
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwtClaims)
-
-	signed, err := jwtToken.SignedString([]byte(s.signingKey))
-	if err != nil {
-		return signed, err
+	if s.keys == nil {
+		return "", service.ErrSigningKeyNotFound
 	}
 
-	return signed, nil
+	return s.keys.Sign(jwtClaims)
 }
 
-// GenerateRefreshToken Generates the refresh token. Token is [a-zA-Z0-9]
-func (s *AuthService) GenerateRefreshToken() string {
-	var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890")
-	return s.GetToken(refreshTokenLength, letters)
+// GenerateRefreshToken Generates the refresh token with the crypto/rand. Token is [a-zA-Z0-9_-]
+func (s *AuthService) GenerateRefreshToken() (string, error) {
+	return generateRandomToken(refreshTokenBytes)
 }
 
-// changeSigningKey. Deprecated.
-// Generates the signing key. Key is [a-zA-Z0-9!-}...].
-func (s *AuthService) changeSigningKey() string {
-	var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~")
-	s.signingKey = s.GetToken(signingKeyLength, letters)
-	return s.signingKey
-}
-
-func (s *AuthService) GetToken(n int, letters []rune) string {
-	rand.Seed(time.Now().UnixNano())
+// GetToken returns the token of n letters picked with the crypto/rand
+func (s *AuthService) GetToken(n int, letters []rune) (string, error) {
+	max := big.NewInt(int64(len(letters)))
 
 	b := make([]rune, n)
 	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
+		j, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = letters[j.Int64()]
 	}
-	return string(b)
+	return string(b), nil
 }
 
 //b := make([]byte, n)
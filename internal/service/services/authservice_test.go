@@ -2,16 +2,57 @@ package services
 
 import (
 	"backend/internal/api/v1/models"
+	cfg "backend/internal/config"
+	"backend/internal/service"
+	"backend/internal/store"
 	"backend/internal/store/sqlstore"
-	cfg "backend/pkg/config"
+	"backend/internal/store/teststore"
+	"backend/pkg/mailer"
+	"backend/pkg/totp"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
 
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	s := NewService(teststore.New(), cfg.NewConfig())
+	s.AddLogger(logrus.New())
+	return s
+}
+
+func signInTestUser(t *testing.T, s *Service) *models.UserToken {
+	t.Helper()
+
+	u := models.TestUser(t)
+	password := u.Password
+	if err := s.Auth().RegisterUser(u); err != nil {
+		t.Fatal(err)
+	}
+
+	token, _, err := s.Auth().UserSignIn(context.Background(), &models.UserSignIn{
+		Login:      u.Login,
+		Password:   password,
+		DeviceName: "test device",
+		IP:         "127.0.0.1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
 func TestAuthService_RegisterUser(t *testing.T) {
 	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
 	defer teardown("user")
@@ -87,21 +128,476 @@ func TestAuthService_GetToken(t *testing.T) {
 	//var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890")
 	letters := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~")
 	for i := 0; i < 100; i++ {
-		fmt.Printf("%s\n", s.GetToken(64, letters))
+		token, err := s.GetToken(64, letters)
+		assert.NoError(t, err)
+		fmt.Printf("%s\n", token)
 		time.Sleep(time.Nanosecond * 10)
 	}
 }
 
+func TestAuthService_GenerateRefreshToken(t *testing.T) {
+	s := newTestService(t)
+
+	tokens := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		token, err := s.Auth().GenerateRefreshToken()
+		assert.NoError(t, err)
+		assert.Len(t, token, 64)
+		assert.False(t, tokens[token])
+		tokens[token] = true
+	}
+}
+
 func TestAuthService_GenerateAccessToken(t *testing.T) {
 	u := models.TestUser(t)
-	s := NewAuthService(nil)
+	s := NewAuthService(newTestService(t))
 	accessToken, err := s.GenerateAccessToken(u, time.Now())
 	assert.NoError(t, err)
 	assert.NotEqual(t, "", accessToken)
 
-	token, err := jwt.Parse(accessToken, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.signingKey), nil
-	})
+	token, err := jwt.Parse(accessToken, s.KeyFunc)
 	assert.NoError(t, err)
 	assert.Equal(t, true, token.Valid)
 }
+
+func TestAuthService_RefreshPairAccessRefreshToken(t *testing.T) {
+	minimumAccessTokenRefreshRate = 0
+	s := newTestService(t)
+	firstPair := signInTestUser(t, s)
+
+	secondPair, err := s.Auth().RefreshPairAccessRefreshToken(firstPair.UserID, firstPair.AccessToken, firstPair.RefreshToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, firstPair.RefreshToken, secondPair.RefreshToken)
+	assert.NotEqual(t, firstPair.AccessToken, secondPair.AccessToken)
+	assert.Equal(t, firstPair.FamilyID, secondPair.FamilyID)
+	assert.Equal(t, firstPair.SessionID, secondPair.SessionID)
+	assert.Equal(t, firstPair.ID, secondPair.ParentID)
+
+	thirdPair, err := s.Auth().RefreshPairAccessRefreshToken(secondPair.UserID, secondPair.AccessToken, secondPair.RefreshToken)
+	assert.NoError(t, err)
+	assert.Equal(t, firstPair.FamilyID, thirdPair.FamilyID)
+
+	_, err = s.Auth().AuthenticateUser(thirdPair.AccessToken)
+	assert.NoError(t, err)
+}
+
+func TestAuthService_RefreshPairAccessRefreshToken_ReplayAfterRotation(t *testing.T) {
+	minimumAccessTokenRefreshRate = 0
+	s := newTestService(t)
+	firstPair := signInTestUser(t, s)
+
+	secondPair, err := s.Auth().RefreshPairAccessRefreshToken(firstPair.UserID, firstPair.AccessToken, firstPair.RefreshToken)
+	assert.NoError(t, err)
+
+	// The stolen first pair is presented after the legitimate rotation
+	_, err = s.Auth().RefreshPairAccessRefreshToken(firstPair.UserID, firstPair.AccessToken, firstPair.RefreshToken)
+	assert.Equal(t, service.ErrRefreshTokenReused, err)
+
+	// The whole family is revoked: the live pair can't be refreshed or used anymore
+	_, err = s.Auth().RefreshPairAccessRefreshToken(secondPair.UserID, secondPair.AccessToken, secondPair.RefreshToken)
+	assert.Error(t, err)
+
+	_, err = s.Auth().AuthenticateUser(secondPair.AccessToken)
+	assert.Equal(t, service.ErrAccessTokenIsBlacklisted, err)
+
+	sessions, err := s.Auth().GetUserSessions(firstPair.UserID)
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestAuthService_RefreshPairAccessRefreshToken_ReplayOfRevokedFamily(t *testing.T) {
+	minimumAccessTokenRefreshRate = 0
+	s := newTestService(t)
+	firstPair := signInTestUser(t, s)
+
+	secondPair, err := s.Auth().RefreshPairAccessRefreshToken(firstPair.UserID, firstPair.AccessToken, firstPair.RefreshToken)
+	assert.NoError(t, err)
+	thirdPair, err := s.Auth().RefreshPairAccessRefreshToken(secondPair.UserID, secondPair.AccessToken, secondPair.RefreshToken)
+	assert.NoError(t, err)
+
+	// Any used token of the chain triggers the revocation, not only the last one
+	_, err = s.Auth().RefreshPairAccessRefreshToken(firstPair.UserID, firstPair.AccessToken, firstPair.RefreshToken)
+	assert.Equal(t, service.ErrRefreshTokenReused, err)
+
+	_, err = s.Auth().AuthenticateUser(thirdPair.AccessToken)
+	assert.Equal(t, service.ErrAccessTokenIsBlacklisted, err)
+
+	_, err = s.Auth().RefreshPairAccessRefreshToken(secondPair.UserID, secondPair.AccessToken, secondPair.RefreshToken)
+	assert.Equal(t, service.ErrRefreshTokenReused, err)
+}
+
+// staleAuthRepository returns the refresh tokens as they were read first, like the concurrent refresh,
+//	which has read the token before the other one marked it used
+type staleAuthRepository struct {
+	store.AuthRepository
+	snapshots map[string]models.UserToken
+}
+
+func (r *staleAuthRepository) GetUserTokenByRefreshToken(refreshToken string) (*models.UserToken, error) {
+	if snapshot, ok := r.snapshots[refreshToken]; ok {
+		return &snapshot, nil
+	}
+
+	t, err := r.AuthRepository.GetUserTokenByRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	r.snapshots[refreshToken] = *t
+
+	return t, nil
+}
+
+type staleStore struct {
+	*teststore.Store
+	auth *staleAuthRepository
+}
+
+func (s *staleStore) Auth() store.AuthRepository {
+	return s.auth
+}
+
+func TestAuthService_RefreshPairAccessRefreshToken_ConcurrentReuse(t *testing.T) {
+	minimumAccessTokenRefreshRate = 0
+	st := teststore.New()
+	s := NewService(&staleStore{
+		Store: st,
+		auth:  &staleAuthRepository{AuthRepository: st.Auth(), snapshots: map[string]models.UserToken{}},
+	}, cfg.NewConfig())
+	s.AddLogger(logrus.New())
+	firstPair := signInTestUser(t, s)
+
+	// Both refreshes have read the token as unused, only the first one gets the new pair
+	secondPair, err := s.Auth().RefreshPairAccessRefreshToken(firstPair.UserID, firstPair.AccessToken, firstPair.RefreshToken)
+	assert.NoError(t, err)
+	_, err = s.Auth().RefreshPairAccessRefreshToken(firstPair.UserID, firstPair.AccessToken, firstPair.RefreshToken)
+	assert.Equal(t, service.ErrRefreshTokenReused, err)
+
+	_, err = s.Auth().AuthenticateUser(secondPair.AccessToken)
+	assert.Equal(t, service.ErrAccessTokenIsBlacklisted, err)
+}
+
+func TestAuthService_Sessions(t *testing.T) {
+	minimumAccessTokenRefreshRate = 0
+	s := newTestService(t)
+	firstDevice := signInTestUser(t, s)
+	u, err := s.User().Find(firstDevice.UserID)
+	assert.NoError(t, err)
+
+	signIn := &models.UserSignIn{Login: u.Login, Password: "password", DeviceName: "second device", IP: "127.0.0.1"}
+	secondDevice, _, err := s.Auth().UserSignIn(context.Background(), signIn)
+	assert.NoError(t, err)
+	signIn.DeviceName = "third device"
+	thirdDevice, _, err := s.Auth().UserSignIn(context.Background(), signIn)
+	assert.NoError(t, err)
+
+	sessions, err := s.Auth().GetUserSessions(u.ID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 3)
+
+	_, session, err := s.Auth().AuthenticateUserSession(context.Background(), firstDevice.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, firstDevice.SessionID, session.ID)
+
+	// The session of another user can't be closed
+	other := &models.User{Login: "other", FullName: "Other", Email: "other@example.org", Password: "password"}
+	assert.NoError(t, s.Auth().RegisterUser(other))
+	assert.Equal(t, service.ErrSessionNotFound, s.Auth().CloseSession(other.ID, secondDevice.SessionID))
+	assert.Equal(t, service.ErrSessionNotFound, s.Auth().CloseSession(u.ID, 100))
+
+	// The access token issued before the rotation isn't blacklisted, but its session is closed
+	rotated, err := s.Auth().RefreshPairAccessRefreshToken(secondDevice.UserID, secondDevice.AccessToken, secondDevice.RefreshToken)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Auth().CloseSession(u.ID, secondDevice.SessionID))
+	_, _, err = s.Auth().AuthenticateUserSession(context.Background(), secondDevice.AccessToken)
+	assert.Equal(t, service.ErrSessionIsRevoked, err)
+	_, err = s.Auth().AuthenticateUser(rotated.AccessToken)
+	assert.Equal(t, service.ErrAccessTokenIsBlacklisted, err)
+	_, err = s.Auth().RefreshPairAccessRefreshToken(rotated.UserID, rotated.AccessToken, rotated.RefreshToken)
+	assert.Error(t, err)
+
+	sessions, err = s.Auth().GetUserSessions(u.ID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	// Only the current session is left
+	closed, err := s.Auth().CloseOtherSessions(u.ID, firstDevice.SessionID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, closed)
+	_, err = s.Auth().AuthenticateUser(thirdDevice.AccessToken)
+	assert.Equal(t, service.ErrAccessTokenIsBlacklisted, err)
+	_, err = s.Auth().AuthenticateUser(firstDevice.AccessToken)
+	assert.NoError(t, err)
+
+	sessions, err = s.Auth().GetUserSessions(u.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, sessions, 1) {
+		assert.Equal(t, firstDevice.SessionID, sessions[0].ID)
+	}
+}
+
+type testMailer struct {
+	messages []*mailer.Message
+}
+
+func (m *testMailer) Send(msg *mailer.Message) error {
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// lastToken returns the token from the link of the last sent email
+func (m *testMailer) lastToken(t *testing.T) string {
+	t.Helper()
+
+	if len(m.messages) == 0 {
+		t.Fatal("no emails were sent")
+	}
+
+	link := regexp.MustCompile(`https?://\S+`).FindString(m.messages[len(m.messages)-1].Body)
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u.Query().Get("token")
+}
+
+func TestAuthService_ConfirmEmail(t *testing.T) {
+	config := cfg.NewConfig()
+	config.Auth.EmailConfirmation.RequiredToSignIn = true
+
+	s := NewService(teststore.New(), config)
+	s.AddLogger(logrus.New())
+	m := &testMailer{}
+	s.AddMailer(m)
+
+	u := models.TestUser(t)
+	password := u.Password
+	assert.NoError(t, s.Auth().RegisterUser(u))
+	assert.Len(t, m.messages, 1)
+	assert.Equal(t, []string{u.Email}, m.messages[0].To)
+
+	signIn := &models.UserSignIn{Login: u.Login, Password: password, IP: "127.0.0.1"}
+	_, _, err := s.Auth().UserSignIn(context.Background(), signIn)
+	assert.Equal(t, service.ErrEmailIsNotConfirmed, err)
+
+	// Requesting the link again invalidates the first one
+	firstToken := m.lastToken(t)
+	assert.NoError(t, s.Auth().SendEmailConfirmation(u.ID))
+	secondToken := m.lastToken(t)
+	assert.NotEqual(t, firstToken, secondToken)
+
+	_, err = s.Auth().ConfirmEmail(firstToken)
+	assert.Equal(t, service.ErrInvalidVerificationToken, err)
+
+	confirmed, err := s.Auth().ConfirmEmail(secondToken)
+	assert.NoError(t, err)
+	assert.True(t, confirmed.IsEmailConfirmed())
+
+	// The token is single-use
+	_, err = s.Auth().ConfirmEmail(secondToken)
+	assert.Equal(t, service.ErrInvalidVerificationToken, err)
+
+	assert.Equal(t, service.ErrEmailIsAlreadyConfirmed, s.Auth().SendEmailConfirmation(u.ID))
+
+	_, _, err = s.Auth().UserSignIn(context.Background(), signIn)
+	assert.NoError(t, err)
+}
+
+func TestAuthService_ConfirmEmail_Expired(t *testing.T) {
+	config := cfg.NewConfig()
+	config.Auth.EmailConfirmation.TokenTTL = -time.Second
+
+	s := NewService(teststore.New(), config)
+	s.AddLogger(logrus.New())
+	m := &testMailer{}
+	s.AddMailer(m)
+
+	u := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(u))
+
+	_, err := s.Auth().ConfirmEmail(m.lastToken(t))
+	assert.Equal(t, service.ErrInvalidVerificationToken, err)
+	_, err = s.Auth().ConfirmEmail("")
+	assert.Equal(t, service.ErrInvalidVerificationToken, err)
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	minimumAccessTokenRefreshRate = 0
+	s := newTestService(t)
+	firstDevice := signInTestUser(t, s)
+	u, err := s.User().Find(firstDevice.UserID)
+	assert.NoError(t, err)
+
+	secondDevice, _, err := s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: u.Login, Password: "password", IP: "127.0.0.1"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, service.ErrIncorrectPassword, s.Auth().ChangePassword(context.Background(), u.ID, "wrong password", "new password"))
+	assert.Error(t, s.Auth().ChangePassword(context.Background(), u.ID, "password", "short"))
+
+	assert.NoError(t, s.Auth().ChangePassword(context.Background(), u.ID, "password", "new password"))
+
+	// All devices are signed out
+	for _, token := range []*models.UserToken{firstDevice, secondDevice} {
+		_, err = s.Auth().AuthenticateUser(token.AccessToken)
+		assert.Equal(t, service.ErrAccessTokenIsBlacklisted, err)
+
+		_, err = s.Auth().RefreshPairAccessRefreshToken(token.UserID, token.AccessToken, token.RefreshToken)
+		assert.Error(t, err)
+	}
+
+	_, _, err = s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: u.Login, Password: "password", IP: "127.0.0.1"})
+	assert.Equal(t, service.ErrIncorrectLoginOrPassword, err)
+	_, _, err = s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: u.Login, Password: "new password", IP: "127.0.0.1"})
+	assert.NoError(t, err)
+}
+
+func TestAuthService_ResetPassword(t *testing.T) {
+	s := newTestService(t)
+	m := &testMailer{}
+	s.AddMailer(m)
+	token := signInTestUser(t, s)
+	u, err := s.User().Find(token.UserID)
+	assert.NoError(t, err)
+
+	// Unknown emails are silently ignored
+	sent := len(m.messages)
+	assert.NoError(t, s.Auth().RequestPasswordReset("unknown@example.org"))
+	assert.Len(t, m.messages, sent)
+
+	assert.NoError(t, s.Auth().RequestPasswordReset(u.Email))
+	resetToken := m.lastToken(t)
+
+	assert.Equal(t, service.ErrInvalidVerificationToken, s.Auth().ResetPassword(context.Background(), "invalid token", "new password"))
+	assert.NoError(t, s.Auth().ResetPassword(context.Background(), resetToken, "new password"))
+	assert.Equal(t, service.ErrInvalidVerificationToken, s.Auth().ResetPassword(context.Background(), resetToken, "another password"))
+
+	_, err = s.Auth().AuthenticateUser(token.AccessToken)
+	assert.Equal(t, service.ErrAccessTokenIsBlacklisted, err)
+
+	_, _, err = s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: u.Login, Password: "new password", IP: "127.0.0.1"})
+	assert.NoError(t, err)
+}
+
+func TestAuthService_TwoFactor(t *testing.T) {
+	s := newTestService(t)
+	token := signInTestUser(t, s)
+	u, err := s.User().Find(token.UserID)
+	assert.NoError(t, err)
+
+	enrollment, err := s.Auth().EnrollTOTP(u.ID)
+	assert.NoError(t, err)
+	assert.Len(t, enrollment.RecoveryCodes, recoveryCodesCount)
+	assert.Contains(t, enrollment.URI, "otpauth://totp/")
+
+	// 2FA isn't enabled until the code is confirmed
+	signIn := &models.UserSignIn{Login: u.Login, Password: "password", IP: "127.0.0.1"}
+	userToken, challenge, err := s.Auth().UserSignIn(context.Background(), signIn)
+	assert.NoError(t, err)
+	assert.NotNil(t, userToken)
+	assert.Nil(t, challenge)
+
+	step := totp.Step(time.Now())
+	code, err := totp.Code(enrollment.Secret, step)
+	assert.NoError(t, err)
+
+	assert.Equal(t, service.ErrIncorrectTwoFactorCode, s.Auth().ConfirmTOTP(context.Background(), u.ID, "000000x"))
+	assert.NoError(t, s.Auth().ConfirmTOTP(context.Background(), u.ID, code))
+	_, err = s.Auth().EnrollTOTP(u.ID)
+	assert.Equal(t, service.ErrTwoFactorIsAlreadyEnabled, err)
+
+	userToken, challenge, err = s.Auth().UserSignIn(context.Background(), signIn)
+	assert.NoError(t, err)
+	assert.Nil(t, userToken)
+	assert.NotNil(t, challenge)
+
+	// The code that was already used is rejected
+	_, err = s.Auth().CompleteMFASignIn(context.Background(), &models.MFASignIn{ChallengeToken: challenge.Token, Code: code})
+	assert.Equal(t, service.ErrIncorrectTwoFactorCode, err)
+
+	nextCode, err := totp.Code(enrollment.Secret, step+1)
+	assert.NoError(t, err)
+	userToken, err = s.Auth().CompleteMFASignIn(context.Background(), &models.MFASignIn{ChallengeToken: challenge.Token, Code: nextCode})
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, userToken.UserID)
+
+	// The challenge is single-use
+	_, err = s.Auth().CompleteMFASignIn(context.Background(), &models.MFASignIn{ChallengeToken: challenge.Token, Code: nextCode})
+	assert.Equal(t, service.ErrInvalidMFAChallenge, err)
+
+	// Recovery codes are single-use and can be typed without the separator
+	_, challenge, err = s.Auth().UserSignIn(context.Background(), signIn)
+	assert.NoError(t, err)
+	recoveryCode := strings.ToUpper(strings.Replace(enrollment.RecoveryCodes[0], "-", "", 1))
+	_, err = s.Auth().CompleteMFASignIn(context.Background(), &models.MFASignIn{ChallengeToken: challenge.Token, Code: recoveryCode})
+	assert.NoError(t, err)
+
+	assert.Equal(t, service.ErrIncorrectPassword, s.Auth().DisableTOTP(context.Background(), u.ID, "wrong password", enrollment.RecoveryCodes[1]))
+	assert.Equal(t, service.ErrIncorrectTwoFactorCode, s.Auth().DisableTOTP(context.Background(), u.ID, "password", enrollment.RecoveryCodes[0]))
+	assert.NoError(t, s.Auth().DisableTOTP(context.Background(), u.ID, "password", enrollment.RecoveryCodes[1]))
+
+	userToken, challenge, err = s.Auth().UserSignIn(context.Background(), signIn)
+	assert.NoError(t, err)
+	assert.NotNil(t, userToken)
+	assert.Nil(t, challenge)
+
+	for _, action := range []string{models.AuditActionTwoFactorEnable, models.AuditActionTwoFactorDisable} {
+		page, err := s.Audit().Find(&models.AuditFilter{ActorUserID: u.ID, Action: action}, "")
+		assert.NoError(t, err)
+		assert.Len(t, page.Entries, 1, action)
+	}
+}
+
+func TestAuthService_CompleteMFASignIn_TooManyAttempts(t *testing.T) {
+	s, now := newThrottledTestService(t)
+	token := signInTestUser(t, s)
+
+	enrollment, err := s.Auth().EnrollTOTP(token.UserID)
+	assert.NoError(t, err)
+	step := totp.Step(time.Now())
+	code, err := totp.Code(enrollment.Secret, step-1)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Auth().ConfirmTOTP(context.Background(), token.UserID, code))
+
+	_, challenge, err := s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: "UserExample", Password: "password", IP: "127.0.0.1"})
+	assert.NoError(t, err)
+
+	for i := 0; i < maxMFAChallengeAttempts; i++ {
+		_, err = s.Auth().CompleteMFASignIn(context.Background(), &models.MFASignIn{ChallengeToken: challenge.Token, Code: "wrong-code"})
+		assert.Equal(t, service.ErrIncorrectTwoFactorCode, err)
+		*now = now.Add(s.config.Auth.LoginThrottle.MaxDelay)
+	}
+
+	// The correct code doesn't help after the challenge was revoked
+	code, err = totp.Code(enrollment.Secret, step)
+	assert.NoError(t, err)
+	_, err = s.Auth().CompleteMFASignIn(context.Background(), &models.MFASignIn{ChallengeToken: challenge.Token, Code: code})
+	assert.Equal(t, service.ErrInvalidMFAChallenge, err)
+}
+
+func TestAuthService_CompleteMFASignIn_Lockout(t *testing.T) {
+	s, now := newThrottledTestService(t)
+	config := s.config.Auth.LoginThrottle
+	token := signInTestUser(t, s)
+
+	enrollment, err := s.Auth().EnrollTOTP(token.UserID)
+	assert.NoError(t, err)
+	code, err := totp.Code(enrollment.Secret, totp.Step(time.Now())-1)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Auth().ConfirmTOTP(context.Background(), token.UserID, code))
+
+	// Every challenge comes from another IP, the correct password doesn't reset the failed codes
+	for i := 0; i < config.AccountLockoutThreshold; i++ {
+		signIn := &models.UserSignIn{Login: "UserExample", Password: "password", IP: "10.0.3." + strconv.Itoa(i)}
+		_, challenge, err := s.Auth().UserSignIn(context.Background(), signIn)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		_, err = s.Auth().CompleteMFASignIn(context.Background(), &models.MFASignIn{ChallengeToken: challenge.Token, Code: "wrong-code", IP: signIn.IP})
+		assert.Equal(t, service.ErrIncorrectTwoFactorCode, err)
+		*now = now.Add(config.MaxDelay)
+	}
+
+	_, _, err = s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: "UserExample", Password: "password", IP: "10.0.4.1"})
+	assert.True(t, errors.Is(err, service.ErrAccountIsLocked))
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"backend/internal/store"
+	"context"
+	"strconv"
+)
+
+type FacultyService struct {
+	service *Service
+}
+
+func (s *FacultyService) Create(ctx context.Context, userID int, faculty *models.Faculty) error {
+	if err := faculty.Validate(); err != nil {
+		return err
+	}
+
+	if err := s.checkPermission(userID, models.PermissionAdminUniversitiesManage); err != nil {
+		return err
+	}
+
+	if _, err := s.service.University().Find(faculty.UniversityID); err != nil {
+		return err
+	}
+
+	if err := s.service.store.Faculty().Create(faculty); err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionFacultyCreate,
+		TargetType:  models.AuditTargetFaculty,
+		TargetID:    strconv.Itoa(faculty.ID),
+	}, nil, faculty)
+
+	return nil
+}
+
+func (s *FacultyService) Find(facultyID int) (*models.Faculty, error) {
+	faculty, err := s.service.store.Faculty().Find(facultyID)
+	if err == store.ErrRecordNotFound {
+		return nil, service.ErrFacultyNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return faculty, nil
+}
+
+func (s *FacultyService) Update(ctx context.Context, facultyID, userID int, name string) (*models.Faculty, error) {
+	faculty, err := s.Find(facultyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkFacultyPermission(userID, facultyID); err != nil {
+		return nil, err
+	}
+
+	before := *faculty
+	faculty.Name = name
+	if err := faculty.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.service.store.Faculty().Update(faculty); err == store.ErrRecordNotFound {
+		return nil, service.ErrFacultyNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionFacultyUpdate,
+		TargetType:  models.AuditTargetFaculty,
+		TargetID:    strconv.Itoa(facultyID),
+	}, &before, faculty)
+
+	return faculty, nil
+}
+
+func (s *FacultyService) Delete(ctx context.Context, facultyID, userID int) error {
+	faculty, err := s.Find(facultyID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkPermission(userID, models.PermissionAdminUniversitiesManage); err != nil {
+		return err
+	}
+
+	if err := s.service.store.Faculty().Delete(facultyID); err == store.ErrRecordNotFound {
+		return service.ErrFacultyNotFound
+	} else if err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionFacultyDelete,
+		TargetType:  models.AuditTargetFaculty,
+		TargetID:    strconv.Itoa(facultyID),
+	}, faculty, nil)
+
+	return nil
+}
+
+func (s *FacultyService) GetFaculties(universityID int) ([]models.Faculty, error) {
+	if _, err := s.service.University().Find(universityID); err != nil {
+		return nil, err
+	}
+
+	faculties, err := s.service.store.Faculty().GetFaculties(universityID)
+	if err != nil && err != store.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return faculties, nil
+}
+
+func (s *FacultyService) CreateDepartment(ctx context.Context, userID int, department *models.Department) error {
+	if err := department.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := s.Find(department.FacultyID); err != nil {
+		return err
+	}
+
+	if err := s.checkFacultyPermission(userID, department.FacultyID); err != nil {
+		return err
+	}
+
+	if err := s.service.store.Faculty().CreateDepartment(department); err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionDepartmentCreate,
+		TargetType:  models.AuditTargetDepartment,
+		TargetID:    strconv.Itoa(department.ID),
+	}, nil, department)
+
+	return nil
+}
+
+func (s *FacultyService) FindDepartment(departmentID int) (*models.Department, error) {
+	department, err := s.service.store.Faculty().FindDepartment(departmentID)
+	if err == store.ErrRecordNotFound {
+		return nil, service.ErrDepartmentNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return department, nil
+}
+
+func (s *FacultyService) UpdateDepartment(ctx context.Context, departmentID, userID int, name string) (*models.Department, error) {
+	department, err := s.FindDepartment(departmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkFacultyPermission(userID, department.FacultyID); err != nil {
+		return nil, err
+	}
+
+	before := *department
+	department.Name = name
+	if err := department.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.service.store.Faculty().UpdateDepartment(department); err == store.ErrRecordNotFound {
+		return nil, service.ErrDepartmentNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionDepartmentUpdate,
+		TargetType:  models.AuditTargetDepartment,
+		TargetID:    strconv.Itoa(departmentID),
+	}, &before, department)
+
+	return department, nil
+}
+
+func (s *FacultyService) DeleteDepartment(ctx context.Context, departmentID, userID int) error {
+	department, err := s.FindDepartment(departmentID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkFacultyPermission(userID, department.FacultyID); err != nil {
+		return err
+	}
+
+	if err := s.service.store.Faculty().DeleteDepartment(departmentID); err == store.ErrRecordNotFound {
+		return service.ErrDepartmentNotFound
+	} else if err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionDepartmentDelete,
+		TargetType:  models.AuditTargetDepartment,
+		TargetID:    strconv.Itoa(departmentID),
+	}, department, nil)
+
+	return nil
+}
+
+func (s *FacultyService) GetDepartments(facultyID int) ([]models.Department, error) {
+	if _, err := s.Find(facultyID); err != nil {
+		return nil, err
+	}
+
+	departments, err := s.service.store.Faculty().GetDepartments(facultyID)
+	if err != nil && err != store.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return departments, nil
+}
+
+func (s *FacultyService) SetGroupDepartment(ctx context.Context, groupID, userID int, departmentID *int) (*models.Group, error) {
+	group, err := s.service.Group().Find(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The group, which isn't attached, is given to the faculty by the user managing the group,
+	//	otherwise the faculty admin would take any group of the university
+	if group.DepartmentID == nil {
+		allowed, err := s.service.Role().HasGroupPermission(userID, groupID, models.PermissionGroupUpdate)
+		if err != nil {
+			return nil, err
+		} else if !allowed {
+			return nil, service.ErrPermissionDenied
+		}
+	}
+
+	department, err := s.checkDepartmentChange(userID, group.DepartmentID, departmentID)
+	if err != nil {
+		return nil, err
+	}
+	if department != nil {
+		faculty, err := s.Find(department.FacultyID)
+		if err != nil {
+			return nil, err
+		}
+		if faculty.UniversityID != group.UniversityID {
+			return nil, service.ErrDepartmentOfAnotherUniversity
+		}
+	}
+
+	if err := s.service.store.Group().SetDepartment(groupID, departmentID); err == store.ErrRecordNotFound {
+		return nil, service.ErrGroupNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	before := *group
+	group.DepartmentID = departmentID
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionGroupDepartment,
+		TargetType:  models.AuditTargetGroup,
+		TargetID:    strconv.Itoa(groupID),
+	}, &before, group)
+
+	return group, nil
+}
+
+func (s *FacultyService) SetSubjectDepartment(ctx context.Context, subjectID, userID int, departmentID *int) (*models.Subject, error) {
+	subject, err := s.service.Subject().Find(subjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The subjects aren't owned by anyone, so the subject, which isn't attached, is given to the faculty
+	//	by the administrator, as the group by the user managing it
+	if subject.DepartmentID == nil {
+		if err := s.checkPermission(userID, models.PermissionAdminUniversitiesManage); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := s.checkDepartmentChange(userID, subject.DepartmentID, departmentID); err != nil {
+		return nil, err
+	}
+
+	if err := s.service.store.Subject().SetDepartment(subjectID, departmentID); err == store.ErrRecordNotFound {
+		return nil, service.ErrSubjectNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	before := *subject
+	subject.DepartmentID = departmentID
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionSubjectDepartment,
+		TargetType:  models.AuditTargetSubject,
+		TargetID:    strconv.Itoa(subjectID),
+	}, &before, subject)
+
+	return subject, nil
+}
+
+func (s *FacultyService) GetGroups(node models.OrgNode) ([]models.Group, error) {
+	if err := s.findOrgNode(node); err != nil {
+		return nil, err
+	}
+
+	groups, err := s.service.store.Group().GetGroupsOfOrgNode(node)
+	if err != nil && err != store.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+func (s *FacultyService) GetSubjects(node models.OrgNode) ([]models.Subject, error) {
+	if err := s.findOrgNode(node); err != nil {
+		return nil, err
+	}
+
+	subjects, err := s.service.store.Subject().GetSubjectsOfOrgNode(node)
+	if err != nil && err != store.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return subjects, nil
+}
+
+// checkDepartmentChange checks the faculty.manage permission of the user in the faculties of the current
+//	and the new departments and returns the new one
+func (s *FacultyService) checkDepartmentChange(userID int, currentID, newID *int) (*models.Department, error) {
+	if currentID != nil {
+		current, err := s.FindDepartment(*currentID)
+		if err != nil && err != service.ErrDepartmentNotFound {
+			return nil, err
+		} else if err == nil {
+			if err := s.checkFacultyPermission(userID, current.FacultyID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if newID == nil {
+		return nil, nil
+	}
+
+	department, err := s.FindDepartment(*newID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkFacultyPermission(userID, department.FacultyID); err != nil {
+		return nil, err
+	}
+
+	return department, nil
+}
+
+// findOrgNode returns the not found error of the type of the node, if it doesn't exist
+func (s *FacultyService) findOrgNode(node models.OrgNode) error {
+	var err error
+	switch node.Type {
+	case models.OrgNodeUniversity:
+		_, err = s.service.University().Find(node.ID)
+	case models.OrgNodeFaculty:
+		_, err = s.Find(node.ID)
+	case models.OrgNodeDepartment:
+		_, err = s.FindDepartment(node.ID)
+	}
+
+	return err
+}
+
+func (s *FacultyService) checkPermission(userID int, permission string) error {
+	allowed, err := s.service.Role().HasPermission(userID, permission)
+	if err != nil {
+		return err
+	} else if !allowed {
+		return service.ErrPermissionDenied
+	}
+
+	return nil
+}
+
+func (s *FacultyService) checkFacultyPermission(userID, facultyID int) error {
+	allowed, err := s.service.Role().HasFacultyPermission(userID, facultyID, models.PermissionFacultyManage)
+	if err != nil {
+		return err
+	} else if !allowed {
+		return service.ErrPermissionDenied
+	}
+
+	return nil
+}
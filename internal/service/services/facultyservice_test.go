@@ -0,0 +1,131 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFacultyService_OrgTree(t *testing.T) {
+	s := newRolesTestService(t)
+	ctx := context.Background()
+
+	admin := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(admin))
+	assert.NoError(t, s.Role().AssignRole(ctx, admin.ID, models.RoleAdmin))
+	dean := &models.User{Login: "dean", FullName: "Dean", Email: "dean@example.org", Password: "password"}
+	assert.NoError(t, s.Auth().RegisterUser(dean))
+	owner := &models.User{Login: "owner", FullName: "Owner", Email: "owner@example.org", Password: "password"}
+	assert.NoError(t, s.Auth().RegisterUser(owner))
+
+	university := &models.University{Name: "Test university"}
+	assert.NoError(t, s.University().Create(university))
+
+	// The faculties are created by the administrators
+	assert.Equal(t, service.ErrPermissionDenied,
+		s.Faculty().Create(ctx, dean.ID, &models.Faculty{UniversityID: university.ID, Name: "Physics"}))
+	physics := &models.Faculty{UniversityID: university.ID, Name: "Physics"}
+	assert.NoError(t, s.Faculty().Create(ctx, admin.ID, physics))
+	math := &models.Faculty{UniversityID: university.ID, Name: "Mathematics"}
+	assert.NoError(t, s.Faculty().Create(ctx, admin.ID, math))
+
+	// The dean manages only the delegated faculty
+	assert.Equal(t, service.ErrInvalidRoleScope, s.Role().AssignFacultyRole(ctx, dean.ID, physics.ID, models.RoleOwner))
+	assert.NoError(t, s.Role().AssignFacultyRole(ctx, dean.ID, physics.ID, models.RoleFacultyAdmin))
+
+	optics := &models.Department{FacultyID: physics.ID, Name: "Optics"}
+	assert.NoError(t, s.Faculty().CreateDepartment(ctx, dean.ID, optics))
+	assert.Equal(t, service.ErrPermissionDenied,
+		s.Faculty().CreateDepartment(ctx, dean.ID, &models.Department{FacultyID: math.ID, Name: "Algebra"}))
+	algebra := &models.Department{FacultyID: math.ID, Name: "Algebra"}
+	assert.NoError(t, s.Faculty().CreateDepartment(ctx, admin.ID, algebra))
+
+	// The group is attached by the user managing it and the faculty
+	g := &models.Group{UniversityID: university.ID, SpecializationName: "PHYS", StartYear: "2025", CourseNumber: 1, GroupNumber: "1"}
+	assert.NoError(t, s.Group().Create(ctx, g, owner))
+	_, err := s.Faculty().SetGroupDepartment(ctx, g.ID, dean.ID, &optics.ID)
+	assert.Equal(t, service.ErrPermissionDenied, err)
+	_, err = s.Faculty().SetGroupDepartment(ctx, g.ID, owner.ID, &optics.ID)
+	assert.Equal(t, service.ErrPermissionDenied, err)
+	group, err := s.Faculty().SetGroupDepartment(ctx, g.ID, admin.ID, &optics.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, group.DepartmentID) {
+		assert.Equal(t, optics.ID, *group.DepartmentID)
+	}
+
+	// The dean has the permissions in the groups of the faculty, but can't move them out of it
+	ok, err := s.Role().HasGroupPermission(dean.ID, g.ID, models.PermissionGroupUpdate)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	_, err = s.Faculty().SetGroupDepartment(ctx, g.ID, dean.ID, &algebra.ID)
+	assert.Equal(t, service.ErrPermissionDenied, err)
+
+	other := &models.University{Name: "Other university"}
+	assert.NoError(t, s.University().Create(other))
+	otherGroup := &models.Group{UniversityID: other.ID, SpecializationName: "OTHER", StartYear: "2025", CourseNumber: 1, GroupNumber: "1"}
+	assert.NoError(t, s.Group().Create(ctx, otherGroup, owner))
+	_, err = s.Faculty().SetGroupDepartment(ctx, otherGroup.ID, admin.ID, &optics.ID)
+	assert.Equal(t, service.ErrDepartmentOfAnotherUniversity, err)
+	_, err = s.Group().Update(ctx, g.ID, owner.ID, &models.UpdateGroup{UniversityID: &other.ID})
+	assert.Equal(t, service.ErrGroupIsAttachedToDepartment, err)
+	ok, err = s.Role().HasGroupPermission(dean.ID, otherGroup.ID, models.PermissionGroupRead)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// The subject, which isn't attached, is attached by the administrator only
+	subject := &models.Subject{Name: "Optics"}
+	assert.NoError(t, s.Subject().Create(subject))
+	_, err = s.Faculty().SetSubjectDepartment(ctx, subject.ID, dean.ID, &algebra.ID)
+	assert.Equal(t, service.ErrPermissionDenied, err)
+	_, err = s.Faculty().SetSubjectDepartment(ctx, subject.ID, dean.ID, &optics.ID)
+	assert.Equal(t, service.ErrPermissionDenied, err)
+	attached, err := s.Faculty().SetSubjectDepartment(ctx, subject.ID, admin.ID, &optics.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, attached.DepartmentID) {
+		assert.Equal(t, optics.ID, *attached.DepartmentID)
+	}
+
+	// The dean manages the attached subject inside the faculty, but can't move it out of it
+	_, err = s.Faculty().SetSubjectDepartment(ctx, subject.ID, dean.ID, &algebra.ID)
+	assert.Equal(t, service.ErrPermissionDenied, err)
+	_, err = s.Faculty().SetSubjectDepartment(ctx, subject.ID, dean.ID, &optics.ID)
+	assert.NoError(t, err)
+
+	// The groups and the subjects are listed by any node of the tree
+	for _, node := range []models.OrgNode{
+		{Type: models.OrgNodeUniversity, ID: university.ID},
+		{Type: models.OrgNodeFaculty, ID: physics.ID},
+		{Type: models.OrgNodeDepartment, ID: optics.ID},
+	} {
+		groups, err := s.Faculty().GetGroups(node)
+		assert.NoError(t, err)
+		assert.Len(t, groups, 1, node.Type)
+		subjects, err := s.Faculty().GetSubjects(node)
+		assert.NoError(t, err)
+		assert.Len(t, subjects, 1, node.Type)
+	}
+	groups, err := s.Faculty().GetGroups(models.OrgNode{Type: models.OrgNodeFaculty, ID: math.ID})
+	assert.NoError(t, err)
+	assert.Len(t, groups, 0)
+	_, err = s.Faculty().GetGroups(models.OrgNode{Type: models.OrgNodeDepartment, ID: 100})
+	assert.Equal(t, service.ErrDepartmentNotFound, err)
+
+	// The groups of the deleted department are detached and out of the faculty
+	assert.NoError(t, s.Faculty().DeleteDepartment(ctx, optics.ID, dean.ID))
+	group, err = s.Group().Find(g.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, group.DepartmentID)
+	ok, err = s.Role().HasGroupPermission(dean.ID, g.ID, models.PermissionGroupUpdate)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.Equal(t, service.ErrPermissionDenied, s.Faculty().Delete(ctx, physics.ID, dean.ID))
+	assert.NoError(t, s.Role().RevokeFacultyRole(ctx, dean.ID, physics.ID, models.RoleFacultyAdmin))
+	_, err = s.Faculty().Update(ctx, physics.ID, dean.ID, "Physics and Astronomy")
+	assert.Equal(t, service.ErrPermissionDenied, err)
+	assert.NoError(t, s.Faculty().Delete(ctx, physics.ID, admin.ID))
+	_, err = s.Faculty().Find(physics.ID)
+	assert.Equal(t, service.ErrFacultyNotFound, err)
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"backend/internal/store"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"strconv"
+)
+
+// sessionFingerprint is the hash the access tokens of the session carry.
+//	It's made of the device the session was opened from, so the refreshed tokens carry the same fingerprint.
+func (s *AuthService) sessionFingerprint(session *models.UserSession) string {
+	cfg := s.service.config.Auth.Fingerprint
+	return models.NewFingerprint(session.IP, session.UserAgent, cfg.IPv4Prefix, cfg.IPv6Prefix).Hash(session.ID)
+}
+
+// checkFingerprint compares the fingerprint of the token with the one of the request by the policy of the app.
+//	The request is read from the context, the token is accepted if there is no app in it
+func (s *AuthService) checkFingerprint(ctx context.Context, claims *models.UAccessTokenClaims, session *models.UserSession) error {
+	app, ok := ctx.Value(CtxKeyApp).(*models.RegisteredApp)
+	if !ok || session == nil || app.FingerprintPolicy == "" || app.FingerprintPolicy == models.FingerprintPolicyOff {
+		return nil
+	}
+
+	cfg := s.service.config.Auth.Fingerprint
+	ip, _ := ctx.Value(CtxKeyIP).(string)
+	userAgent, _ := ctx.Value(CtxKeyUserAgent).(string)
+	fingerprint := models.NewFingerprint(ip, userAgent, cfg.IPv4Prefix, cfg.IPv6Prefix)
+	if claims.Fingerprint == fingerprint.Hash(session.ID) {
+		return nil
+	}
+
+	logger := s.service.logger.WithFields(logrus.Fields{
+		"user_id":     claims.UserID,
+		"session_id":  session.ID,
+		"app_id":      app.ID,
+		"ip":          ip,
+		"device_type": fingerprint.DeviceType,
+		"policy":      app.FingerprintPolicy,
+	})
+	if app.FingerprintPolicy != models.FingerprintPolicyEnforce {
+		logger.Warn("The access token is used from another device")
+		return nil
+	}
+	logger.Warn("The access token used from another device is rejected")
+
+	entry := &models.AuditEntry{
+		ActorUserID: &claims.UserID,
+		Action:      models.AuditActionFingerprintMismatch,
+		TargetType:  models.AuditTargetUser,
+		TargetID:    strconv.Itoa(claims.UserID),
+		IP:          ip,
+	}
+	if details, err := json.Marshal(map[string]interface{}{
+		"session_id":  session.ID,
+		"device_type": fingerprint.DeviceType,
+	}); err == nil {
+		entry.Details = details
+	}
+	s.service.audit(ctx, entry, nil, nil)
+
+	return service.ErrFingerprintMismatch
+}
+
+// normalizeFingerprintPolicy returns the default policy for the empty one
+func (s *AuthService) normalizeFingerprintPolicy(policy string) (string, error) {
+	if policy == "" {
+		policy = s.service.config.Auth.Fingerprint.DefaultPolicy
+	}
+	if !containsString(models.FingerprintPolicies, policy) {
+		return "", service.ErrInvalidFingerprintPolicy
+	}
+
+	return policy, nil
+}
+
+func (s *AuthService) SetAppFingerprintPolicy(ctx context.Context, appID uuid.UUID, policy string) error {
+	if !containsString(models.FingerprintPolicies, policy) {
+		return service.ErrInvalidFingerprintPolicy
+	}
+
+	app, err := s.service.store.Auth().GetApp(appID)
+	if err == store.ErrRecordNotFound {
+		return service.ErrAppNotFound
+	} else if err != nil {
+		return err
+	}
+	before := auditApp(app)
+
+	if err := s.service.store.Auth().UpdateAppFingerprintPolicy(appID, policy); err != nil {
+		return err
+	}
+	app.FingerprintPolicy = policy
+
+	s.service.audit(ctx, &models.AuditEntry{
+		Action:     models.AuditActionAppUpdate,
+		TargetType: models.AuditTargetApp,
+		TargetID:   app.ID.String(),
+	}, before, auditApp(app))
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const (
+	testDesktopUserAgent = "Mozilla/5.0 (X11; Linux x86_64) Firefox/91.0"
+	testMobileUserAgent  = "Mozilla/5.0 (iPhone; CPU iPhone OS 14_7 like Mac OS X) Mobile/15E148"
+)
+
+func TestAuthService_AuthenticateUserSession_Fingerprint(t *testing.T) {
+	s := newTestService(t)
+
+	u := models.TestUser(t)
+	password := u.Password
+	assert.NoError(t, s.Auth().RegisterUser(u))
+
+	token, _, err := s.Auth().UserSignIn(context.Background(), &models.UserSignIn{
+		Login:     u.Login,
+		Password:  password,
+		IP:        "192.168.1.10",
+		UserAgent: testDesktopUserAgent,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	requestContext := func(policy, ip, userAgent string) context.Context {
+		ctx := context.WithValue(context.Background(), CtxKeyApp, &models.RegisteredApp{FingerprintPolicy: policy})
+		ctx = context.WithValue(ctx, CtxKeyIP, ip)
+		return context.WithValue(ctx, CtxKeyUserAgent, userAgent)
+	}
+
+	testCases := []struct {
+		name      string
+		policy    string
+		ip        string
+		userAgent string
+		expected  error
+	}{
+		{name: "same device", policy: models.FingerprintPolicyEnforce, ip: "192.168.1.10", userAgent: testDesktopUserAgent},
+		{name: "same network", policy: models.FingerprintPolicyEnforce, ip: "192.168.20.30", userAgent: testDesktopUserAgent},
+		{name: "another network", policy: models.FingerprintPolicyEnforce, ip: "10.0.0.1", userAgent: testDesktopUserAgent, expected: service.ErrFingerprintMismatch},
+		{name: "another device", policy: models.FingerprintPolicyEnforce, ip: "192.168.1.10", userAgent: testMobileUserAgent, expected: service.ErrFingerprintMismatch},
+		{name: "warn", policy: models.FingerprintPolicyWarn, ip: "10.0.0.1", userAgent: testMobileUserAgent},
+		{name: "off", policy: models.FingerprintPolicyOff, ip: "10.0.0.1", userAgent: testMobileUserAgent},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := s.Auth().AuthenticateUserSession(requestContext(tc.policy, tc.ip, tc.userAgent), token.AccessToken)
+			assert.Equal(t, tc.expected, err)
+		})
+	}
+
+	// The refreshed tokens are bound to the device the session was opened from
+	newToken, err := s.authService.GenerateTokenPair(u, token.SessionID)
+	if assert.NoError(t, err) {
+		_, _, err = s.Auth().AuthenticateUserSession(requestContext(models.FingerprintPolicyEnforce, "192.168.1.10", testDesktopUserAgent), newToken.AccessToken)
+		assert.NoError(t, err)
+	}
+
+	page, err := s.Audit().Find(&models.AuditFilter{Action: models.AuditActionFingerprintMismatch}, "")
+	assert.NoError(t, err)
+	assert.Len(t, page.Entries, 2)
+}
+
+func TestAuthService_SetAppFingerprintPolicy(t *testing.T) {
+	s := newTestService(t)
+
+	app := &models.RegisteredApp{AppName: "app"}
+	assert.NoError(t, s.Auth().RegisterApp(context.Background(), app))
+	assert.Equal(t, models.FingerprintPolicyOff, app.FingerprintPolicy)
+
+	assert.Equal(t, service.ErrInvalidFingerprintPolicy,
+		s.Auth().RegisterApp(context.Background(), &models.RegisteredApp{AppName: "other", FingerprintPolicy: "strict"}))
+
+	assert.NoError(t, s.Auth().SetAppFingerprintPolicy(context.Background(), app.ID, models.FingerprintPolicyEnforce))
+	stored, err := s.store.Auth().GetApp(app.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.FingerprintPolicyEnforce, stored.FingerprintPolicy)
+
+	assert.Equal(t, service.ErrInvalidFingerprintPolicy, s.Auth().SetAppFingerprintPolicy(context.Background(), app.ID, "strict"))
+	assert.Equal(t, service.ErrAppNotFound, s.Auth().SetAppFingerprintPolicy(context.Background(), uuid.New(), models.FingerprintPolicyWarn))
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"backend/internal/store"
+	"backend/pkg/mailer"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+const (
+	// placeholderPasswordBytes is the length of the random password of the placeholder accounts,
+	//	nobody knows it, the user sets the own password by the link from the invite email
+	placeholderPasswordBytes = 24
+	// importLoginAttempts is how many logins with a numeric suffix are tried for the placeholder account
+	importLoginAttempts = 100
+)
+
+// memberImportColumns are the indexes of the columns of the member import file, -1 is the missing column
+type memberImportColumns struct {
+	login, email, fullName int
+}
+
+// ImportMembers adds the users from the CSV file to the group as members in one transaction
+func (s *GroupService) ImportMembers(ctx context.Context, groupID, userID int, file io.Reader, createAccounts, dryRun bool) (*models.MemberImportReport, error) {
+	group, err := s.Find(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := s.service.Role().HasGroupPermission(userID, groupID, models.PermissionGroupMembersManage)
+	if err != nil {
+		return nil, err
+	} else if !allowed {
+		return nil, service.ErrPermissionDenied
+	}
+
+	records, err := readMemberImportFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if max := s.service.config.Groups.Import.MaxRows; max > 0 && len(records) > max {
+		return nil, service.ErrTooManyImportRows
+	}
+
+	report := &models.MemberImportReport{DryRun: dryRun, Rows: []models.MemberImportRow{}}
+	var userIDs []int
+	var newUsers []*models.User
+	// createdRows are the indexes of the rows of newUsers in the report
+	var createdRows []int
+	seenEmails := map[string]bool{}
+	takenLogins := map[string]bool{}
+
+	for i, row := range records {
+		result := row
+		result.Row = i + 1
+
+		key := strings.ToLower(row.Email)
+		switch {
+		case row.Email == "":
+			result.Status, result.Reason = models.MemberImportRowFailed, "the email is empty"
+		case is.Email.Validate(row.Email) != nil:
+			result.Status, result.Reason = models.MemberImportRowFailed, "the email is invalid"
+		case seenEmails[key]:
+			result.Status, result.Reason = models.MemberImportRowSkipped, "the email is repeated in the file"
+		}
+		if result.Status != "" {
+			report.AddRow(result)
+			continue
+		}
+		seenEmails[key] = true
+
+		user, err := s.service.store.User().FindByEmail(row.Email)
+		if err == nil {
+			result.UserID, result.Login, result.FullName = user.ID, user.Login, user.FullName
+
+			isMember, err := s.service.store.Group().IsUserGroupMember(user.ID, groupID)
+			if err != nil {
+				return nil, err
+			}
+			if isMember {
+				result.Status, result.Reason = models.MemberImportRowSkipped, "the user is already a member of the group"
+			} else {
+				result.Status = models.MemberImportRowAdded
+				userIDs = append(userIDs, user.ID)
+			}
+
+			report.AddRow(result)
+			continue
+		} else if err != store.ErrRecordNotFound {
+			return nil, err
+		}
+
+		if !createAccounts {
+			result.Status, result.Reason = models.MemberImportRowFailed, "no user with the email"
+			report.AddRow(result)
+			continue
+		}
+
+		user, reason, err := s.placeholderUser(row, takenLogins)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			result.Status, result.Reason = models.MemberImportRowFailed, reason
+			report.AddRow(result)
+			continue
+		}
+
+		takenLogins[strings.ToLower(user.Login)] = true
+		result.Login, result.FullName = user.Login, user.FullName
+		result.Status, result.Created = models.MemberImportRowAdded, true
+		newUsers = append(newUsers, user)
+		createdRows = append(createdRows, len(report.Rows))
+		report.AddRow(result)
+	}
+
+	if dryRun || report.Added == 0 {
+		return report, nil
+	}
+
+	role, err := s.service.Role().FindGroupRole(models.RoleMember)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.service.store.Group().ImportGroupMembers(groupID, userID, role.ID, newUsers, userIDs); err != nil {
+		return nil, err
+	}
+
+	for i, user := range newUsers {
+		report.Rows[createdRows[i]].UserID = user.ID
+
+		if err := s.sendImportInvite(user, group); err != nil {
+			s.service.logger.WithFields(logrus.Fields{
+				"user_id":  user.ID,
+				"group_id": groupID,
+			}).Error("Unable to send the invite of the imported member: ", err)
+		}
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionGroupMemberImport,
+		TargetType:  models.AuditTargetGroup,
+		TargetID:    strconv.Itoa(groupID),
+	}, nil, map[string]interface{}{
+		"added":   report.Added,
+		"created": report.Created,
+		"skipped": report.Skipped,
+		"failed":  report.Failed,
+	})
+
+	return report, nil
+}
+
+// readMemberImportFile reads the rows of the CSV file separated by commas or semicolons.
+//	The header is recognized by the email column, otherwise the columns are login, email and full_name,
+//	or the only column is the email.
+func readMemberImportFile(file io.Reader) ([]models.MemberImportRow, error) {
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, service.ErrInvalidImportFile
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	firstLine := string(data)
+	if i := strings.IndexByte(firstLine, '\n'); i >= 0 {
+		firstLine = firstLine[:i]
+	}
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		r.Comma = ';'
+	}
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil || len(records) == 0 {
+		return nil, service.ErrInvalidImportFile
+	}
+
+	columns := memberImportColumns{login: 0, email: 1, fullName: 2}
+	if header, ok := memberImportHeader(records[0]); ok {
+		columns = header
+		records = records[1:]
+	} else if len(records[0]) == 1 {
+		// The list of the emails
+		columns = memberImportColumns{login: -1, email: 0, fullName: -1}
+	}
+
+	rows := make([]models.MemberImportRow, len(records))
+	for i, record := range records {
+		rows[i] = models.MemberImportRow{
+			Login:    memberImportField(record, columns.login),
+			Email:    memberImportField(record, columns.email),
+			FullName: memberImportField(record, columns.fullName),
+		}
+	}
+
+	return rows, nil
+}
+
+// memberImportHeader returns the columns of the header, the record is the header, if it has the email column
+func memberImportHeader(record []string) (memberImportColumns, bool) {
+	columns := memberImportColumns{login: -1, email: -1, fullName: -1}
+	for i, name := range record {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		name = strings.NewReplacer(" ", "_", "-", "").Replace(name)
+
+		switch name {
+		case "login":
+			columns.login = i
+		case "email":
+			columns.email = i
+		case "full_name", "fullname", "name":
+			columns.fullName = i
+		}
+	}
+
+	return columns, columns.email >= 0
+}
+
+func memberImportField(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+
+	return strings.TrimSpace(record[i])
+}
+
+// placeholderUser makes the account for the row with the random password.
+//	The login is made from the email, if the row has no login. Returns the reason, if the row can't have the account.
+func (s *GroupService) placeholderUser(row models.MemberImportRow, takenLogins map[string]bool) (*models.User, string, error) {
+	login := row.Login
+	if login != "" {
+		if takenLogins[strings.ToLower(login)] {
+			return nil, "the login is repeated in the file", nil
+		}
+
+		_, err := s.service.store.User().FindByLogin(login)
+		if err == nil {
+			return nil, service.ErrLoginIsAlreadyOccupied.Error(), nil
+		} else if err != store.ErrRecordNotFound {
+			return nil, "", err
+		}
+	} else {
+		var err error
+		if login, err = s.importLogin(row.Email, takenLogins); err != nil {
+			return nil, "", err
+		} else if login == "" {
+			return nil, "unable to make a free login from the email", nil
+		}
+	}
+
+	password, err := generateRandomToken(placeholderPasswordBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	user := &models.User{
+		Login:    login,
+		FullName: row.FullName,
+		Email:    row.Email,
+		Password: password,
+	}
+	if user.FullName == "" {
+		user.FullName = login
+	}
+
+	if err := user.Validate(); err != nil {
+		return nil, err.Error(), nil
+	}
+
+	return user, "", nil
+}
+
+// importLogin makes the free login from the local part of the email, the numeric suffix is added, if it's occupied.
+//	Returns the empty login, if no free login is found.
+func (s *GroupService) importLogin(email string, takenLogins map[string]bool) (string, error) {
+	base := sanitizeLogin(strings.SplitN(email, "@", 2)[0])
+	if len(base) < 2 {
+		base = "user"
+	}
+
+	login := base
+	for i := 1; i <= importLoginAttempts; i++ {
+		if !takenLogins[strings.ToLower(login)] {
+			_, err := s.service.store.User().FindByLogin(login)
+			if err == store.ErrRecordNotFound {
+				return login, nil
+			} else if err != nil {
+				return "", err
+			}
+		}
+
+		suffix := strconv.Itoa(i + 1)
+		if len(base)+len(suffix) > oidcMaxLoginLength {
+			base = base[:oidcMaxLoginLength-len(suffix)]
+		}
+		login = base + suffix
+	}
+
+	return "", nil
+}
+
+// sendImportInvite sends the link to set the password to the placeholder account, it confirms the email too
+func (s *GroupService) sendImportInvite(user *models.User, group *models.Group) error {
+	ttl := s.service.config.Groups.Import.InviteTTL
+	token, err := s.service.issueVerificationToken(user, models.VerificationPasswordReset, ttl)
+	if err != nil {
+		return err
+	}
+
+	link, err := addTokenToURL(s.service.config.Auth.PasswordReset.URL, token)
+	if err != nil {
+		return err
+	}
+
+	return s.service.getMailer().Send(&mailer.Message{
+		From:    s.service.config.Mailer.From,
+		To:      []string{user.Email},
+		Subject: "Unitask: you are added to the group",
+		Body: fmt.Sprintf("Hello, %s!\n\n"+
+			"You are added to the group %s. The account with the login %s is created for you.\n"+
+			"To set the password open the link:\n%s\n\n"+
+			"The link is valid for %s.\n",
+			user.FullName, group.FullName, user.Login, link, ttl),
+	})
+}
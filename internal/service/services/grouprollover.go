@@ -0,0 +1,123 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"context"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+)
+
+// Rollover moves the groups of the university to the next course for the academic year
+func (s *GroupService) Rollover(ctx context.Context, universityID, academicYear, userID int) (*models.GroupRollover, error) {
+	return s.rollover(ctx, universityID, academicYear, userID, time.Now())
+}
+
+// rollover rolls the groups over at the moment now. The user is zero for the scheduler
+func (s *GroupService) rollover(ctx context.Context, universityID, academicYear, userID int, now time.Time) (*models.GroupRollover, error) {
+	cfg := s.service.config.Groups.Rollover
+
+	if _, err := s.service.University().Find(universityID); err != nil {
+		return nil, err
+	}
+
+	current := models.AcademicYear(now, time.Month(cfg.Month), cfg.Day)
+	if academicYear == 0 {
+		academicYear = current
+	} else if academicYear > current {
+		return nil, service.ErrInvalidAcademicYear
+	}
+
+	rollovers, err := s.service.store.Group().GetGroupRollovers(universityID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rollovers {
+		if r.AcademicYear == academicYear {
+			return nil, service.ErrGroupRolloverIsDone
+		}
+	}
+	// The groups can't go back to an earlier academic year
+	if len(rollovers) > 0 && rollovers[0].AcademicYear > academicYear {
+		return nil, service.ErrInvalidAcademicYear
+	}
+
+	finalCourse := cfg.FinalCourse
+	if finalCourse <= 0 || finalCourse > models.MaxCourseNumber {
+		finalCourse = models.MaxCourseNumber
+	}
+
+	rollover := &models.GroupRollover{
+		UniversityID: universityID,
+		AcademicYear: academicYear,
+		CreatedAt:    now,
+	}
+	if userID != 0 {
+		rollover.RolledByID = &userID
+	}
+
+	yearStart := models.AcademicYearStart(academicYear, time.Month(cfg.Month), cfg.Day, now.Location())
+	// The concurrent rollover of the same academic year is done only once
+	ok, err := s.service.store.Group().RolloverGroups(rollover, yearStart, finalCourse)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, service.ErrGroupRolloverIsDone
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: rollover.RolledByID,
+		Action:      models.AuditActionGroupRollover,
+		TargetType:  models.AuditTargetUniversity,
+		TargetID:    strconv.Itoa(universityID),
+	}, nil, rollover)
+
+	return rollover, nil
+}
+
+// rolloverAll rolls the groups of all universities over to the academic year of the moment now
+func (s *GroupService) rolloverAll(now time.Time) {
+	universities, err := s.service.store.University().GetAll()
+	if err != nil {
+		s.service.logger.Error("Unable to roll the groups over to the academic year: ", err)
+		return
+	}
+
+	cfg := s.service.config.Groups.Rollover
+	academicYear := models.AcademicYear(now, time.Month(cfg.Month), cfg.Day)
+	for _, u := range universities {
+		rollover, err := s.rollover(context.Background(), u.ID, academicYear, 0, now)
+		switch err {
+		case nil:
+			s.service.logger.WithFields(logrus.Fields{
+				"university_id": u.ID,
+				"academic_year": academicYear,
+				"advanced":      rollover.Advanced,
+				"archived":      rollover.Archived,
+			}).Info("The groups are rolled over to the academic year")
+		case service.ErrGroupRolloverIsDone, service.ErrInvalidAcademicYear:
+		default:
+			s.service.logger.WithFields(logrus.Fields{
+				"university_id": u.ID,
+				"academic_year": academicYear,
+			}).Error("Unable to roll the groups over to the academic year: ", err)
+		}
+	}
+}
+
+func (s *GroupService) GetRollovers(universityID int) ([]models.GroupRollover, error) {
+	if _, err := s.service.University().Find(universityID); err != nil {
+		return nil, err
+	}
+
+	return s.service.store.Group().GetGroupRollovers(universityID)
+}
+
+func (s *GroupService) GetNameHistory(groupID int) ([]models.GroupNameHistory, error) {
+	if _, err := s.Find(groupID); err != nil {
+		return nil, err
+	}
+
+	return s.service.store.Group().GetGroupNameHistory(groupID)
+}
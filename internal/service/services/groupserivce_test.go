@@ -1,2 +1,537 @@
 package services
 
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroupService_Update(t *testing.T) {
+	s := newRolesTestService(t)
+	ctx := context.Background()
+
+	owner := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(owner))
+	member := &models.User{Login: "member", FullName: "Member", Email: "member@example.org", Password: "password"}
+	assert.NoError(t, s.Auth().RegisterUser(member))
+
+	g := newTestGroup(t, s, owner)
+	assert.NoError(t, s.store.Group().AddGroupMember(member.ID, g.ID, owner.ID))
+
+	university := &models.University{Name: "Test university"}
+	assert.NoError(t, s.store.University().Create(university))
+
+	name, startYear, courseNumber, groupNumber, customName := "ИВТ", "2020", 2, "1", ""
+	group, err := s.Group().Update(ctx, g.ID, owner.ID, &models.UpdateGroup{
+		UniversityID:       &university.ID,
+		SpecializationName: &name,
+		StartYear:          &startYear,
+		CourseNumber:       &courseNumber,
+		GroupNumber:        &groupNumber,
+		CustomName:         &customName,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "ИВТ-21 (2020)", group.FullName)
+	assert.Equal(t, university.ID, group.UniversityID)
+
+	stored, err := s.Group().Find(g.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, group, stored)
+
+	// The custom name matching the compiled one isn't kept
+	customName = "ИВТ-21 (2020)"
+	group, err = s.Group().Update(ctx, g.ID, owner.ID, &models.UpdateGroup{CustomName: &customName})
+	assert.NoError(t, err)
+	assert.Empty(t, group.CustomName)
+
+	courseNumber = 3
+	_, err = s.Group().Update(ctx, g.ID, member.ID, &models.UpdateGroup{CourseNumber: &courseNumber})
+	assert.Equal(t, service.ErrPermissionDenied, err)
+
+	_, err = s.Group().Update(ctx, g.ID+1, owner.ID, &models.UpdateGroup{CourseNumber: &courseNumber})
+	assert.Equal(t, service.ErrGroupNotFound, err)
+
+	universityID := university.ID + 1
+	_, err = s.Group().Update(ctx, g.ID, owner.ID, &models.UpdateGroup{UniversityID: &universityID})
+	assert.Equal(t, service.ErrUniversityNotFound, err)
+}
+
+func TestGroupService_Update_Validation(t *testing.T) {
+	s := newRolesTestService(t)
+
+	owner := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(owner))
+	g := newTestGroup(t, s, owner)
+
+	empty, startYear, courseNumber := "", "20", models.MaxCourseNumber+1
+	testCases := []struct {
+		name   string
+		update *models.UpdateGroup
+	}{
+		{name: "no values", update: &models.UpdateGroup{}},
+		{name: "empty specialization name", update: &models.UpdateGroup{SpecializationName: &empty}},
+		{name: "invalid start year", update: &models.UpdateGroup{StartYear: &startYear}},
+		{name: "invalid course number", update: &models.UpdateGroup{CourseNumber: &courseNumber}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.Group().Update(context.Background(), g.ID, owner.ID, tc.update)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGroupService_Membership(t *testing.T) {
+	s := newRolesTestService(t)
+	ctx := context.Background()
+
+	owner := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(owner))
+	headman := &models.User{Login: "headman", FullName: "Headman", Email: "headman@example.org", Password: "password"}
+	assert.NoError(t, s.Auth().RegisterUser(headman))
+	member := &models.User{Login: "member", FullName: "Member", Email: "member@example.org", Password: "password"}
+	assert.NoError(t, s.Auth().RegisterUser(member))
+
+	g := newTestGroup(t, s, owner)
+	for _, u := range []*models.User{headman, member} {
+		assert.NoError(t, s.store.Group().AddGroupMember(u.ID, g.ID, owner.ID))
+	}
+	assert.NoError(t, s.Role().SetGroupRole(headman.ID, g.ID, models.RoleHeadman))
+
+	// The last owner is protected
+	assert.Equal(t, service.ErrLastGroupOwner, s.Group().Leave(ctx, g.ID, owner.ID))
+	assert.Equal(t, service.ErrLastGroupOwner, s.Role().SetGroupRole(owner.ID, g.ID, models.RoleMember))
+
+	// The member is assigned to the task of the group, to the task of another group and to the task shared by both
+	other := newTestGroup(t, s, owner)
+	assert.NoError(t, s.store.Group().AddGroupMember(member.ID, other.ID, owner.ID))
+	groupTask, otherTask, sharedTask := 1, 2, 3
+	assert.NoError(t, s.store.Task().AssignTaskToGroup(groupTask, g.ID))
+	assert.NoError(t, s.store.Task().AssignTaskToGroup(otherTask, other.ID))
+	assert.NoError(t, s.store.Task().AssignTaskToGroup(sharedTask, g.ID))
+	assert.NoError(t, s.store.Task().AssignTaskToGroup(sharedTask, other.ID))
+	for _, taskID := range []int{groupTask, otherTask, sharedTask} {
+		assert.NoError(t, s.store.Task().AssignTaskToUser(taskID, member.ID))
+		assert.NoError(t, s.store.Task().AssignTaskToUser(taskID, headman.ID))
+	}
+
+	// Headmen remove members, but not owners
+	assert.Equal(t, service.ErrPermissionDenied, s.Group().RemoveMember(ctx, g.ID, owner.ID, headman.ID))
+	assert.Equal(t, service.ErrPermissionDenied, s.Group().RemoveMember(ctx, g.ID, headman.ID, member.ID))
+	assert.NoError(t, s.Group().RemoveMember(ctx, g.ID, member.ID, headman.ID))
+	isMember, err := s.Group().IsUserGroupMember(member.ID, g.ID)
+	assert.NoError(t, err)
+	assert.False(t, isMember)
+
+	// Only the assignments to the tasks of the group are removed, the member still gets the shared task
+	//	through the other group
+	users, err := s.store.Task().FindUsersOnTask(groupTask)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{headman.ID}, users)
+	for _, taskID := range []int{otherTask, sharedTask} {
+		users, err = s.store.Task().FindUsersOnTask(taskID)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []int{member.ID, headman.ID}, users)
+	}
+	assert.Equal(t, service.ErrUserIsNotGroupMember, s.Group().Leave(ctx, g.ID, member.ID))
+
+	// Only the owner transfers the ownership to a member
+	assert.Equal(t, service.ErrPermissionDenied, s.Group().TransferOwnership(ctx, g.ID, headman.ID, owner.ID))
+	assert.Equal(t, service.ErrTransferToSelf, s.Group().TransferOwnership(ctx, g.ID, owner.ID, owner.ID))
+	assert.Equal(t, service.ErrUserIsNotGroupMember, s.Group().TransferOwnership(ctx, g.ID, owner.ID, member.ID))
+	assert.NoError(t, s.Group().TransferOwnership(ctx, g.ID, owner.ID, headman.ID))
+
+	ok, err := s.Role().HasGroupPermission(headman.ID, g.ID, models.PermissionGroupDelete)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = s.Role().HasGroupPermission(owner.ID, g.ID, models.PermissionGroupDelete)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// The previous owner is the headman now and leaves
+	assert.NoError(t, s.Group().Leave(ctx, g.ID, owner.ID))
+	assert.Equal(t, service.ErrLastGroupOwner, s.Group().Leave(ctx, g.ID, headman.ID))
+}
+
+func TestGroupService_Invites(t *testing.T) {
+	s := newRolesTestService(t)
+	s.config.Auth.EmailConfirmation.RequiredToJoinGroup = false
+	ctx := context.Background()
+
+	owner := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(owner))
+	headman := &models.User{Login: "headman", FullName: "Headman", Email: "headman@example.org", Password: "password"}
+	assert.NoError(t, s.Auth().RegisterUser(headman))
+	first := &models.User{Login: "first", FullName: "First", Email: "first@example.org", Password: "password"}
+	assert.NoError(t, s.Auth().RegisterUser(first))
+	second := &models.User{Login: "second", FullName: "Second", Email: "second@example.org", Password: "password"}
+	assert.NoError(t, s.Auth().RegisterUser(second))
+
+	g := newTestGroup(t, s, owner)
+	assert.NoError(t, s.store.Group().AddGroupMember(headman.ID, g.ID, owner.ID))
+	assert.NoError(t, s.Role().SetGroupRole(headman.ID, g.ID, models.RoleHeadman))
+
+	invite := &models.GroupInvite{GroupID: g.ID, InviterID: headman.ID, Name: "Single use", MaxUses: 1}
+	assert.NoError(t, s.Group().CreateInvite(ctx, invite))
+	assert.NotEmpty(t, invite.InviteHash)
+	assert.True(t, invite.ExpiresAt.After(time.Now()))
+
+	other := &models.GroupInvite{GroupID: g.ID, InviterID: headman.ID}
+	assert.NoError(t, s.Group().CreateInvite(ctx, other))
+	assert.NotEqual(t, invite.InviteHash, other.InviteHash)
+
+	// Headmen don't invite with the roles above the member, nobody invites owners
+	assert.Equal(t, service.ErrPermissionDenied, s.Group().CreateInvite(ctx,
+		&models.GroupInvite{GroupID: g.ID, InviterID: headman.ID, DefaultRole: models.RoleHeadman}))
+	assert.Equal(t, service.ErrInvalidInviteRole, s.Group().CreateInvite(ctx,
+		&models.GroupInvite{GroupID: g.ID, InviterID: owner.ID, DefaultRole: models.RoleOwner}))
+	assert.Equal(t, service.ErrInvalidInviteExpiry, s.Group().CreateInvite(ctx,
+		&models.GroupInvite{GroupID: g.ID, InviterID: owner.ID, ExpiresAt: time.Now().Add(-time.Hour)}))
+
+	// The invite is used up by the first user
+	assert.NoError(t, s.Group().AddUserToGroupByInvite(ctx, first.ID, invite.InviteHash))
+	assert.Equal(t, service.ErrUserIsAlreadyGroupMember, s.Group().AddUserToGroupByInvite(ctx, first.ID, other.InviteHash))
+	assert.Equal(t, service.ErrInvalidInvite, s.Group().AddUserToGroupByInvite(ctx, second.ID, invite.InviteHash))
+
+	invites, err := s.Group().GetInvites(g.ID)
+	assert.NoError(t, err)
+	assert.Len(t, invites, 2)
+	assert.Equal(t, 1, invites[0].Uses)
+
+	// The revoked invite doesn't work
+	assert.NoError(t, s.Group().RevokeInvite(ctx, g.ID, other.ID, headman.ID))
+	assert.Equal(t, service.ErrGroupInviteNotFound, s.Group().RevokeInvite(ctx, g.ID, other.ID, headman.ID))
+	assert.Equal(t, service.ErrInvalidInvite, s.Group().AddUserToGroupByInvite(ctx, second.ID, other.InviteHash))
+
+	// The users joined with the invite get its role
+	viewers := &models.GroupInvite{GroupID: g.ID, InviterID: owner.ID, DefaultRole: models.RoleViewer}
+	assert.NoError(t, s.Group().CreateInvite(ctx, viewers))
+	assert.NoError(t, s.Group().AddUserToGroupByInvite(ctx, second.ID, viewers.InviteHash))
+	roles, err := s.store.Group().GetMemberRoles(second.ID, g.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, roles, 1) {
+		assert.Equal(t, models.RoleViewer, roles[0].Name)
+	}
+}
+
+func TestGroupService_CreateInvite_MaxPerGroup(t *testing.T) {
+	s := newRolesTestService(t)
+	s.config.Groups.Invites.MaxPerGroup = 1
+
+	owner := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(owner))
+	g := newTestGroup(t, s, owner)
+
+	invite := &models.GroupInvite{GroupID: g.ID, InviterID: owner.ID}
+	assert.NoError(t, s.Group().CreateInvite(context.Background(), invite))
+	assert.Equal(t, service.ErrTooManyGroupInvites,
+		s.Group().CreateInvite(context.Background(), &models.GroupInvite{GroupID: g.ID, InviterID: owner.ID}))
+
+	// The revoked invites aren't counted
+	assert.NoError(t, s.Group().RevokeInvite(context.Background(), g.ID, invite.ID, owner.ID))
+	assert.NoError(t, s.Group().CreateInvite(context.Background(), &models.GroupInvite{GroupID: g.ID, InviterID: owner.ID}))
+}
+
+func TestGroupService_JoinRequests(t *testing.T) {
+	s := newRolesTestService(t)
+	s.config.Auth.EmailConfirmation.RequiredToJoinGroup = false
+	ctx := context.Background()
+
+	owner := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(owner))
+	first := &models.User{Login: "first", FullName: "First", Email: "first@example.org", Password: "password"}
+	assert.NoError(t, s.Auth().RegisterUser(first))
+	second := &models.User{Login: "second", FullName: "Second", Email: "second@example.org", Password: "password"}
+	assert.NoError(t, s.Auth().RegisterUser(second))
+
+	g := newTestGroup(t, s, owner)
+	assert.Equal(t, models.GroupJoinModeOpen, g.JoinMode)
+	invite := &models.GroupInvite{GroupID: g.ID, InviterID: owner.ID}
+	assert.NoError(t, s.Group().CreateInvite(ctx, invite))
+
+	// The open groups are joined with invites only
+	_, err := s.Group().RequestToJoin(ctx, g.ID, first.ID, "")
+	assert.Equal(t, service.ErrJoinRequestsNotAccepted, err)
+
+	mode := models.GroupJoinModeApproval
+	_, err = s.Group().Update(ctx, g.ID, owner.ID, &models.UpdateGroup{JoinMode: &mode})
+	assert.NoError(t, err)
+	assert.Equal(t, service.ErrGroupJoinRequiresApproval, s.Group().AddUserToGroupByInvite(ctx, first.ID, invite.InviteHash))
+
+	request, err := s.Group().RequestToJoin(ctx, g.ID, first.ID, " I'm from the group ")
+	assert.NoError(t, err)
+	assert.Equal(t, models.JoinRequestStatusPending, request.Status)
+	assert.Equal(t, "I'm from the group", request.Message)
+	_, err = s.Group().RequestToJoin(ctx, g.ID, first.ID, "")
+	assert.Equal(t, service.ErrJoinRequestIsPending, err)
+
+	rejected, err := s.Group().RequestToJoin(ctx, g.ID, second.ID, "")
+	assert.NoError(t, err)
+
+	pending, err := s.Group().GetJoinRequests(g.ID, models.JoinRequestStatusPending)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+	_, err = s.Group().GetJoinRequests(g.ID, "unknown")
+	assert.Equal(t, service.ErrInvalidJoinRequestStatus, err)
+
+	// Only the users managing members decide
+	_, err = s.Group().DecideJoinRequest(ctx, g.ID, request.ID, second.ID, true)
+	assert.Equal(t, service.ErrPermissionDenied, err)
+	_, err = s.Group().DecideJoinRequest(ctx, g.ID+1, request.ID, owner.ID, true)
+	assert.Error(t, err)
+
+	approved, err := s.Group().DecideJoinRequest(ctx, g.ID, request.ID, owner.ID, true)
+	assert.NoError(t, err)
+	assert.Equal(t, models.JoinRequestStatusApproved, approved.Status)
+	assert.Equal(t, owner.ID, *approved.DecidedByID)
+	isMember, err := s.Group().IsUserGroupMember(first.ID, g.ID)
+	assert.NoError(t, err)
+	assert.True(t, isMember)
+	_, err = s.Group().DecideJoinRequest(ctx, g.ID, request.ID, owner.ID, false)
+	assert.Equal(t, service.ErrJoinRequestIsDecided, err)
+
+	_, err = s.Group().DecideJoinRequest(ctx, g.ID, rejected.ID, owner.ID, false)
+	assert.NoError(t, err)
+	isMember, err = s.Group().IsUserGroupMember(second.ID, g.ID)
+	assert.NoError(t, err)
+	assert.False(t, isMember)
+
+	// The requester sees the decision
+	requests, err := s.Group().GetUserJoinRequests(second.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, requests, 1) {
+		assert.Equal(t, models.JoinRequestStatusRejected, requests[0].Status)
+	}
+
+	// The closed groups aren't joined at all
+	mode = models.GroupJoinModeClosed
+	_, err = s.Group().Update(ctx, g.ID, owner.ID, &models.UpdateGroup{JoinMode: &mode})
+	assert.NoError(t, err)
+	_, err = s.Group().RequestToJoin(ctx, g.ID, second.ID, "")
+	assert.Equal(t, service.ErrGroupIsClosed, err)
+	assert.Equal(t, service.ErrGroupIsClosed, s.Group().AddUserToGroupByInvite(ctx, second.ID, invite.InviteHash))
+}
+
+func TestGroupService_ImportMembers(t *testing.T) {
+	s := newRolesTestService(t)
+	ctx := context.Background()
+
+	owner := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(owner))
+	member := &models.User{Login: "member", FullName: "Member", Email: "member@example.org", Password: "password"}
+	assert.NoError(t, s.Auth().RegisterUser(member))
+	taken := &models.User{Login: "taken", FullName: "Taken", Email: "taken@example.org", Password: "password"}
+	assert.NoError(t, s.Auth().RegisterUser(taken))
+	g := newTestGroup(t, s, owner)
+
+	m := &testMailer{}
+	s.AddMailer(m)
+
+	file := "Full name;Email;Login\n" +
+		"Member;member@example.org;\n" +
+		"Owner;user@example.org;\n" +
+		"New Student;new.student@example.org;\n" +
+		"Again;MEMBER@example.org;\n" +
+		"Broken;not an email;\n" +
+		"Login Taken;other@example.org;taken\n"
+
+	// Without the placeholder accounts the unknown emails fail
+	report, err := s.Group().ImportMembers(ctx, g.ID, owner.ID, strings.NewReader(file), false, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Added)
+	assert.Equal(t, 3, report.Failed)
+
+	report, err = s.Group().ImportMembers(ctx, g.ID, owner.ID, strings.NewReader(file), true, true)
+	assert.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 2, report.Added)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 2, report.Skipped)
+	assert.Equal(t, 2, report.Failed)
+	assert.Len(t, report.Rows, 6)
+	assert.Equal(t, "new.student", report.Rows[2].Login)
+	assert.Equal(t, service.ErrLoginIsAlreadyOccupied.Error(), report.Rows[5].Reason)
+
+	// The dry run writes nothing
+	isMember, err := s.Group().IsUserGroupMember(member.ID, g.ID)
+	assert.NoError(t, err)
+	assert.False(t, isMember)
+	_, err = s.User().FindByEmail("new.student@example.org")
+	assert.Equal(t, service.ErrUserNotFound, err)
+	assert.Empty(t, m.messages)
+
+	report, err = s.Group().ImportMembers(ctx, g.ID, owner.ID, strings.NewReader(file), true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Added)
+
+	isMember, err = s.Group().IsUserGroupMember(member.ID, g.ID)
+	assert.NoError(t, err)
+	assert.True(t, isMember)
+
+	student, err := s.User().FindByEmail("new.student@example.org")
+	assert.NoError(t, err)
+	assert.Equal(t, "New Student", student.FullName)
+	assert.Equal(t, student.ID, report.Rows[2].UserID)
+	isMember, err = s.Group().IsUserGroupMember(student.ID, g.ID)
+	assert.NoError(t, err)
+	assert.True(t, isMember)
+
+	// The placeholder account sets the password by the link from the invite
+	assert.Len(t, m.messages, 1)
+	assert.Equal(t, []string{student.Email}, m.messages[0].To)
+	assert.NoError(t, s.Auth().ResetPassword(context.Background(), m.lastToken(t), "Correct-Horse-42"))
+
+	// The members are skipped on the repeated import
+	report, err = s.Group().ImportMembers(ctx, g.ID, owner.ID, strings.NewReader("member@example.org\n"), true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Skipped)
+	_, err = s.Group().ImportMembers(ctx, g.ID, owner.ID, strings.NewReader(""), true, false)
+	assert.Equal(t, service.ErrInvalidImportFile, err)
+
+	_, err = s.Group().ImportMembers(ctx, g.ID, member.ID, strings.NewReader(file), true, true)
+	assert.Equal(t, service.ErrPermissionDenied, err)
+
+	s.config.Groups.Import.MaxRows = 2
+	_, err = s.Group().ImportMembers(ctx, g.ID, owner.ID, strings.NewReader(file), true, true)
+	assert.Equal(t, service.ErrTooManyImportRows, err)
+}
+
+func TestGroupService_Rollover(t *testing.T) {
+	s := newRolesTestService(t)
+	ctx := context.Background()
+
+	owner := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(owner))
+	university := &models.University{Name: "Test university"}
+	assert.NoError(t, s.University().Create(university))
+
+	first := &models.Group{UniversityID: university.ID, SpecializationName: "TEST", StartYear: "2025", CourseNumber: 1, GroupNumber: "1"}
+	assert.NoError(t, s.Group().Create(ctx, first, owner))
+	final := &models.Group{UniversityID: university.ID, SpecializationName: "TEST", StartYear: "2022", CourseNumber: 4, GroupNumber: "1"}
+	assert.NoError(t, s.Group().Create(ctx, final, owner))
+	other := &models.Group{UniversityID: 0, SpecializationName: "OTHER", StartYear: "2025", CourseNumber: 1, GroupNumber: "1"}
+	assert.NoError(t, s.Group().Create(ctx, other, owner))
+
+	// The groups created in the current academic year are already in their course
+	_, err := s.Group().Rollover(ctx, university.ID, 0, owner.ID)
+	assert.NoError(t, err)
+	g, err := s.Group().Find(first.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, g.CourseNumber)
+
+	_, err = s.Group().Rollover(ctx, university.ID, 0, owner.ID)
+	assert.Equal(t, service.ErrGroupRolloverIsDone, err)
+	_, err = s.Group().Rollover(ctx, university.ID, time.Now().Year()+1, owner.ID)
+	assert.Equal(t, service.ErrInvalidAcademicYear, err)
+	_, err = s.Group().Rollover(ctx, 100, 0, owner.ID)
+	assert.Equal(t, service.ErrUniversityNotFound, err)
+
+	// The next academic year
+	groups := s.Group().(*GroupService)
+	now := time.Now().AddDate(1, 0, 0)
+	rollover, err := groups.rollover(ctx, university.ID, 0, 0, now)
+	assert.NoError(t, err)
+	assert.Nil(t, rollover.RolledByID)
+	assert.Equal(t, 1, rollover.Advanced)
+	assert.Equal(t, 1, rollover.Archived)
+
+	_, err = groups.rollover(ctx, university.ID, rollover.AcademicYear-2, 0, now)
+	assert.Equal(t, service.ErrInvalidAcademicYear, err)
+	_, err = groups.rollover(ctx, university.ID, rollover.AcademicYear, 0, now)
+	assert.Equal(t, service.ErrGroupRolloverIsDone, err)
+
+	g, err = s.Group().Find(first.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, g.CourseNumber)
+	assert.Nil(t, g.ArchivedAt)
+	assert.Equal(t, "TEST-21 (2025)", g.FullName)
+
+	g, err = s.Group().Find(final.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, g.CourseNumber)
+	assert.NotNil(t, g.ArchivedAt)
+
+	g, err = s.Group().Find(other.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, g.CourseNumber)
+
+	names, err := s.Group().GetNameHistory(first.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, names, 1) {
+		assert.Equal(t, "TEST-11 (2025)", names[0].FullName)
+		assert.Equal(t, rollover.AcademicYear, names[0].AcademicYear)
+	}
+
+	rollovers, err := s.Group().GetRollovers(university.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, rollovers, 2) {
+		assert.Equal(t, rollover.AcademicYear, rollovers[0].AcademicYear)
+	}
+}
+
+func TestGroupService_ArchiveAndDelete(t *testing.T) {
+	s := newRolesTestService(t)
+	ctx := context.Background()
+
+	owner := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(owner))
+	g := newTestGroup(t, s, owner)
+
+	group, err := s.Group().Archive(ctx, g.ID, owner.ID, true)
+	assert.NoError(t, err)
+	assert.NotNil(t, group.ArchivedAt)
+
+	// The archived group is read-only and hidden from the groups of the user
+	allowed, err := s.Role().HasGroupPermission(owner.ID, g.ID, models.PermissionGroupUpdate)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	allowed, err = s.Role().HasGroupPermission(owner.ID, g.ID, models.PermissionGroupRead)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	_, err = s.Group().GetGroupsUserMemberOf(owner.ID, false)
+	assert.Equal(t, service.ErrUserNotMemberOfAnyGroups, err)
+	groups, err := s.Group().GetGroupsUserMemberOf(owner.ID, true)
+	assert.NoError(t, err)
+	assert.Len(t, groups, 1)
+
+	group, err = s.Group().Archive(ctx, g.ID, owner.ID, false)
+	assert.NoError(t, err)
+	assert.Nil(t, group.ArchivedAt)
+
+	// The deleted group isn't found until it's restored
+	_, err = s.Group().Restore(ctx, g.ID, owner.ID)
+	assert.Equal(t, service.ErrGroupIsNotDeleted, err)
+	assert.NoError(t, s.Group().Delete(ctx, g.ID, owner.ID))
+	_, err = s.Group().Find(g.ID)
+	assert.Equal(t, service.ErrGroupNotFound, err)
+	deleted, err := s.Group().GetDeletedGroups()
+	assert.NoError(t, err)
+	assert.Len(t, deleted, 1)
+
+	group, err = s.Group().Restore(ctx, g.ID, owner.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, group.DeletedAt)
+	_, err = s.Group().Find(g.ID)
+	assert.NoError(t, err)
+
+	// The group deleted before the retention isn't restored and is purged
+	s.config.Groups.Deletion.Retention = time.Hour
+	assert.NoError(t, s.store.Group().SoftDelete(g.ID, time.Now().Add(-2*time.Hour)))
+	_, err = s.Group().Restore(ctx, g.ID, owner.ID)
+	assert.Equal(t, service.ErrGroupRestoreExpired, err)
+
+	purged, err := s.Group().PurgeDeleted()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	deleted, err = s.Group().GetDeletedGroups()
+	assert.NoError(t, err)
+	assert.Len(t, deleted, 0)
+	_, err = s.Group().Restore(ctx, g.ID, owner.ID)
+	assert.Equal(t, service.ErrGroupNotFound, err)
+}
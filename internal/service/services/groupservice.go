@@ -4,23 +4,52 @@ import (
 	"backend/internal/api/v1/models"
 	"backend/internal/service"
 	"backend/internal/store"
-	"crypto/md5"
-	"encoding/hex"
+	"context"
 	"errors"
 	"strconv"
+	"strings"
 	"time"
 )
 
-var (
-	//TODO: create the getting this from the config (via .env)
-	inviteTTL = 24 * time.Hour * 15 // 15 days
-)
+const groupInviteBytes = 16
 
 type GroupService struct {
-	service *Service
+	service   *Service
+	scheduler *periodicJob
+	purger    *periodicJob
+}
+
+// NewGroupService starts the scheduler of the academic year rollover, if its interval is set,
+//	and the purger of the deleted groups, if the retention is set
+func NewGroupService(service *Service) *GroupService {
+	s := &GroupService{
+		service: service,
+	}
+
+	s.scheduler = startPeriodicJob(service.config.Groups.Rollover.Interval, func() {
+		s.rolloverAll(time.Now())
+	})
+
+	if service.config.Groups.Deletion.Retention > 0 {
+		s.purger = startPeriodicJob(service.config.Groups.Deletion.PurgeInterval, func() {
+			if _, err := s.PurgeDeleted(); err != nil && service.logger != nil {
+				service.logger.Error("Unable to purge the deleted groups: ", err)
+			}
+		})
+	}
+
+	return s
+}
+
+func (s *GroupService) Close() {
+	s.scheduler.Stop()
+	s.purger.Stop()
 }
 
-func (s *GroupService) Create(group *models.Group, user *models.User) error {
+func (s *GroupService) Create(ctx context.Context, group *models.Group, user *models.User) error {
+	if group.JoinMode == "" {
+		group.JoinMode = models.GroupJoinModeOpen
+	}
 	if err := group.Validate(); err != nil {
 		return err
 	}
@@ -34,6 +63,21 @@ func (s *GroupService) Create(group *models.Group, user *models.User) error {
 		return err
 	}
 
+	// The creator becomes the owner of the group
+	if err = s.service.store.Group().AddGroupMember(user.ID, group.ID, 0); err != nil {
+		return err
+	}
+
+	if err = s.service.Role().SetGroupRole(user.ID, group.ID, models.RoleOwner); err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		Action:     models.AuditActionGroupCreate,
+		TargetType: models.AuditTargetGroup,
+		TargetID:   strconv.Itoa(group.ID),
+	}, nil, group)
+
 	return nil
 }
 
@@ -56,23 +100,39 @@ func (s *GroupService) GetGroupMembers(groupID int) ([]models.User, error) {
 	return users, err
 }
 
-func (s *GroupService) GetUserPermissions(userID, groupID int) error {
-	roles, err := s.service.store.Group().GetMemberRoles(userID, groupID)
+func (s *GroupService) GetUserPermissions(userID, groupID int) ([]string, error) {
+	if _, err := s.Find(groupID); err != nil {
+		return nil, err
+	}
+
+	globalPermissions, err := s.service.Role().GetUserPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.service.Role().GetMemberPermissions(userID, groupID)
 	if err != nil {
-		return nil
+		return nil, err
+	}
+
+	for _, p := range globalPermissions {
+		if !containsString(permissions, p) {
+			permissions = append(permissions, p)
+		}
 	}
 
-	return errors.New("to do me GetUserPermissions" + strconv.Itoa(len(roles)))
+	return permissions, nil
 }
 
 // Find returns *models.Group by groupID or nil if group not found.
 //	If group was not found, method returns service.ErrGroupNotFound.
 //	If an error occurs during the execution of the method, the method returns error.
+//	The deleted groups aren't found.
 func (s *GroupService) Find(groupID int) (*models.Group, error) {
 	group, err := s.service.store.Group().Find(groupID)
-	if err != nil && err != store.ErrNoRowsFound {
+	if err != nil && err != store.ErrNoRowsFound && err != store.ErrRecordNotFound {
 		return nil, err
-	} else if err == store.ErrNoRowsFound {
+	} else if err != nil || group.DeletedAt != nil {
 		return nil, service.ErrGroupNotFound
 	}
 	return group, nil
@@ -88,31 +148,303 @@ func (s *GroupService) FindByName(name string) (*models.Group, error) {
 	return group, nil
 }
 
-func (s *GroupService) Update(groupID int, updGroup *models.UpdateGroup) error {
-	err := s.service.store.Group().Update(groupID, updGroup)
-	return err
+func (s *GroupService) Update(ctx context.Context, groupID, userID int, updGroup *models.UpdateGroup) (*models.Group, error) {
+	if err := updGroup.Validate(); err != nil {
+		return nil, err
+	}
+
+	group, err := s.Find(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := s.service.Role().HasGroupPermission(userID, groupID, models.PermissionGroupUpdate)
+	if err != nil {
+		return nil, err
+	} else if !allowed {
+		return nil, service.ErrPermissionDenied
+	}
+
+	if updGroup.UniversityID != nil && *updGroup.UniversityID != group.UniversityID {
+		// The group is detached from the department before it moves to another university
+		if group.DepartmentID != nil {
+			return nil, service.ErrGroupIsAttachedToDepartment
+		}
+		if _, err = s.service.University().Find(*updGroup.UniversityID); err != nil {
+			return nil, err
+		}
+	}
+
+	before := *group
+	updGroup.Apply(group)
+
+	// The custom name matching the compiled one is cleared, so it follows the later changes
+	if updGroup.CustomName != nil {
+		updGroup.CustomName = &group.CustomName
+		if group.CustomName != "" {
+			other, err := s.service.store.Group().FindByName(group.CustomName)
+			if err != nil && err != store.ErrRecordNotFound {
+				return nil, err
+			} else if err == nil && other.ID != groupID {
+				return nil, service.ErrGroupNameIsAlreadyOccupied
+			}
+		}
+	}
+
+	if err = s.service.store.Group().Update(groupID, updGroup); err != nil {
+		return nil, err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionGroupUpdate,
+		TargetType:  models.AuditTargetGroup,
+		TargetID:    strconv.Itoa(groupID),
+	}, &before, group)
+
+	return group, nil
 }
 
-func (s *GroupService) Delete(groupID, userID int) error {
-	_, err := s.service.store.Group().Find(groupID)
+// Delete marks the group deleted, it's restored by an administrator within the retention and purged after it
+func (s *GroupService) Delete(ctx context.Context, groupID, userID int) error {
+	group, err := s.Find(groupID)
+	if err != nil {
+		return err
+	}
+
+	allowed, err := s.service.Role().HasGroupPermission(userID, groupID, models.PermissionGroupDelete)
+	if err != nil {
+		return err
+	} else if !allowed {
+		return service.ErrPermissionDenied
+	}
+
+	err = s.service.store.Group().SoftDelete(groupID, time.Now())
 	if err == store.ErrRecordNotFound {
 		return service.ErrGroupNotFound
+	} else if err != nil {
+		return err
 	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionGroupDelete,
+		TargetType:  models.AuditTargetGroup,
+		TargetID:    strconv.Itoa(groupID),
+	}, group, nil)
+
+	return nil
+}
+
+// Archive archives the group or unarchives it, if archive is false. The archived groups are read-only
+func (s *GroupService) Archive(ctx context.Context, groupID, userID int, archive bool) (*models.Group, error) {
+	group, err := s.Find(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := s.service.Role().HasGroupPermission(userID, groupID, models.PermissionGroupDelete)
 	if err != nil {
+		return nil, err
+	} else if !allowed {
+		return nil, service.ErrPermissionDenied
+	}
+
+	if (group.ArchivedAt != nil) == archive {
+		return group, nil
+	}
+
+	before := *group
+	action := models.AuditActionGroupUnarchive
+	group.ArchivedAt = nil
+	if archive {
+		action = models.AuditActionGroupArchive
+		now := time.Now()
+		group.ArchivedAt = &now
+	}
+
+	if err := s.service.store.Group().SetArchived(groupID, group.ArchivedAt); err != nil {
+		return nil, err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      action,
+		TargetType:  models.AuditTargetGroup,
+		TargetID:    strconv.Itoa(groupID),
+	}, &before, group)
+
+	return group, nil
+}
+
+// Restore restores the deleted group within the retention
+func (s *GroupService) Restore(ctx context.Context, groupID, userID int) (*models.Group, error) {
+	group, err := s.service.store.Group().Find(groupID)
+	if err == store.ErrRecordNotFound || err == store.ErrNoRowsFound {
+		return nil, service.ErrGroupNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	if group.DeletedAt == nil {
+		return nil, service.ErrGroupIsNotDeleted
+	}
+	retention := s.service.config.Groups.Deletion.Retention
+	if retention > 0 && time.Since(*group.DeletedAt) > retention {
+		return nil, service.ErrGroupRestoreExpired
+	}
+
+	err = s.service.store.Group().Restore(groupID)
+	if err == store.ErrRecordNotFound {
+		return nil, service.ErrGroupIsNotDeleted
+	} else if err != nil {
+		return nil, err
+	}
+
+	before := *group
+	group.DeletedAt = nil
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionGroupRestore,
+		TargetType:  models.AuditTargetGroup,
+		TargetID:    strconv.Itoa(groupID),
+	}, &before, group)
+
+	return group, nil
+}
+
+func (s *GroupService) GetDeletedGroups() ([]models.Group, error) {
+	return s.service.store.Group().GetDeletedGroups()
+}
+
+func (s *GroupService) PurgeDeleted() (int, error) {
+	retention := s.service.config.Groups.Deletion.Retention
+	if retention <= 0 {
+		return 0, nil
+	}
+
+	return s.service.store.Group().DeleteGroupsDeletedBefore(time.Now().Add(-retention))
+}
+
+func (s *GroupService) Leave(ctx context.Context, groupID, userID int) error {
+	if _, err := s.Find(groupID); err != nil {
 		return err
 	}
 
-	isUserGroupMember, err := s.IsUserGroupMember(userID, groupID)
+	if err := s.removeMember(groupID, userID); err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionGroupMemberLeave,
+		TargetType:  models.AuditTargetGroup,
+		TargetID:    strconv.Itoa(groupID),
+	}, map[string]int{"user_id": userID}, nil)
+
+	return nil
+}
+
+func (s *GroupService) RemoveMember(ctx context.Context, groupID, memberID, userID int) error {
+	if _, err := s.Find(groupID); err != nil {
+		return err
+	}
+
+	allowed, err := s.service.Role().HasGroupPermission(userID, groupID, models.PermissionGroupMembersManage)
+	if err != nil {
+		return err
+	} else if !allowed {
+		return service.ErrPermissionDenied
+	}
+
+	// Headmen manage members, but only the users managing roles remove owners
+	isOwner, err := s.service.Role().IsGroupOwner(memberID, groupID)
+	if err != nil {
+		return err
+	}
+	if isOwner {
+		allowed, err = s.service.Role().HasGroupPermission(userID, groupID, models.PermissionGroupRolesManage)
+		if err != nil {
+			return err
+		} else if !allowed {
+			return service.ErrPermissionDenied
+		}
+	}
+
+	if err = s.removeMember(groupID, memberID); err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionGroupMemberRemove,
+		TargetType:  models.AuditTargetGroup,
+		TargetID:    strconv.Itoa(groupID),
+	}, map[string]int{"user_id": memberID}, nil)
+
+	return nil
+}
+
+// removeMember removes the member, if it isn't the last owner of the group.
+//	The owners are counted by the store in the same transaction, so the owners can't remove each other at once
+func (s *GroupService) removeMember(groupID, userID int) error {
+	owner, err := s.service.Role().FindGroupRole(models.RoleOwner)
 	if err != nil {
 		return err
-	} else if !isUserGroupMember {
+	}
+
+	err = s.service.store.Group().RemoveGroupMember(userID, groupID, owner.ID)
+	if err == store.ErrRecordNotFound {
 		return service.ErrUserIsNotGroupMember
+	} else if err == store.ErrLastMemberWithRole {
+		return service.ErrLastGroupOwner
 	}
 
-	err = s.service.store.Group().Delete(groupID)
 	return err
 }
 
+func (s *GroupService) TransferOwnership(ctx context.Context, groupID, ownerID, newOwnerID int) error {
+	if _, err := s.Find(groupID); err != nil {
+		return err
+	}
+	if ownerID == newOwnerID {
+		return service.ErrTransferToSelf
+	}
+
+	isOwner, err := s.service.Role().IsGroupOwner(ownerID, groupID)
+	if err != nil {
+		return err
+	} else if !isOwner {
+		return service.ErrPermissionDenied
+	}
+
+	owner, err := s.service.Role().FindGroupRole(models.RoleOwner)
+	if err != nil {
+		return err
+	}
+	headman, err := s.service.Role().FindGroupRole(models.RoleHeadman)
+	if err != nil {
+		return err
+	}
+
+	err = s.service.store.Role().TransferMemberRole(groupID, ownerID, newOwnerID, owner.ID, headman.ID)
+	if err == store.ErrRecordNotFound {
+		return service.ErrUserIsNotGroupMember
+	} else if err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &ownerID,
+		Action:      models.AuditActionGroupTransfer,
+		TargetType:  models.AuditTargetGroup,
+		TargetID:    strconv.Itoa(groupID),
+	}, map[string]int{"owner_id": ownerID}, map[string]int{"owner_id": newOwnerID})
+
+	return nil
+}
+
 func (s *GroupService) IsUserGroupMember(userID, groupID int) (bool, error) {
 	return s.service.store.Group().IsUserGroupMember(userID, groupID)
 }
@@ -121,13 +453,13 @@ func (s *GroupService) GetGroupMembersCount(groupID int) (int, error) {
 	return s.service.store.Group().GetMembersCount(groupID)
 }
 
-func (s *GroupService) GetGroupsUserMemberOf(userID int) ([]models.Group, error) {
+func (s *GroupService) GetGroupsUserMemberOf(userID int, includeArchived bool) ([]models.Group, error) {
 	_, err := s.service.User().Find(userID)
 	if err != nil {
 		return nil, err
 	}
 
-	groups, err := s.service.store.Group().GetGroupsUserMemberOf(userID)
+	groups, err := s.service.store.Group().GetGroupsUserMemberOf(userID, includeArchived)
 	if err != nil && err != store.ErrRecordNotFound {
 		return nil, err
 	} else if err != nil {
@@ -136,98 +468,377 @@ func (s *GroupService) GetGroupsUserMemberOf(userID int) ([]models.Group, error)
 	return groups, nil
 }
 
-func (s *GroupService) GetMemberRoles(userID, groupID int) ([]int, error) {
-	panic("implement me")
+func (s *GroupService) GetMemberRoles(userID, groupID int) ([]models.Role, error) {
+	return s.service.store.Group().GetMemberRoles(userID, groupID)
 }
 
 func (s *GroupService) GetRolePermissions(roleID int) ([]models.Permission, error) {
-	panic("implement me")
+	return s.service.store.Group().GetRolePermissions(roleID)
 }
 
 func (s *GroupService) GetRole(roleID int) (*models.Role, error) {
-	panic("implement me")
+	role, err := s.service.store.Group().GetRole(roleID)
+	if err == store.ErrRecordNotFound {
+		return nil, service.ErrRoleNotFound
+	}
+	return role, err
 }
 
 func (s *GroupService) GetRoleByName(roleName string) (*models.Role, error) {
-	panic("implement me")
+	role, err := s.service.store.Group().GetRoleByName(roleName)
+	if err == store.ErrRecordNotFound {
+		return nil, service.ErrRoleNotFound
+	}
+	return role, err
 }
 
-func (s *GroupService) AddUserToGroupByInvite(userID int, invite string) error {
-	//Verifying that the user is exist
-	isUserExist, err := s.service.store.User().IsUserExist(userID)
+func (s *GroupService) CreateInvite(ctx context.Context, invite *models.GroupInvite) error {
+	cfg := s.service.config.Groups.Invites
+
+	if _, err := s.Find(invite.GroupID); err != nil {
+		return err
+	}
+
+	allowed, err := s.service.Role().HasGroupPermission(invite.InviterID, invite.GroupID, models.PermissionGroupInvite)
 	if err != nil {
 		return err
+	} else if !allowed {
+		return service.ErrPermissionDenied
+	}
+
+	invite.Name = strings.TrimSpace(invite.Name)
+	if err = invite.Validate(); err != nil {
+		return err
+	}
+
+	// Only the users managing roles invite with roles above the member
+	switch invite.DefaultRole {
+	case "", models.RoleMember, models.RoleViewer:
+	case models.RoleOwner:
+		return service.ErrInvalidInviteRole
+	default:
+		allowed, err = s.service.Role().HasGroupPermission(invite.InviterID, invite.GroupID, models.PermissionGroupRolesManage)
+		if err != nil {
+			return err
+		} else if !allowed {
+			return service.ErrPermissionDenied
+		}
+	}
+	if invite.DefaultRole != "" {
+		if _, err = s.service.Role().FindGroupRole(invite.DefaultRole); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	if invite.ExpiresAt.IsZero() {
+		invite.ExpiresAt = now.Add(cfg.DefaultTTL)
+	}
+	if !invite.ExpiresAt.After(now) || (cfg.MaxTTL > 0 && invite.ExpiresAt.After(now.Add(cfg.MaxTTL))) {
+		return service.ErrInvalidInviteExpiry
+	}
+
+	if cfg.MaxPerGroup > 0 {
+		invites, err := s.GetInvites(invite.GroupID)
+		if err != nil {
+			return err
+		}
+
+		count := 0
+		for i := range invites {
+			if invites[i].Valid(now) {
+				count++
+			}
+		}
+		if count >= cfg.MaxPerGroup {
+			return service.ErrTooManyGroupInvites
+		}
+	}
+
+	invite.InviteHash, err = generateRandomToken(groupInviteBytes)
+	if err != nil {
+		return err
+	}
+	invite.Uses = 0
+	invite.CreatedAt = now
+	invite.RevokedAt = nil
+
+	if err = s.service.store.Group().AddGroupInvite(invite); err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &invite.InviterID,
+		Action:      models.AuditActionGroupInviteCreate,
+		TargetType:  models.AuditTargetGroup,
+		TargetID:    strconv.Itoa(invite.GroupID),
+	}, nil, auditInvite(invite))
+
+	return nil
+}
+
+// GetInvites returns not revoked invites of the group. Expired and used up invites are returned too
+func (s *GroupService) GetInvites(groupID int) ([]models.GroupInvite, error) {
+	invites, err := s.service.store.Group().GetGroupInvites(groupID)
+	if err != nil && err != store.ErrRecordNotFound {
+		return nil, err
+	}
+	if invites == nil {
+		invites = []models.GroupInvite{}
+	}
+
+	return invites, nil
+}
+
+func (s *GroupService) RevokeInvite(ctx context.Context, groupID, inviteID, userID int) error {
+	allowed, err := s.service.Role().HasGroupPermission(userID, groupID, models.PermissionGroupInvite)
+	if err != nil {
+		return err
+	} else if !allowed {
+		return service.ErrPermissionDenied
+	}
+
+	err = s.service.store.Group().RevokeGroupInvite(groupID, inviteID, time.Now())
+	if err == store.ErrRecordNotFound {
+		return service.ErrGroupInviteNotFound
+	} else if err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionGroupInviteRevoke,
+		TargetType:  models.AuditTargetGroup,
+		TargetID:    strconv.Itoa(groupID),
+	}, map[string]int{"invite_id": inviteID}, nil)
+
+	return nil
+}
+
+// checkCanJoin checks the user joining the group isn't its member yet and has confirmed the email, if it's required
+func (s *GroupService) checkCanJoin(userID, groupID int) error {
+	user, err := s.service.store.User().Find(userID)
+	if err == store.ErrRecordNotFound {
+		return service.ErrUserNotFound
+	} else if err != nil {
+		return err
 	}
-	if !isUserExist {
-		return store.ErrUserNotFound
+
+	if s.service.config.Auth.EmailConfirmation.RequiredToJoinGroup && !user.IsEmailConfirmed() {
+		return service.ErrEmailIsNotConfirmed
+	}
+
+	isMember, err := s.service.store.Group().IsUserGroupMember(userID, groupID)
+	if err != nil {
+		return err
+	} else if isMember {
+		return service.ErrUserIsAlreadyGroupMember
 	}
 
+	return nil
+}
+
+func (s *GroupService) AddUserToGroupByInvite(ctx context.Context, userID int, invite string) error {
 	groupInvite, err := s.service.store.Group().GetGroupInviteByHash(invite)
 	if err == store.ErrRecordNotFound {
-		return errors.New("invalid invite")
+		return service.ErrInvalidInvite
 	} else if err != nil {
 		return err
 	}
 
-	if time.Now().After(groupInvite.ExpiresAt) {
-		return errors.New("invite has expired")
+	if !groupInvite.Valid(time.Now()) {
+		return service.ErrInvalidInvite
 	}
 
-	err = s.service.store.Group().AddGroupMember(userID, groupInvite.GroupID, groupInvite.InviterID)
+	group, err := s.Find(groupInvite.GroupID)
+	if err != nil {
+		return err
+	}
+	if group.ArchivedAt != nil {
+		return service.ErrGroupIsArchived
+	}
+	switch group.JoinMode {
+	case models.GroupJoinModeClosed:
+		return service.ErrGroupIsClosed
+	case models.GroupJoinModeApproval:
+		return service.ErrGroupJoinRequiresApproval
+	}
+
+	if err = s.checkCanJoin(userID, group.ID); err != nil {
+		return err
+	}
+
+	roleName := groupInvite.DefaultRole
+	if roleName == "" {
+		roleName = models.RoleMember
+	}
+	role, err := s.service.Role().FindGroupRole(roleName)
 	if err != nil {
 		return err
 	}
 
+	// The invite is checked again in the transaction, it may be used up by the concurrent joins
+	err = s.service.store.Group().JoinGroupByInvite(userID, groupInvite.ID, role.ID)
+	if err == store.ErrRecordNotFound {
+		return service.ErrInvalidInvite
+	} else if err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionGroupMemberJoin,
+		TargetType:  models.AuditTargetGroup,
+		TargetID:    strconv.Itoa(groupInvite.GroupID),
+	}, nil, map[string]interface{}{"user_id": userID, "invite_id": groupInvite.ID, "role": roleName})
+
 	return nil
 }
 
-func (s *GroupService) GetOrCreateInviteLink(groupID int, inviterID int) (*models.GroupInvite, error) {
-	group, err := s.service.store.Group().Find(groupID)
+// auditInvite is the invite in the audit log without the hash, the log doesn't let to join the group
+func auditInvite(invite *models.GroupInvite) models.GroupInvite {
+	result := *invite
+	result.InviteHash = ""
+	return result
+}
+
+// RequestToJoin creates the pending request of the user to join the group in the approval mode
+func (s *GroupService) RequestToJoin(ctx context.Context, groupID, userID int, message string) (*models.GroupJoinRequest, error) {
+	group, err := s.Find(groupID)
 	if err != nil {
 		return nil, err
 	}
+	if group.ArchivedAt != nil {
+		return nil, service.ErrGroupIsArchived
+	}
+	switch group.JoinMode {
+	case models.GroupJoinModeClosed:
+		return nil, service.ErrGroupIsClosed
+	case models.GroupJoinModeApproval:
+	default:
+		return nil, service.ErrJoinRequestsNotAccepted
+	}
 
-	invite, err := s.service.store.Group().GetGroupInvite(groupID)
-	if err == nil {
-		if time.Now().After(invite.ExpiresAt) {
-			err = s.service.store.Group().DeleteGroupInviteByHash(invite.InviteHash)
-			if err != nil {
-				return nil, err
-			}
+	if err = s.checkCanJoin(userID, groupID); err != nil {
+		return nil, err
+	}
+
+	requests, err := s.GetUserJoinRequests(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range requests {
+		if requests[i].GroupID == groupID && requests[i].Status == models.JoinRequestStatusPending {
+			return nil, service.ErrJoinRequestIsPending
 		}
-		return invite, err
-	} else if err != store.ErrRecordNotFound {
+	}
+
+	request := &models.GroupJoinRequest{
+		GroupID:   groupID,
+		UserID:    userID,
+		Message:   strings.TrimSpace(message),
+		Status:    models.JoinRequestStatusPending,
+		CreatedAt: time.Now(),
+	}
+	if err = request.Validate(); err != nil {
 		return nil, err
 	}
 
-	URLEncoder := md5.New()
-	URLEncoder.Write([]byte(strconv.Itoa(group.ID)))
-	URLEncoder.Write([]byte(strconv.Itoa(group.UniversityID)))
-	URLEncoder.Write([]byte(group.CustomName))
+	if err = s.service.store.Group().CreateJoinRequest(request); err != nil {
+		return nil, err
+	}
 
-	inviteHash := hex.EncodeToString(URLEncoder.Sum(nil))
+	return request, nil
+}
 
-	invite = &models.GroupInvite{
-		GroupID:    groupID,
-		InviterID:  inviterID,
-		InviteHash: inviteHash,
-		ExpiresAt:  time.Now().Add(inviteTTL),
+func (s *GroupService) GetJoinRequests(groupID int, status string) ([]models.GroupJoinRequest, error) {
+	if status != "" && !containsString(models.JoinRequestStatuses, status) {
+		return nil, service.ErrInvalidJoinRequestStatus
 	}
 
-	err = s.service.store.Group().AddGroupInviteHash(invite)
-	if err != nil {
+	requests, err := s.service.store.Group().GetJoinRequests(groupID, status)
+	if err != nil && err != store.ErrRecordNotFound {
 		return nil, err
 	}
-	//domain-name.com/invite/ + inviteHash
-	return invite, nil
+	if requests == nil {
+		requests = []models.GroupJoinRequest{}
+	}
+
+	return requests, nil
 }
 
-func (s *GroupService) GetInviteLink(groupID int) (*models.GroupInvite, error) {
-	invite, err := s.service.store.Group().GetGroupInvite(groupID)
-	if err == nil {
-		return invite, nil
-	} else if err == store.ErrRecordNotFound {
-		return nil, service.ErrGroupHaveNotInvitation
+// GetUserJoinRequests returns the join requests of the user, the newest first
+func (s *GroupService) GetUserJoinRequests(userID int) ([]models.GroupJoinRequest, error) {
+	requests, err := s.service.store.Group().GetUserJoinRequests(userID)
+	if err != nil && err != store.ErrRecordNotFound {
+		return nil, err
+	}
+	if requests == nil {
+		requests = []models.GroupJoinRequest{}
+	}
+
+	return requests, nil
+}
+
+func (s *GroupService) DecideJoinRequest(ctx context.Context, groupID, requestID, userID int, approve bool) (*models.GroupJoinRequest, error) {
+	allowed, err := s.service.Role().HasGroupPermission(userID, groupID, models.PermissionGroupMembersManage)
+	if err != nil {
+		return nil, err
+	} else if !allowed {
+		return nil, service.ErrPermissionDenied
+	}
+
+	request, err := s.service.store.Group().FindJoinRequest(requestID)
+	if err == store.ErrRecordNotFound || (err == nil && request.GroupID != groupID) {
+		return nil, service.ErrJoinRequestNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if request.Status != models.JoinRequestStatusPending {
+		return nil, service.ErrJoinRequestIsDecided
 	}
-	return nil, err
+
+	now := time.Now()
+	action := models.AuditActionJoinRequestReject
+	if approve {
+		action = models.AuditActionJoinRequestApprove
+
+		// The requester may be added to the group another way after the request
+		isMember, err := s.service.store.Group().IsUserGroupMember(request.UserID, groupID)
+		if err != nil {
+			return nil, err
+		} else if isMember {
+			return nil, service.ErrUserIsAlreadyGroupMember
+		}
+
+		role, err := s.service.Role().FindGroupRole(models.RoleMember)
+		if err != nil {
+			return nil, err
+		}
+		err = s.service.store.Group().ApproveJoinRequest(requestID, userID, role.ID, now)
+	} else {
+		err = s.service.store.Group().RejectJoinRequest(requestID, userID, now)
+	}
+	if err == store.ErrRecordNotFound {
+		return nil, service.ErrJoinRequestIsDecided
+	} else if err != nil {
+		return nil, err
+	}
+
+	before := *request
+	request.Status = models.JoinRequestStatusRejected
+	if approve {
+		request.Status = models.JoinRequestStatusApproved
+	}
+	request.DecidedByID = &userID
+	request.DecidedAt = &now
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      action,
+		TargetType:  models.AuditTargetGroup,
+		TargetID:    strconv.Itoa(groupID),
+	}, before, request)
+
+	return request, nil
 }
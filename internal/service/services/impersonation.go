@@ -0,0 +1,123 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"context"
+	"encoding/json"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+)
+
+func (s *AuthService) Impersonate(ctx context.Context, admin *models.User, userID int, reason, ip, userAgent string) (*models.Impersonation, error) {
+	if admin.ID == userID {
+		return nil, service.ErrImpersonationNotAllowed
+	}
+
+	user, err := s.service.User().Find(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Administrators can't act as each other
+	isAdministrator, err := s.service.Role().HasPermission(user.ID, models.PermissionAdminPanel)
+	if err != nil {
+		return nil, err
+	}
+	if isAdministrator {
+		return nil, service.ErrImpersonationNotAllowed
+	}
+
+	// The session expires with the token, so it isn't listed as active after the impersonation
+	now := time.Now()
+	expiresAt := now.Add(s.service.config.Auth.Impersonation.TokenTTL)
+	session := &models.UserSession{
+		UserID:         user.ID,
+		DeviceName:     "Impersonation by " + admin.Login,
+		UserAgent:      userAgent,
+		IP:             ip,
+		ImpersonatorID: &admin.ID,
+		ExpiresAt:      &expiresAt,
+	}
+	if err := s.service.store.Session().Create(session); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.generateImpersonationAccessToken(user, admin.ID, session, now, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// The impersonation isn't started, if it can't be written to the audit log
+	details, err := json.Marshal(map[string]interface{}{
+		"session_id": session.ID,
+		"reason":     reason,
+		"expires_at": expiresAt,
+	})
+	if err == nil {
+		err = s.service.Audit().Record(ctx, &models.AuditEntry{
+			ActorUserID: &admin.ID,
+			Action:      models.AuditActionImpersonationStart,
+			TargetType:  models.AuditTargetUser,
+			TargetID:    strconv.Itoa(user.ID),
+			IP:          ip,
+			Details:     details,
+			CreatedAt:   now,
+		})
+	}
+	if err != nil {
+		_ = s.service.store.Session().Revoke(session.ID)
+		return nil, err
+	}
+
+	s.service.logger.WithFields(logrus.Fields{
+		"admin_id": admin.ID,
+		"user_id":  user.ID,
+	}).Warn("The administrator impersonates the user")
+
+	return &models.Impersonation{
+		AccessToken:    accessToken,
+		ExpiresAt:      expiresAt,
+		UserID:         user.ID,
+		ImpersonatorID: admin.ID,
+		SessionID:      session.ID,
+	}, nil
+}
+
+// generateImpersonationAccessToken works as generateSessionAccessToken with the administrator's id and the own TTL
+func (s *AuthService) generateImpersonationAccessToken(user *models.User, adminID int, session *models.UserSession, issuedAt, expiresAt time.Time) (string, error) {
+	jwtClaims := models.UAccessTokenClaims{
+		UserID:         user.ID,
+		SessionID:      session.ID,
+		ImpersonatorID: adminID,
+		Fingerprint:    s.sessionFingerprint(session),
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.New().String(),
+			IssuedAt:  issuedAt.Unix(),
+			ExpiresAt: expiresAt.Unix(),
+			Subject:   user.Login,
+		},
+	}
+	if s.keys == nil {
+		return "", service.ErrSigningKeyNotFound
+	}
+
+	return s.keys.Sign(jwtClaims)
+}
+
+// checkImpersonation checks that the administrator's id of the token is the one of its session,
+//	so the ordinary token can't be used with the impersonation session and vice versa
+func checkImpersonation(claims *models.UAccessTokenClaims, session *models.UserSession) error {
+	impersonatorID := 0
+	if session != nil && session.ImpersonatorID != nil {
+		impersonatorID = *session.ImpersonatorID
+	}
+	if claims.ImpersonatorID != impersonatorID {
+		return service.ErrInvalidUserToken
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAuthService_Impersonate(t *testing.T) {
+	s := newRolesTestService(t)
+
+	admin := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(admin))
+	assert.NoError(t, s.Role().AssignRole(context.Background(), admin.ID, "admin"))
+
+	other := models.TestUser(t)
+	other.Login, other.Email = "OtherAdmin", "other-admin@example.org"
+	assert.NoError(t, s.Auth().RegisterUser(other))
+	assert.NoError(t, s.Role().AssignRole(context.Background(), other.ID, "admin"))
+
+	u := models.TestUser(t)
+	u.Login, u.Email = "Student", "student@example.org"
+	assert.NoError(t, s.Auth().RegisterUser(u))
+
+	impersonation, err := s.Auth().Impersonate(context.Background(), admin, u.ID, "support ticket", "127.0.0.1", "test")
+	assert.NoError(t, err)
+	if !assert.NotNil(t, impersonation) {
+		return
+	}
+	assert.Equal(t, u.ID, impersonation.UserID)
+	assert.Equal(t, admin.ID, impersonation.ImpersonatorID)
+	assert.WithinDuration(t, time.Now().Add(s.config.Auth.Impersonation.TokenTTL), impersonation.ExpiresAt, time.Minute)
+
+	claims, err := s.Auth().CheckAccessToken(impersonation.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, claims.UserID)
+	assert.Equal(t, admin.ID, claims.ImpersonatorID)
+
+	user, session, err := s.Auth().AuthenticateUserSession(context.Background(), impersonation.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, user.ID)
+	if assert.True(t, session.IsImpersonated()) {
+		assert.Equal(t, admin.ID, *session.ImpersonatorID)
+	}
+
+	// The session is closed as any other one
+	assert.NoError(t, s.Auth().UserLogout(u.ID, session.ID))
+	_, _, err = s.Auth().AuthenticateUserSession(context.Background(), impersonation.AccessToken)
+	assert.Error(t, err)
+
+	_, err = s.Auth().Impersonate(context.Background(), admin, admin.ID, "", "127.0.0.1", "test")
+	assert.Equal(t, service.ErrImpersonationNotAllowed, err)
+	_, err = s.Auth().Impersonate(context.Background(), admin, other.ID, "", "127.0.0.1", "test")
+	assert.Equal(t, service.ErrImpersonationNotAllowed, err)
+	_, err = s.Auth().Impersonate(context.Background(), admin, 100, "", "127.0.0.1", "test")
+	assert.Equal(t, service.ErrUserNotFound, err)
+}
+
+func TestCheckImpersonation(t *testing.T) {
+	adminID := 1
+	impersonated := &models.UserSession{ID: 1, UserID: 2, ImpersonatorID: &adminID}
+	ordinary := &models.UserSession{ID: 2, UserID: 2}
+
+	assert.NoError(t, checkImpersonation(&models.UAccessTokenClaims{UserID: 2, ImpersonatorID: adminID}, impersonated))
+	assert.NoError(t, checkImpersonation(&models.UAccessTokenClaims{UserID: 2}, ordinary))
+	assert.NoError(t, checkImpersonation(&models.UAccessTokenClaims{UserID: 2}, nil))
+	assert.Equal(t, service.ErrInvalidUserToken, checkImpersonation(&models.UAccessTokenClaims{UserID: 2}, impersonated))
+	assert.Equal(t, service.ErrInvalidUserToken, checkImpersonation(&models.UAccessTokenClaims{UserID: 2, ImpersonatorID: adminID}, ordinary))
+	assert.Equal(t, service.ErrInvalidUserToken, checkImpersonation(&models.UAccessTokenClaims{UserID: 2, ImpersonatorID: adminID}, nil))
+}
+
+func TestAuthService_Impersonate_SessionExpires(t *testing.T) {
+	s := newRolesTestService(t)
+
+	admin := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(admin))
+	assert.NoError(t, s.Role().AssignRole(context.Background(), admin.ID, "admin"))
+
+	u := models.TestUser(t)
+	u.Login, u.Email = "Student", "student@example.org"
+	assert.NoError(t, s.Auth().RegisterUser(u))
+
+	impersonation, err := s.Auth().Impersonate(context.Background(), admin, u.ID, "support ticket", "127.0.0.1", "test")
+	assert.NoError(t, err)
+	if !assert.NotNil(t, impersonation) {
+		return
+	}
+
+	sessions, err := s.Auth().GetUserSessions(u.ID)
+	assert.NoError(t, err)
+	if !assert.Len(t, sessions, 1) || !assert.NotNil(t, sessions[0].ExpiresAt) {
+		return
+	}
+	assert.WithinDuration(t, impersonation.ExpiresAt, *sessions[0].ExpiresAt, time.Second)
+
+	// The test store returns the stored session, so it's expired in place
+	session, err := s.store.Session().Find(sessions[0].ID)
+	assert.NoError(t, err)
+	expiredAt := time.Now().Add(-time.Second)
+	session.ExpiresAt = &expiredAt
+
+	sessions, err = s.Auth().GetUserSessions(u.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+	_, err = s.authService.getActiveSession(session.ID)
+	assert.Equal(t, service.ErrSessionIsRevoked, err)
+}
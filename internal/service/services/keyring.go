@@ -0,0 +1,365 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/config"
+	"backend/internal/service"
+	"backend/internal/store"
+	"backend/pkg/auth"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	rsaKeyBits = 2048
+	// keyRingReloadRate is how often the key ring is reloaded from the store,
+	//so the keys rotated by other API server replicas are picked up
+	keyRingReloadRate = 1 * time.Minute
+	// keyRingMissReloadRate limits reloads caused by tokens with an unknown kid
+	keyRingMissReloadRate = 10 * time.Second
+)
+
+// ringKey is the parsed signing key
+type ringKey struct {
+	id         string
+	method     jwt.SigningMethod
+	privateKey interface{}
+	publicKey  interface{}
+	createdAt  time.Time
+}
+
+// keyRing signs access tokens with the active key and verifies them with any key that isn't retired yet.
+//	With the HS256 algorithm the ring consists of the single shared SigningKey from the config.
+//	With RS256 and EdDSA keys are kept in the store and rotated every rotationInterval,
+//	the previous key verifies tokens for the overlap window after the rotation.
+type keyRing struct {
+	sync.RWMutex
+	reloadMu   sync.Mutex
+	repository store.SigningKeyRepository
+	logger     *logrus.Logger
+	now        func() time.Time
+
+	algorithm        string
+	rotationInterval time.Duration
+	overlap          time.Duration
+
+	keys       map[string]*ringKey
+	active     *ringKey
+	lastReload time.Time
+
+	reloader *periodicJob
+}
+
+func newKeyRing(repository store.SigningKeyRepository, cfg config.JWTConfig, logger *logrus.Logger) (*keyRing, error) {
+	r := &keyRing{
+		repository:       repository,
+		logger:           logger,
+		now:              time.Now,
+		algorithm:        cfg.SigningAlgorithm,
+		rotationInterval: cfg.KeyRotationInterval,
+		overlap:          cfg.KeyOverlap,
+		keys:             make(map[string]*ringKey),
+	}
+	if r.algorithm == "" {
+		r.algorithm = config.SigningAlgorithmHS256
+	}
+	if r.overlap < cfg.AccessTokenTTL {
+		r.overlap = cfg.AccessTokenTTL
+	}
+
+	switch r.algorithm {
+	case config.SigningAlgorithmHS256:
+		key := &ringKey{
+			method:     jwt.SigningMethodHS256,
+			privateKey: []byte(cfg.SigningKey),
+			publicKey:  []byte(cfg.SigningKey),
+		}
+		r.keys[key.id] = key
+		r.active = key
+		return r, nil
+	case config.SigningAlgorithmRS256, config.SigningAlgorithmEdDSA:
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", r.algorithm)
+	}
+
+	if err := r.Reload(); err != nil {
+		return r, err
+	}
+	r.reloader = startPeriodicJob(keyRingReloadRate, func() {
+		if err := r.Reload(); err != nil {
+			r.logError("Unable to reload the signing key ring: ", err)
+		}
+	})
+
+	return r, nil
+}
+
+// Reload loads the valid keys from the store and rotates the active key, if it's time to do it
+func (r *keyRing) Reload() error {
+	if r.repository == nil {
+		return nil
+	}
+
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	now := r.now()
+	stored, err := r.repository.FindValid(now)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*ringKey, len(stored))
+	var active *ringKey
+	for i := range stored {
+		key, err := parseSigningKey(&stored[i])
+		if err != nil {
+			r.logError(fmt.Sprintf("Unable to parse the signing key %s: ", stored[i].ID), err)
+			continue
+		}
+
+		keys[key.id] = key
+		// Keys are ordered from the newest, so the first active key of the configured algorithm signs tokens
+		if active == nil && stored[i].IsActive() && stored[i].Algorithm == r.algorithm {
+			active = key
+		}
+	}
+
+	if active == nil || (r.rotationInterval > 0 && now.Sub(active.createdAt) >= r.rotationInterval) {
+		newKey, err := r.rotate(now)
+		if err != nil {
+			return err
+		}
+		keys[newKey.id] = newKey
+		active = newKey
+	}
+
+	r.Lock()
+	r.keys = keys
+	r.active = active
+	r.lastReload = now
+	r.Unlock()
+
+	return nil
+}
+
+// rotate generates the new active key and retires the previous ones after the overlap window.
+//	Other replicas sign with the previous key until their next reload, so the window is extended by the reload rate.
+func (r *keyRing) rotate(now time.Time) (*ringKey, error) {
+	stored, err := generateSigningKey(r.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	stored.CreatedAt = now
+
+	if err := r.repository.Create(stored); err != nil {
+		return nil, err
+	}
+
+	if err := r.repository.RetireActive(stored.ID, now.Add(r.overlap+keyRingReloadRate)); err != nil {
+		return nil, err
+	}
+
+	if _, err := r.repository.DeleteRetired(now); err != nil {
+		return nil, err
+	}
+
+	if r.logger != nil {
+		r.logger.WithFields(logrus.Fields{
+			"kid":       stored.ID,
+			"algorithm": stored.Algorithm,
+		}).Info("The signing key was rotated")
+	}
+
+	return parseSigningKey(stored)
+}
+
+// Sign signs the claims with the active key and puts its id to the kid header
+func (r *keyRing) Sign(claims jwt.Claims) (string, error) {
+	r.RLock()
+	key := r.active
+	r.RUnlock()
+
+	if key == nil {
+		return "", service.ErrSigningKeyNotFound
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	if key.id != "" {
+		token.Header["kid"] = key.id
+	}
+
+	return token.SignedString(key.privateKey)
+}
+
+// Keyfunc returns the key the token was signed with. It's used as jwt.Keyfunc
+func (r *keyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := r.findKey(kid)
+	if !ok && kid != "" && r.reloadAfterMiss() {
+		key, ok = r.findKey(kid)
+	}
+	if !ok {
+		return nil, service.ErrSigningKeyNotFound
+	}
+
+	// The algorithm in the header must match the key, otherwise
+	//the public key could be used as the HMAC secret
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, service.ErrInvalidSigningMethod
+	}
+
+	return key.publicKey, nil
+}
+
+func (r *keyRing) findKey(kid string) (*ringKey, bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	key, ok := r.keys[kid]
+	return key, ok
+}
+
+// reloadAfterMiss reloads the ring, if a token signed by an unknown key was received.
+//	The key could be created by another replica after the last reload.
+func (r *keyRing) reloadAfterMiss() bool {
+	if r.repository == nil || r.algorithm == config.SigningAlgorithmHS256 {
+		return false
+	}
+
+	r.RLock()
+	lastReload := r.lastReload
+	r.RUnlock()
+
+	if r.now().Sub(lastReload) < keyRingMissReloadRate {
+		return false
+	}
+
+	if err := r.Reload(); err != nil {
+		r.logError("Unable to reload the signing key ring: ", err)
+		return false
+	}
+
+	return true
+}
+
+// JWKS returns public keys of the ring. The HMAC key is secret, so it isn't published
+func (r *keyRing) JWKS() models.JSONWebKeySet {
+	r.RLock()
+	defer r.RUnlock()
+
+	set := models.JSONWebKeySet{
+		Keys: make([]models.JSONWebKey, 0, len(r.keys)),
+	}
+	for _, key := range r.keys {
+		jwk := models.JSONWebKey{
+			Use:       "sig",
+			KeyID:     key.id,
+			Algorithm: key.method.Alg(),
+		}
+
+		switch publicKey := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+func (r *keyRing) Close() {
+	r.reloader.Stop()
+}
+
+func (r *keyRing) logError(msg string, err error) {
+	if r.logger != nil {
+		r.logger.Error(msg, err)
+	}
+}
+
+// generateSigningKey generates the new key pair and encodes the private key to PEM
+func generateSigningKey(algorithm string) (*models.SigningKey, error) {
+	var privateKey interface{}
+	var err error
+
+	switch algorithm {
+	case config.SigningAlgorithmRS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case config.SigningAlgorithmEdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SigningKey{
+		ID:         uuid.New().String(),
+		Algorithm:  algorithm,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+	}, nil
+}
+
+func parseSigningKey(k *models.SigningKey) (*ringKey, error) {
+	block, _ := pem.Decode([]byte(k.PrivateKey))
+	if block == nil {
+		return nil, errors.New("the private key isn't PEM encoded")
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &ringKey{
+		id:         k.ID,
+		privateKey: privateKey,
+		createdAt:  k.CreatedAt,
+	}
+
+	switch privateKey := privateKey.(type) {
+	case *rsa.PrivateKey:
+		key.method = jwt.SigningMethodRS256
+		key.publicKey = &privateKey.PublicKey
+	case ed25519.PrivateKey:
+		key.method = auth.SigningMethodEd25519
+		key.publicKey = privateKey.Public()
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+
+	if key.method.Alg() != k.Algorithm {
+		return nil, fmt.Errorf("the key algorithm %q doesn't match the private key", k.Algorithm)
+	}
+
+	return key, nil
+}
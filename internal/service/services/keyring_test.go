@@ -0,0 +1,153 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	cfg "backend/internal/config"
+	"backend/internal/service"
+	"backend/internal/store/teststore"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func testKeyRing(t *testing.T, algorithm string) (*keyRing, *teststore.Store) {
+	t.Helper()
+
+	config := cfg.NewConfig().Auth.JWT
+	config.SigningAlgorithm = algorithm
+
+	s := teststore.New()
+	r, err := newKeyRing(s.SigningKey(), config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(r.Close)
+
+	return r, s
+}
+
+func testAccessTokenClaims() *models.UAccessTokenClaims {
+	return &models.UAccessTokenClaims{
+		UserID: 1,
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: time.Now().Add(time.Minute).Unix(),
+		},
+	}
+}
+
+func TestKeyRing_SignAndVerify(t *testing.T) {
+	testCases := []struct {
+		algorithm string
+		keyType   string
+	}{
+		{algorithm: cfg.SigningAlgorithmRS256, keyType: "RSA"},
+		{algorithm: cfg.SigningAlgorithmEdDSA, keyType: "OKP"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.algorithm, func(t *testing.T) {
+			r, _ := testKeyRing(t, tc.algorithm)
+
+			signed, err := r.Sign(testAccessTokenClaims())
+			assert.NoError(t, err)
+
+			token, err := jwt.ParseWithClaims(signed, &models.UAccessTokenClaims{}, r.Keyfunc)
+			assert.NoError(t, err)
+			assert.True(t, token.Valid)
+			assert.Equal(t, tc.algorithm, token.Header["alg"])
+
+			jwks := r.JWKS()
+			if assert.Len(t, jwks.Keys, 1) {
+				assert.Equal(t, token.Header["kid"], jwks.Keys[0].KeyID)
+				assert.Equal(t, tc.keyType, jwks.Keys[0].KeyType)
+			}
+		})
+	}
+}
+
+func TestKeyRing_Rotation(t *testing.T) {
+	r, _ := testKeyRing(t, cfg.SigningAlgorithmEdDSA)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	oldToken, err := r.Sign(testAccessTokenClaims())
+	assert.NoError(t, err)
+
+	// The rotation is due: the new key signs, the old one still verifies
+	now = now.Add(r.rotationInterval)
+	assert.NoError(t, r.Reload())
+	assert.Len(t, r.JWKS().Keys, 2)
+
+	newToken, err := r.Sign(testAccessTokenClaims())
+	assert.NoError(t, err)
+
+	_, err = jwt.Parse(oldToken, r.Keyfunc)
+	assert.NoError(t, err)
+	_, err = jwt.Parse(newToken, r.Keyfunc)
+	assert.NoError(t, err)
+
+	// After the overlap window the old key is dropped
+	now = now.Add(r.overlap + keyRingReloadRate + time.Second)
+	assert.NoError(t, r.Reload())
+	assert.Len(t, r.JWKS().Keys, 1)
+
+	_, err = jwt.Parse(newToken, r.Keyfunc)
+	assert.NoError(t, err)
+	_, err = jwt.Parse(oldToken, r.Keyfunc)
+	assert.Error(t, err)
+}
+
+func TestKeyRing_SharedBetweenReplicas(t *testing.T) {
+	config := cfg.NewConfig().Auth.JWT
+	config.SigningAlgorithm = cfg.SigningAlgorithmRS256
+
+	s := teststore.New()
+	first, err := newKeyRing(s.SigningKey(), config, nil)
+	assert.NoError(t, err)
+	defer first.Close()
+	second, err := newKeyRing(s.SigningKey(), config, nil)
+	assert.NoError(t, err)
+	defer second.Close()
+
+	signed, err := first.Sign(testAccessTokenClaims())
+	assert.NoError(t, err)
+
+	_, err = jwt.Parse(signed, second.Keyfunc)
+	assert.NoError(t, err)
+}
+
+func TestKeyRing_RejectsAlgorithmConfusion(t *testing.T) {
+	r, _ := testKeyRing(t, cfg.SigningAlgorithmRS256)
+	kid := r.JWKS().Keys[0].KeyID
+
+	// The HMAC token with the kid of the RSA key must not be verified with the public key
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, testAccessTokenClaims())
+	token.Header["kid"] = kid
+	signed, err := token.SignedString([]byte("secret"))
+	assert.NoError(t, err)
+
+	_, err = jwt.Parse(signed, r.Keyfunc)
+	if assert.Error(t, err) {
+		assert.Equal(t, service.ErrInvalidSigningMethod, err.(*jwt.ValidationError).Inner)
+	}
+}
+
+func TestKeyRing_HS256(t *testing.T) {
+	r, s := testKeyRing(t, cfg.SigningAlgorithmHS256)
+
+	signed, err := r.Sign(testAccessTokenClaims())
+	assert.NoError(t, err)
+
+	// Tokens issued before the key ring have no kid and must still be accepted
+	token, err := jwt.Parse(signed, r.Keyfunc)
+	assert.NoError(t, err)
+	assert.Nil(t, token.Header["kid"])
+
+	// The shared secret is never published or stored
+	assert.Empty(t, r.JWKS().Keys)
+	keys, err := s.SigningKey().FindValid(time.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
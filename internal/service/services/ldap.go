@@ -0,0 +1,128 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"backend/pkg/ldapauth"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+// ldapIssuerPrefix prefixes the base DN in the issuer of the linked directory accounts
+const ldapIssuerPrefix = "ldap:"
+
+// ldapAuthenticator binds with the password against the directory.
+//	The directory account is linked or provisioned as the OIDC accounts are, the directory emails are trusted.
+type ldapAuthenticator struct {
+	auth   *AuthService
+	client *ldapauth.Client
+}
+
+func newLDAPAuthenticator(s *AuthService) *ldapAuthenticator {
+	cfg := s.service.config.Auth.LDAP
+	groupAttribute := ""
+	if cfg.GroupSync.Enabled {
+		groupAttribute = cfg.GroupSync.GroupAttribute
+	}
+
+	return &ldapAuthenticator{
+		auth: s,
+		client: ldapauth.New(ldapauth.Config{
+			URL:                cfg.URL,
+			StartTLS:           cfg.StartTLS,
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+			Timeout:            cfg.Timeout,
+			BindDN:             cfg.BindDN,
+			BindPassword:       cfg.BindPassword,
+			BaseDN:             cfg.BaseDN,
+			UserFilter:         cfg.UserFilter,
+			IDAttribute:        cfg.IDAttribute,
+			LoginAttribute:     cfg.LoginAttribute,
+			FullNameAttribute:  cfg.FullNameAttribute,
+			EmailAttribute:     cfg.EmailAttribute,
+			GroupAttribute:     groupAttribute,
+		}),
+	}
+}
+
+func (a *ldapAuthenticator) Name() string {
+	return "ldap"
+}
+
+func (a *ldapAuthenticator) Authenticate(signIn *models.UserSignIn, _ *models.User) (*models.User, error) {
+	cfg := a.auth.service.config.Auth.LDAP
+
+	username := signIn.Login
+	if username == "" {
+		username = signIn.Email
+	}
+
+	entry, err := a.client.Authenticate(username, signIn.Password)
+	if err == ldapauth.ErrInvalidCredentials {
+		return nil, service.ErrIncorrectLoginOrPassword
+	} else if err != nil {
+		return nil, err
+	}
+
+	user, err := a.auth.findOrLinkExternalUser(&externalAccount{
+		Issuer:            ldapIssuerPrefix + cfg.BaseDN,
+		Subject:           entry.ID,
+		Email:             entry.Email,
+		EmailVerified:     true,
+		Name:              entry.FullName,
+		PreferredUsername: entry.Login,
+	}, cfg.AutoProvision, cfg.LinkByEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.GroupSync.Enabled {
+		a.syncGroups(user, entry.Groups)
+	}
+
+	return user, nil
+}
+
+// syncGroups adds the user to the groups mapped to the directory groups of the entry.
+//	The memberships aren't removed, the sync errors don't fail the sign-in.
+func (a *ldapAuthenticator) syncGroups(user *models.User, directoryGroups []string) {
+	for _, mapping := range a.auth.service.config.Auth.LDAP.GroupSync.Groups {
+		if !containsFold(directoryGroups, mapping.DN) {
+			continue
+		}
+
+		if err := a.addGroupMember(user.ID, mapping.GroupID); err != nil {
+			a.auth.service.logger.WithFields(logrus.Fields{
+				"user_id":  user.ID,
+				"group_id": mapping.GroupID,
+			}).Error("Unable to sync the directory group: ", err)
+		}
+	}
+}
+
+func (a *ldapAuthenticator) addGroupMember(userID, groupID int) error {
+	isMember, err := a.auth.service.store.Group().IsUserGroupMember(userID, groupID)
+	if err != nil || isMember {
+		return err
+	}
+
+	exists, err := a.auth.service.store.Group().IsGroupExist(groupID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return service.ErrGroupNotFound
+	}
+
+	return a.auth.service.store.Group().AddGroupMember(userID, groupID, 0)
+}
+
+// containsFold reports whether the list contains the string, DNs are compared case-insensitively
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
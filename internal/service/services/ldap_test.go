@@ -0,0 +1,139 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	cfg "backend/internal/config"
+	"backend/internal/service"
+	"backend/pkg/ldapauth/ldaptest"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newLDAPTestService(t *testing.T) (*Service, *ldaptest.Server) {
+	t.Helper()
+
+	srv, err := ldaptest.NewServer(
+		ldaptest.Entry{DN: "cn=unitask,dc=university,dc=example", Password: "service"},
+		ldaptest.Entry{
+			DN:       "uid=student,ou=people,dc=university,dc=example",
+			Password: "directory-password",
+			Attributes: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"student"},
+				"cn":          {"Student Name"},
+				"mail":        {"student@university.example"},
+				"entryUUID":   {"4a1f3c2e-0001"},
+				"memberOf":    {"CN=Students,ou=groups,dc=university,dc=example"},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+
+	s := newTestService(t)
+	s.config.Auth.LDAP.Enabled = true
+	s.config.Auth.LDAP.URL = srv.URL
+	s.config.Auth.LDAP.BindDN = "cn=unitask,dc=university,dc=example"
+	s.config.Auth.LDAP.BindPassword = "service"
+	s.config.Auth.LDAP.BaseDN = "ou=people,dc=university,dc=example"
+	s.config.Auth.LDAP.IDAttribute = "entryUUID"
+	return s, srv
+}
+
+func TestAuthService_UserSignIn_LDAP(t *testing.T) {
+	s, _ := newLDAPTestService(t)
+
+	token, _, err := s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: "student", Password: "directory-password", IP: "127.0.0.1"})
+	assert.NoError(t, err)
+	if !assert.NotNil(t, token) {
+		return
+	}
+
+	u, err := s.User().Find(token.UserID)
+	assert.NoError(t, err)
+	assert.Equal(t, "student", u.Login)
+	assert.Equal(t, "Student Name", u.FullName)
+	assert.Equal(t, "student@university.example", u.Email)
+	assert.True(t, u.IsEmailConfirmed())
+
+	identities, err := s.Auth().GetExternalIdentities(u.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, identities, 1) {
+		assert.Equal(t, "ldap:ou=people,dc=university,dc=example", identities[0].Issuer)
+		assert.Equal(t, "4a1f3c2e-0001", identities[0].Subject)
+	}
+
+	// The next sign-in by the email finds the provisioned user
+	token, _, err = s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Email: "student@university.example", Password: "directory-password", IP: "127.0.0.1"})
+	assert.NoError(t, err)
+	if assert.NotNil(t, token) {
+		assert.Equal(t, u.ID, token.UserID)
+	}
+
+	_, _, err = s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: "student", Password: "wrong", IP: "127.0.0.1"})
+	assert.Equal(t, service.ErrIncorrectLoginOrPassword, err)
+
+	// The local users are still authenticated with the local password
+	local := models.TestUser(t)
+	password := local.Password
+	assert.NoError(t, s.Auth().RegisterUser(local))
+	token, _, err = s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: local.Login, Password: password, IP: "127.0.0.1"})
+	assert.NoError(t, err)
+	if assert.NotNil(t, token) {
+		assert.Equal(t, local.ID, token.UserID)
+	}
+}
+
+func TestAuthService_UserSignIn_LDAPUnavailable(t *testing.T) {
+	s, srv := newLDAPTestService(t)
+	local := models.TestUser(t)
+	password := local.Password
+	assert.NoError(t, s.Auth().RegisterUser(local))
+	srv.Close()
+
+	// The credentials rejected by the local check are incorrect, the failure of the directory isn't shown
+	_, _, err := s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: "student", Password: "directory-password", IP: "127.0.0.1"})
+	assert.Equal(t, service.ErrIncorrectLoginOrPassword, err)
+	_, _, err = s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: local.Login, Password: "wrong password", IP: "127.0.0.2"})
+	assert.Equal(t, service.ErrIncorrectLoginOrPassword, err)
+
+	// The attempts are throttled even while the directory is unavailable
+	attempts, err := s.authService.throttle.repository.Find(loginAttemptsIPKey("127.0.0.1"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempts.Failures)
+	attempts, err = s.authService.throttle.repository.Find(loginAttemptsAccountKey(local.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempts.Failures)
+
+	// The local users don't depend on the directory
+	_, _, err = s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: local.Login, Password: password, IP: "127.0.0.3"})
+	assert.NoError(t, err)
+}
+
+func TestAuthService_UserSignIn_LDAPGroupSync(t *testing.T) {
+	s, _ := newLDAPTestService(t)
+
+	g := models.TestGroup(t)
+	assert.NoError(t, s.store.Group().Create(g))
+
+	s.config.Auth.LDAP.GroupSync.Enabled = true
+	s.config.Auth.LDAP.GroupSync.Groups = []cfg.LDAPGroupMapping{
+		{DN: "cn=students,ou=groups,dc=university,dc=example", GroupID: g.ID},
+		{DN: "cn=staff,ou=groups,dc=university,dc=example", GroupID: g.ID + 1},
+	}
+
+	for i := 0; i < 2; i++ {
+		token, _, err := s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: "student", Password: "directory-password", IP: "127.0.0.1"})
+		assert.NoError(t, err)
+		if !assert.NotNil(t, token) {
+			return
+		}
+
+		isMember, err := s.Group().IsUserGroupMember(token.UserID, g.ID)
+		assert.NoError(t, err)
+		assert.True(t, isMember)
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/config"
+	"backend/internal/service"
+	"backend/internal/store"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// loginThrottle slows down password guessing.
+//	Failed sign-ins are counted per account and per IP address. After the free attempts every next attempt
+//	is accepted only after the exponential delay, and after the threshold the key is locked for a while.
+//	Counters are kept in the store, so they are shared between API server replicas.
+type loginThrottle struct {
+	repository store.LoginAttemptRepository
+	config     config.LoginThrottleConfig
+	logger     *logrus.Logger
+	now        func() time.Time
+
+	janitor *periodicJob
+}
+
+func newLoginThrottle(repository store.LoginAttemptRepository, cfg config.LoginThrottleConfig, logger *logrus.Logger) *loginThrottle {
+	t := &loginThrottle{
+		repository: repository,
+		config:     cfg,
+		logger:     logger,
+		now:        time.Now,
+	}
+	if !cfg.Enabled {
+		return t
+	}
+
+	t.janitor = startPeriodicJob(cfg.FailureWindow, func() {
+		now := t.now()
+		if _, err := t.repository.DeleteStale(now.Add(-t.config.FailureWindow), now); err != nil && t.logger != nil {
+			t.logger.Error("Unable to delete stale login attempts: ", err)
+		}
+	})
+
+	return t
+}
+
+func loginAttemptsAccountKey(userID int) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+func loginAttemptsIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+func isIPKey(key string) bool {
+	return strings.HasPrefix(key, loginAttemptsIPKey(""))
+}
+
+// check returns *service.ThrottledError, if the next attempt of the key isn't accepted yet
+func (t *loginThrottle) check(key string) error {
+	if !t.config.Enabled {
+		return nil
+	}
+
+	a, err := t.repository.Find(key)
+	if err == store.ErrRecordNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	now := t.now()
+	if a.IsLocked(now) {
+		return &service.ThrottledError{Err: t.lockError(key), RetryAfter: *a.LockedUntil}
+	}
+
+	if now.Sub(a.LastFailureAt) >= t.config.FailureWindow {
+		return nil
+	}
+
+	retryAfter := a.LastFailureAt.Add(t.delay(a.Failures))
+	if now.Before(retryAfter) {
+		return &service.ThrottledError{Err: service.ErrTooManySignInAttempts, RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// fail counts the failed attempt and locks the key, if the threshold was reached.
+//	The threshold isn't checked, if it isn't positive.
+func (t *loginThrottle) fail(key string) error {
+	if !t.config.Enabled {
+		return nil
+	}
+
+	now := t.now()
+	a, err := t.repository.AddFailure(key, now, now.Add(-t.config.FailureWindow))
+	if err != nil {
+		return err
+	}
+
+	lockoutThreshold := t.config.AccountLockoutThreshold
+	if isIPKey(key) {
+		lockoutThreshold = t.config.IPLockoutThreshold
+	}
+	if lockoutThreshold <= 0 || a.Failures < lockoutThreshold {
+		return nil
+	}
+
+	if t.logger != nil {
+		t.logger.WithFields(logrus.Fields{
+			"key":      key,
+			"failures": a.Failures,
+		}).Warn("Sign-in is locked because of too many failed attempts")
+	}
+
+	return t.repository.Lock(key, now.Add(t.config.LockoutDuration))
+}
+
+// reset forgets failed attempts and the lock of the key
+func (t *loginThrottle) reset(key string) error {
+	return t.repository.Delete(key)
+}
+
+// delay returns how long to wait after the last failure before the next attempt
+func (t *loginThrottle) delay(failures int) time.Duration {
+	if failures < t.config.FreeAttempts {
+		return 0
+	}
+
+	delay := t.config.BaseDelay
+	for i := t.config.FreeAttempts; i < failures && delay < t.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > t.config.MaxDelay {
+		delay = t.config.MaxDelay
+	}
+
+	return delay
+}
+
+func (t *loginThrottle) lockError(key string) error {
+	if isIPKey(key) {
+		return service.ErrTooManySignInAttempts
+	}
+
+	return service.ErrAccountIsLocked
+}
+
+func (t *loginThrottle) Close() {
+	t.janitor.Stop()
+}
+
+// UnlockUser removes the lock and forgets failed sign-in attempts of the user
+func (s *AuthService) UnlockUser(userID int) error {
+	if _, err := s.service.store.User().Find(userID); err == store.ErrRecordNotFound {
+		return service.ErrUserNotFound
+	} else if err != nil {
+		return err
+	}
+
+	return s.throttle.reset(loginAttemptsAccountKey(userID))
+}
+
+// failSignIn counts the failed sign-in of the account (if it's known) and of the IP address
+func (s *AuthService) failSignIn(user *models.User, ip string) {
+	var err error
+	if user != nil {
+		err = s.throttle.fail(loginAttemptsAccountKey(user.ID))
+	}
+	if ip != "" {
+		if ipErr := s.throttle.fail(loginAttemptsIPKey(ip)); ipErr != nil {
+			err = ipErr
+		}
+	}
+
+	if err != nil && s.service.logger != nil {
+		s.service.logger.Error("Unable to count the failed sign-in attempt: ", err)
+	}
+}
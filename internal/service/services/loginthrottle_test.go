@@ -0,0 +1,143 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// newThrottledTestService returns the service with the fake clock of the login throttle
+func newThrottledTestService(t *testing.T) (*Service, *time.Time) {
+	t.Helper()
+
+	s := newTestService(t)
+	s.Auth()
+
+	now := time.Now()
+	s.authService.throttle.now = func() time.Time {
+		return now
+	}
+
+	return s, &now
+}
+
+func TestLoginThrottle_Delay(t *testing.T) {
+	s, _ := newThrottledTestService(t)
+	throttle := s.authService.throttle
+	throttle.config.FreeAttempts = 3
+	throttle.config.BaseDelay = time.Second
+	throttle.config.MaxDelay = 10 * time.Second
+
+	testCases := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{failures: 0, expected: 0},
+		{failures: 2, expected: 0},
+		{failures: 3, expected: time.Second},
+		{failures: 4, expected: 2 * time.Second},
+		{failures: 6, expected: 8 * time.Second},
+		{failures: 7, expected: 10 * time.Second},
+		{failures: 100, expected: 10 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, throttle.delay(tc.failures), tc.failures)
+	}
+}
+
+func TestAuthService_UserSignIn_Throttled(t *testing.T) {
+	s, now := newThrottledTestService(t)
+	config := s.config.Auth.LoginThrottle
+	token := signInTestUser(t, s)
+
+	wrong := &models.UserSignIn{Login: "UserExample", Password: "wrong password", IP: "10.0.0.1"}
+	correct := &models.UserSignIn{Login: "UserExample", Password: "password", IP: "10.0.0.1"}
+
+	for i := 0; i < config.FreeAttempts; i++ {
+		_, _, err := s.Auth().UserSignIn(context.Background(), wrong)
+		assert.Equal(t, service.ErrIncorrectLoginOrPassword, err)
+	}
+
+	// The correct password isn't checked until the delay is over
+	_, _, err := s.Auth().UserSignIn(context.Background(), correct)
+	assert.True(t, errors.Is(err, service.ErrTooManySignInAttempts))
+	var throttled *service.ThrottledError
+	assert.True(t, errors.As(err, &throttled))
+	assert.Equal(t, now.Add(config.BaseDelay), throttled.RetryAfter)
+
+	*now = now.Add(config.BaseDelay)
+	_, _, err = s.Auth().UserSignIn(context.Background(), correct)
+	assert.NoError(t, err)
+
+	// The successful sign-in resets the account counter, but not the IP one
+	_, _, err = s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: "UserExample", Password: "password", IP: "10.0.0.2"})
+	assert.NoError(t, err)
+
+	_, err = s.authService.throttle.repository.Find(loginAttemptsAccountKey(token.UserID))
+	assert.Error(t, err)
+	attempts, err := s.authService.throttle.repository.Find(loginAttemptsIPKey("10.0.0.1"))
+	assert.NoError(t, err)
+	assert.Equal(t, config.FreeAttempts, attempts.Failures)
+}
+
+func TestAuthService_UserSignIn_Lockout(t *testing.T) {
+	s, now := newThrottledTestService(t)
+	config := s.config.Auth.LoginThrottle
+	token := signInTestUser(t, s)
+
+	// Every attempt comes from another IP, so only the account is locked
+	for i := 0; i < config.AccountLockoutThreshold; i++ {
+		_, _, err := s.Auth().UserSignIn(context.Background(), &models.UserSignIn{
+			Login:    "UserExample",
+			Password: "wrong password",
+			IP:       "10.0.1." + string(rune('a'+i)),
+		})
+		assert.Equal(t, service.ErrIncorrectLoginOrPassword, err)
+		*now = now.Add(config.MaxDelay)
+	}
+
+	correct := &models.UserSignIn{Login: "UserExample", Password: "password", IP: "10.0.2.1"}
+	_, _, err := s.Auth().UserSignIn(context.Background(), correct)
+	assert.True(t, errors.Is(err, service.ErrAccountIsLocked))
+
+	// The lock expires
+	*now = now.Add(config.LockoutDuration)
+	_, _, err = s.Auth().UserSignIn(context.Background(), correct)
+	assert.NoError(t, err)
+
+	// Or the administrator removes it
+	for i := 0; i < config.AccountLockoutThreshold; i++ {
+		_, _, _ = s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: "UserExample", Password: "wrong password"})
+		*now = now.Add(config.MaxDelay)
+	}
+	_, _, err = s.Auth().UserSignIn(context.Background(), correct)
+	assert.True(t, errors.Is(err, service.ErrAccountIsLocked))
+
+	assert.NoError(t, s.Auth().UnlockUser(token.UserID))
+	assert.Equal(t, service.ErrUserNotFound, s.Auth().UnlockUser(token.UserID+100))
+	_, _, err = s.Auth().UserSignIn(context.Background(), correct)
+	assert.NoError(t, err)
+}
+
+func TestAuthService_UserSignIn_IPLockout(t *testing.T) {
+	s, _ := newThrottledTestService(t)
+	s.authService.throttle.config.IPLockoutThreshold = 2
+	signInTestUser(t, s)
+
+	// Unknown logins are counted for the IP address
+	for i := 0; i < 2; i++ {
+		_, _, err := s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: "unknown", Password: "password", IP: "10.0.0.1"})
+		assert.Equal(t, service.ErrIncorrectLoginOrPassword, err)
+	}
+
+	_, _, err := s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: "UserExample", Password: "password", IP: "10.0.0.1"})
+	assert.True(t, errors.Is(err, service.ErrTooManySignInAttempts))
+
+	_, _, err = s.Auth().UserSignIn(context.Background(), &models.UserSignIn{Login: "UserExample", Password: "password", IP: "10.0.0.2"})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"backend/internal/store"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+const (
+	appSecretBytes = 32
+	appTokenBytes  = 32
+)
+
+// IssueClientCredentialsToken issues the app token by the OAuth2 client credentials grant (RFC 6749, section 4.4).
+//	The scope must be a subset of the scope of the app, all scopes of the app are granted if it's empty.
+//	Returns service.ErrAppAuthorization if the client is unknown or the secret is incorrect
+//	and service.ErrInvalidAppScope if the scope isn't allowed.
+func (s *AuthService) IssueClientCredentialsToken(appID, appSecret, scope string) (*models.AppToken, error) {
+	appUUID, err := uuid.Parse(appID)
+	if err != nil {
+		return nil, service.ErrAppAuthorization
+	}
+
+	app, err := s.service.store.Auth().GetApp(appUUID)
+	if err == store.ErrRecordNotFound {
+		return nil, service.ErrAppAuthorization
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !isAppSecretValid(app, appSecret) {
+		return nil, service.ErrAppAuthorization
+	}
+
+	scope, err = normalizeAppScope(scope, app.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	token := &models.AppToken{
+		AppID:               app.ID,
+		IssueTokenTimestamp: now,
+		StartTimestamp:      now,
+		ExpirationTimestamp: now.Add(s.service.config.Auth.OAuth.TokenTTL),
+		Scope:               scope,
+	}
+	token.AppToken, err = s.GenerateAppToken(app, token.StartTimestamp, token.ExpirationTimestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.service.store.Auth().AddAppToken(token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// normalizeAppScope checks that the requested scopes are known and allowed
+//and returns them space-delimited without duplicates. Empty scope means all allowed scopes.
+func normalizeAppScope(scope, allowedScope string) (string, error) {
+	allowed := models.ParseScope(allowedScope)
+	requested := models.ParseScope(scope)
+	if len(requested) == 0 {
+		requested = allowed
+	}
+
+	result := make([]string, 0, len(requested))
+	for _, s := range requested {
+		if !containsString(models.AppScopes, s) || !containsString(allowed, s) {
+			return "", service.ErrInvalidAppScope
+		}
+		if !containsString(result, s) {
+			result = append(result, s)
+		}
+	}
+
+	return strings.Join(result, " "), nil
+}
+
+func isAppSecretValid(app *models.RegisteredApp, appSecret string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashToken(appSecret)), []byte(app.SecretHash)) == 1
+}
+
+func generateAppSecret() (string, error) {
+	return generateRandomToken(appSecretBytes)
+}
+
+// generateRandomToken returns n random bytes encoded with the URL-safe base64
+func generateRandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAuthService_IssueClientCredentialsToken(t *testing.T) {
+	s := newTestService(t)
+
+	app := &models.RegisteredApp{AppName: "test app", Scope: models.AppScopeAuth}
+	assert.NoError(t, s.Auth().RegisterApp(context.Background(), app))
+	assert.NotEmpty(t, app.AppSecret)
+
+	// Only the hash of the secret is stored
+	stored, err := s.store.Auth().GetApp(app.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, stored.AppSecret)
+	assert.NotEqual(t, app.AppSecret, stored.SecretHash)
+
+	token, err := s.Auth().IssueClientCredentialsToken(app.ID.String(), app.AppSecret, "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.AppScopeAuth, token.Scope)
+	assert.Equal(t, s.config.Auth.OAuth.TokenTTL, token.ExpirationTimestamp.Sub(token.StartTimestamp))
+
+	ok, err := s.Auth().IsAppTokenValid(token.AppToken)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = s.Auth().IssueClientCredentialsToken(app.ID.String(), "wrong secret", "")
+	assert.Equal(t, service.ErrAppAuthorization, err)
+	_, err = s.Auth().IssueClientCredentialsToken("not uuid", app.AppSecret, "")
+	assert.Equal(t, service.ErrAppAuthorization, err)
+	_, err = s.Auth().IssueClientCredentialsToken(app.ID.String(), app.AppSecret, models.AppScopeAPI)
+	assert.Equal(t, service.ErrInvalidAppScope, err)
+
+	assert.Equal(t, service.ErrInvalidAppScope, s.Auth().RegisterApp(context.Background(), &models.RegisteredApp{AppName: "other", Scope: "unknown"}))
+}
+
+func TestAuthService_IssueClientCredentialsToken_Expiration(t *testing.T) {
+	s := newTestService(t)
+	s.config.Auth.OAuth.TokenTTL = time.Millisecond
+
+	app := &models.RegisteredApp{AppName: "test app"}
+	assert.NoError(t, s.Auth().RegisterApp(context.Background(), app))
+	assert.Equal(t, "auth api", app.Scope)
+
+	token, err := s.Auth().IssueClientCredentialsToken(app.ID.String(), app.AppSecret, "api api")
+	assert.NoError(t, err)
+	assert.Equal(t, models.AppScopeAPI, token.Scope)
+
+	time.Sleep(5 * time.Millisecond)
+	ok, err := s.Auth().IsAppTokenValid(token.AppToken)
+	assert.Equal(t, service.ErrInvalidAppToken, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"backend/internal/store"
+	"backend/pkg/oidc"
+	"context"
+	"crypto/rand"
+	"github.com/sirupsen/logrus"
+	"math/big"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	oidcStateBytes = 32
+	// oidcLoginAttempts is how many logins with a random suffix are tried, if the login from the provider is occupied
+	oidcLoginAttempts  = 10
+	oidcMaxLoginLength = 32
+)
+
+// OIDCAuthorization creates the state of the sign-in and returns the provider URL with the PKCE code challenge.
+//	The state, the code verifier and the nonce are stored, only the state is given to the client.
+func (s *AuthService) OIDCAuthorization() (*models.OIDCAuthorization, error) {
+	if s.oidc == nil {
+		return nil, service.ErrOIDCIsDisabled
+	}
+
+	state, err := generateRandomToken(oidcStateBytes)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := generateRandomToken(oidcStateBytes)
+	if err != nil {
+		return nil, err
+	}
+	codeVerifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := s.oidc.AuthCodeURL(state, nonce, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.service.store.OIDC().DeleteExpiredLoginStates(now); err != nil {
+		return nil, err
+	}
+
+	loginState := &models.OIDCLoginState{
+		StateHash:    hashToken(state),
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(s.service.config.Auth.OIDC.StateTTL),
+	}
+	if err := s.service.store.OIDC().CreateLoginState(loginState); err != nil {
+		return nil, err
+	}
+
+	return &models.OIDCAuthorization{
+		URL:       url,
+		State:     state,
+		ExpiresAt: loginState.ExpiresAt,
+	}, nil
+}
+
+// OIDCSignIn completes the sign-in with the provider and opens the session.
+//	The provider replaces only the password, the user with 2FA enabled gets the MFA challenge as in UserSignIn.
+func (s *AuthService) OIDCSignIn(ctx context.Context, signIn *models.OIDCSignIn) (*models.UserToken, *models.MFAChallenge, error) {
+	if s.oidc == nil {
+		return nil, nil, service.ErrOIDCIsDisabled
+	}
+	if signIn.State == "" || signIn.Code == "" {
+		return nil, nil, service.ErrInvalidOIDCState
+	}
+
+	// The state is single-use, so the code can't be replayed with it
+	loginState, err := s.service.store.OIDC().UseLoginState(hashToken(signIn.State), time.Now())
+	if err == store.ErrRecordNotFound {
+		return nil, nil, service.ErrInvalidOIDCState
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	token, err := s.oidc.Exchange(signIn.Code, loginState.CodeVerifier)
+	if err != nil {
+		s.service.logger.Warn("OIDC code exchange failed: ", err)
+		return nil, nil, service.ErrOIDCSignInFailed
+	}
+
+	claims, err := s.oidc.VerifyIDToken(token.IDToken, loginState.Nonce)
+	if err != nil {
+		s.service.logger.Warn("OIDC ID token is rejected: ", err)
+		return nil, nil, service.ErrOIDCSignInFailed
+	}
+
+	cfg := s.service.config.Auth.OIDC
+	user, err := s.findOrLinkExternalUser(&externalAccount{
+		Issuer:            s.oidc.Issuer(),
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		EmailVerified:     claims.EmailVerified,
+		Name:              claims.Name,
+		PreferredUsername: claims.PreferredUsername,
+	}, cfg.AutoProvision, cfg.LinkByEmail)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s.completeSignIn(ctx, user, signIn.DeviceName, signIn.UserAgent, signIn.IP)
+}
+
+func (s *AuthService) GetExternalIdentities(userID int) ([]models.ExternalIdentity, error) {
+	identities, err := s.service.store.OIDC().FindIdentitiesByUser(userID)
+	if err != nil && err != store.ErrRecordNotFound {
+		return nil, err
+	}
+	if identities == nil {
+		identities = []models.ExternalIdentity{}
+	}
+
+	return identities, nil
+}
+
+// externalAccount is the account of the identity provider or the directory
+type externalAccount struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+// findOrLinkExternalUser returns the user linked to the subject.
+//	On the first sign-in the subject is linked to the user with the same verified email (if linkByEmail is set),
+//	otherwise the user is created (if autoProvision is set).
+func (s *AuthService) findOrLinkExternalUser(account *externalAccount, autoProvision, linkByEmail bool) (*models.User, error) {
+	now := time.Now()
+
+	identity, err := s.service.store.OIDC().FindIdentity(account.Issuer, account.Subject)
+	if err == nil {
+		if err := s.service.store.OIDC().UpdateIdentityLastLogin(identity.ID, now); err != nil {
+			return nil, err
+		}
+		return s.service.User().Find(identity.UserID)
+	} else if err != store.ErrRecordNotFound {
+		return nil, err
+	}
+
+	var user *models.User
+	if account.Email != "" {
+		user, err = s.service.User().FindByEmail(account.Email)
+		if err != nil && err != service.ErrUserNotFound {
+			return nil, err
+		}
+	}
+
+	switch {
+	case user != nil && linkByEmail && account.EmailVerified:
+		if !user.IsEmailConfirmed() {
+			if err := s.service.store.User().ConfirmEmail(user.ID, now); err != nil {
+				return nil, err
+			}
+			user.EmailConfirmedAt = &now
+		}
+	case user != nil:
+		return nil, service.ErrEmailIsAlreadyOccupied
+	case !autoProvision:
+		return nil, service.ErrExternalAccountIsNotLinked
+	default:
+		user, err = s.provisionExternalUser(account)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	identity = &models.ExternalIdentity{
+		UserID:      user.ID,
+		Issuer:      account.Issuer,
+		Subject:     account.Subject,
+		Email:       account.Email,
+		CreatedAt:   now,
+		LastLoginAt: &now,
+	}
+	if err := s.service.store.OIDC().CreateIdentity(identity); err != nil {
+		return nil, err
+	}
+
+	s.service.logger.WithFields(logrus.Fields{
+		"user_id": user.ID,
+		"issuer":  identity.Issuer,
+	}).Info("The identity provider account was linked")
+
+	return user, nil
+}
+
+// provisionExternalUser creates the user from the external account.
+//	The user gets the random password, it can be changed with the password reset.
+func (s *AuthService) provisionExternalUser(account *externalAccount) (*models.User, error) {
+	if account.Email == "" {
+		return nil, service.ErrInvalidUserEmail
+	}
+
+	password, err := generateRandomToken(appSecretBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	login, err := s.externalUserLogin(account)
+	if err != nil {
+		return nil, err
+	}
+
+	fullName := strings.TrimSpace(account.Name)
+	if fullName == "" {
+		fullName = login
+	}
+
+	user := &models.User{
+		Login:    login,
+		FullName: fullName,
+		Email:    account.Email,
+		Password: password,
+	}
+	if err := user.Validate(); err != nil {
+		return nil, err
+	}
+	if err := user.BeforeCreate(); err != nil {
+		return nil, err
+	}
+	if err := s.service.store.User().Create(user); err != nil {
+		return nil, err
+	}
+	user.Sanitize()
+
+	if account.EmailVerified {
+		now := time.Now()
+		if err := s.service.store.User().ConfirmEmail(user.ID, now); err != nil {
+			return nil, err
+		}
+		user.EmailConfirmedAt = &now
+	} else if err := s.sendEmailConfirmation(user); err != nil {
+		s.service.logger.WithFields(logrus.Fields{
+			"user_id": user.ID,
+		}).Error("Unable to send the email confirmation: ", err)
+	}
+
+	return user, nil
+}
+
+// externalUserLogin returns the free login made from the preferred username or the email of the user.
+//	A random suffix is added, if the login is occupied.
+func (s *AuthService) externalUserLogin(account *externalAccount) (string, error) {
+	base := sanitizeLogin(account.PreferredUsername)
+	if len(base) < 2 {
+		base = sanitizeLogin(strings.SplitN(account.Email, "@", 2)[0])
+	}
+	if len(base) < 2 {
+		base = "user"
+	}
+
+	login := base
+	for i := 0; i < oidcLoginAttempts; i++ {
+		_, err := s.service.User().FindByLogin(login)
+		if err == service.ErrUserNotFound {
+			return login, nil
+		} else if err != nil {
+			return "", err
+		}
+
+		n, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		suffix := "_" + n.String()
+		if len(base)+len(suffix) > oidcMaxLoginLength {
+			base = base[:oidcMaxLoginLength-len(suffix)]
+		}
+		login = base + suffix
+	}
+
+	return "", service.ErrLoginIsAlreadyOccupied
+}
+
+// sanitizeLogin keeps ASCII letters, digits, dots, dashes and underscores
+func sanitizeLogin(s string) string {
+	b := strings.Builder{}
+	for _, r := range s {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-' || r == '_') {
+			b.WriteRune(r)
+		}
+		if b.Len() == oidcMaxLoginLength {
+			break
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"backend/pkg/oidc/oidctest"
+	"backend/pkg/totp"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newOIDCTestService(t *testing.T) (*Service, *oidctest.Provider) {
+	t.Helper()
+
+	p, err := oidctest.NewProvider("unitask", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+
+	s := newTestService(t)
+	s.config.Auth.OIDC.Enabled = true
+	s.config.Auth.OIDC.Issuer = p.Issuer()
+	s.config.Auth.OIDC.ClientID = "unitask"
+	s.config.Auth.OIDC.ClientSecret = "secret"
+	s.config.Auth.OIDC.RedirectURL = "http://localhost:3000/login/oidc"
+	return s, p
+}
+
+// oidcSignIn goes through the authorization code flow as the identity
+func oidcSignIn(t *testing.T, s *Service, p *oidctest.Provider, identity oidctest.Identity) (*models.UserToken, *models.MFAChallenge, error) {
+	t.Helper()
+
+	authorization, err := s.Auth().OIDCAuthorization()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state, err := p.Authorize(authorization.URL, identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, authorization.State, state)
+
+	return s.Auth().OIDCSignIn(context.Background(), &models.OIDCSignIn{Code: code, State: state, IP: "127.0.0.1"})
+}
+
+func TestAuthService_OIDCSignIn_AutoProvision(t *testing.T) {
+	s, p := newOIDCTestService(t)
+	identity := oidctest.Identity{
+		Subject:           "s-1",
+		Email:             "student@university.example",
+		EmailVerified:     true,
+		Name:              "Student Name",
+		PreferredUsername: "student",
+	}
+
+	token, _, err := oidcSignIn(t, s, p, identity)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token.AccessToken)
+
+	u, err := s.User().Find(token.UserID)
+	assert.NoError(t, err)
+	assert.Equal(t, "student", u.Login)
+	assert.Equal(t, "Student Name", u.FullName)
+	assert.True(t, u.IsEmailConfirmed())
+
+	identities, err := s.Auth().GetExternalIdentities(u.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, identities, 1) {
+		assert.Equal(t, p.Issuer(), identities[0].Issuer)
+		assert.Equal(t, "s-1", identities[0].Subject)
+	}
+
+	// The next sign-in finds the linked user even if the email was changed at the provider
+	identity.Email = "renamed@university.example"
+	token, _, err = oidcSignIn(t, s, p, identity)
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, token.UserID)
+
+	// The occupied login gets the suffix
+	token, _, err = oidcSignIn(t, s, p, oidctest.Identity{Subject: "s-2", Email: "other@university.example", PreferredUsername: "student"})
+	assert.NoError(t, err)
+	other, err := s.User().Find(token.UserID)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "student", other.Login)
+	assert.Contains(t, other.Login, "student_")
+	assert.False(t, other.IsEmailConfirmed())
+}
+
+func TestAuthService_OIDCSignIn_ExistingUser(t *testing.T) {
+	s, p := newOIDCTestService(t)
+	u := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(u))
+	identity := oidctest.Identity{Subject: "s-1", Email: u.Email, EmailVerified: true}
+
+	_, _, err := oidcSignIn(t, s, p, identity)
+	assert.Equal(t, service.ErrEmailIsAlreadyOccupied, err)
+
+	s.config.Auth.OIDC.LinkByEmail = true
+	// Unverified emails aren't trusted for linking
+	_, _, err = oidcSignIn(t, s, p, oidctest.Identity{Subject: "s-1", Email: u.Email})
+	assert.Equal(t, service.ErrEmailIsAlreadyOccupied, err)
+
+	token, _, err := oidcSignIn(t, s, p, identity)
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, token.UserID)
+
+	s.config.Auth.OIDC.AutoProvision = false
+	_, _, err = oidcSignIn(t, s, p, oidctest.Identity{Subject: "s-2", Email: "new@university.example", EmailVerified: true})
+	assert.Equal(t, service.ErrExternalAccountIsNotLinked, err)
+}
+
+func TestAuthService_OIDCSignIn_InvalidState(t *testing.T) {
+	s, p := newOIDCTestService(t)
+
+	authorization, err := s.Auth().OIDCAuthorization()
+	assert.NoError(t, err)
+	code, state, err := p.Authorize(authorization.URL, oidctest.Identity{Subject: "s-1", Email: "student@university.example"})
+	assert.NoError(t, err)
+
+	_, _, err = s.Auth().OIDCSignIn(context.Background(), &models.OIDCSignIn{Code: code, State: "forged", IP: "127.0.0.1"})
+	assert.Equal(t, service.ErrInvalidOIDCState, err)
+
+	_, _, err = s.Auth().OIDCSignIn(context.Background(), &models.OIDCSignIn{Code: "forged", State: state, IP: "127.0.0.1"})
+	assert.Equal(t, service.ErrOIDCSignInFailed, err)
+
+	// The state is single-use
+	_, _, err = s.Auth().OIDCSignIn(context.Background(), &models.OIDCSignIn{Code: code, State: state, IP: "127.0.0.1"})
+	assert.Equal(t, service.ErrInvalidOIDCState, err)
+}
+
+func TestAuthService_OIDCSignIn_Disabled(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.Auth().OIDCAuthorization()
+	assert.Equal(t, service.ErrOIDCIsDisabled, err)
+	_, _, err = s.Auth().OIDCSignIn(context.Background(), &models.OIDCSignIn{Code: "code", State: "state"})
+	assert.Equal(t, service.ErrOIDCIsDisabled, err)
+}
+
+func TestAuthService_OIDCSignIn_TwoFactor(t *testing.T) {
+	s, p := newOIDCTestService(t)
+	s.config.Auth.OIDC.LinkByEmail = true
+	u := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(u))
+
+	enrollment, err := s.Auth().EnrollTOTP(u.ID)
+	assert.NoError(t, err)
+	step := totp.Step(time.Now())
+	code, err := totp.Code(enrollment.Secret, step-1)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Auth().ConfirmTOTP(context.Background(), u.ID, code))
+
+	// The provider replaces only the password, the linked user still needs the second factor
+	token, challenge, err := oidcSignIn(t, s, p, oidctest.Identity{Subject: "s-1", Email: u.Email, EmailVerified: true})
+	assert.NoError(t, err)
+	assert.Nil(t, token)
+	if !assert.NotNil(t, challenge) {
+		return
+	}
+
+	code, err = totp.Code(enrollment.Secret, step)
+	assert.NoError(t, err)
+	token, err = s.Auth().CompleteMFASignIn(context.Background(), &models.MFASignIn{ChallengeToken: challenge.Token, Code: code})
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, token.UserID)
+}
+
+func TestAuthService_OIDCSignIn_EmailConfirmationRequired(t *testing.T) {
+	s, p := newOIDCTestService(t)
+	s.config.Auth.EmailConfirmation.RequiredToSignIn = true
+
+	_, _, err := oidcSignIn(t, s, p, oidctest.Identity{Subject: "s-1", Email: "student@university.example", PreferredUsername: "student"})
+	assert.Equal(t, service.ErrEmailIsNotConfirmed, err)
+
+	token, _, err := oidcSignIn(t, s, p, oidctest.Identity{Subject: "s-2", Email: "other@university.example", EmailVerified: true})
+	assert.NoError(t, err)
+	assert.NotNil(t, token)
+}
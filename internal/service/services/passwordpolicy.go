@@ -0,0 +1,124 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/config"
+	"backend/internal/service"
+	"backend/pkg/breached"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minPersonalInfoLength is the length of the shortest login or word of the name that isn't allowed in the password
+const minPersonalInfoLength = 3
+
+// passwordPolicy checks new passwords. All violated rules are returned at once, so the client can show them together.
+//	The breached passwords are looked up in the local list, the password isn't sent anywhere.
+type passwordPolicy struct {
+	config   config.PasswordPolicyConfig
+	breached *breached.List
+	logger   *logrus.Logger
+}
+
+func newPasswordPolicy(cfg config.PasswordPolicyConfig, logger *logrus.Logger) *passwordPolicy {
+	p := &passwordPolicy{
+		config: cfg,
+		logger: logger,
+	}
+
+	if cfg.BreachedPasswordsFile != "" {
+		list, err := breached.Open(cfg.BreachedPasswordsFile)
+		if err != nil && logger != nil {
+			logger.Error("Unable to load the breached passwords: ", err)
+		}
+		p.breached = list
+	}
+
+	return p
+}
+
+// check returns *service.PasswordPolicyError, if the password of the user violates the policy
+func (p *passwordPolicy) check(password string, user *models.User) error {
+	var violations []models.PasswordPolicyViolation
+	violate := func(rule, message string) {
+		violations = append(violations, models.PasswordPolicyViolation{Rule: rule, Message: message})
+	}
+
+	if utf8.RuneCountInString(password) < p.config.MinLength {
+		violate(models.PasswordRuleMinLength, fmt.Sprintf("the password must be at least %d characters long", p.config.MinLength))
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r):
+			hasSymbol = true
+		}
+	}
+	if p.config.RequireLowercase && !hasLower {
+		violate(models.PasswordRuleLowercase, "the password must contain a lowercase letter")
+	}
+	if p.config.RequireUppercase && !hasUpper {
+		violate(models.PasswordRuleUppercase, "the password must contain an uppercase letter")
+	}
+	if p.config.RequireDigit && !hasDigit {
+		violate(models.PasswordRuleDigit, "the password must contain a digit")
+	}
+	if p.config.RequireSymbol && !hasSymbol {
+		violate(models.PasswordRuleSymbol, "the password must contain a symbol")
+	}
+
+	if p.config.DisallowPersonalInfo && user != nil && containsPersonalInfo(password, user) {
+		violate(models.PasswordRulePersonalInfo, "the password must not contain the login, the email or the name")
+	}
+
+	// The password isn't rejected, if the list can't be read
+	if p.breached != nil {
+		isBreached, err := p.breached.Contains(password)
+		if err != nil && p.logger != nil {
+			p.logger.Error("Unable to check the breached passwords: ", err)
+		}
+		if isBreached {
+			violate(models.PasswordRuleBreached, "the password is known from data breaches")
+		}
+	}
+
+	if violations != nil {
+		return &service.PasswordPolicyError{Violations: violations}
+	}
+
+	return nil
+}
+
+// containsPersonalInfo checks the password for the login, the email, its local part and the words of the full name
+func containsPersonalInfo(password string, user *models.User) bool {
+	password = strings.ToLower(password)
+
+	values := append([]string{user.Login, user.Email}, strings.Fields(user.FullName)...)
+	if i := strings.LastIndexByte(user.Email, '@'); i > 0 {
+		values = append(values, user.Email[:i])
+	}
+
+	for _, value := range values {
+		if utf8.RuneCountInString(value) >= minPersonalInfoLength && strings.Contains(password, strings.ToLower(value)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *passwordPolicy) Close() {
+	if p.breached != nil {
+		_ = p.breached.Close()
+	}
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/config"
+	"backend/internal/service"
+	"backend/pkg/breached"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func violatedRules(err error) []string {
+	var policyErr *service.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return nil
+	}
+
+	rules := make([]string, len(policyErr.Violations))
+	for i, v := range policyErr.Violations {
+		rules[i] = v.Rule
+	}
+	return rules
+}
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	user := &models.User{Login: "Student", Email: "ivanov@university.example", FullName: "Ivan Ivanov"}
+
+	testCases := []struct {
+		name     string
+		config   config.PasswordPolicyConfig
+		password string
+		expected []string
+	}{
+		{
+			name:     "valid",
+			config:   config.NewConfig().Auth.PasswordPolicy,
+			password: "correct horse battery staple",
+		},
+		{
+			name:     "short",
+			config:   config.PasswordPolicyConfig{MinLength: 10},
+			password: "short",
+			expected: []string{models.PasswordRuleMinLength},
+		},
+		{
+			name: "character classes",
+			config: config.PasswordPolicyConfig{
+				RequireLowercase: true,
+				RequireUppercase: true,
+				RequireDigit:     true,
+				RequireSymbol:    true,
+			},
+			password: "password",
+			expected: []string{models.PasswordRuleUppercase, models.PasswordRuleDigit, models.PasswordRuleSymbol},
+		},
+		{
+			name: "all character classes",
+			config: config.PasswordPolicyConfig{
+				RequireLowercase: true,
+				RequireUppercase: true,
+				RequireDigit:     true,
+				RequireSymbol:    true,
+			},
+			password: "Pa55-word",
+		},
+		{
+			name:     "login",
+			config:   config.PasswordPolicyConfig{DisallowPersonalInfo: true},
+			password: "my-student-password",
+			expected: []string{models.PasswordRulePersonalInfo},
+		},
+		{
+			name:     "email local part",
+			config:   config.PasswordPolicyConfig{DisallowPersonalInfo: true},
+			password: "IVANOV1999",
+			expected: []string{models.PasswordRulePersonalInfo},
+		},
+		{
+			name:     "several rules",
+			config:   config.PasswordPolicyConfig{MinLength: 12, RequireDigit: true, DisallowPersonalInfo: true},
+			password: "ivan",
+			expected: []string{models.PasswordRuleMinLength, models.PasswordRuleDigit, models.PasswordRulePersonalInfo},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := newPasswordPolicy(tc.config, nil).check(tc.password, user)
+			if tc.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, service.ErrPasswordPolicy))
+			assert.Equal(t, tc.expected, violatedRules(err))
+		})
+	}
+}
+
+func TestPasswordPolicy_CheckBreached(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(breached.Hash("qwerty123")+":3912816\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := newPasswordPolicy(config.PasswordPolicyConfig{BreachedPasswordsFile: path}, nil)
+	defer p.Close()
+
+	assert.Equal(t, []string{models.PasswordRuleBreached}, violatedRules(p.check("qwerty123", nil)))
+	assert.NoError(t, p.check("correct horse battery staple", nil))
+}
+
+func TestAuthService_ChangePassword_PasswordPolicy(t *testing.T) {
+	s := newTestService(t)
+	s.config.Auth.PasswordPolicy.RequireDigit = true
+
+	u := models.TestUser(t)
+	u.Password = "password1"
+	assert.NoError(t, s.Auth().RegisterUser(u))
+
+	err := s.Auth().ChangePassword(context.Background(), u.ID, "password1", "UserExample-2021")
+	assert.Equal(t, []string{models.PasswordRulePersonalInfo}, violatedRules(err))
+
+	err = s.Auth().ChangePassword(context.Background(), u.ID, "password1", "new password")
+	assert.Equal(t, []string{models.PasswordRuleDigit}, violatedRules(err))
+
+	assert.NoError(t, s.Auth().ChangePassword(context.Background(), u.ID, "password1", "new password 2"))
+
+	other := models.TestUser(t)
+	other.Login, other.Email, other.Password = "Student", "student@example.org", "short1"
+	assert.Equal(t, []string{models.PasswordRuleMinLength}, violatedRules(s.Auth().RegisterUser(other)))
+}
+
+func TestAuthService_ResetPassword_PasswordPolicy(t *testing.T) {
+	s := newTestService(t)
+	s.config.Auth.PasswordPolicy.RequireDigit = true
+	m := &testMailer{}
+	s.AddMailer(m)
+
+	u := models.TestUser(t)
+	u.Password = "password1"
+	assert.NoError(t, s.Auth().RegisterUser(u))
+
+	assert.NoError(t, s.Auth().RequestPasswordReset(u.Email))
+	resetToken := m.lastToken(t)
+
+	// The rejected password doesn't use the link
+	err := s.Auth().ResetPassword(context.Background(), resetToken, "new password")
+	assert.Equal(t, []string{models.PasswordRuleDigit}, violatedRules(err))
+
+	assert.NoError(t, s.Auth().ResetPassword(context.Background(), resetToken, "new password 2"))
+	assert.Equal(t, service.ErrInvalidVerificationToken, s.Auth().ResetPassword(context.Background(), resetToken, "new password 3"))
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// periodicJob runs the function every interval in the background until it is stopped
+type periodicJob struct {
+	stop chan struct{}
+	once sync.Once
+}
+
+// startPeriodicJob starts the job. Returns nil, if the interval isn't positive.
+//Stop is safe to call on the nil job.
+func startPeriodicJob(interval time.Duration, job func()) *periodicJob {
+	if interval <= 0 {
+		return nil
+	}
+
+	j := &periodicJob{
+		stop: make(chan struct{}),
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				job()
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+
+	return j
+}
+
+func (j *periodicJob) Stop() {
+	if j == nil {
+		return
+	}
+	j.once.Do(func() {
+		close(j.stop)
+	})
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"backend/internal/store"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	personalTokenBytes = 32
+
+	personalTokenLastUsedUpdateRate = 1 * time.Minute
+)
+
+// CreatePersonalToken creates the personal access token of the user.
+//	The token expires at expiresAt or after the default TTL, if expiresAt is zero.
+//	Returns the raw token, only its hash is stored.
+func (s *AuthService) CreatePersonalToken(ctx context.Context, userID int, name, scope string, expiresAt time.Time) (*models.PersonalToken, string, error) {
+	cfg := s.service.config.Auth.PersonalTokens
+
+	scopes := make([]string, 0)
+	for _, sc := range models.ParseScope(scope) {
+		if !containsString(models.TokenScopes, sc) {
+			return nil, "", service.ErrInvalidTokenScope
+		}
+		if !containsString(scopes, sc) {
+			scopes = append(scopes, sc)
+		}
+	}
+
+	now := time.Now()
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(cfg.DefaultTTL)
+	}
+	if !expiresAt.After(now) || (cfg.MaxTTL > 0 && expiresAt.After(now.Add(cfg.MaxTTL))) {
+		return nil, "", service.ErrInvalidTokenExpiry
+	}
+
+	t := &models.PersonalToken{
+		UserID:    userID,
+		Name:      strings.TrimSpace(name),
+		Scope:     strings.Join(scopes, " "),
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+	if err := t.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	if cfg.MaxPerUser > 0 {
+		tokens, err := s.GetPersonalTokens(userID)
+		if err != nil {
+			return nil, "", err
+		}
+
+		// Expired tokens are still listed, but they don't count towards the limit
+		active := 0
+		for _, token := range tokens {
+			if token.Valid(now) {
+				active++
+			}
+		}
+		if active >= cfg.MaxPerUser {
+			return nil, "", service.ErrTooManyPersonalTokens
+		}
+	}
+
+	random, err := generateRandomToken(personalTokenBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	token := models.PersonalTokenPrefix + random
+	t.TokenHash = hashToken(token)
+
+	if err := s.service.store.PersonalToken().Create(t); err != nil {
+		return nil, "", err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionPersonalTokenCreate,
+		TargetType:  models.AuditTargetPersonalToken,
+		TargetID:    strconv.Itoa(t.ID),
+	}, nil, t)
+
+	return t, token, nil
+}
+
+// GetPersonalTokens returns not revoked tokens of the user. Expired tokens are returned too
+func (s *AuthService) GetPersonalTokens(userID int) ([]models.PersonalToken, error) {
+	tokens, err := s.service.store.PersonalToken().FindByUser(userID)
+	if err != nil && err != store.ErrRecordNotFound {
+		return nil, err
+	}
+	if tokens == nil {
+		tokens = []models.PersonalToken{}
+	}
+
+	return tokens, nil
+}
+
+func (s *AuthService) RevokePersonalToken(ctx context.Context, userID, tokenID int) error {
+	err := s.service.store.PersonalToken().Revoke(userID, tokenID, time.Now())
+	if err == store.ErrRecordNotFound {
+		return service.ErrPersonalTokenNotFound
+	} else if err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionPersonalTokenRevoke,
+		TargetType:  models.AuditTargetPersonalToken,
+		TargetID:    strconv.Itoa(tokenID),
+	}, nil, nil)
+
+	return nil
+}
+
+// AuthenticatePersonalToken returns the owner of the personal access token and updates the time of its last use
+func (s *AuthService) AuthenticatePersonalToken(token string) (*models.User, *models.PersonalToken, error) {
+	if !strings.HasPrefix(token, models.PersonalTokenPrefix) {
+		return nil, nil, service.ErrInvalidPersonalToken
+	}
+
+	t, err := s.service.store.PersonalToken().FindByHash(hashToken(token))
+	if err == store.ErrRecordNotFound {
+		return nil, nil, service.ErrInvalidPersonalToken
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if !t.Valid(now) {
+		return nil, nil, service.ErrInvalidPersonalToken
+	}
+
+	if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) > personalTokenLastUsedUpdateRate {
+		if err := s.service.store.PersonalToken().UpdateLastUsed(t.ID, now); err != nil {
+			return nil, nil, err
+		}
+		t.LastUsedAt = &now
+	}
+
+	u, err := s.service.User().Find(t.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return u, t, nil
+}
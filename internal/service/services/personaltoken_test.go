@@ -0,0 +1,123 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthService_PersonalTokens(t *testing.T) {
+	s := newTestService(t)
+	signInTestUser(t, s)
+	u, err := s.User().FindByLogin("UserExample")
+	assert.NoError(t, err)
+
+	pt, token, err := s.Auth().CreatePersonalToken(context.Background(), u.ID, "telegram bot", "tasks:read tasks:write tasks:read", time.Time{})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, models.PersonalTokenPrefix))
+	assert.Equal(t, "tasks:read tasks:write", pt.Scope)
+	assert.NotEqual(t, token, pt.TokenHash)
+	assert.WithinDuration(t, time.Now().Add(s.config.Auth.PersonalTokens.DefaultTTL), pt.ExpiresAt, time.Minute)
+
+	authUser, authToken, err := s.Auth().AuthenticatePersonalToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, authUser.ID)
+	assert.True(t, authToken.HasScope(models.TokenScopeTasksWrite))
+	assert.False(t, authToken.HasScope(models.TokenScopeGroupsWrite))
+
+	tokens, err := s.Auth().GetPersonalTokens(u.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, tokens, 1) {
+		assert.NotNil(t, tokens[0].LastUsedAt)
+	}
+
+	assert.Equal(t, service.ErrPersonalTokenNotFound, s.Auth().RevokePersonalToken(context.Background(), u.ID+1, pt.ID))
+	assert.NoError(t, s.Auth().RevokePersonalToken(context.Background(), u.ID, pt.ID))
+	assert.Equal(t, service.ErrPersonalTokenNotFound, s.Auth().RevokePersonalToken(context.Background(), u.ID, pt.ID))
+
+	_, _, err = s.Auth().AuthenticatePersonalToken(token)
+	assert.Equal(t, service.ErrInvalidPersonalToken, err)
+	tokens, err = s.Auth().GetPersonalTokens(u.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, tokens)
+}
+
+func TestAuthService_PersonalTokens_RevokedOnPasswordReset(t *testing.T) {
+	s := newTestService(t)
+	m := &testMailer{}
+	s.AddMailer(m)
+	signInTestUser(t, s)
+	u, err := s.User().FindByLogin("UserExample")
+	assert.NoError(t, err)
+
+	_, first, err := s.Auth().CreatePersonalToken(context.Background(), u.ID, "telegram bot", "tasks:read", time.Time{})
+	assert.NoError(t, err)
+	_, second, err := s.Auth().CreatePersonalToken(context.Background(), u.ID, "calendar", "tasks:read", time.Time{})
+	assert.NoError(t, err)
+
+	// The forgotten password may be reset by the one who has stolen the tokens too
+	assert.NoError(t, s.Auth().RequestPasswordReset(u.Email))
+	assert.NoError(t, s.Auth().ResetPassword(context.Background(), m.lastToken(t), "new password"))
+
+	for _, token := range []string{first, second} {
+		_, _, err = s.Auth().AuthenticatePersonalToken(token)
+		assert.Equal(t, service.ErrInvalidPersonalToken, err)
+	}
+	tokens, err := s.Auth().GetPersonalTokens(u.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, tokens)
+
+	// The tokens created after the reset are valid
+	_, token, err := s.Auth().CreatePersonalToken(context.Background(), u.ID, "telegram bot", "tasks:read", time.Time{})
+	assert.NoError(t, err)
+	_, _, err = s.Auth().AuthenticatePersonalToken(token)
+	assert.NoError(t, err)
+}
+
+func TestAuthService_CreatePersonalToken_Invalid(t *testing.T) {
+	s := newTestService(t)
+	s.config.Auth.PersonalTokens.MaxPerUser = 1
+
+	_, _, err := s.Auth().CreatePersonalToken(context.Background(), 1, "bot", "tasks:delete", time.Time{})
+	assert.Equal(t, service.ErrInvalidTokenScope, err)
+	_, _, err = s.Auth().CreatePersonalToken(context.Background(), 1, "bot", "", time.Time{})
+	assert.Error(t, err)
+	_, _, err = s.Auth().CreatePersonalToken(context.Background(), 1, "", "tasks:read", time.Time{})
+	assert.Error(t, err)
+	_, _, err = s.Auth().CreatePersonalToken(context.Background(), 1, "bot", "tasks:read", time.Now().Add(-time.Hour))
+	assert.Equal(t, service.ErrInvalidTokenExpiry, err)
+	_, _, err = s.Auth().CreatePersonalToken(context.Background(), 1, "bot", "tasks:read", time.Now().Add(2*s.config.Auth.PersonalTokens.MaxTTL))
+	assert.Equal(t, service.ErrInvalidTokenExpiry, err)
+
+	_, _, err = s.Auth().CreatePersonalToken(context.Background(), 1, "bot", "tasks:read", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	_, _, err = s.Auth().CreatePersonalToken(context.Background(), 1, "other bot", "tasks:read", time.Time{})
+	assert.Equal(t, service.ErrTooManyPersonalTokens, err)
+
+	_, _, err = s.Auth().AuthenticatePersonalToken(models.PersonalTokenPrefix + "unknown")
+	assert.Equal(t, service.ErrInvalidPersonalToken, err)
+}
+
+func TestAuthService_CreatePersonalToken_ExpiredTokensAreNotCounted(t *testing.T) {
+	s := newTestService(t)
+	s.config.Auth.PersonalTokens.MaxPerUser = 1
+
+	now := time.Now()
+	assert.NoError(t, s.store.PersonalToken().Create(&models.PersonalToken{
+		UserID:    1,
+		Name:      "expired bot",
+		Scope:     models.TokenScopeTasksRead,
+		TokenHash: hashToken("expired"),
+		CreatedAt: now.Add(-2 * time.Hour),
+		ExpiresAt: now.Add(-time.Hour),
+	}))
+
+	_, _, err := s.Auth().CreatePersonalToken(context.Background(), 1, "bot", models.TokenScopeTasksRead, time.Time{})
+	assert.NoError(t, err)
+	_, _, err = s.Auth().CreatePersonalToken(context.Background(), 1, "other bot", models.TokenScopeTasksRead, time.Time{})
+	assert.Equal(t, service.ErrTooManyPersonalTokens, err)
+}
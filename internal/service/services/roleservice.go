@@ -0,0 +1,389 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"backend/internal/store"
+	"backend/pkg/ServiceData"
+	"context"
+	"fmt"
+	"strconv"
+)
+
+type RoleService struct {
+	service *Service
+}
+
+func (s *RoleService) SeedRoles(data ServiceData.Data) error {
+	permissions := make([]models.Permission, 0)
+	permissionTypes := make(map[string]string)
+	for _, item := range data.PermissionList {
+		for _, name := range item.PermissionsList {
+			permissions = append(permissions, models.Permission{Name: name, Type: item.Type})
+			permissionTypes[name] = item.Type
+		}
+	}
+
+	roles := make([]models.Role, 0, len(data.Roles))
+	roleIndexes := make(map[string]int, len(data.Roles))
+	for _, r := range data.Roles {
+		if r.Scope != models.RoleScopeGlobal && r.Scope != models.RoleScopeGroup && r.Scope != models.RoleScopeFaculty {
+			return fmt.Errorf("the role %q has unknown scope %q", r.Name, r.Scope)
+		}
+
+		roleIndexes[r.Name] = len(roles)
+		roles = append(roles, models.Role{
+			Name:        r.Name,
+			Description: r.Description,
+			Scope:       r.Scope,
+		})
+	}
+
+	for _, item := range data.RolePermissions {
+		i, ok := roleIndexes[item.Role]
+		if !ok {
+			return fmt.Errorf("the permissions are listed for the unknown role %q", item.Role)
+		}
+
+		for _, name := range item.PermissionsList {
+			permissionType, ok := permissionTypes[name]
+			if !ok {
+				return fmt.Errorf("the role %q has the unknown permission %q", item.Role, name)
+			}
+			// Group roles can't grant permissions of the whole service
+			if roles[i].Scope == models.RoleScopeGroup && permissionType != models.RoleScopeGroup {
+				return fmt.Errorf("the group role %q has the %s permission %q", item.Role, permissionType, name)
+			}
+			// Faculty roles grant permissions in the faculty and its groups
+			if roles[i].Scope == models.RoleScopeFaculty && permissionType == models.RoleScopeGlobal {
+				return fmt.Errorf("the faculty role %q has the global permission %q", item.Role, name)
+			}
+
+			roles[i].Permissions = append(roles[i].Permissions, models.RolePermissions{
+				Permission: models.Permission{Name: name, Type: permissionType},
+				State:      true,
+			})
+		}
+	}
+
+	if err := s.service.store.Role().Seed(permissions, roles); err != nil {
+		return err
+	}
+
+	for _, login := range s.service.config.Roles.Administrators {
+		user, err := s.service.store.User().FindByLogin(login)
+		if err == store.ErrRecordNotFound {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if err := s.assignRole(user.ID, models.RoleAdmin); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *RoleService) HasPermission(userID int, permission string) (bool, error) {
+	permissions, err := s.GetUserPermissions(userID)
+	if err != nil {
+		return false, err
+	}
+
+	return containsString(permissions, permission), nil
+}
+
+// HasGroupPermission checks the permission of the user in the group and in the faculty of its department.
+//	The archived group is read-only for everyone, nobody has permissions in the deleted one.
+func (s *RoleService) HasGroupPermission(userID, groupID int, permission string) (bool, error) {
+	var departmentID *int
+	group, err := s.service.store.Group().Find(groupID)
+	if err != nil && err != store.ErrRecordNotFound {
+		return false, err
+	} else if err == nil {
+		if group.DeletedAt != nil || group.ArchivedAt != nil && !models.IsArchivedGroupPermission(permission) {
+			return false, nil
+		}
+		departmentID = group.DepartmentID
+	}
+
+	ok, err := s.HasPermission(userID, permission)
+	if err != nil || ok {
+		return ok, err
+	}
+
+	// The faculty roles work in the groups of the departments of the faculty
+	if departmentID != nil {
+		department, err := s.service.store.Faculty().FindDepartment(*departmentID)
+		if err != nil && err != store.ErrRecordNotFound {
+			return false, err
+		} else if err == nil {
+			permissions, err := s.GetFacultyPermissions(userID, department.FacultyID)
+			if err != nil {
+				return false, err
+			} else if containsString(permissions, permission) {
+				return true, nil
+			}
+		}
+	}
+
+	permissions, err := s.GetMemberPermissions(userID, groupID)
+	if err != nil {
+		return false, err
+	}
+
+	return containsString(permissions, permission), nil
+}
+
+func (s *RoleService) HasFacultyPermission(userID, facultyID int, permission string) (bool, error) {
+	ok, err := s.HasPermission(userID, permission)
+	if err != nil || ok {
+		return ok, err
+	}
+
+	permissions, err := s.GetFacultyPermissions(userID, facultyID)
+	if err != nil {
+		return false, err
+	}
+
+	return containsString(permissions, permission), nil
+}
+
+func (s *RoleService) IsGroupPermission(permission string) (bool, error) {
+	p, err := s.service.store.Role().FindPermission(permission)
+	if err == store.ErrRecordNotFound {
+		return false, service.ErrPermissionNotFound
+	} else if err != nil {
+		return false, err
+	}
+
+	return p.Type == models.RoleScopeGroup, nil
+}
+
+func (s *RoleService) GetUserPermissions(userID int) ([]string, error) {
+	roles, err := s.service.store.User().GetUserRoles(userID)
+	if err != nil && err != store.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return s.getRolesPermissions(roles)
+}
+
+// GetMemberPermissions returns nothing, if the user isn't a member of the group
+func (s *RoleService) GetMemberPermissions(userID, groupID int) ([]string, error) {
+	isMember, err := s.service.store.Group().IsUserGroupMember(userID, groupID)
+	if err != nil || !isMember {
+		return nil, err
+	}
+
+	roles, err := s.service.store.Group().GetMemberRoles(userID, groupID)
+	if err != nil && err != store.ErrRecordNotFound {
+		return nil, err
+	}
+
+	// Members joined before roles were introduced have no roles
+	if len(roles) == 0 {
+		role, err := s.findRole(models.RoleMember)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, *role)
+	}
+
+	return s.getRolesPermissions(roles)
+}
+
+func (s *RoleService) GetFacultyPermissions(userID, facultyID int) ([]string, error) {
+	roles, err := s.service.store.Role().GetFacultyRoles(userID, facultyID)
+	if err != nil && err != store.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return s.getRolesPermissions(roles)
+}
+
+func (s *RoleService) AssignRole(ctx context.Context, userID int, roleName string) error {
+	if err := s.assignRole(userID, roleName); err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		Action:     models.AuditActionRoleAssign,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.Itoa(userID),
+	}, nil, map[string]string{"role": roleName})
+
+	return nil
+}
+
+// assignRole works as AssignRole without the audit log, the administrators from the config are assigned on every start
+func (s *RoleService) assignRole(userID int, roleName string) error {
+	role, err := s.findRoleOfScope(roleName, models.RoleScopeGlobal)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.service.User().Find(userID); err != nil {
+		return err
+	}
+
+	return s.service.store.Role().AddUserRole(userID, role.ID)
+}
+
+func (s *RoleService) RevokeRole(ctx context.Context, userID int, roleName string) error {
+	role, err := s.findRoleOfScope(roleName, models.RoleScopeGlobal)
+	if err != nil {
+		return err
+	}
+
+	if err := s.service.store.Role().RemoveUserRole(userID, role.ID); err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		Action:     models.AuditActionRoleRevoke,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.Itoa(userID),
+	}, map[string]string{"role": roleName}, nil)
+
+	return nil
+}
+
+func (s *RoleService) SetGroupRole(userID, groupID int, roleName string) error {
+	role, err := s.findRoleOfScope(roleName, models.RoleScopeGroup)
+	if err != nil {
+		return err
+	}
+
+	owner, err := s.findRole(models.RoleOwner)
+	if err != nil {
+		return err
+	}
+
+	// The last owner can't be demoted, the owners are counted by the store in the same transaction
+	err = s.service.store.Role().SetMemberRole(userID, groupID, role.ID, owner.ID)
+	if err == store.ErrRecordNotFound {
+		return service.ErrUserIsNotGroupMember
+	} else if err == store.ErrLastMemberWithRole {
+		return service.ErrLastGroupOwner
+	}
+
+	return err
+}
+
+func (s *RoleService) AssignFacultyRole(ctx context.Context, userID, facultyID int, roleName string) error {
+	role, err := s.findRoleOfScope(roleName, models.RoleScopeFaculty)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.service.User().Find(userID); err != nil {
+		return err
+	}
+	if _, err := s.service.Faculty().Find(facultyID); err != nil {
+		return err
+	}
+
+	if err := s.service.store.Role().AddFacultyRole(userID, facultyID, role.ID); err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		Action:     models.AuditActionFacultyRoleAssign,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.Itoa(userID),
+	}, nil, map[string]interface{}{"role": roleName, "faculty_id": facultyID})
+
+	return nil
+}
+
+func (s *RoleService) RevokeFacultyRole(ctx context.Context, userID, facultyID int, roleName string) error {
+	role, err := s.findRoleOfScope(roleName, models.RoleScopeFaculty)
+	if err != nil {
+		return err
+	}
+
+	if err := s.service.store.Role().RemoveFacultyRole(userID, facultyID, role.ID); err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		Action:     models.AuditActionFacultyRoleRevoke,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.Itoa(userID),
+	}, map[string]interface{}{"role": roleName, "faculty_id": facultyID}, nil)
+
+	return nil
+}
+
+func (s *RoleService) IsGroupOwner(userID, groupID int) (bool, error) {
+	roles, err := s.service.store.Group().GetMemberRoles(userID, groupID)
+	if err != nil && err != store.ErrRecordNotFound {
+		return false, err
+	}
+
+	for _, role := range roles {
+		if role.Name == models.RoleOwner {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (s *RoleService) FindGroupRole(roleName string) (*models.Role, error) {
+	return s.findRoleOfScope(roleName, models.RoleScopeGroup)
+}
+
+func (s *RoleService) findRole(roleName string) (*models.Role, error) {
+	role, err := s.service.store.Group().GetRoleByName(roleName)
+	if err == store.ErrRecordNotFound {
+		return nil, service.ErrRoleNotFound
+	}
+
+	return role, err
+}
+
+func (s *RoleService) findRoleOfScope(roleName, scope string) (*models.Role, error) {
+	role, err := s.findRole(roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	if role.Scope != scope {
+		return nil, service.ErrInvalidRoleScope
+	}
+
+	return role, nil
+}
+
+// getRolesPermissions returns names of the permissions of all roles without duplicates
+func (s *RoleService) getRolesPermissions(roles []models.Role) ([]string, error) {
+	permissions := make([]string, 0)
+	for _, role := range roles {
+		rolePermissions, err := s.service.store.Group().GetRolePermissions(role.ID)
+		if err != nil && err != store.ErrRecordNotFound {
+			return nil, err
+		}
+
+		for _, p := range rolePermissions {
+			if !containsString(permissions, p.Name) {
+				permissions = append(permissions, p.Name)
+			}
+		}
+	}
+
+	return permissions, nil
+}
+
+func containsString(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"backend/pkg/ServiceData"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newRolesTestService(t *testing.T) *Service {
+	t.Helper()
+
+	data, err := ServiceData.NewServiceData("../../../configs").GetDataFromFile("service_roles.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestService(t)
+	if err := s.Role().SeedRoles(data); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func newTestGroup(t *testing.T, s *Service, owner *models.User) *models.Group {
+	t.Helper()
+
+	g := models.TestGroup(t)
+	g.SpecializationName = "TEST"
+	if err := s.Group().Create(context.Background(), g, owner); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestRoleService_GroupRoles(t *testing.T) {
+	s := newRolesTestService(t)
+
+	owner := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(owner))
+	member := &models.User{Login: "member", FullName: "Member", Email: "member@example.org", Password: "password"}
+	assert.NoError(t, s.Auth().RegisterUser(member))
+	stranger := &models.User{Login: "stranger", FullName: "Stranger", Email: "stranger@example.org", Password: "password"}
+	assert.NoError(t, s.Auth().RegisterUser(stranger))
+
+	g := newTestGroup(t, s, owner)
+	assert.NoError(t, s.store.Group().AddGroupMember(member.ID, g.ID, owner.ID))
+
+	// The creator is the owner
+	ok, err := s.Role().HasGroupPermission(owner.ID, g.ID, models.PermissionGroupDelete)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// Members without roles have the member role
+	ok, err = s.Role().HasGroupPermission(member.ID, g.ID, models.PermissionTaskRead)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = s.Role().HasGroupPermission(member.ID, g.ID, models.PermissionTaskCreate)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = s.Role().HasGroupPermission(stranger.ID, g.ID, models.PermissionTaskRead)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.Equal(t, service.ErrPermissionDenied, s.Group().Delete(context.Background(), g.ID, member.ID))
+
+	assert.NoError(t, s.Role().SetGroupRole(member.ID, g.ID, models.RoleHeadman))
+	ok, err = s.Role().HasGroupPermission(member.ID, g.ID, models.PermissionTaskCreate)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = s.Role().HasGroupPermission(member.ID, g.ID, models.PermissionGroupRolesManage)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.Equal(t, service.ErrUserIsNotGroupMember, s.Role().SetGroupRole(stranger.ID, g.ID, models.RoleMember))
+	assert.Equal(t, service.ErrInvalidRoleScope, s.Role().SetGroupRole(member.ID, g.ID, models.RoleAdmin))
+	assert.Equal(t, service.ErrRoleNotFound, s.Role().SetGroupRole(member.ID, g.ID, "unknown"))
+}
+
+func TestRoleService_GlobalRoles(t *testing.T) {
+	s := newRolesTestService(t)
+
+	u := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(u))
+	stranger := &models.User{Login: "stranger", FullName: "Stranger", Email: "stranger@example.org", Password: "password"}
+	assert.NoError(t, s.Auth().RegisterUser(stranger))
+	g := newTestGroup(t, s, stranger)
+
+	ok, err := s.Role().HasPermission(u.ID, models.PermissionAdminPanel)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.Equal(t, service.ErrInvalidRoleScope, s.Role().AssignRole(context.Background(), u.ID, models.RoleOwner))
+	assert.NoError(t, s.Role().AssignRole(context.Background(), u.ID, models.RoleAdmin))
+
+	ok, err = s.Role().HasPermission(u.ID, models.PermissionAdminPanel)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	// Global roles work in all groups
+	ok, err = s.Role().HasGroupPermission(u.ID, g.ID, models.PermissionGroupDelete)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.NoError(t, s.Role().RevokeRole(context.Background(), u.ID, models.RoleAdmin))
+	ok, err = s.Role().HasPermission(u.ID, models.PermissionAdminPanel)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	isGroupPermission, err := s.Role().IsGroupPermission(models.PermissionTaskCreate)
+	assert.NoError(t, err)
+	assert.True(t, isGroupPermission)
+	_, err = s.Role().IsGroupPermission("unknown")
+	assert.Equal(t, service.ErrPermissionNotFound, err)
+}
+
+func TestRoleService_SeedRoles_Administrators(t *testing.T) {
+	s := newTestService(t)
+	u := models.TestUser(t)
+	assert.NoError(t, s.Auth().RegisterUser(u))
+	s.config.Roles.Administrators = []string{u.Login, "missing"}
+
+	data, err := ServiceData.NewServiceData("../../../configs").GetDataFromFile("service_roles.json", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Role().SeedRoles(data))
+	// Seeding is repeatable
+	assert.NoError(t, s.Role().SeedRoles(data))
+
+	ok, err := s.Role().HasPermission(u.ID, models.PermissionAdminRolesManage)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	data.RolePermissions = append(data.RolePermissions, ServiceData.DataRolePermissionsItem{
+		Role:            models.RoleMember,
+		PermissionsList: []string{models.PermissionAdminPanel},
+	})
+	assert.Error(t, s.Role().SeedRoles(data))
+}
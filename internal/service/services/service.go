@@ -5,6 +5,7 @@ import (
 	"backend/internal/config"
 	"backend/internal/service"
 	"backend/internal/store"
+	"backend/pkg/mailer"
 	"context"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
@@ -12,23 +13,32 @@ import (
 
 type ctxKey int8
 
+// Keys of the request context the services read, they are set by the API server
 const (
 	CtxKeyUser = ctxKey(iota)
 	CtxKeyRequestID
 	CtxKeyAppID
+	CtxKeySession
+	CtxKeyIP
+	CtxKeyApp
+	CtxKeyUserAgent
 )
 
 type Service struct {
 	store  store.Store
 	logger *logrus.Logger
+	mailer mailer.Mailer
 	config *config.Config
 
 	authService       *AuthService
 	userService       *UserService
 	taskService       *TaskService
 	universityService *UniversityService
+	facultyService    *FacultyService
 	groupService      *GroupService
 	subjectService    *SubjectService
+	roleService       *RoleService
+	auditService      *AuditService
 }
 
 func NewService(store store.Store, config *config.Config) *Service {
@@ -42,6 +52,32 @@ func (s *Service) AddLogger(logger *logrus.Logger) {
 	s.logger = logger
 }
 
+func (s *Service) AddMailer(mailer mailer.Mailer) {
+	s.mailer = mailer
+}
+
+// getMailer returns the added mailer. Emails are written to the log, if no mailer was added
+func (s *Service) getMailer() mailer.Mailer {
+	if s.mailer == nil {
+		s.mailer = mailer.NewLogMailer(s.logger)
+	}
+
+	return s.mailer
+}
+
+// Close stops background jobs of the started services
+func (s *Service) Close() {
+	if s.authService != nil {
+		s.authService.Close()
+	}
+	if s.auditService != nil {
+		s.auditService.Close()
+	}
+	if s.groupService != nil {
+		s.groupService.Close()
+	}
+}
+
 func (s *Service) Auth() service.AuthService {
 	if s.authService == nil {
 		s.authService = NewAuthService(s)
@@ -83,11 +119,20 @@ func (s *Service) University() service.UniversityService {
 	return s.universityService
 }
 
-func (s *Service) Group() service.GroupService {
-	if s.groupService == nil {
-		s.groupService = &GroupService{
+func (s *Service) Faculty() service.FacultyService {
+	if s.facultyService == nil {
+		s.facultyService = &FacultyService{
 			service: s,
 		}
+		s.logger.Info("The faculty service was started")
+	}
+
+	return s.facultyService
+}
+
+func (s *Service) Group() service.GroupService {
+	if s.groupService == nil {
+		s.groupService = NewGroupService(s)
 		s.logger.Info("The group service was started")
 	}
 
@@ -105,6 +150,26 @@ func (s *Service) Subject() service.SubjectService {
 	return s.subjectService
 }
 
+func (s *Service) Role() service.RoleService {
+	if s.roleService == nil {
+		s.roleService = &RoleService{
+			service: s,
+		}
+		s.logger.Info("The role service was started")
+	}
+
+	return s.roleService
+}
+
+func (s *Service) Audit() service.AuditService {
+	if s.auditService == nil {
+		s.auditService = NewAuditService(s)
+		s.logger.Info("The audit service was started")
+	}
+
+	return s.auditService
+}
+
 func (s *Service) getUserFromContext(ctx context.Context) (*models.User, error) {
 	user, ok := ctx.Value(CtxKeyUser).(*models.User)
 	if !ok {
@@ -123,6 +188,15 @@ func (s *Service) getRequestIDFromContext(ctx context.Context) (uuid.UUID, error
 	return uid, nil
 }
 
+func (s *Service) getSessionFromContext(ctx context.Context) (*models.UserSession, error) {
+	session, ok := ctx.Value(CtxKeySession).(*models.UserSession)
+	if !ok || session == nil {
+		return nil, service.ErrSessionNotFoundInContext
+	}
+
+	return session, nil
+}
+
 func (s *Service) getAppIDFromContext(ctx context.Context) (uuid.UUID, error) {
 	uid, ok := ctx.Value(CtxKeyAppID).(uuid.UUID)
 	if !ok {
@@ -6,22 +6,45 @@ import (
 	"backend/internal/store"
 	"context"
 	"sort"
+	"strconv"
 )
 
 type TaskService struct {
 	service *Service
 }
 
+// CreateGroupTask creates the task of the groups.
+//	Requires: The author must have the task.create permission in all the groups
 func (s *TaskService) CreateGroupTask(ctx context.Context, task *models.Task) error {
 	if err := task.Validate(); err != nil {
 		return err
 	}
 
+	for _, groupID := range task.GroupsID {
+		if groupID == 0 {
+			continue
+		}
+
+		allowed, err := s.service.Role().HasGroupPermission(task.AddedByID, groupID, models.PermissionTaskCreate)
+		if err != nil {
+			return err
+		} else if !allowed {
+			return service.ErrPermissionDenied
+		}
+	}
+
 	err := s.service.store.Task().CreateGroupTask(task)
 	if err != nil {
 		return err
 	}
 
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &task.AddedByID,
+		Action:      models.AuditActionTaskCreate,
+		TargetType:  models.AuditTargetTask,
+		TargetID:    strconv.Itoa(task.ID),
+	}, nil, task)
+
 	return nil
 }
 
@@ -35,6 +58,13 @@ func (s *TaskService) CreateUserTask(ctx context.Context, task *models.Task) err
 		return err
 	}
 
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &task.AddedByID,
+		Action:      models.AuditActionTaskCreate,
+		TargetType:  models.AuditTargetTask,
+		TargetID:    strconv.Itoa(task.ID),
+	}, nil, task)
+
 	return nil
 }
 
@@ -137,7 +167,7 @@ func (s *TaskService) GetAllUserTasks(ctx context.Context, userID, limit, offset
 	var TasksAvailableToUser []models.Task
 
 	//Adding tasks assigned to groups that the user is member of
-	groupsUserMemberOf, err := s.service.Group().GetGroupsUserMemberOf(userID)
+	groupsUserMemberOf, err := s.service.Group().GetGroupsUserMemberOf(userID, false)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/internal/service"
+	"backend/internal/store"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// newTokenBlacklist returns the blacklist chosen in the config.
+//The store blacklist is used by default, because the memory one isn't shared between API server replicas.
+func newTokenBlacklist(s *Service) service.TokenBlacklist {
+	cfg := s.config.Auth.Blacklist
+
+	switch cfg.Storage {
+	case config.BlacklistStorageMemory:
+		return NewMemoryTokenBlacklist(cfg.CleanupInterval, s.logBlacklistPruneError)
+	case config.BlacklistStoragePostgres:
+	default:
+		if s.logger != nil {
+			s.logger.Warnf("Unknown token blacklist storage %q. The %q storage is used",
+				cfg.Storage, config.BlacklistStoragePostgres)
+		}
+	}
+
+	return NewStoreTokenBlacklist(s.store.TokenBlacklist(), cfg.CleanupInterval, s.logBlacklistPruneError)
+}
+
+func (s *Service) logBlacklistPruneError(err error) {
+	if s.logger != nil {
+		s.logger.Error("Unable to prune the token blacklist: ", err)
+	}
+}
+
+// hashToken returns the hex encoded SHA-256 hash of the token.
+//Tokens are stored only as hashes, so the blacklist can't be used to restore them.
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// startBlacklistJanitor periodically prunes expired tokens from the blacklist
+func startBlacklistJanitor(blacklist service.TokenBlacklist, interval time.Duration, onError func(error)) *periodicJob {
+	return startPeriodicJob(interval, func() {
+		if _, err := blacklist.Prune(); err != nil && onError != nil {
+			onError(err)
+		}
+	})
+}
+
+// MemoryTokenBlacklist keeps hashes of revoked tokens in the process memory.
+//It is lost on restart and isn't shared between API server replicas.
+type MemoryTokenBlacklist struct {
+	sync.RWMutex
+	blockedTokens map[string]time.Time
+	janitor       *periodicJob
+	now           func() time.Time
+}
+
+func NewMemoryTokenBlacklist(cleanupInterval time.Duration, onPruneError func(error)) *MemoryTokenBlacklist {
+	b := &MemoryTokenBlacklist{
+		blockedTokens: make(map[string]time.Time, 20),
+		now:           time.Now,
+	}
+	b.janitor = startBlacklistJanitor(b, cleanupInterval, onPruneError)
+
+	return b
+}
+
+func (b *MemoryTokenBlacklist) Add(token string, expiresAt time.Time) error {
+	hash := hashToken(token)
+
+	b.Lock()
+	if exp, ok := b.blockedTokens[hash]; !ok || exp.Before(expiresAt) {
+		b.blockedTokens[hash] = expiresAt
+	}
+	b.Unlock()
+
+	return nil
+}
+
+func (b *MemoryTokenBlacklist) IsBlacklisted(token string) (bool, error) {
+	b.RLock()
+	exp, ok := b.blockedTokens[hashToken(token)]
+	b.RUnlock()
+
+	return ok && exp.After(b.now()), nil
+}
+
+func (b *MemoryTokenBlacklist) Prune() (int, error) {
+	currentTime := b.now()
+	deleted := 0
+
+	b.Lock()
+	for hash, exp := range b.blockedTokens {
+		if !exp.After(currentTime) {
+			delete(b.blockedTokens, hash)
+			deleted++
+		}
+	}
+	b.Unlock()
+
+	return deleted, nil
+}
+
+func (b *MemoryTokenBlacklist) Len() int {
+	b.RLock()
+	defer b.RUnlock()
+
+	return len(b.blockedTokens)
+}
+
+func (b *MemoryTokenBlacklist) Close() {
+	b.janitor.Stop()
+}
+
+// StoreTokenBlacklist keeps hashes of revoked tokens in the store.
+//With the sqlstore the revocation survives restarts and is shared between API server replicas.
+type StoreTokenBlacklist struct {
+	repository store.TokenBlacklistRepository
+	janitor    *periodicJob
+	now        func() time.Time
+}
+
+func NewStoreTokenBlacklist(repository store.TokenBlacklistRepository, cleanupInterval time.Duration, onPruneError func(error)) *StoreTokenBlacklist {
+	b := &StoreTokenBlacklist{
+		repository: repository,
+		now:        time.Now,
+	}
+	b.janitor = startBlacklistJanitor(b, cleanupInterval, onPruneError)
+
+	return b
+}
+
+func (b *StoreTokenBlacklist) Add(token string, expiresAt time.Time) error {
+	return b.repository.Add(hashToken(token), expiresAt)
+}
+
+func (b *StoreTokenBlacklist) IsBlacklisted(token string) (bool, error) {
+	return b.repository.Exists(hashToken(token), b.now())
+}
+
+func (b *StoreTokenBlacklist) Prune() (int, error) {
+	deleted, err := b.repository.DeleteExpired(b.now())
+	return int(deleted), err
+}
+
+func (b *StoreTokenBlacklist) Close() {
+	b.janitor.Stop()
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"backend/internal/store/teststore"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryTokenBlacklist_IsBlacklisted(t *testing.T) {
+	now := time.Now()
+	b := NewMemoryTokenBlacklist(0, nil)
+	b.now = func() time.Time { return now }
+
+	assert.NoError(t, b.Add("token", now.Add(time.Minute)))
+
+	isBlacklisted, err := b.IsBlacklisted("token")
+	assert.NoError(t, err)
+	assert.True(t, isBlacklisted)
+
+	isBlacklisted, err = b.IsBlacklisted("other token")
+	assert.NoError(t, err)
+	assert.False(t, isBlacklisted)
+
+	// The token has expired by itself, so it doesn't need to be blacklisted
+	now = now.Add(2 * time.Minute)
+	isBlacklisted, err = b.IsBlacklisted("token")
+	assert.NoError(t, err)
+	assert.False(t, isBlacklisted)
+}
+
+func TestMemoryTokenBlacklist_Prune(t *testing.T) {
+	now := time.Now()
+	b := NewMemoryTokenBlacklist(0, nil)
+	b.now = func() time.Time { return now }
+
+	assert.NoError(t, b.Add("expired", now.Add(-time.Second)))
+	assert.NoError(t, b.Add("live", now.Add(time.Minute)))
+
+	deleted, err := b.Prune()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	assert.Equal(t, 1, b.Len())
+}
+
+func TestMemoryTokenBlacklist_Janitor(t *testing.T) {
+	b := NewMemoryTokenBlacklist(10*time.Millisecond, nil)
+	defer b.Close()
+
+	assert.NoError(t, b.Add("expired", time.Now().Add(-time.Second)))
+	assert.Eventually(t, func() bool {
+		return b.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+
+	// Closing twice must be safe
+	b.Close()
+}
+
+func TestMemoryTokenBlacklist_Concurrency(t *testing.T) {
+	b := NewMemoryTokenBlacklist(time.Millisecond, nil)
+	defer b.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			token := strconv.Itoa(i)
+			assert.NoError(t, b.Add(token, time.Now().Add(time.Minute)))
+			isBlacklisted, err := b.IsBlacklisted(token)
+			assert.NoError(t, err)
+			assert.True(t, isBlacklisted)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 20, b.Len())
+}
+
+func TestStoreTokenBlacklist(t *testing.T) {
+	now := time.Now()
+	s := teststore.New()
+	b := NewStoreTokenBlacklist(s.TokenBlacklist(), 0, nil)
+	b.now = func() time.Time { return now }
+
+	assert.NoError(t, b.Add("token", now.Add(time.Minute)))
+	assert.NoError(t, b.Add("expired", now.Add(-time.Minute)))
+
+	// Another API server instance sharing the store sees the revocation
+	replica := NewStoreTokenBlacklist(s.TokenBlacklist(), 0, nil)
+	isBlacklisted, err := replica.IsBlacklisted("token")
+	assert.NoError(t, err)
+	assert.True(t, isBlacklisted)
+
+	isBlacklisted, err = b.IsBlacklisted("expired")
+	assert.NoError(t, err)
+	assert.False(t, isBlacklisted)
+
+	deleted, err := b.Prune()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"backend/internal/store"
+	"backend/pkg/totp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	recoveryCodesCount = 10
+	recoveryCodeBytes  = 10
+	// totpSkew is the number of time steps before and after the current one that are accepted
+	totpSkew = 1
+	// maxMFAChallengeAttempts is the number of incorrect codes after which the challenge is revoked
+	maxMFAChallengeAttempts = 5
+)
+
+func (s *AuthService) EnrollTOTP(userID int) (*models.TwoFactorEnrollment, error) {
+	user, err := s.service.store.User().Find(userID)
+	if err == store.ErrRecordNotFound {
+		return nil, service.ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.isTwoFactorEnabled(userID)
+	if err != nil {
+		return nil, err
+	} else if enabled {
+		return nil, service.ErrTwoFactorIsAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.service.store.TwoFactor().SaveTOTP(&models.UserTOTP{
+		UserID:    userID,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := s.generateRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorEnrollment{
+		Secret:        secret,
+		URI:           totp.URI(s.service.config.Auth.TwoFactor.Issuer, user.Login, secret),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+func (s *AuthService) ConfirmTOTP(ctx context.Context, userID int, code string) error {
+	t, err := s.service.store.TwoFactor().FindTOTP(userID)
+	if err == store.ErrRecordNotFound {
+		return service.ErrTwoFactorIsNotEnrolled
+	} else if err != nil {
+		return err
+	}
+
+	if t.IsEnabled() {
+		return service.ErrTwoFactorIsAlreadyEnabled
+	}
+
+	ok, err := s.checkTOTPCode(t, code)
+	if err != nil {
+		return err
+	} else if !ok {
+		return service.ErrIncorrectTwoFactorCode
+	}
+
+	if err := s.service.store.TwoFactor().ConfirmTOTP(userID, time.Now()); err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionTwoFactorEnable,
+		TargetType:  models.AuditTargetUser,
+		TargetID:    strconv.Itoa(userID),
+	}, nil, nil)
+
+	return nil
+}
+
+func (s *AuthService) DisableTOTP(ctx context.Context, userID int, password, code string) error {
+	user, err := s.service.store.User().Find(userID)
+	if err == store.ErrRecordNotFound {
+		return service.ErrUserNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if !user.ComparePassword(password) {
+		return service.ErrIncorrectPassword
+	}
+
+	t, err := s.service.store.TwoFactor().FindTOTP(userID)
+	if err == store.ErrRecordNotFound {
+		return service.ErrTwoFactorIsNotEnabled
+	} else if err != nil {
+		return err
+	}
+
+	if !t.IsEnabled() {
+		return service.ErrTwoFactorIsNotEnabled
+	}
+
+	ok, err := s.checkSecondFactor(t, code)
+	if err != nil {
+		return err
+	} else if !ok {
+		return service.ErrIncorrectTwoFactorCode
+	}
+
+	if err := s.service.store.TwoFactor().DeleteTOTP(userID); err != nil {
+		return err
+	}
+
+	if err := s.service.store.TwoFactor().DeleteRecoveryCodes(userID); err != nil {
+		return err
+	}
+
+	s.service.audit(ctx, &models.AuditEntry{
+		ActorUserID: &userID,
+		Action:      models.AuditActionTwoFactorDisable,
+		TargetType:  models.AuditTargetUser,
+		TargetID:    strconv.Itoa(userID),
+	}, nil, nil)
+
+	return nil
+}
+
+func (s *AuthService) CompleteMFASignIn(ctx context.Context, signIn *models.MFASignIn) (*models.UserToken, error) {
+	if signIn.ChallengeToken == "" {
+		return nil, service.ErrInvalidMFAChallenge
+	}
+
+	challenge, err := s.service.store.VerificationToken().FindByHash(models.VerificationMFAChallenge, hashToken(signIn.ChallengeToken))
+	if err == store.ErrRecordNotFound {
+		return nil, service.ErrInvalidMFAChallenge
+	} else if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !challenge.Valid(now) || challenge.Attempts >= maxMFAChallengeAttempts {
+		return nil, service.ErrInvalidMFAChallenge
+	}
+
+	// Incorrect codes are throttled as incorrect passwords, so the code can't be guessed with new challenges
+	if signIn.IP != "" {
+		if err := s.throttle.check(loginAttemptsIPKey(signIn.IP)); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.throttle.check(loginAttemptsAccountKey(challenge.UserID)); err != nil {
+		return nil, err
+	}
+
+	t, err := s.service.store.TwoFactor().FindTOTP(challenge.UserID)
+	if err == store.ErrRecordNotFound {
+		return nil, service.ErrInvalidMFAChallenge
+	} else if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.checkSecondFactor(t, signIn.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		attempts, err := s.service.store.VerificationToken().AddAttempt(challenge.ID)
+		if err != nil {
+			return nil, err
+		}
+		if attempts >= maxMFAChallengeAttempts {
+			if _, err := s.service.store.VerificationToken().MarkUsed(challenge.ID, now); err != nil {
+				return nil, err
+			}
+		}
+
+		s.failSignIn(&models.User{ID: challenge.UserID}, signIn.IP)
+		s.auditFailedSignIn(ctx, &models.User{ID: challenge.UserID}, signIn.IP, map[string]interface{}{
+			"second_factor": true,
+			"attempts":      attempts,
+		})
+		return nil, service.ErrIncorrectTwoFactorCode
+	}
+
+	// The challenge is marked in one query, so it can't be exchanged twice by concurrent requests
+	ok, err = s.service.store.VerificationToken().MarkUsed(challenge.ID, now)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, service.ErrInvalidMFAChallenge
+	}
+
+	user, err := s.service.store.User().Find(challenge.UserID)
+	if err == store.ErrRecordNotFound {
+		return nil, service.ErrInvalidMFAChallenge
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := s.throttle.reset(loginAttemptsAccountKey(user.ID)); err != nil {
+		return nil, err
+	}
+
+	return s.openSession(ctx, user, signIn.DeviceName, signIn.UserAgent, signIn.IP)
+}
+
+func (s *AuthService) isTwoFactorEnabled(userID int) (bool, error) {
+	t, err := s.service.store.TwoFactor().FindTOTP(userID)
+	if err == store.ErrRecordNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return t.IsEnabled(), nil
+}
+
+// issueMFAChallenge creates the short-lived token that proves the password of the user was accepted
+func (s *AuthService) issueMFAChallenge(user *models.User) (*models.MFAChallenge, error) {
+	ttl := s.service.config.Auth.TwoFactor.ChallengeTTL
+	token, err := s.service.issueVerificationToken(user, models.VerificationMFAChallenge, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MFAChallenge{
+		Token:     token,
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
+}
+
+// checkSecondFactor accepts either the current TOTP code or one of the unused recovery codes
+func (s *AuthService) checkSecondFactor(t *models.UserTOTP, code string) (bool, error) {
+	code = normalizeCode(code)
+	if isTOTPCode(code) {
+		return s.checkTOTPCode(t, code)
+	}
+
+	return s.service.store.TwoFactor().UseRecoveryCode(t.UserID, hashToken(code), time.Now())
+}
+
+// checkTOTPCode verifies the code and remembers its time step, so the same code can't be used twice
+func (s *AuthService) checkTOTPCode(t *models.UserTOTP, code string) (bool, error) {
+	step, ok := totp.Verify(t.Secret, normalizeCode(code), time.Now(), totpSkew)
+	if !ok {
+		return false, nil
+	}
+
+	return s.service.store.TwoFactor().UseTOTPStep(t.UserID, step)
+}
+
+// generateRecoveryCodes replaces recovery codes of the user. Returns raw codes, only their hashes are stored
+func (s *AuthService) generateRecoveryCodes(userID int) ([]string, error) {
+	codes := make([]string, recoveryCodesCount)
+	hashes := make([]string, recoveryCodesCount)
+	for i := range codes {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		code := hex.EncodeToString(b)
+		codes[i] = code[:len(code)/2] + "-" + code[len(code)/2:]
+		hashes[i] = hashToken(code)
+	}
+
+	if err := s.service.store.TwoFactor().ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// normalizeCode removes separators users type in recovery codes
+func normalizeCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totp.Digits {
+		return false
+	}
+
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -11,6 +11,10 @@ type UniversityService struct {
 }
 
 func (s *UniversityService) Create(university *models.University) error {
+	if err := university.Validate(); err != nil {
+		return err
+	}
+
 	err := s.service.store.University().Create(university)
 	if err != nil {
 		return err
@@ -27,3 +31,11 @@ func (s *UniversityService) Find(id int) (*models.University, error) {
 	}
 	return university, nil
 }
+
+func (s *UniversityService) GetAll() ([]models.University, error) {
+	universities, err := s.service.store.University().GetAll()
+	if err != nil && err != store.ErrRecordNotFound {
+		return nil, err
+	}
+	return universities, nil
+}
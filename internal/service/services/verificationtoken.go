@@ -0,0 +1,105 @@
+package services
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/service"
+	"backend/internal/store"
+	"crypto/rand"
+	"encoding/base64"
+	"net/url"
+	"time"
+)
+
+const verificationTokenBytes = 32
+
+// issueVerificationToken invalidates previous tokens of the user with the purpose and creates a new one.
+//	Returns the raw token, only its hash is stored.
+func (s *Service) issueVerificationToken(user *models.User, purpose string, ttl time.Duration) (string, error) {
+	b := make([]byte, verificationTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	now := time.Now()
+	if err := s.store.VerificationToken().InvalidateUserTokens(user.ID, purpose, now); err != nil {
+		return "", err
+	}
+
+	err := s.store.VerificationToken().Create(&models.VerificationToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		Email:     user.Email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// useVerificationToken checks the token and marks it as used.
+//	Returns service.ErrInvalidVerificationToken, if the token is unknown, expired or already used.
+func (s *Service) useVerificationToken(purpose, token string) (*models.VerificationToken, error) {
+	t, err := s.findVerificationToken(purpose, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.markVerificationTokenUsed(t); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// findVerificationToken checks the token without using it.
+//	Returns service.ErrInvalidVerificationToken, if the token is unknown, expired or already used.
+func (s *Service) findVerificationToken(purpose, token string) (*models.VerificationToken, error) {
+	if token == "" {
+		return nil, service.ErrInvalidVerificationToken
+	}
+
+	t, err := s.store.VerificationToken().FindByHash(purpose, hashToken(token))
+	if err == store.ErrRecordNotFound {
+		return nil, service.ErrInvalidVerificationToken
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !t.Valid(time.Now()) {
+		return nil, service.ErrInvalidVerificationToken
+	}
+
+	return t, nil
+}
+
+// markVerificationTokenUsed marks the token found by findVerificationToken as used
+func (s *Service) markVerificationTokenUsed(t *models.VerificationToken) error {
+	// The token is marked in one query, so it can't be used twice by concurrent requests
+	ok, err := s.store.VerificationToken().MarkUsed(t.ID, time.Now())
+	if err != nil {
+		return err
+	} else if !ok {
+		return service.ErrInvalidVerificationToken
+	}
+
+	return nil
+}
+
+// addTokenToURL adds the token to the link as the "token" query parameter
+func addTokenToURL(rawURL, token string) (string, error) {
+	link, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
@@ -16,6 +16,9 @@ var (
 
 	ErrUserNotFound  = errors.New("user not found")
 	ErrGroupNotFound = errors.New("group not found")
+
+	//ErrLastMemberWithRole The member can't lose the role, no other member of the group has it
+	ErrLastMemberWithRole = errors.New("the last member of the group with the role")
 	//ErrLink
 )
 
@@ -3,12 +3,14 @@ package store
 import (
 	"backend/internal/api/v1/models"
 	"github.com/google/uuid"
+	"time"
 )
 
 type AuthRepository interface {
 	RegisterApp(app *models.RegisteredApp) error
 	DeleteApp(appUUID uuid.UUID) error
 	GetApp(appUUID uuid.UUID) (*models.RegisteredApp, error)
+	UpdateAppFingerprintPolicy(appUUID uuid.UUID, policy string) error
 	GetAppByName(name string) (*models.RegisteredApp, error)
 
 	AddAppToken(t *models.AppToken) error
@@ -19,11 +21,127 @@ type AuthRepository interface {
 
 	AddUserToken(t *models.UserToken) error
 	GetUserToken(userID int) (*models.UserToken, error)
+	GetUserTokenByRefreshToken(refreshToken string) (*models.UserToken, error)
+	// GetSessionToken returns the last issued token pair of the session
+	GetSessionToken(sessionID int) (*models.UserToken, error)
 	RemoveUserTokens(userID int) (int, error)
-	ClearUserTokens() error
 
 	SetUserTokenInvalidByToken(token string) error
 	SetUserTokenInvalidByUserID(userID int) error
+	SetUserTokenInvalidBySessionID(sessionID int) error
+
+	// MarkUserTokenUsed marks the refresh token as exchanged for a new pair.
+	//Returns ErrRecordNotFound, if the token is already used, e.g. by the concurrent refresh
+	MarkUserTokenUsed(tokenID int) error
+	// GetUserTokenFamily returns all token pairs issued by rotation from the same sign-in
+	GetUserTokenFamily(familyID uuid.UUID) ([]models.UserToken, error)
+	GetUserTokensIssuedAfter(userID int, issuedAfter time.Time) ([]models.UserToken, error)
+	SetUserTokenInvalidByFamily(familyID uuid.UUID) error
+}
+
+type SessionRepository interface {
+	Create(session *models.UserSession) error
+	Find(sessionID int) (*models.UserSession, error)
+	// FindByUser returns active sessions of the user ordered by the last use
+	FindByUser(userID int) ([]models.UserSession, error)
+	UpdateLastSeen(sessionID int, lastSeenAt time.Time) error
+	Revoke(sessionID int) error
+}
+
+type PersonalTokenRepository interface {
+	Create(token *models.PersonalToken) error
+	// FindByHash returns store.ErrRecordNotFound, if there is no token with the hash
+	FindByHash(tokenHash string) (*models.PersonalToken, error)
+	// FindByUser returns not revoked tokens of the user, the newest first
+	FindByUser(userID int) ([]models.PersonalToken, error)
+	// Revoke returns store.ErrRecordNotFound, if the user has no active token with the id
+	Revoke(userID, tokenID int, revokedAt time.Time) error
+	// RevokeByUser revokes all active tokens of the user
+	RevokeByUser(userID int, revokedAt time.Time) error
+	UpdateLastUsed(tokenID int, lastUsedAt time.Time) error
+}
+
+type OIDCRepository interface {
+	CreateIdentity(identity *models.ExternalIdentity) error
+	// FindIdentity returns store.ErrRecordNotFound, if the subject isn't linked to any user
+	FindIdentity(issuer, subject string) (*models.ExternalIdentity, error)
+	FindIdentitiesByUser(userID int) ([]models.ExternalIdentity, error)
+	UpdateIdentityLastLogin(identityID int, lastLoginAt time.Time) error
+
+	CreateLoginState(state *models.OIDCLoginState) error
+	// UseLoginState marks the valid state as used and returns it.
+	//Returns store.ErrRecordNotFound, if the state is unknown, expired or already used
+	UseLoginState(stateHash string, usedAt time.Time) (*models.OIDCLoginState, error)
+	DeleteExpiredLoginStates(now time.Time) error
+}
+
+// AuditRepository is append-only, the entries are never updated and are deleted only by the retention
+type AuditRepository interface {
+	Create(entry *models.AuditEntry) error
+	// Find returns up to filter.Limit entries matching the filter with ids less than filter.Cursor (if it's set),
+	//the newest first
+	Find(filter *models.AuditFilter) ([]models.AuditEntry, error)
+	// DeleteCreatedBefore deletes the entries older than the time and returns the number of deleted ones
+	DeleteCreatedBefore(createdBefore time.Time) (int, error)
+}
+
+type SigningKeyRepository interface {
+	Create(key *models.SigningKey) error
+	// FindValid returns keys that can verify tokens at the given time, the newest first
+	FindValid(now time.Time) ([]models.SigningKey, error)
+	// RetireActive sets the retirement time to all active keys except the given one
+	RetireActive(exceptKeyID string, retiredAt time.Time) error
+	DeleteRetired(before time.Time) (int64, error)
+}
+
+type VerificationTokenRepository interface {
+	Create(token *models.VerificationToken) error
+	// FindByHash returns store.ErrRecordNotFound, if there is no token with the hash and purpose
+	FindByHash(purpose, tokenHash string) (*models.VerificationToken, error)
+	// MarkUsed returns false, if the token was already used
+	MarkUsed(tokenID int, usedAt time.Time) (bool, error)
+	// InvalidateUserTokens marks all unused tokens of the user with the purpose as used
+	InvalidateUserTokens(userID int, purpose string, usedAt time.Time) error
+	// AddAttempt increments the number of failed attempts to use the token and returns it
+	AddAttempt(tokenID int) (int, error)
+}
+
+// LoginAttemptRepository stores counters of failed sign-in attempts
+type LoginAttemptRepository interface {
+	// Find returns ErrRecordNotFound, if the key has no failed attempts
+	Find(key string) (*models.LoginAttempts, error)
+	// AddFailure increments the counter of the key and returns it.
+	//The counter starts over, if the last failure was before resetBefore
+	AddFailure(key string, at, resetBefore time.Time) (*models.LoginAttempts, error)
+	// Lock locks the key until the time and resets its counter
+	Lock(key string, until time.Time) error
+	Delete(key string) error
+	// DeleteStale deletes unlocked counters that last failed before the time
+	DeleteStale(failedBefore, now time.Time) (int64, error)
+}
+
+type TwoFactorRepository interface {
+	// SaveTOTP creates or replaces the TOTP secret of the user
+	SaveTOTP(t *models.UserTOTP) error
+	FindTOTP(userID int) (*models.UserTOTP, error)
+	ConfirmTOTP(userID int, confirmedAt time.Time) error
+	// UseTOTPStep saves the time step of the accepted code.
+	//Returns false, if the step isn't newer than the last used one
+	UseTOTPStep(userID int, step int64) (bool, error)
+	DeleteTOTP(userID int) error
+
+	ReplaceRecoveryCodes(userID int, codeHashes []string) error
+	// UseRecoveryCode returns false, if the user has no unused code with the hash
+	UseRecoveryCode(userID int, codeHash string, usedAt time.Time) (bool, error)
+	DeleteRecoveryCodes(userID int) error
+}
+
+// TokenBlacklistRepository stores SHA-256 hashes of revoked access tokens until they expire
+type TokenBlacklistRepository interface {
+	Add(tokenHash string, expiresAt time.Time) error
+	// Exists reports whether the hash is blacklisted and hasn't expired at the given time
+	Exists(tokenHash string, now time.Time) (bool, error)
+	DeleteExpired(now time.Time) (int64, error)
 }
 
 type UserRepository interface {
@@ -35,6 +153,8 @@ type UserRepository interface {
 	// FindByEmail returns store.ErrRecordNotFound if db driver returned sql.ErrNoRows
 	//or another error if unknown one occurred.
 	FindByEmail(string) (*models.User, error)
+	ConfirmEmail(userID int, confirmedAt time.Time) error
+	UpdatePassword(userID int, encryptedPassword string) error
 
 	// CreateTester create user for testing
 	CreateTester() (*models.User, error)
@@ -46,9 +166,48 @@ type UserRepository interface {
 	/*FindByFullName(string) (*models.User, error)*/
 }
 
+// RoleRepository stores roles, their permissions and the roles granted to users.
+//	Roles of users are read by UserRepository.GetUserRoles, GroupRepository.GetMemberRoles and GetFacultyRoles
+type RoleRepository interface {
+	// Seed creates or updates the permissions and the roles, and replaces permissions of the roles
+	Seed(permissions []models.Permission, roles []models.Role) error
+	FindPermission(name string) (*models.Permission, error)
+
+	AddUserRole(userID, roleID int) error
+	RemoveUserRole(userID, roleID int) error
+	// SetMemberRole replaces roles of the group member with the role.
+	//Returns ErrRecordNotFound, if the user isn't a member of the group, and ErrLastMemberWithRole,
+	//if the member loses the keptRoleID role and no other member has it. Zero keptRoleID disables the check
+	SetMemberRole(userID, groupID, roleID, keptRoleID int) error
+	// TransferMemberRole replaces roles of the toUserID member with the role and roles of the fromUserID member
+	//with the replacement role in one transaction. Returns ErrRecordNotFound, if any of the users isn't a member of the group
+	TransferMemberRole(groupID, fromUserID, toUserID, roleID, replacementRoleID int) error
+
+	AddFacultyRole(userID, facultyID, roleID int) error
+	RemoveFacultyRole(userID, facultyID, roleID int) error
+	GetFacultyRoles(userID, facultyID int) ([]models.Role, error)
+}
+
 type UniversityRepository interface {
 	Create(university *models.University) error
 	Find(universityID int) (*models.University, error)
+	GetAll() ([]models.University, error)
+}
+
+// FacultyRepository stores the org tree of the universities: the faculties and their departments.
+//	Deleting the faculty deletes its departments, the groups and the subjects of the deleted departments are detached
+type FacultyRepository interface {
+	Create(faculty *models.Faculty) error
+	Find(facultyID int) (*models.Faculty, error)
+	Update(faculty *models.Faculty) error
+	Delete(facultyID int) error
+	GetFaculties(universityID int) ([]models.Faculty, error)
+
+	CreateDepartment(department *models.Department) error
+	FindDepartment(departmentID int) (*models.Department, error)
+	UpdateDepartment(department *models.Department) error
+	DeleteDepartment(departmentID int) error
+	GetDepartments(facultyID int) ([]models.Department, error)
 }
 
 type GroupRepository interface {
@@ -57,27 +216,75 @@ type GroupRepository interface {
 	Find(int) (*models.Group, error)
 	FindByName(string) (*models.Group, error)
 	Update(int, *models.UpdateGroup) error
+	// Delete deletes the group with its members, invites and the links to the tasks forever
 	Delete(int) error
+	// SoftDelete marks the group deleted. Returns ErrRecordNotFound, if the group is already deleted
+	SoftDelete(groupID int, deletedAt time.Time) error
+	// Restore returns ErrRecordNotFound, if the group isn't deleted
+	Restore(groupID int) error
+	// SetArchived archives the group at the time or unarchives it, if archivedAt is nil
+	SetArchived(groupID int, archivedAt *time.Time) error
+	GetDeletedGroups() ([]models.Group, error)
+	// DeleteGroupsDeletedBefore deletes forever the groups deleted before the time and returns the number of them
+	DeleteGroupsDeletedBefore(t time.Time) (int, error)
+	// SetDepartment attaches the group to the department or detaches it, if departmentID is nil
+	SetDepartment(groupID int, departmentID *int) error
+	// GetGroupsOfOrgNode returns the groups of the departments of the university, the faculty or the department,
+	//except the deleted ones
+	GetGroupsOfOrgNode(node models.OrgNode) ([]models.Group, error)
 
 	IsGroupExist(groupID int) (bool, error)
 
 	AddGroupMember(userID, groupID int, inviterID int) error
+	// ImportGroupMembers creates the new users and adds them and the users with userIDs to the group with the role
+	//in one transaction. IDs of the new users are set on success
+	ImportGroupMembers(groupID, inviterID, roleID int, newUsers []*models.User, userIDs []int) error
+	// RemoveGroupMember removes the member with its roles and the assignments of the user to the tasks of the group,
+	//except the tasks shared with the other groups of the user.
+	//Returns ErrRecordNotFound, if the user isn't a member of the group, and ErrLastMemberWithRole,
+	//if the member is the only one with the keptRoleID role. Zero keptRoleID disables the check
+	RemoveGroupMember(userID, groupID, keptRoleID int) error
 
 	IsUserGroupMember(userID, groupID int) (bool, error)
-	GetGroupsUserMemberOf(userID int) ([]models.Group, error) //Get IDs of groups that this user is a member of
+	// GetGroupsUserMemberOf returns not deleted groups the user is a member of, the archived ones only if includeArchived
+	GetGroupsUserMemberOf(userID int, includeArchived bool) ([]models.Group, error)
 	GetGroupMembers(groupID int) ([]models.User, error)
 	GetMembersCount(groupID int) (int, error)
+	CountMembersWithRole(groupID, roleID int) (int, error)
 	GetMemberRoles(userID, groupID int) ([]models.Role, error) //Get the roles that this user have
 	GetRolePermissions(roleID int) ([]models.Permission, error)
 
 	GetRole(roleID int) (*models.Role, error)
 	GetRoleByName(roleName string) (*models.Role, error)
 
-	GetGroupInvite(groupID int) (*models.GroupInvite, error)
+	AddGroupInvite(invite *models.GroupInvite) error
+	// GetGroupInvites returns not revoked invites of the group. Expired and used up invites are returned too
+	GetGroupInvites(groupID int) ([]models.GroupInvite, error)
 	GetGroupInviteByHash(inviteHash string) (*models.GroupInvite, error)
-	AddGroupInviteHash(invite *models.GroupInvite) error
-	DeleteGroupInvites(groupID int) error
-	DeleteGroupInviteByHash(hash string) error
+	// JoinGroupByInvite adds the user to the group of the invite with the role and counts the use of the invite
+	//in one transaction. Returns ErrRecordNotFound, if the invite is revoked, expired or used up at the moment
+	JoinGroupByInvite(userID, inviteID, roleID int) error
+	// RevokeGroupInvite returns ErrRecordNotFound, if the group has no such invite or it's already revoked
+	RevokeGroupInvite(groupID, inviteID int, revokedAt time.Time) error
+
+	CreateJoinRequest(request *models.GroupJoinRequest) error
+	FindJoinRequest(requestID int) (*models.GroupJoinRequest, error)
+	// GetJoinRequests returns the join requests of the group with the status, or all of them, if the status is empty
+	GetJoinRequests(groupID int, status string) ([]models.GroupJoinRequest, error)
+	GetUserJoinRequests(userID int) ([]models.GroupJoinRequest, error)
+	// ApproveJoinRequest adds the requester to the group with the role and records the decision in one transaction.
+	//Returns ErrRecordNotFound, if the request isn't pending
+	ApproveJoinRequest(requestID, deciderID, roleID int, decidedAt time.Time) error
+	// RejectJoinRequest returns ErrRecordNotFound, if the request isn't pending
+	RejectJoinRequest(requestID, deciderID int, decidedAt time.Time) error
+
+	// RolloverGroups moves the not archived groups of the university created before the yearStart to the next course
+	//and archives the groups of the finalCourse in one transaction, the previous names are kept in the history.
+	//Returns false, if the groups of the university are already rolled over to the academic year
+	RolloverGroups(rollover *models.GroupRollover, yearStart time.Time, finalCourse int) (bool, error)
+	// GetGroupRollovers returns the rollovers of the university, the latest academic year first
+	GetGroupRollovers(universityID int) ([]models.GroupRollover, error)
+	GetGroupNameHistory(groupID int) ([]models.GroupNameHistory, error)
 }
 
 type SubjectRepository interface {
@@ -85,6 +292,10 @@ type SubjectRepository interface {
 	GetAll(limit, offset int) ([]models.Subject, error)
 	Find(int) (*models.Subject, error)
 	Delete(int) (*models.Subject, error)
+	// SetDepartment attaches the subject to the department or detaches it, if departmentID is nil
+	SetDepartment(subjectID int, departmentID *int) error
+	// GetSubjectsOfOrgNode returns the subjects of the departments of the university, the faculty or the department
+	GetSubjectsOfOrgNode(node models.OrgNode) ([]models.Subject, error)
 }
 
 type TaskRepository interface {
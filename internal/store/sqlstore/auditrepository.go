@@ -0,0 +1,103 @@
+package sqlstore
+
+import (
+	"backend/internal/api/v1/models"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type AuditRepository struct {
+	store *Store
+}
+
+const auditEntryColumns = `id, actor_user_id, actor_app_id, impersonator_id, action, target_type, target_id, request_id, ip, 
+	before, after, details, created_at`
+
+func (r *AuditRepository) Create(e *models.AuditEntry) error {
+	query := `INSERT INTO auditlog (actor_user_id, actor_app_id, impersonator_id, action, target_type, target_id, request_id, 
+				ip, before, after, details, created_at) 
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`
+	return r.store.db.QueryRow(query,
+		e.ActorUserID,
+		e.ActorAppID,
+		e.ImpersonatorID,
+		e.Action,
+		e.TargetType,
+		e.TargetID,
+		e.RequestID,
+		e.IP,
+		nullableJSON(e.Before),
+		nullableJSON(e.After),
+		nullableJSON(e.Details),
+		e.CreatedAt).Scan(&e.ID)
+}
+
+func (r *AuditRepository) Find(f *models.AuditFilter) ([]models.AuditEntry, error) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if f.Cursor > 0 {
+		where("id < $%d", f.Cursor)
+	}
+	if f.ActorUserID != 0 {
+		where("actor_user_id = $%d", f.ActorUserID)
+	}
+	if f.ActorAppID != nil {
+		where("actor_app_id = $%d", *f.ActorAppID)
+	}
+	if f.Action != "" {
+		where("action = $%d", f.Action)
+	}
+	if f.TargetType != "" {
+		where("target_type = $%d", f.TargetType)
+	}
+	if f.TargetID != "" {
+		where("target_id = $%d", f.TargetID)
+	}
+	if f.RequestID != "" {
+		where("request_id = $%d", f.RequestID)
+	}
+	if !f.From.IsZero() {
+		where("created_at >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		where("created_at < $%d", f.To)
+	}
+
+	query := `SELECT ` + auditEntryColumns + ` FROM auditlog`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, f.Limit)
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d`, len(args))
+
+	var entries []models.AuditEntry
+	if err := r.store.db.Select(&entries, query, args...); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (r *AuditRepository) DeleteCreatedBefore(createdBefore time.Time) (int, error) {
+	res, err := r.store.db.Exec(`DELETE FROM auditlog WHERE created_at < $1`, createdBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// nullableJSON returns nil for the empty JSON, so it's stored as NULL
+func nullableJSON(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
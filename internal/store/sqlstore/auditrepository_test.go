@@ -0,0 +1,51 @@
+package sqlstore
+
+import (
+	"backend/internal/api/v1/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAuditRepository_DeleteActor(t *testing.T) {
+	db, teardown := TestDB(t, databaseDriver, databaseURL)
+	defer teardown("user", "auditlog")
+
+	s := New(db)
+	u := models.TestUser(t)
+	assert.NoError(t, s.User().Create(u))
+
+	e := &models.AuditEntry{
+		ActorUserID:    &u.ID,
+		ImpersonatorID: &u.ID,
+		Action:         models.AuditActionSignIn,
+		CreatedAt:      time.Now(),
+	}
+	assert.NoError(t, s.Audit().Create(e))
+
+	_, err := db.Exec(`DELETE FROM "user" WHERE id = $1`, u.ID)
+	assert.NoError(t, err)
+
+	entries, err := s.Audit().Find(&models.AuditFilter{Action: models.AuditActionSignIn, Limit: 10})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Nil(t, entries[0].ActorUserID)
+		assert.Nil(t, entries[0].ImpersonatorID)
+		assert.Equal(t, e.ID, entries[0].ID)
+	}
+}
+
+func TestAuditRepository_Update(t *testing.T) {
+	db, teardown := TestDB(t, databaseDriver, databaseURL)
+	defer teardown("auditlog")
+
+	s := New(db)
+	e := &models.AuditEntry{
+		Action:    models.AuditActionSignIn,
+		CreatedAt: time.Now(),
+	}
+	assert.NoError(t, s.Audit().Create(e))
+
+	_, err := db.Exec(`UPDATE auditlog SET action = $1 WHERE id = $2`, models.AuditActionSignInFailed, e.ID)
+	assert.Error(t, err)
+}
@@ -15,8 +15,8 @@ type AuthRepository struct {
 //	App auth
 
 func (r *AuthRepository) RegisterApp(app *models.RegisteredApp) error {
-	query := `INSERT INTO registeredapp (id, app_name, app_secret) VALUES ($1, $2, $3) returning id`
-	err := r.store.db.QueryRow(query, app.ID, app.AppName, app.AppSecret).Scan(&app.ID)
+	query := `INSERT INTO registeredapp (id, app_name, app_secret_hash, scope, fingerprint_policy) VALUES ($1, $2, $3, $4, $5) returning id`
+	err := r.store.db.QueryRow(query, app.ID, app.AppName, app.SecretHash, app.Scope, app.FingerprintPolicy).Scan(&app.ID)
 	if err != nil {
 		return err
 	}
@@ -27,14 +27,32 @@ func (r *AuthRepository) RegisterApp(app *models.RegisteredApp) error {
 func (r *AuthRepository) GetApp(appUUID uuid.UUID) (*models.RegisteredApp, error) {
 	app := &models.RegisteredApp{}
 
-	query := `SELECT id, app_name, app_secret FROM registeredapp WHERE id = $1`
+	query := `SELECT id, app_name, app_secret_hash, scope, fingerprint_policy FROM registeredapp WHERE id = $1`
 	err := r.store.db.QueryRow(query, appUUID).Scan(
 		&app.ID,
 		&app.AppName,
-		&app.AppSecret)
+		&app.SecretHash,
+		&app.Scope,
+		&app.FingerprintPolicy)
 	return app, store.HandleErrorNoRows(err)
 }
 
+func (r *AuthRepository) UpdateAppFingerprintPolicy(appUUID uuid.UUID, policy string) error {
+	query := `UPDATE registeredapp SET fingerprint_policy = $2 WHERE id = $1`
+	res, err := r.store.db.Exec(query, appUUID, policy)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	return nil
+}
+
 func (r *AuthRepository) DeleteApp(appUUID uuid.UUID) error {
 	query := `DELETE FROM registeredapp WHERE id = $1`
 	_, err := r.store.db.Exec(query, appUUID)
@@ -43,14 +61,15 @@ func (r *AuthRepository) DeleteApp(appUUID uuid.UUID) error {
 
 func (r *AuthRepository) AddAppToken(t *models.AppToken) error {
 	query := `INSERT INTO apptoken 
-    	(token, app_id, issue_timestamp, start_timestamp, expiration_timestamp) 
-		VALUES($1, $2, $3, $4, $5) RETURNING token`
+    	(token, app_id, issue_timestamp, start_timestamp, expiration_timestamp, scope) 
+		VALUES($1, $2, $3, $4, $5, $6) RETURNING token`
 	return r.store.db.QueryRow(query,
 		t.AppToken,
 		t.AppID,
 		t.IssueTokenTimestamp,
 		t.StartTimestamp,
-		t.ExpirationTimestamp).Scan(&t.AppToken)
+		t.ExpirationTimestamp,
+		t.Scope).Scan(&t.AppToken)
 }
 
 func (r *AuthRepository) RemoveAppTokens(appUUID uuid.UUID) error {
@@ -62,79 +81,196 @@ func (r *AuthRepository) RemoveAppTokens(appUUID uuid.UUID) error {
 func (r *AuthRepository) GetAppTokenInfo(token string) (*models.AppToken, error) {
 	t := &models.AppToken{}
 
-	query := `SELECT token, app_id, issue_timestamp, start_timestamp, expiration_timestamp FROM apptoken WHERE token = $1 limit 1`
+	query := `SELECT token, app_id, issue_timestamp, start_timestamp, expiration_timestamp, scope FROM apptoken WHERE token = $1 limit 1`
 	err := r.store.db.QueryRow(query, token).
 		Scan(&t.AppToken,
 			&t.AppID,
 			&t.IssueTokenTimestamp,
 			&t.StartTimestamp,
-			&t.ExpirationTimestamp)
+			&t.ExpirationTimestamp,
+			&t.Scope)
 	return t, store.HandleErrorNoRows(err)
 }
 
 func (r *AuthRepository) GetAppTokenByAppUUID(appUUID uuid.UUID) (*models.AppToken, error) {
 	t := &models.AppToken{}
 
-	query := `SELECT token, app_id, issue_timestamp, start_timestamp, expiration_timestamp
+	query := `SELECT token, app_id, issue_timestamp, start_timestamp, expiration_timestamp, scope
 				FROM apptoken WHERE app_id = $1 ORDER BY issue_timestamp DESC LIMIT 1`
 	err := r.store.db.QueryRow(query, appUUID.String()).
 		Scan(&t.AppToken,
 			&t.AppID,
 			&t.IssueTokenTimestamp,
 			&t.StartTimestamp,
-			&t.ExpirationTimestamp)
+			&t.ExpirationTimestamp,
+			&t.Scope)
 	return t, store.HandleErrorNoRows(err)
 }
 
 func (r *AuthRepository) GetAppByName(name string) (*models.RegisteredApp, error) {
 	app := &models.RegisteredApp{}
 
-	query := `SELECT id, app_name, app_secret FROM registeredapp WHERE app_name = $1`
-	err := r.store.db.QueryRow(query, name).Scan(&app.ID, &app.AppName, &app.AppSecret)
+	query := `SELECT id, app_name, app_secret_hash, scope, fingerprint_policy FROM registeredapp WHERE app_name = $1`
+	err := r.store.db.QueryRow(query, name).Scan(&app.ID, &app.AppName, &app.SecretHash, &app.Scope, &app.FingerprintPolicy)
 	return app, store.HandleErrorNoRows(err)
 }
 
 //	User auth
 
+//	The tokens issued before the sessions have neither the session nor the family, the zero FamilyID means no family
+const userTokenColumns = `id, user_id, COALESCE(session_id, 0), COALESCE(family_id, '00000000-0000-0000-0000-000000000000'),
+				COALESCE(parent_id, 0), access_token, refresh_token,
+				issue_token_timestamp, start_timestamp, expiration_timestamp, used_at`
+
+func scanUserToken(row interface{ Scan(...interface{}) error }, t *models.UserToken) error {
+	return row.Scan(
+		&t.ID,
+		&t.UserID,
+		&t.SessionID,
+		&t.FamilyID,
+		&t.ParentID,
+		&t.AccessToken,
+		&t.RefreshToken,
+		&t.IssueTokenTimestamp,
+		&t.StartTimestamp,
+		&t.ExpirationTimestamp,
+		&t.UsedAt)
+}
+
 func (r *AuthRepository) AddUserToken(t *models.UserToken) error {
-	query := `DELETE FROM usertoken WHERE user_id = $1 AND
-                            id NOT IN (SELECT id FROM usertoken WHERE user_id = $1
-                                    	ORDER BY issue_token_timestamp DESC LIMIT 4)`
-	_, err := r.store.db.Exec(query, t.UserID)
+	//	Expired tokens of the session are no longer needed to detect the reuse
+	query := `DELETE FROM usertoken WHERE session_id = $1 AND expiration_timestamp < $2`
+	_, err := r.store.db.Exec(query, t.SessionID, time.Now())
 	if err != nil {
 		return err
 	}
+
+	var parentID interface{}
+	if t.ParentID != 0 {
+		parentID = t.ParentID
+	}
+
 	query = `INSERT INTO usertoken 
-				(user_id, access_token, refresh_token, issue_token_timestamp, start_timestamp, expiration_timestamp)  
-				VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+				(user_id, session_id, family_id, parent_id, access_token, refresh_token, issue_token_timestamp, start_timestamp, expiration_timestamp)  
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
 	return r.store.db.QueryRow(query,
 		t.UserID,
+		t.SessionID,
+		t.FamilyID,
+		parentID,
 		t.AccessToken,
 		t.RefreshToken,
 		t.IssueTokenTimestamp,
 		t.StartTimestamp,
 		t.ExpirationTimestamp,
-	).Err()
+	).Scan(&t.ID)
 }
 
 func (r *AuthRepository) GetUserToken(userID int) (*models.UserToken, error) {
 	u := &models.UserToken{}
 
-	query := `SELECT id, user_id, access_token, refresh_token, issue_token_timestamp, start_timestamp, expiration_timestamp 
+	query := `SELECT ` + userTokenColumns + ` 
 				FROM usertoken 
 				WHERE user_id = $1 
 				order by issue_token_timestamp desc limit 1`
-	err := r.store.db.QueryRow(query, userID).Scan(
-		&u.ID,
-		&u.UserID,
-		&u.AccessToken,
-		&u.RefreshToken,
-		&u.IssueTokenTimestamp,
-		&u.StartTimestamp,
-		&u.ExpirationTimestamp)
+	err := scanUserToken(r.store.db.QueryRow(query, userID), u)
+	return u, store.HandleErrorNoRows(err)
+}
+
+func (r *AuthRepository) GetUserTokenByRefreshToken(refreshToken string) (*models.UserToken, error) {
+	u := &models.UserToken{}
+
+	query := `SELECT ` + userTokenColumns + ` 
+				FROM usertoken 
+				WHERE refresh_token = $1`
+	err := scanUserToken(r.store.db.QueryRow(query, refreshToken), u)
+	return u, store.HandleErrorNoRows(err)
+}
+
+func (r *AuthRepository) GetSessionToken(sessionID int) (*models.UserToken, error) {
+	u := &models.UserToken{}
+
+	query := `SELECT ` + userTokenColumns + ` 
+				FROM usertoken 
+				WHERE session_id = $1 
+				order by issue_token_timestamp desc limit 1`
+	err := scanUserToken(r.store.db.QueryRow(query, sessionID), u)
 	return u, store.HandleErrorNoRows(err)
 }
 
+func (r *AuthRepository) GetUserTokenFamily(familyID uuid.UUID) ([]models.UserToken, error) {
+	var tokens []models.UserToken
+
+	query := `SELECT ` + userTokenColumns + ` 
+				FROM usertoken 
+				WHERE family_id = $1 
+				order by issue_token_timestamp`
+	rows, err := r.store.db.Query(query, familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		t := models.UserToken{}
+		if err := scanUserToken(rows, &t); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+
+	return tokens, rows.Err()
+}
+
+func (r *AuthRepository) GetUserTokensIssuedAfter(userID int, issuedAfter time.Time) ([]models.UserToken, error) {
+	var tokens []models.UserToken
+
+	query := `SELECT ` + userTokenColumns + ` 
+				FROM usertoken 
+				WHERE user_id = $1 AND issue_token_timestamp > $2 
+				order by issue_token_timestamp`
+	rows, err := r.store.db.Query(query, userID, issuedAfter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		t := models.UserToken{}
+		if err := scanUserToken(rows, &t); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+
+	return tokens, rows.Err()
+}
+
+func (r *AuthRepository) MarkUserTokenUsed(tokenID int) error {
+	//	The condition makes the exchange atomic: only one of the concurrent refreshes marks the token
+	query := `UPDATE usertoken SET used_at = $1 WHERE id = $2 AND used_at IS NULL`
+	res, err := r.store.db.Exec(query, time.Now(), tokenID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *AuthRepository) SetUserTokenInvalidByFamily(familyID uuid.UUID) error {
+	query := `UPDATE usertoken
+				SET expiration_timestamp = $1, exit_timestamp = $1
+				WHERE family_id = $2 AND expiration_timestamp > $1`
+	_, err := r.store.db.Exec(query, time.Now(), familyID)
+	return err
+}
+
 func (r *AuthRepository) AddUserRefreshToken(token *models.UserToken) error {
 	query := `INSERT INTO usertoken VALUES ($1) RETURNING id`
 	err := r.store.db.QueryRow(query, token).Scan(&token.ID)
@@ -158,15 +294,6 @@ func (r *AuthRepository) RemoveUserTokens(userID int) (int, error) {
 	return deletedRows, store.HandleIgnoreErrorNoRows(err)
 }
 
-func (r *AuthRepository) ClearUserTokens() error {
-	query := `DELETE FROM usertoken WHERE
-                            user_id in (select id from "user" where login = 'admin') AND
-                            id NOT IN (SELECT id FROM usertoken WHERE user_id = (select id from "user" where login = 'admin')
-                                    	ORDER BY issue_token_timestamp DESC LIMIT 1)`
-	_, err := r.store.db.Exec(query)
-	return err
-}
-
 func (r *AuthRepository) SetUserTokenInvalidByToken(token string) error {
 	query := `UPDATE usertoken 
 				SET	expiration_timestamp = $1, exit_timestamp = $1
@@ -178,7 +305,15 @@ func (r *AuthRepository) SetUserTokenInvalidByToken(token string) error {
 func (r *AuthRepository) SetUserTokenInvalidByUserID(userID int) error {
 	query := `UPDATE usertoken
 				SET expiration_timestamp = $1, exit_timestamp = $1
-				WHERE user_id = $2 AND expiration_timestamp < $1`
+				WHERE user_id = $2 AND expiration_timestamp > $1`
 	_, err := r.store.db.Exec(query, time.Now(), userID)
 	return store.HandleErrorNoRows(err)
 }
+
+func (r *AuthRepository) SetUserTokenInvalidBySessionID(sessionID int) error {
+	query := `UPDATE usertoken
+				SET expiration_timestamp = $1, exit_timestamp = $1
+				WHERE session_id = $2 AND expiration_timestamp > $1`
+	_, err := r.store.db.Exec(query, time.Now(), sessionID)
+	return store.HandleErrorNoRows(err)
+}
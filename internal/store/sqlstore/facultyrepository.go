@@ -0,0 +1,99 @@
+package sqlstore
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/store"
+	"fmt"
+)
+
+type FacultyRepository struct {
+	store *Store
+}
+
+func (r *FacultyRepository) Create(faculty *models.Faculty) error {
+	query := `INSERT INTO faculty (university_id, name) VALUES ($1, $2) RETURNING id, created_at`
+	return r.store.db.QueryRow(query, faculty.UniversityID, faculty.Name).Scan(&faculty.ID, &faculty.CreatedAt)
+}
+
+func (r *FacultyRepository) Find(facultyID int) (*models.Faculty, error) {
+	faculty := &models.Faculty{}
+	query := `SELECT id, university_id, name, created_at FROM faculty WHERE id = $1`
+	err := r.store.db.Get(faculty, query, facultyID)
+	return faculty, store.HandleErrorNoRows(err)
+}
+
+func (r *FacultyRepository) Update(faculty *models.Faculty) error {
+	query := `UPDATE faculty SET name = $2 WHERE id = $1`
+	return r.exec(query, faculty.ID, faculty.Name)
+}
+
+func (r *FacultyRepository) Delete(facultyID int) error {
+	return r.exec(`DELETE FROM faculty WHERE id = $1`, facultyID)
+}
+
+func (r *FacultyRepository) GetFaculties(universityID int) ([]models.Faculty, error) {
+	var faculties []models.Faculty
+	query := `SELECT id, university_id, name, created_at FROM faculty WHERE university_id = $1 ORDER BY name, id`
+	err := r.store.db.Select(&faculties, query, universityID)
+	return faculties, store.HandleErrorNoRows(err)
+}
+
+func (r *FacultyRepository) CreateDepartment(department *models.Department) error {
+	query := `INSERT INTO department (faculty_id, name) VALUES ($1, $2) RETURNING id, created_at`
+	return r.store.db.QueryRow(query, department.FacultyID, department.Name).Scan(&department.ID, &department.CreatedAt)
+}
+
+func (r *FacultyRepository) FindDepartment(departmentID int) (*models.Department, error) {
+	department := &models.Department{}
+	query := `SELECT id, faculty_id, name, created_at FROM department WHERE id = $1`
+	err := r.store.db.Get(department, query, departmentID)
+	return department, store.HandleErrorNoRows(err)
+}
+
+func (r *FacultyRepository) UpdateDepartment(department *models.Department) error {
+	query := `UPDATE department SET name = $2 WHERE id = $1`
+	return r.exec(query, department.ID, department.Name)
+}
+
+func (r *FacultyRepository) DeleteDepartment(departmentID int) error {
+	return r.exec(`DELETE FROM department WHERE id = $1`, departmentID)
+}
+
+func (r *FacultyRepository) GetDepartments(facultyID int) ([]models.Department, error) {
+	var departments []models.Department
+	query := `SELECT id, faculty_id, name, created_at FROM department WHERE faculty_id = $1 ORDER BY name, id`
+	err := r.store.db.Select(&departments, query, facultyID)
+	return departments, store.HandleErrorNoRows(err)
+}
+
+// exec returns ErrRecordNotFound, if the query doesn't change any row
+func (r *FacultyRepository) exec(query string, args ...interface{}) error {
+	res, err := r.store.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// orgNodeCondition returns the condition on the department_id and the university_id columns
+//	selecting the rows of the node of the org tree. The ID of the node is the $1 argument of the query
+func orgNodeCondition(node models.OrgNode) (string, error) {
+	switch node.Type {
+	case models.OrgNodeUniversity:
+		return `department_id IN (SELECT d.id FROM department d JOIN faculty f ON f.id = d.faculty_id
+			WHERE f.university_id = $1)`, nil
+	case models.OrgNodeFaculty:
+		return `department_id IN (SELECT id FROM department WHERE faculty_id = $1)`, nil
+	case models.OrgNodeDepartment:
+		return `department_id = $1`, nil
+	default:
+		return "", fmt.Errorf("unknown node of the org tree %q", node.Type)
+	}
+}
@@ -3,6 +3,7 @@ package sqlstore
 import (
 	"backend/internal/api/v1/models"
 	"backend/internal/store"
+	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/jackc/pgconn"
@@ -15,9 +16,12 @@ type GroupRepository struct {
 	store *Store
 }
 
+const groupColumns = `id, custom_name, university_id, specialization_name, start_year, course_number, group_number, join_mode,
+	created_at, archived_at, deleted_at, department_id`
+
 func (r *GroupRepository) Create(g *models.Group) error {
-	query := `INSERT INTO public.group (custom_name, university_id, specialization_name, start_year, course_number, group_number, created_at) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`
+	query := `INSERT INTO public.group (custom_name, university_id, specialization_name, start_year, course_number, group_number, join_mode, created_at) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at`
 
 	err := r.store.db.QueryRow(query,
 		g.CustomName,
@@ -26,6 +30,7 @@ func (r *GroupRepository) Create(g *models.Group) error {
 		g.StartYear,
 		g.CourseNumber,
 		g.GroupNumber,
+		g.JoinMode,
 		time.Now()).Scan(&g.ID, &g.CreatedAt)
 	g.CompileFullGroupNameAndCompareCustom()
 	return err
@@ -34,7 +39,7 @@ func (r *GroupRepository) Create(g *models.Group) error {
 func (r *GroupRepository) GetAllGroups(limit, offset int) ([]models.Group, error) {
 	var groups []models.Group
 
-	query := `SELECT * FROM public.group ORDER BY id`
+	query := `SELECT ` + groupColumns + ` FROM public.group WHERE deleted_at IS NULL ORDER BY id`
 	query, err := r.store.AddLimitAndOffsetToQuery(query, limit, offset)
 	if err != nil {
 		return nil, err
@@ -54,7 +59,7 @@ func (r *GroupRepository) GetAllGroups(limit, offset int) ([]models.Group, error
 
 func (r *GroupRepository) Find(id int) (*models.Group, error) {
 	g := &models.Group{}
-	query := `SELECT id, custom_name, university_id, specialization_name, start_year, course_number, group_number, created_at FROM public.group where id = $1`
+	query := `SELECT ` + groupColumns + ` FROM public.group where id = $1`
 	err := r.store.db.QueryRow(query, id).Scan(
 		&g.ID,
 		&g.CustomName,
@@ -63,14 +68,18 @@ func (r *GroupRepository) Find(id int) (*models.Group, error) {
 		&g.StartYear,
 		&g.CourseNumber,
 		&g.GroupNumber,
-		&g.CreatedAt)
+		&g.JoinMode,
+		&g.CreatedAt,
+		&g.ArchivedAt,
+		&g.DeletedAt,
+		&g.DepartmentID)
 	g.CompileFullGroupNameAndCompareCustom()
 	return g, store.HandleErrorNoRows(err)
 }
 
 func (r *GroupRepository) FindByName(name string) (*models.Group, error) {
 	g := &models.Group{}
-	query := `SELECT id, custom_name, university_id, specialization_name, start_year, course_number, group_number, created_at  FROM public.group WHERE custom_name = $1`
+	query := `SELECT ` + groupColumns + ` FROM public.group WHERE custom_name = $1`
 	err := r.store.db.QueryRow(query, name).Scan(
 		&g.ID,
 		&g.CustomName,
@@ -79,7 +88,11 @@ func (r *GroupRepository) FindByName(name string) (*models.Group, error) {
 		&g.StartYear,
 		&g.CourseNumber,
 		&g.GroupNumber,
-		&g.CreatedAt)
+		&g.JoinMode,
+		&g.CreatedAt,
+		&g.ArchivedAt,
+		&g.DeletedAt,
+		&g.DepartmentID)
 	g.CompileFullGroupNameAndCompareCustom()
 	return g, store.HandleErrorNoRows(err)
 }
@@ -89,15 +102,31 @@ func (r *GroupRepository) Update(groupID int, up *models.UpdateGroup) error {
 	args := make([]interface{}, 0)
 	argID := 1
 
-	if up.Name != nil {
-		setValues = append(setValues, fmt.Sprintf("specialization_name=$%d", argID))
-		args = append(args, *up.Name)
+	set := func(column string, value interface{}) {
+		setValues = append(setValues, fmt.Sprintf("%s=$%d", column, argID))
+		args = append(args, value)
 		argID++
 	}
 	if up.UniversityID != nil {
-		setValues = append(setValues, fmt.Sprintf("university_id=$%d", argID))
-		args = append(args, *up.UniversityID)
-		argID++
+		set("university_id", *up.UniversityID)
+	}
+	if up.CustomName != nil {
+		set("custom_name", *up.CustomName)
+	}
+	if up.SpecializationName != nil {
+		set("specialization_name", *up.SpecializationName)
+	}
+	if up.StartYear != nil {
+		set("start_year", *up.StartYear)
+	}
+	if up.CourseNumber != nil {
+		set("course_number", *up.CourseNumber)
+	}
+	if up.GroupNumber != nil {
+		set("group_number", *up.GroupNumber)
+	}
+	if up.JoinMode != nil {
+		set("join_mode", *up.JoinMode)
 	}
 
 	setQuery := strings.Join(setValues, ", ")
@@ -106,9 +135,6 @@ func (r *GroupRepository) Update(groupID int, up *models.UpdateGroup) error {
 		setQuery, argID)
 	args = append(args, groupID)
 
-	fmt.Printf("updateQuery: %s\n", query)
-	fmt.Printf("args: %v\n", args)
-
 	logrus.Debugf("updateQuery: %s", query)
 	logrus.Debugf("args: %s", args)
 
@@ -144,6 +170,8 @@ func (r *GroupRepository) Delete(id int) (err error) {
 
 	tx.MustExec(`DELETE FROM groupinvitehashes WHERE group_id = $1`, id)
 
+	tx.MustExec(`DELETE FROM groupjoinrequest WHERE group_id = $1`, id)
+
 	tx.MustExec(`DELETE FROM "group" WHERE id = $1`, id)
 
 	err = tx.Commit()
@@ -151,6 +179,87 @@ func (r *GroupRepository) Delete(id int) (err error) {
 	return store.HandleIgnoreErrorNoRows(err)
 }
 
+func (r *GroupRepository) SoftDelete(groupID int, deletedAt time.Time) error {
+	query := `UPDATE "group" SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`
+	return r.updateOne(query, deletedAt, groupID)
+}
+
+func (r *GroupRepository) Restore(groupID int) error {
+	query := `UPDATE "group" SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`
+	return r.updateOne(query, groupID)
+}
+
+func (r *GroupRepository) SetArchived(groupID int, archivedAt *time.Time) error {
+	query := `UPDATE "group" SET archived_at = $1 WHERE id = $2`
+	return r.updateOne(query, archivedAt, groupID)
+}
+
+// updateOne returns ErrRecordNotFound, if the query doesn't change the group
+func (r *GroupRepository) updateOne(query string, args ...interface{}) error {
+	res, err := r.store.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *GroupRepository) GetDeletedGroups() ([]models.Group, error) {
+	var groups []models.Group
+
+	query := `SELECT ` + groupColumns + ` FROM "group" WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC`
+	err := r.store.db.Select(&groups, query)
+	for i := range groups {
+		groups[i].CompileFullGroupNameAndCompareCustom()
+	}
+
+	return groups, store.HandleErrorNoRows(err)
+}
+
+func (r *GroupRepository) DeleteGroupsDeletedBefore(t time.Time) (int, error) {
+	var ids []int
+	query := `SELECT id FROM "group" WHERE deleted_at < $1`
+	if err := r.store.db.Select(&ids, query, t); err != nil {
+		return 0, err
+	}
+
+	for i, id := range ids {
+		if err := r.Delete(id); err != nil {
+			return i, err
+		}
+	}
+
+	return len(ids), nil
+}
+
+func (r *GroupRepository) SetDepartment(groupID int, departmentID *int) error {
+	query := `UPDATE "group" SET department_id = $2 WHERE id = $1 AND deleted_at IS NULL`
+	return r.updateOne(query, groupID, departmentID)
+}
+
+func (r *GroupRepository) GetGroupsOfOrgNode(node models.OrgNode) ([]models.Group, error) {
+	var groups []models.Group
+
+	condition, err := orgNodeCondition(node)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + groupColumns + ` FROM "group" WHERE deleted_at IS NULL AND ` + condition + ` ORDER BY id`
+	err = r.store.db.Select(&groups, query, node.ID)
+	for i := range groups {
+		groups[i].CompileFullGroupNameAndCompareCustom()
+	}
+
+	return groups, store.HandleErrorNoRows(err)
+}
+
 func (r *GroupRepository) IsGroupExist(groupID int) (bool, error) {
 	query := `SELECT FROM public.group WHERE id = $1`
 	err := r.store.db.QueryRow(query, groupID).Err()
@@ -186,6 +295,117 @@ func (r *GroupRepository) AddGroupMember(userID, groupID int, inviterID int) err
 	return nil
 }
 
+func (r *GroupRepository) ImportGroupMembers(groupID, inviterID, roleID int, newUsers []*models.User, userIDs []int) error {
+	tx, err := r.store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids := append([]int{}, userIDs...)
+	query := `INSERT INTO public.user (login, full_name, email, encrypted_password, created_at)
+				VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	for _, u := range newUsers {
+		if err := u.Validate(); err != nil {
+			return err
+		}
+		if err := u.BeforeCreate(); err != nil {
+			return err
+		}
+
+		if err := tx.QueryRow(query, u.Login, u.FullName, u.Email, u.EncryptedPassword, time.Now()).
+			Scan(&u.ID, &u.CreatedAt); err != nil {
+			return err
+		}
+		ids = append(ids, u.ID)
+	}
+
+	for _, id := range ids {
+		var memberID int
+		query = `INSERT INTO groupmember (user_id, group_id, invited_by_id) VALUES ($1, $2, $3) RETURNING id`
+		if err := tx.QueryRow(query, id, groupID, inviterID).Scan(&memberID); err != nil {
+			return err
+		}
+
+		query = `INSERT INTO groupmemberroles (group_member_id, role_id) VALUES ($1, $2)`
+		if _, err := tx.Exec(query, memberID, roleID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *GroupRepository) RemoveGroupMember(userID, groupID, keptRoleID int) error {
+	tx, err := r.store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var memberID int
+	query := `SELECT id FROM groupmember WHERE user_id = $1 AND group_id = $2`
+	if err := tx.QueryRow(query, userID, groupID).Scan(&memberID); err != nil {
+		return store.HandleErrorNoRows(err)
+	}
+
+	if keptRoleID != 0 {
+		if err := checkNotLastMemberWithRole(tx, groupID, memberID, keptRoleID); err != nil {
+			return err
+		}
+	}
+
+	//	The tasks shared with the other groups of the user are kept
+	query = `DELETE FROM taskonuser WHERE user_id = $1 AND task_id IN
+				(SELECT task_id FROM taskongroup WHERE group_id = $2) AND task_id NOT IN
+				(SELECT task_id FROM taskongroup WHERE group_id IN
+					(SELECT group_id FROM groupmember WHERE user_id = $1 AND group_id <> $2))`
+	if _, err := tx.Exec(query, userID, groupID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM groupmemberroles WHERE group_member_id = $1`, memberID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM groupmember WHERE id = $1`, memberID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// checkNotLastMemberWithRole locks the roles of the group members with the role till the end of the transaction,
+//	so the concurrent changes of the members are serialized, and returns store.ErrLastMemberWithRole,
+//	if the member is the only one with the role
+func checkNotLastMemberWithRole(tx *sql.Tx, groupID, memberID, roleID int) error {
+	query := `SELECT group_member_id FROM groupmemberroles WHERE role_id = $1 AND group_member_id IN
+				(SELECT id FROM groupmember WHERE group_id = $2) FOR UPDATE`
+	rows, err := tx.Query(query, roleID, groupID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	count, hasRole := 0, false
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		count++
+		hasRole = hasRole || id == memberID
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if hasRole && count <= 1 {
+		return store.ErrLastMemberWithRole
+	}
+	return nil
+}
+
 func (r *GroupRepository) IsUserGroupMember(userID, groupID int) (bool, error) {
 	query := `SELECT FROM groupmember WHERE user_id = $1 AND group_id = $2`
 	err := r.store.db.QueryRow(query, userID, groupID).Scan()
@@ -213,12 +433,25 @@ func (r *GroupRepository) GetMembersCount(groupID int) (int, error) {
 	return countMembers, nil
 }
 
-func (r *GroupRepository) GetGroupsUserMemberOf(userID int) ([]models.Group, error) {
+func (r *GroupRepository) CountMembersWithRole(groupID, roleID int) (int, error) {
+	var count int
+
+	query := `SELECT count(*) FROM groupmemberroles WHERE role_id = $1 AND group_member_id IN
+				(SELECT id FROM groupmember WHERE group_id = $2)`
+	err := r.store.db.QueryRow(query, roleID, groupID).Scan(&count)
+	if err != nil {
+		return 0, store.HandleErrorNoRows(err)
+	}
+	return count, nil
+}
+
+func (r *GroupRepository) GetGroupsUserMemberOf(userID int, includeArchived bool) ([]models.Group, error) {
 	var groups []models.Group
 
-	query := `SELECT id, custom_name, university_id, specialization_name, start_year, course_number, group_number, created_at FROM "group" WHERE id IN 
-                          (SELECT group_id FROM groupmember WHERE user_id = $1) ORDER BY id`
-	err := r.store.db.Select(&groups, query, userID)
+	query := `SELECT ` + groupColumns + ` FROM "group" WHERE id IN 
+                          (SELECT group_id FROM groupmember WHERE user_id = $1)
+				AND deleted_at IS NULL AND ($2 OR archived_at IS NULL) ORDER BY id`
+	err := r.store.db.Select(&groups, query, userID, includeArchived)
 	for i := range groups {
 		groups[i].CompileFullGroupNameAndCompareCustom()
 	}
@@ -228,33 +461,20 @@ func (r *GroupRepository) GetGroupsUserMemberOf(userID int) ([]models.Group, err
 func (r *GroupRepository) GetMemberRoles(userID, groupID int) ([]models.Role, error) {
 	var roles []models.Role
 
-	query := `SELECT id, name, description FROM public.role 
+	query := `SELECT id, name, description, scope FROM public.role 
 				WHERE id in (SELECT role_id FROM groupmemberroles 
 				      WHERE group_member_id = (SELECT id FROM groupmember 
 				          WHERE user_id = $1 AND group_id = $2))`
-	rows, err := r.store.db.Query(query, userID, groupID)
-	if err != nil {
-		return roles, store.HandleErrorNoRows(err)
-	}
-
-	for rows.Next() {
-		role := models.Role{}
-		//Parsing row with role
-		if err := rows.Scan(&role); err != nil {
-			return nil, err
-		}
-
-		roles = append(roles, role)
-	}
+	err := r.store.db.Select(&roles, query, userID, groupID)
 
-	return roles, nil
+	return roles, store.HandleErrorNoRows(err)
 }
 
 func (r *GroupRepository) GetRolePermissions(roleID int) ([]models.Permission, error) {
 	var permissions []models.Permission
-	query := `SELECT id, name FROM permission 
-				WHERE id in ( SELECT permission_id FROM rolepermissions WHERE role_id = $1) ORDER BY id`
-	err := r.store.db.Select(permissions, query, roleID)
+	query := `SELECT id, name, type FROM permission 
+				WHERE id in ( SELECT permission_id FROM rolepermissions WHERE role_id = $1 AND state_boolean) ORDER BY id`
+	err := r.store.db.Select(&permissions, query, roleID)
 
 	return permissions, store.HandleErrorNoRows(err)
 }
@@ -262,8 +482,8 @@ func (r *GroupRepository) GetRolePermissions(roleID int) ([]models.Permission, e
 func (r *GroupRepository) GetRole(roleID int) (*models.Role, error) {
 	role := &models.Role{}
 
-	query := `SELECT id, name, description FROM public.role WHERE id = $1`
-	err := r.store.db.Select(role, query, roleID)
+	query := `SELECT id, name, description, scope FROM public.role WHERE id = $1`
+	err := r.store.db.Get(role, query, roleID)
 
 	return role, store.HandleErrorNoRows(err)
 }
@@ -271,61 +491,256 @@ func (r *GroupRepository) GetRole(roleID int) (*models.Role, error) {
 func (r *GroupRepository) GetRoleByName(roleName string) (*models.Role, error) {
 	role := &models.Role{}
 
-	query := `SELECT id, name, description FROM public.role WHERE name = $1`
-	err := r.store.db.Select(role, query, roleName)
+	query := `SELECT id, name, description, scope FROM public.role WHERE name = $1`
+	err := r.store.db.Get(role, query, roleName)
 
 	return role, store.HandleErrorNoRows(err)
 }
 
-func (r *GroupRepository) GetGroupInvite(groupID int) (*models.GroupInvite, error) {
-	invite := &models.GroupInvite{}
+const groupInviteColumns = `id, group_id, inviter_id, name, hash, max_uses, uses, default_role, created_at, expires_at, revoked_at`
 
-	query := `SELECT group_id, inviter_id, hash, expires_at FROM groupinvitehashes
-				WHERE group_id = $1`
-	err := r.store.db.QueryRow(query, groupID).Scan(
-		&invite.GroupID,
-		&invite.InviterID,
-		&invite.InviteHash,
-		&invite.ExpiresAt)
-	return invite, store.HandleErrorNoRows(err)
+func (r *GroupRepository) AddGroupInvite(invite *models.GroupInvite) error {
+	query := `INSERT INTO groupinvitehashes (group_id, inviter_id, name, hash, max_uses, default_role, created_at, expires_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+
+	return r.store.db.QueryRow(query,
+		invite.GroupID,
+		invite.InviterID,
+		invite.Name,
+		invite.InviteHash,
+		invite.MaxUses,
+		invite.DefaultRole,
+		invite.CreatedAt,
+		invite.ExpiresAt).Scan(&invite.ID)
+}
+
+func (r *GroupRepository) GetGroupInvites(groupID int) ([]models.GroupInvite, error) {
+	var invites []models.GroupInvite
+
+	query := `SELECT ` + groupInviteColumns + ` FROM groupinvitehashes
+				WHERE group_id = $1 AND revoked_at IS NULL ORDER BY id`
+	err := r.store.db.Select(&invites, query, groupID)
+
+	return invites, store.HandleErrorNoRows(err)
 }
 
 func (r *GroupRepository) GetGroupInviteByHash(inviteHash string) (*models.GroupInvite, error) {
 	invite := &models.GroupInvite{}
 
-	query := `SELECT group_id, inviter_id, hash, expires_at FROM groupinvitehashes
-				WHERE hash = $1`
-	err := r.store.db.QueryRow(query, inviteHash).Scan(
-		&invite.GroupID,
-		&invite.InviterID,
-		&invite.InviteHash,
-		&invite.ExpiresAt)
+	query := `SELECT ` + groupInviteColumns + ` FROM groupinvitehashes WHERE hash = $1`
+	err := r.store.db.Get(invite, query, inviteHash)
+
 	return invite, store.HandleErrorNoRows(err)
 }
 
-func (r *GroupRepository) AddGroupInviteHash(invite *models.GroupInvite) error {
-	query := `INSERT INTO groupinvitehashes (group_id, inviter_id, hash, expires_at) VALUES
-				($1, $2, $3, $4)`
+func (r *GroupRepository) JoinGroupByInvite(userID, inviteID, roleID int) error {
+	tx, err := r.store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var groupID, inviterID int
+	query := `UPDATE groupinvitehashes SET uses = uses + 1
+				WHERE id = $1 AND revoked_at IS NULL AND expires_at > now() AND (max_uses = 0 OR uses < max_uses)
+				RETURNING group_id, inviter_id`
+	if err := tx.QueryRow(query, inviteID).Scan(&groupID, &inviterID); err != nil {
+		return store.HandleErrorNoRows(err)
+	}
 
-	err := r.store.db.QueryRow(query,
-		invite.GroupID,
-		invite.InviterID,
-		invite.InviteHash,
-		invite.ExpiresAt).Err()
+	var memberID int
+	query = `INSERT INTO groupmember (user_id, group_id, invited_by_id) VALUES ($1, $2, $3) RETURNING id`
+	if err := tx.QueryRow(query, userID, groupID, inviterID).Scan(&memberID); err != nil {
+		return err
+	}
 
-	return err
+	query = `INSERT INTO groupmemberroles (group_member_id, role_id) VALUES ($1, $2)`
+	if _, err := tx.Exec(query, memberID, roleID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *GroupRepository) DeleteGroupInvites(groupID int) error {
-	query := `DELETE FROM groupinvitehashes WHERE group_id = $1`
-	_, err := r.store.db.Exec(query, groupID)
-	return err
+func (r *GroupRepository) RevokeGroupInvite(groupID, inviteID int, revokedAt time.Time) error {
+	query := `UPDATE groupinvitehashes SET revoked_at = $1 WHERE id = $2 AND group_id = $3 AND revoked_at IS NULL`
+	res, err := r.store.db.Exec(query, revokedAt, inviteID, groupID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	return nil
 }
 
-func (r *GroupRepository) DeleteGroupInviteByHash(hash string) error {
-	query := `DELETE FROM groupinvitehashes WHERE hash = $1`
-	_, err := r.store.db.Exec(query, hash)
-	return err
+const joinRequestColumns = `id, group_id, user_id, message, status, created_at, decided_by_id, decided_at`
+
+func (r *GroupRepository) CreateJoinRequest(request *models.GroupJoinRequest) error {
+	query := `INSERT INTO groupjoinrequest (group_id, user_id, message, status, created_at)
+				VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	return r.store.db.QueryRow(query,
+		request.GroupID,
+		request.UserID,
+		request.Message,
+		request.Status,
+		request.CreatedAt).Scan(&request.ID)
+}
+
+func (r *GroupRepository) FindJoinRequest(requestID int) (*models.GroupJoinRequest, error) {
+	request := &models.GroupJoinRequest{}
+
+	query := `SELECT ` + joinRequestColumns + ` FROM groupjoinrequest WHERE id = $1`
+	err := r.store.db.Get(request, query, requestID)
+
+	return request, store.HandleErrorNoRows(err)
+}
+
+func (r *GroupRepository) GetJoinRequests(groupID int, status string) ([]models.GroupJoinRequest, error) {
+	var requests []models.GroupJoinRequest
+
+	query := `SELECT ` + joinRequestColumns + ` FROM groupjoinrequest
+				WHERE group_id = $1 AND ($2 = '' OR status = $2) ORDER BY id`
+	err := r.store.db.Select(&requests, query, groupID, status)
+
+	return requests, store.HandleErrorNoRows(err)
+}
+
+func (r *GroupRepository) GetUserJoinRequests(userID int) ([]models.GroupJoinRequest, error) {
+	var requests []models.GroupJoinRequest
+
+	query := `SELECT ` + joinRequestColumns + ` FROM groupjoinrequest WHERE user_id = $1 ORDER BY id DESC`
+	err := r.store.db.Select(&requests, query, userID)
+
+	return requests, store.HandleErrorNoRows(err)
+}
+
+func (r *GroupRepository) ApproveJoinRequest(requestID, deciderID, roleID int, decidedAt time.Time) error {
+	tx, err := r.store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var groupID, userID int
+	query := `UPDATE groupjoinrequest SET status = $1, decided_by_id = $2, decided_at = $3
+				WHERE id = $4 AND status = $5 RETURNING group_id, user_id`
+	err = tx.QueryRow(query, models.JoinRequestStatusApproved, deciderID, decidedAt, requestID, models.JoinRequestStatusPending).
+		Scan(&groupID, &userID)
+	if err != nil {
+		return store.HandleErrorNoRows(err)
+	}
+
+	var memberID int
+	query = `INSERT INTO groupmember (user_id, group_id, invited_by_id) VALUES ($1, $2, $3) RETURNING id`
+	if err := tx.QueryRow(query, userID, groupID, deciderID).Scan(&memberID); err != nil {
+		return err
+	}
+
+	query = `INSERT INTO groupmemberroles (group_member_id, role_id) VALUES ($1, $2)`
+	if _, err := tx.Exec(query, memberID, roleID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *GroupRepository) RejectJoinRequest(requestID, deciderID int, decidedAt time.Time) error {
+	query := `UPDATE groupjoinrequest SET status = $1, decided_by_id = $2, decided_at = $3 WHERE id = $4 AND status = $5`
+	res, err := r.store.db.Exec(query, models.JoinRequestStatusRejected, deciderID, decidedAt, requestID, models.JoinRequestStatusPending)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *GroupRepository) RolloverGroups(rollover *models.GroupRollover, yearStart time.Time, finalCourse int) (bool, error) {
+	tx, err := r.store.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO grouprollover (university_id, academic_year, rolled_by_id, created_at) VALUES ($1, $2, $3, $4)
+				ON CONFLICT (university_id, academic_year) DO NOTHING RETURNING id`
+	err = tx.QueryRow(query, rollover.UniversityID, rollover.AcademicYear, rollover.RolledByID, rollover.CreatedAt).
+		Scan(&rollover.ID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	var groups []models.Group
+	query = `SELECT ` + groupColumns + ` FROM "group"
+				WHERE university_id = $1 AND archived_at IS NULL AND deleted_at IS NULL AND created_at < $2
+				ORDER BY id FOR UPDATE`
+	if err := tx.Select(&groups, query, rollover.UniversityID, yearStart); err != nil {
+		return false, err
+	}
+
+	for _, g := range groups {
+		g.CompileFullGroupNameAndCompareCustom()
+
+		query = `INSERT INTO groupnamehistory (group_id, full_name, custom_name, course_number, academic_year, changed_at)
+					VALUES ($1, $2, $3, $4, $5, $6)`
+		_, err := tx.Exec(query, g.ID, g.FullName, g.CustomName, g.CourseNumber, rollover.AcademicYear, rollover.CreatedAt)
+		if err != nil {
+			return false, err
+		}
+
+		if g.CourseNumber >= finalCourse {
+			query = `UPDATE "group" SET archived_at = $1 WHERE id = $2`
+			_, err = tx.Exec(query, rollover.CreatedAt, g.ID)
+			rollover.Archived++
+		} else {
+			query = `UPDATE "group" SET course_number = course_number + 1 WHERE id = $1`
+			_, err = tx.Exec(query, g.ID)
+			rollover.Advanced++
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	query = `UPDATE grouprollover SET advanced = $1, archived = $2 WHERE id = $3`
+	if _, err := tx.Exec(query, rollover.Advanced, rollover.Archived, rollover.ID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *GroupRepository) GetGroupRollovers(universityID int) ([]models.GroupRollover, error) {
+	var rollovers []models.GroupRollover
+
+	query := `SELECT id, university_id, academic_year, rolled_by_id, advanced, archived, created_at FROM grouprollover
+				WHERE university_id = $1 ORDER BY academic_year DESC`
+	err := r.store.db.Select(&rollovers, query, universityID)
+
+	return rollovers, store.HandleErrorNoRows(err)
+}
+
+func (r *GroupRepository) GetGroupNameHistory(groupID int) ([]models.GroupNameHistory, error) {
+	var names []models.GroupNameHistory
+
+	query := `SELECT id, group_id, full_name, custom_name, course_number, academic_year, changed_at FROM groupnamehistory
+				WHERE group_id = $1 ORDER BY id`
+	err := r.store.db.Select(&names, query, groupID)
+
+	return names, store.HandleErrorNoRows(err)
 }
 
 /*
@@ -41,10 +41,10 @@ func TestGroupRepository_Delete(t *testing.T) {
 	assert.NoError(t, s.Group().Create(g))
 
 	uid := 3
-	name := "Updated group"
+	name := "Updated"
 	ug := models.UpdateGroup{
-		UniversityID: &uid,
-		Name:         &name,
+		UniversityID:       &uid,
+		SpecializationName: &name,
 	}
 	assert.NoError(t, s.Group().Update(1, &ug))
 	g2, err := s.Group().Find(g.ID)
@@ -63,17 +63,17 @@ func TestGroupRepository_Update(t *testing.T) {
 	assert.NoError(t, s.Group().Create(g))
 
 	uid := 3
-	name := "Updated group"
+	name := "Updated"
 	ug := models.UpdateGroup{
-		UniversityID: &uid,
-		Name:         &name,
+		UniversityID:       &uid,
+		SpecializationName: &name,
 	}
 	assert.NoError(t, s.Group().Update(1, &ug))
 	updGroup, err := s.Group().Find(g.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, g.ID, updGroup.ID)
 	assert.Equal(t, *ug.UniversityID, updGroup.UniversityID)
-	assert.Equal(t, *ug.Name, updGroup.CustomName)
+	assert.Equal(t, *ug.SpecializationName, updGroup.SpecializationName)
 }
 
 func TestGroupRepository_GetGroupMembers(t *testing.T) {
@@ -0,0 +1,61 @@
+package sqlstore
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/store"
+	"time"
+)
+
+type LoginAttemptRepository struct {
+	store *Store
+}
+
+func (r *LoginAttemptRepository) Find(key string) (*models.LoginAttempts, error) {
+	a := &models.LoginAttempts{}
+
+	query := `SELECT key, failures, last_failure_at, locked_until FROM loginattempt WHERE key = $1`
+	if err := r.store.db.Get(a, query, key); err != nil {
+		return nil, store.HandleErrorNoRows(err)
+	}
+
+	return a, nil
+}
+
+// AddFailure increments the counter in one query, so concurrent attempts from several replicas are all counted
+func (r *LoginAttemptRepository) AddFailure(key string, at, resetBefore time.Time) (*models.LoginAttempts, error) {
+	a := &models.LoginAttempts{}
+
+	query := `INSERT INTO loginattempt (key, failures, last_failure_at) 
+				VALUES ($1, 1, $2) 
+				ON CONFLICT (key) DO UPDATE 
+				SET failures = CASE WHEN loginattempt.last_failure_at < $3 THEN 1 ELSE loginattempt.failures + 1 END, 
+				    last_failure_at = EXCLUDED.last_failure_at 
+				RETURNING key, failures, last_failure_at, locked_until`
+	if err := r.store.db.Get(a, query, key, at, resetBefore); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (r *LoginAttemptRepository) Lock(key string, until time.Time) error {
+	query := `UPDATE loginattempt SET locked_until = $1, failures = 0 WHERE key = $2`
+	_, err := r.store.db.Exec(query, until, key)
+	return err
+}
+
+func (r *LoginAttemptRepository) Delete(key string) error {
+	query := `DELETE FROM loginattempt WHERE key = $1`
+	_, err := r.store.db.Exec(query, key)
+	return err
+}
+
+func (r *LoginAttemptRepository) DeleteStale(failedBefore, now time.Time) (int64, error) {
+	query := `DELETE FROM loginattempt WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until < $2)`
+	res, err := r.store.db.Exec(query, failedBefore, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
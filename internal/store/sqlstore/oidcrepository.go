@@ -0,0 +1,83 @@
+package sqlstore
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/store"
+	"time"
+)
+
+type OIDCRepository struct {
+	store *Store
+}
+
+const externalIdentityColumns = `id, user_id, issuer, subject, email, created_at, last_login_at`
+
+func (r *OIDCRepository) CreateIdentity(i *models.ExternalIdentity) error {
+	query := `INSERT INTO externalidentity (user_id, issuer, subject, email, created_at, last_login_at) 
+				VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	return r.store.db.QueryRow(query,
+		i.UserID,
+		i.Issuer,
+		i.Subject,
+		i.Email,
+		i.CreatedAt,
+		i.LastLoginAt).Scan(&i.ID)
+}
+
+func (r *OIDCRepository) FindIdentity(issuer, subject string) (*models.ExternalIdentity, error) {
+	i := &models.ExternalIdentity{}
+
+	query := `SELECT ` + externalIdentityColumns + ` FROM externalidentity WHERE issuer = $1 AND subject = $2`
+	if err := r.store.db.Get(i, query, issuer, subject); err != nil {
+		return nil, store.HandleErrorNoRows(err)
+	}
+
+	return i, nil
+}
+
+func (r *OIDCRepository) FindIdentitiesByUser(userID int) ([]models.ExternalIdentity, error) {
+	var identities []models.ExternalIdentity
+
+	query := `SELECT ` + externalIdentityColumns + ` FROM externalidentity WHERE user_id = $1 ORDER BY id`
+	if err := r.store.db.Select(&identities, query, userID); err != nil {
+		return nil, err
+	}
+
+	return identities, nil
+}
+
+func (r *OIDCRepository) UpdateIdentityLastLogin(identityID int, lastLoginAt time.Time) error {
+	query := `UPDATE externalidentity SET last_login_at = $1 WHERE id = $2`
+	_, err := r.store.db.Exec(query, lastLoginAt, identityID)
+	return err
+}
+
+func (r *OIDCRepository) CreateLoginState(s *models.OIDCLoginState) error {
+	query := `INSERT INTO oidcloginstate (state_hash, code_verifier, nonce, created_at, expires_at) 
+				VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	return r.store.db.QueryRow(query,
+		s.StateHash,
+		s.CodeVerifier,
+		s.Nonce,
+		s.CreatedAt,
+		s.ExpiresAt).Scan(&s.ID)
+}
+
+func (r *OIDCRepository) UseLoginState(stateHash string, usedAt time.Time) (*models.OIDCLoginState, error) {
+	s := &models.OIDCLoginState{}
+
+	query := `UPDATE oidcloginstate SET used_at = $1 
+				WHERE state_hash = $2 AND used_at IS NULL AND expires_at > $1 
+				RETURNING id, state_hash, code_verifier, nonce, created_at, expires_at, used_at`
+	if err := r.store.db.Get(s, query, usedAt, stateHash); err != nil {
+		return nil, store.HandleErrorNoRows(err)
+	}
+
+	return s, nil
+}
+
+func (r *OIDCRepository) DeleteExpiredLoginStates(now time.Time) error {
+	query := `DELETE FROM oidcloginstate WHERE expires_at <= $1`
+	_, err := r.store.db.Exec(query, now)
+	return err
+}
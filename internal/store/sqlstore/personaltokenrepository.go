@@ -0,0 +1,77 @@
+package sqlstore
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/store"
+	"time"
+)
+
+type PersonalTokenRepository struct {
+	store *Store
+}
+
+const personalTokenColumns = `id, user_id, name, token_hash, scope, created_at, expires_at, last_used_at, revoked_at`
+
+func (r *PersonalTokenRepository) Create(t *models.PersonalToken) error {
+	query := `INSERT INTO personaltoken (user_id, name, token_hash, scope, created_at, expires_at) 
+				VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	return r.store.db.QueryRow(query,
+		t.UserID,
+		t.Name,
+		t.TokenHash,
+		t.Scope,
+		t.CreatedAt,
+		t.ExpiresAt).Scan(&t.ID)
+}
+
+func (r *PersonalTokenRepository) FindByHash(tokenHash string) (*models.PersonalToken, error) {
+	t := &models.PersonalToken{}
+
+	query := `SELECT ` + personalTokenColumns + ` FROM personaltoken WHERE token_hash = $1`
+	if err := r.store.db.Get(t, query, tokenHash); err != nil {
+		return nil, store.HandleErrorNoRows(err)
+	}
+
+	return t, nil
+}
+
+func (r *PersonalTokenRepository) FindByUser(userID int) ([]models.PersonalToken, error) {
+	var tokens []models.PersonalToken
+
+	query := `SELECT ` + personalTokenColumns + ` FROM personaltoken 
+				WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC`
+	if err := r.store.db.Select(&tokens, query, userID); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (r *PersonalTokenRepository) Revoke(userID, tokenID int, revokedAt time.Time) error {
+	query := `UPDATE personaltoken SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`
+	res, err := r.store.db.Exec(query, revokedAt, tokenID, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *PersonalTokenRepository) RevokeByUser(userID int, revokedAt time.Time) error {
+	query := `UPDATE personaltoken SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`
+	_, err := r.store.db.Exec(query, revokedAt, userID)
+	return err
+}
+
+func (r *PersonalTokenRepository) UpdateLastUsed(tokenID int, lastUsedAt time.Time) error {
+	query := `UPDATE personaltoken SET last_used_at = $1 WHERE id = $2`
+	_, err := r.store.db.Exec(query, lastUsedAt, tokenID)
+	return err
+}
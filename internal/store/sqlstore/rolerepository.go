@@ -0,0 +1,160 @@
+package sqlstore
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/store"
+)
+
+type RoleRepository struct {
+	store *Store
+}
+
+func (r *RoleRepository) Seed(permissions []models.Permission, roles []models.Role) error {
+	tx, err := r.store.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	permissionIDs := make(map[string]int, len(permissions))
+	for i := range permissions {
+		query := `INSERT INTO permission (name, type) VALUES ($1, $2) 
+					ON CONFLICT (name) DO UPDATE SET type = EXCLUDED.type RETURNING id`
+		if err := tx.QueryRow(query, permissions[i].Name, permissions[i].Type).Scan(&permissions[i].ID); err != nil {
+			return err
+		}
+		permissionIDs[permissions[i].Name] = permissions[i].ID
+	}
+
+	for i := range roles {
+		query := `INSERT INTO role (name, description, scope) VALUES ($1, $2, $3) 
+					ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description, scope = EXCLUDED.scope 
+					RETURNING id`
+		if err := tx.QueryRow(query, roles[i].Name, roles[i].Description, roles[i].Scope).Scan(&roles[i].ID); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`DELETE FROM rolepermissions WHERE role_id = $1`, roles[i].ID); err != nil {
+			return err
+		}
+
+		for _, rp := range roles[i].Permissions {
+			permissionID, ok := permissionIDs[rp.Permission.Name]
+			if !ok {
+				if err := tx.Get(&permissionID, `SELECT id FROM permission WHERE name = $1`, rp.Permission.Name); err != nil {
+					return store.HandleErrorNoRows(err)
+				}
+			}
+
+			query := `INSERT INTO rolepermissions (permission_id, role_id, state_boolean) VALUES ($1, $2, $3)`
+			if _, err := tx.Exec(query, permissionID, roles[i].ID, rp.State); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *RoleRepository) FindPermission(name string) (*models.Permission, error) {
+	p := &models.Permission{}
+
+	query := `SELECT id, name, type FROM permission WHERE name = $1`
+	if err := r.store.db.Get(p, query, name); err != nil {
+		return nil, store.HandleErrorNoRows(err)
+	}
+
+	return p, nil
+}
+
+func (r *RoleRepository) AddUserRole(userID, roleID int) error {
+	query := `INSERT INTO userroles (user_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	_, err := r.store.db.Exec(query, userID, roleID)
+	return err
+}
+
+func (r *RoleRepository) RemoveUserRole(userID, roleID int) error {
+	query := `DELETE FROM userroles WHERE user_id = $1 AND role_id = $2`
+	_, err := r.store.db.Exec(query, userID, roleID)
+	return err
+}
+
+func (r *RoleRepository) AddFacultyRole(userID, facultyID, roleID int) error {
+	query := `INSERT INTO facultyroles (user_id, faculty_id, role_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+	_, err := r.store.db.Exec(query, userID, facultyID, roleID)
+	return err
+}
+
+func (r *RoleRepository) RemoveFacultyRole(userID, facultyID, roleID int) error {
+	query := `DELETE FROM facultyroles WHERE user_id = $1 AND faculty_id = $2 AND role_id = $3`
+	_, err := r.store.db.Exec(query, userID, facultyID, roleID)
+	return err
+}
+
+func (r *RoleRepository) GetFacultyRoles(userID, facultyID int) ([]models.Role, error) {
+	var roles []models.Role
+
+	query := `SELECT id, name, description, scope FROM public.role 
+				WHERE id in (SELECT role_id FROM facultyroles WHERE user_id = $1 AND faculty_id = $2)`
+	err := r.store.db.Select(&roles, query, userID, facultyID)
+
+	return roles, store.HandleErrorNoRows(err)
+}
+
+func (r *RoleRepository) SetMemberRole(userID, groupID, roleID, keptRoleID int) error {
+	tx, err := r.store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var memberID int
+	query := `SELECT id FROM groupmember WHERE user_id = $1 AND group_id = $2`
+	if err := tx.QueryRow(query, userID, groupID).Scan(&memberID); err != nil {
+		return store.HandleErrorNoRows(err)
+	}
+
+	if keptRoleID != 0 && keptRoleID != roleID {
+		if err := checkNotLastMemberWithRole(tx, groupID, memberID, keptRoleID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM groupmemberroles WHERE group_member_id = $1`, memberID); err != nil {
+		return err
+	}
+
+	query = `INSERT INTO groupmemberroles (group_member_id, role_id) VALUES ($1, $2)`
+	if _, err := tx.Exec(query, memberID, roleID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *RoleRepository) TransferMemberRole(groupID, fromUserID, toUserID, roleID, replacementRoleID int) error {
+	tx, err := r.store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, member := range []struct{ userID, roleID int }{{toUserID, roleID}, {fromUserID, replacementRoleID}} {
+		var memberID int
+		query := `SELECT id FROM groupmember WHERE user_id = $1 AND group_id = $2`
+		if err := tx.QueryRow(query, member.userID, groupID).Scan(&memberID); err != nil {
+			return store.HandleErrorNoRows(err)
+		}
+
+		if _, err := tx.Exec(`DELETE FROM groupmemberroles WHERE group_member_id = $1`, memberID); err != nil {
+			return err
+		}
+
+		query = `INSERT INTO groupmemberroles (group_member_id, role_id) VALUES ($1, $2)`
+		if _, err := tx.Exec(query, memberID, member.roleID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
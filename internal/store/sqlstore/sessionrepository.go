@@ -0,0 +1,78 @@
+package sqlstore
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/store"
+	"time"
+)
+
+type SessionRepository struct {
+	store *Store
+}
+
+func (r *SessionRepository) Create(s *models.UserSession) error {
+	query := `INSERT INTO usersession (user_id, device_name, user_agent, ip, created_at, last_seen_at, impersonator_id, 
+				expires_at) 
+				VALUES ($1, $2, $3, $4, $5, $5, $6, $7) RETURNING id, created_at, last_seen_at`
+	return r.store.db.QueryRow(query,
+		s.UserID,
+		s.DeviceName,
+		s.UserAgent,
+		s.IP,
+		time.Now(),
+		s.ImpersonatorID,
+		s.ExpiresAt,
+	).Scan(
+		&s.ID,
+		&s.CreatedAt,
+		&s.LastSeenAt,
+	)
+}
+
+func (r *SessionRepository) Find(sessionID int) (*models.UserSession, error) {
+	s := &models.UserSession{}
+
+	query := `SELECT id, user_id, device_name, user_agent, ip, created_at, last_seen_at, revoked_at, impersonator_id, 
+				expires_at 
+				FROM usersession WHERE id = $1`
+	err := r.store.db.QueryRow(query, sessionID).Scan(
+		&s.ID,
+		&s.UserID,
+		&s.DeviceName,
+		&s.UserAgent,
+		&s.IP,
+		&s.CreatedAt,
+		&s.LastSeenAt,
+		&s.RevokedAt,
+		&s.ImpersonatorID,
+		&s.ExpiresAt)
+	if err != nil {
+		return nil, store.HandleErrorNoRows(err)
+	}
+
+	return s, nil
+}
+
+func (r *SessionRepository) FindByUser(userID int) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+
+	query := `SELECT id, user_id, device_name, user_agent, ip, created_at, last_seen_at, revoked_at, impersonator_id, 
+				expires_at 
+				FROM usersession WHERE user_id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > $2) 
+				ORDER BY last_seen_at DESC`
+	err := r.store.db.Select(&sessions, query, userID, time.Now())
+
+	return sessions, store.HandleIgnoreErrorNoRows(err)
+}
+
+func (r *SessionRepository) UpdateLastSeen(sessionID int, lastSeenAt time.Time) error {
+	query := `UPDATE usersession SET last_seen_at = $1 WHERE id = $2`
+	_, err := r.store.db.Exec(query, lastSeenAt, sessionID)
+	return err
+}
+
+func (r *SessionRepository) Revoke(sessionID int) error {
+	query := `UPDATE usersession SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`
+	_, err := r.store.db.Exec(query, time.Now(), sessionID)
+	return err
+}
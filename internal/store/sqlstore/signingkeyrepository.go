@@ -0,0 +1,48 @@
+package sqlstore
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/store"
+	"time"
+)
+
+type SigningKeyRepository struct {
+	store *Store
+}
+
+func (r *SigningKeyRepository) Create(k *models.SigningKey) error {
+	query := `INSERT INTO signingkey (kid, algorithm, private_key, created_at) VALUES ($1, $2, $3, $4)`
+	_, err := r.store.db.Exec(query,
+		k.ID,
+		k.Algorithm,
+		k.PrivateKey,
+		k.CreatedAt,
+	)
+	return err
+}
+
+func (r *SigningKeyRepository) FindValid(now time.Time) ([]models.SigningKey, error) {
+	var keys []models.SigningKey
+
+	query := `SELECT kid, algorithm, private_key, created_at, retired_at FROM signingkey 
+				WHERE retired_at IS NULL OR retired_at > $1 ORDER BY created_at DESC`
+	err := r.store.db.Select(&keys, query, now)
+
+	return keys, store.HandleIgnoreErrorNoRows(err)
+}
+
+func (r *SigningKeyRepository) RetireActive(exceptKeyID string, retiredAt time.Time) error {
+	query := `UPDATE signingkey SET retired_at = $1 WHERE retired_at IS NULL AND kid != $2`
+	_, err := r.store.db.Exec(query, retiredAt, exceptKeyID)
+	return err
+}
+
+func (r *SigningKeyRepository) DeleteRetired(before time.Time) (int64, error) {
+	query := `DELETE FROM signingkey WHERE retired_at <= $1`
+	res, err := r.store.db.Exec(query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
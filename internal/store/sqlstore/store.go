@@ -11,12 +11,23 @@ import (
 type Store struct {
 	db *sqlx.DB
 
-	authRepository       *AuthRepository
-	userRepository       *UserRepository
-	taskRepository       *TaskRepository
-	universityRepository *UniversityRepository
-	groupRepository      *GroupRepository
-	subjectRepository    *SubjectRepository
+	authRepository              *AuthRepository
+	sessionRepository           *SessionRepository
+	tokenBlacklistRepository    *TokenBlacklistRepository
+	signingKeyRepository        *SigningKeyRepository
+	verificationTokenRepository *VerificationTokenRepository
+	roleRepository              *RoleRepository
+	loginAttemptRepository      *LoginAttemptRepository
+	personalTokenRepository     *PersonalTokenRepository
+	oidcRepository              *OIDCRepository
+	auditRepository             *AuditRepository
+	twoFactorRepository         *TwoFactorRepository
+	userRepository              *UserRepository
+	taskRepository              *TaskRepository
+	universityRepository        *UniversityRepository
+	facultyRepository           *FacultyRepository
+	groupRepository             *GroupRepository
+	subjectRepository           *SubjectRepository
 }
 
 func New(db *sqlx.DB) *Store {
@@ -35,6 +46,106 @@ func (s *Store) Auth() store.AuthRepository {
 	return s.authRepository
 }
 
+func (s *Store) Session() store.SessionRepository {
+	if s.sessionRepository == nil {
+		s.sessionRepository = &SessionRepository{
+			store: s,
+		}
+	}
+
+	return s.sessionRepository
+}
+
+func (s *Store) TokenBlacklist() store.TokenBlacklistRepository {
+	if s.tokenBlacklistRepository == nil {
+		s.tokenBlacklistRepository = &TokenBlacklistRepository{
+			store: s,
+		}
+	}
+
+	return s.tokenBlacklistRepository
+}
+
+func (s *Store) SigningKey() store.SigningKeyRepository {
+	if s.signingKeyRepository == nil {
+		s.signingKeyRepository = &SigningKeyRepository{
+			store: s,
+		}
+	}
+
+	return s.signingKeyRepository
+}
+
+func (s *Store) VerificationToken() store.VerificationTokenRepository {
+	if s.verificationTokenRepository == nil {
+		s.verificationTokenRepository = &VerificationTokenRepository{
+			store: s,
+		}
+	}
+
+	return s.verificationTokenRepository
+}
+
+func (s *Store) Role() store.RoleRepository {
+	if s.roleRepository == nil {
+		s.roleRepository = &RoleRepository{
+			store: s,
+		}
+	}
+
+	return s.roleRepository
+}
+
+func (s *Store) PersonalToken() store.PersonalTokenRepository {
+	if s.personalTokenRepository == nil {
+		s.personalTokenRepository = &PersonalTokenRepository{
+			store: s,
+		}
+	}
+
+	return s.personalTokenRepository
+}
+
+func (s *Store) OIDC() store.OIDCRepository {
+	if s.oidcRepository == nil {
+		s.oidcRepository = &OIDCRepository{
+			store: s,
+		}
+	}
+
+	return s.oidcRepository
+}
+
+func (s *Store) Audit() store.AuditRepository {
+	if s.auditRepository == nil {
+		s.auditRepository = &AuditRepository{
+			store: s,
+		}
+	}
+
+	return s.auditRepository
+}
+
+func (s *Store) LoginAttempt() store.LoginAttemptRepository {
+	if s.loginAttemptRepository == nil {
+		s.loginAttemptRepository = &LoginAttemptRepository{
+			store: s,
+		}
+	}
+
+	return s.loginAttemptRepository
+}
+
+func (s *Store) TwoFactor() store.TwoFactorRepository {
+	if s.twoFactorRepository == nil {
+		s.twoFactorRepository = &TwoFactorRepository{
+			store: s,
+		}
+	}
+
+	return s.twoFactorRepository
+}
+
 func (s *Store) User() store.UserRepository {
 	if s.userRepository == nil {
 		s.userRepository = &UserRepository{
@@ -64,6 +175,15 @@ func (s *Store) University() store.UniversityRepository {
 	return s.universityRepository
 }
 
+func (s *Store) Faculty() store.FacultyRepository {
+	if s.facultyRepository == nil {
+		s.facultyRepository = &FacultyRepository{
+			store: s,
+		}
+	}
+	return s.facultyRepository
+}
+
 func (s *Store) Group() store.GroupRepository {
 	if s.groupRepository == nil {
 		s.groupRepository = &GroupRepository{
@@ -19,7 +19,7 @@ func (r *SubjectRepository) Create(s *models.Subject) error {
 
 func (r *SubjectRepository) GetAll(limit, offset int) ([]models.Subject, error) {
 	var subjects []models.Subject
-	query := `SELECT id, name FROM subject ORDER BY id`
+	query := `SELECT id, name, department_id FROM subject ORDER BY id`
 
 	query, err := r.store.AddLimitAndOffsetToQuery(query, limit, offset)
 	if err != nil {
@@ -38,11 +38,12 @@ func (r *SubjectRepository) Find(id int) (*models.Subject, error) {
 	s := &models.Subject{}
 
 	if err := r.store.db.QueryRow(
-		"SELECT id, name FROM subject WHERE id = $1",
+		"SELECT id, name, department_id FROM subject WHERE id = $1",
 		id,
 	).Scan(
 		&s.ID,
 		&s.Name,
+		&s.DepartmentID,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, store.ErrRecordNotFound
@@ -55,8 +56,36 @@ func (r *SubjectRepository) Find(id int) (*models.Subject, error) {
 
 func (r *SubjectRepository) Delete(id int) (*models.Subject, error) {
 	subject := &models.Subject{}
-	err := r.store.db.QueryRow("DELETE FROM subject WHERE id = $1 RETURNING id, name", id).Scan(
+	err := r.store.db.QueryRow("DELETE FROM subject WHERE id = $1 RETURNING id, name, department_id", id).Scan(
 		&subject.ID,
-		&subject.Name)
+		&subject.Name,
+		&subject.DepartmentID)
 	return subject, store.HandleErrorNoRows(err)
 }
+
+func (r *SubjectRepository) SetDepartment(subjectID int, departmentID *int) error {
+	res, err := r.store.db.Exec("UPDATE subject SET department_id = $2 WHERE id = $1", subjectID, departmentID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *SubjectRepository) GetSubjectsOfOrgNode(node models.OrgNode) ([]models.Subject, error) {
+	var subjects []models.Subject
+
+	condition, err := orgNodeCondition(node)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.store.db.Select(&subjects, "SELECT id, name, department_id FROM subject WHERE "+condition+" ORDER BY name, id", node.ID)
+	return subjects, store.HandleErrorNoRows(err)
+}
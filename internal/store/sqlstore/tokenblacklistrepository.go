@@ -0,0 +1,35 @@
+package sqlstore
+
+import (
+	"time"
+)
+
+type TokenBlacklistRepository struct {
+	store *Store
+}
+
+func (r *TokenBlacklistRepository) Add(tokenHash string, expiresAt time.Time) error {
+	query := `INSERT INTO accesstokenblacklist (token_hash, expires_at) VALUES ($1, $2) 
+				ON CONFLICT (token_hash) DO UPDATE SET expires_at = GREATEST(accesstokenblacklist.expires_at, EXCLUDED.expires_at)`
+	_, err := r.store.db.Exec(query, tokenHash, expiresAt)
+	return err
+}
+
+func (r *TokenBlacklistRepository) Exists(tokenHash string, now time.Time) (bool, error) {
+	var exists bool
+
+	query := `SELECT EXISTS(SELECT 1 FROM accesstokenblacklist WHERE token_hash = $1 AND expires_at > $2)`
+	err := r.store.db.QueryRow(query, tokenHash, now).Scan(&exists)
+
+	return exists, err
+}
+
+func (r *TokenBlacklistRepository) DeleteExpired(now time.Time) (int64, error) {
+	query := `DELETE FROM accesstokenblacklist WHERE expires_at <= $1`
+	res, err := r.store.db.Exec(query, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,98 @@
+package sqlstore
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/store"
+	"time"
+)
+
+type TwoFactorRepository struct {
+	store *Store
+}
+
+func (r *TwoFactorRepository) SaveTOTP(t *models.UserTOTP) error {
+	query := `INSERT INTO usertotp (user_id, secret, created_at, confirmed_at, last_used_step) 
+				VALUES ($1, $2, $3, $4, $5) 
+				ON CONFLICT (user_id) DO UPDATE 
+				SET secret = EXCLUDED.secret, created_at = EXCLUDED.created_at, 
+				    confirmed_at = EXCLUDED.confirmed_at, last_used_step = EXCLUDED.last_used_step`
+	_, err := r.store.db.Exec(query,
+		t.UserID,
+		t.Secret,
+		t.CreatedAt,
+		t.ConfirmedAt,
+		t.LastUsedStep,
+	)
+	return err
+}
+
+func (r *TwoFactorRepository) FindTOTP(userID int) (*models.UserTOTP, error) {
+	t := &models.UserTOTP{}
+
+	query := `SELECT user_id, secret, created_at, confirmed_at, last_used_step FROM usertotp WHERE user_id = $1`
+	if err := r.store.db.Get(t, query, userID); err != nil {
+		return nil, store.HandleErrorNoRows(err)
+	}
+
+	return t, nil
+}
+
+func (r *TwoFactorRepository) ConfirmTOTP(userID int, confirmedAt time.Time) error {
+	query := `UPDATE usertotp SET confirmed_at = $1 WHERE user_id = $2`
+	_, err := r.store.db.Exec(query, confirmedAt, userID)
+	return err
+}
+
+func (r *TwoFactorRepository) UseTOTPStep(userID int, step int64) (bool, error) {
+	query := `UPDATE usertotp SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1`
+	res, err := r.store.db.Exec(query, step, userID)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *TwoFactorRepository) DeleteTOTP(userID int) error {
+	query := `DELETE FROM usertotp WHERE user_id = $1`
+	_, err := r.store.db.Exec(query, userID)
+	return err
+}
+
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := r.store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM recoverycode WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(`INSERT INTO recoverycode (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *TwoFactorRepository) UseRecoveryCode(userID int, codeHash string, usedAt time.Time) (bool, error) {
+	query := `UPDATE recoverycode SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`
+	res, err := r.store.db.Exec(query, usedAt, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *TwoFactorRepository) DeleteRecoveryCodes(userID int) error {
+	query := `DELETE FROM recoverycode WHERE user_id = $1`
+	_, err := r.store.db.Exec(query, userID)
+	return err
+}
@@ -32,3 +32,12 @@ func (r *UniversityRepository) Find(id int) (*models.University, error) {
 		&university.AddedAt)
 	return university, store.HandleErrorNoRows(err)
 }
+
+func (r *UniversityRepository) GetAll() ([]models.University, error) {
+	var universities []models.University
+
+	query := `SELECT id, name, location, site, added_at FROM university ORDER BY id`
+	err := r.store.db.Select(&universities, query)
+
+	return universities, store.HandleErrorNoRows(err)
+}
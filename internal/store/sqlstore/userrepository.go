@@ -37,7 +37,7 @@ func (r *UserRepository) Create(u *models.User) error {
 func (r *UserRepository) GetAll(limit, offset int) ([]models.User, error) {
 	var users []models.User
 
-	query := `SELECT id, login, full_name, email, encrypted_password, created_at FROM public.user ORDER BY id`
+	query := `SELECT id, login, full_name, email, encrypted_password, created_at, email_confirmed_at FROM public.user ORDER BY id`
 	query, err := r.store.AddLimitAndOffsetToQuery(query, limit, offset)
 	if err != nil {
 		return nil, err
@@ -58,6 +58,7 @@ func (r *UserRepository) GetAll(limit, offset int) ([]models.User, error) {
 			&u.Email,
 			&u.EncryptedPassword,
 			&u.CreatedAt,
+			&u.EmailConfirmedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -72,7 +73,7 @@ func (r *UserRepository) GetAll(limit, offset int) ([]models.User, error) {
 
 func (r *UserRepository) FindByLogin(login string) (*models.User, error) {
 	u := &models.User{}
-	query := `SELECT id, login, full_name, email, encrypted_password, created_at FROM "user" WHERE login = $1`
+	query := `SELECT id, login, full_name, email, encrypted_password, created_at, email_confirmed_at FROM "user" WHERE login = $1`
 	if err := r.store.db.QueryRow(query, login).Scan(
 		&u.ID,
 		&u.Login,
@@ -80,6 +81,7 @@ func (r *UserRepository) FindByLogin(login string) (*models.User, error) {
 		&u.Email,
 		&u.EncryptedPassword,
 		&u.CreatedAt,
+		&u.EmailConfirmedAt,
 	); err != nil {
 		return nil, store.HandleErrorNoRows(err)
 	}
@@ -92,7 +94,7 @@ func (r *UserRepository) FindByLogin(login string) (*models.User, error) {
 func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
 	u := &models.User{}
 
-	query := `SELECT id, login, full_name, email, encrypted_password, created_at FROM "user" WHERE email = $1`
+	query := `SELECT id, login, full_name, email, encrypted_password, created_at, email_confirmed_at FROM "user" WHERE email = $1`
 	if err := r.store.db.QueryRow(query, email).Scan(
 		&u.ID,
 		&u.Login,
@@ -100,6 +102,7 @@ func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
 		&u.Email,
 		&u.EncryptedPassword,
 		&u.CreatedAt,
+		&u.EmailConfirmedAt,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, store.ErrRecordNotFound
@@ -114,7 +117,7 @@ func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
 func (r *UserRepository) Find(id int) (*models.User, error) {
 	u := &models.User{}
 
-	query := `SELECT id, login, full_name, email, encrypted_password, created_at FROM "user" WHERE id = $1`
+	query := `SELECT id, login, full_name, email, encrypted_password, created_at, email_confirmed_at FROM "user" WHERE id = $1`
 	if err := r.store.db.QueryRow(query, id).Scan(
 		&u.ID,
 		&u.Login,
@@ -122,6 +125,7 @@ func (r *UserRepository) Find(id int) (*models.User, error) {
 		&u.Email,
 		&u.EncryptedPassword,
 		&u.CreatedAt,
+		&u.EmailConfirmedAt,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, store.ErrRecordNotFound
@@ -178,6 +182,18 @@ func (r *UserRepository) CreateTester() (*models.User, error) {
 	return okU, nil
 }
 
+func (r *UserRepository) ConfirmEmail(userID int, confirmedAt time.Time) error {
+	query := `UPDATE "user" SET email_confirmed_at = $1 WHERE id = $2`
+	_, err := r.store.db.Exec(query, confirmedAt, userID)
+	return err
+}
+
+func (r *UserRepository) UpdatePassword(userID int, encryptedPassword string) error {
+	query := `UPDATE "user" SET encrypted_password = $1 WHERE id = $2`
+	_, err := r.store.db.Exec(query, encryptedPassword, userID)
+	return err
+}
+
 func (r *UserRepository) IsUserExist(userID int) (bool, error) {
 	query := `SELECT FROM public.user WHERE id = $1`
 	_, err := r.store.db.Exec(query, userID)
@@ -188,7 +204,7 @@ func (r *UserRepository) GetUserRoles(userID int) ([]models.Role, error) {
 	var roles []models.Role
 
 	err := r.store.db.Select(&roles,
-		"SELECT id, name, description FROM public.role WHERE id in (SELECT user_id FROM userroles WHERE user_id = $1)",
+		"SELECT id, name, description, scope FROM public.role WHERE id in (SELECT role_id FROM userroles WHERE user_id = $1)",
 		userID)
 	return roles, store.HandleErrorNoRows(err)
 }
@@ -0,0 +1,63 @@
+package sqlstore
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/store"
+	"time"
+)
+
+type VerificationTokenRepository struct {
+	store *Store
+}
+
+func (r *VerificationTokenRepository) Create(t *models.VerificationToken) error {
+	query := `INSERT INTO verificationtoken (user_id, purpose, token_hash, email, created_at, expires_at) 
+				VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	return r.store.db.QueryRow(query,
+		t.UserID,
+		t.Purpose,
+		t.TokenHash,
+		t.Email,
+		t.CreatedAt,
+		t.ExpiresAt,
+	).Scan(&t.ID)
+}
+
+func (r *VerificationTokenRepository) FindByHash(purpose, tokenHash string) (*models.VerificationToken, error) {
+	t := &models.VerificationToken{}
+
+	query := `SELECT id, user_id, purpose, token_hash, email, created_at, expires_at, used_at, attempts 
+				FROM verificationtoken WHERE purpose = $1 AND token_hash = $2`
+	err := r.store.db.Get(t, query, purpose, tokenHash)
+	if err != nil {
+		return nil, store.HandleErrorNoRows(err)
+	}
+
+	return t, nil
+}
+
+func (r *VerificationTokenRepository) MarkUsed(tokenID int, usedAt time.Time) (bool, error) {
+	query := `UPDATE verificationtoken SET used_at = $1 WHERE id = $2 AND used_at IS NULL`
+	res, err := r.store.db.Exec(query, usedAt, tokenID)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *VerificationTokenRepository) InvalidateUserTokens(userID int, purpose string, usedAt time.Time) error {
+	query := `UPDATE verificationtoken SET used_at = $1 WHERE user_id = $2 AND purpose = $3 AND used_at IS NULL`
+	_, err := r.store.db.Exec(query, usedAt, userID, purpose)
+	return err
+}
+
+func (r *VerificationTokenRepository) AddAttempt(tokenID int) (int, error) {
+	var attempts int
+
+	query := `UPDATE verificationtoken SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts`
+	err := r.store.db.QueryRow(query, tokenID).Scan(&attempts)
+
+	return attempts, store.HandleErrorNoRows(err)
+}
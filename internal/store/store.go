@@ -2,9 +2,20 @@ package store
 
 type Store interface {
 	Auth() AuthRepository
+	Session() SessionRepository
+	PersonalToken() PersonalTokenRepository
+	OIDC() OIDCRepository
+	Audit() AuditRepository
+	TokenBlacklist() TokenBlacklistRepository
+	SigningKey() SigningKeyRepository
+	VerificationToken() VerificationTokenRepository
+	TwoFactor() TwoFactorRepository
+	LoginAttempt() LoginAttemptRepository
+	Role() RoleRepository
 	User() UserRepository
 	Task() TaskRepository
 	University() UniversityRepository
+	Faculty() FacultyRepository
 	Group() GroupRepository
 	Subject() SubjectRepository
 }
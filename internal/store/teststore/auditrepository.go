@@ -0,0 +1,70 @@
+package teststore
+
+import (
+	"backend/internal/api/v1/models"
+	"time"
+)
+
+type AuditRepository struct {
+	store   *Store
+	entries []models.AuditEntry
+	// lastID keeps ids unique after old entries are deleted
+	lastID int64
+}
+
+func (r *AuditRepository) Create(entry *models.AuditEntry) error {
+	r.lastID++
+	entry.ID = r.lastID
+	r.entries = append(r.entries, *entry)
+
+	return nil
+}
+
+func (r *AuditRepository) Find(filter *models.AuditFilter) ([]models.AuditEntry, error) {
+	entries := make([]models.AuditEntry, 0)
+	for i := len(r.entries) - 1; i >= 0 && len(entries) < filter.Limit; i-- {
+		if e := r.entries[i]; matchesAuditFilter(&e, filter) {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries, nil
+}
+
+func (r *AuditRepository) DeleteCreatedBefore(createdBefore time.Time) (int, error) {
+	kept := make([]models.AuditEntry, 0, len(r.entries))
+	for _, e := range r.entries {
+		if !e.CreatedAt.Before(createdBefore) {
+			kept = append(kept, e)
+		}
+	}
+	deleted := len(r.entries) - len(kept)
+	r.entries = kept
+
+	return deleted, nil
+}
+
+func matchesAuditFilter(e *models.AuditEntry, f *models.AuditFilter) bool {
+	switch {
+	case f.Cursor > 0 && e.ID >= f.Cursor:
+		return false
+	case f.ActorUserID != 0 && (e.ActorUserID == nil || *e.ActorUserID != f.ActorUserID):
+		return false
+	case f.ActorAppID != nil && (e.ActorAppID == nil || *e.ActorAppID != *f.ActorAppID):
+		return false
+	case f.Action != "" && e.Action != f.Action:
+		return false
+	case f.TargetType != "" && e.TargetType != f.TargetType:
+		return false
+	case f.TargetID != "" && e.TargetID != f.TargetID:
+		return false
+	case f.RequestID != "" && e.RequestID != f.RequestID:
+		return false
+	case !f.From.IsZero() && e.CreatedAt.Before(f.From):
+		return false
+	case !f.To.IsZero() && !e.CreatedAt.Before(f.To):
+		return false
+	}
+
+	return true
+}
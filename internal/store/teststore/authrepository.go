@@ -2,66 +2,230 @@ package teststore
 
 import (
 	"backend/internal/api/v1/models"
+	"backend/internal/store"
 	"github.com/google/uuid"
+	"time"
 )
 
 type AuthRepository struct {
-	store *Store
-	users map[int]*models.User //Map[user.email]*User
+	store      *Store
+	users      map[int]*models.User //Map[user.email]*User
+	userTokens []*models.UserToken
+	apps       map[uuid.UUID]*models.RegisteredApp
+	appTokens  []*models.AppToken
 }
 
 func (r *AuthRepository) RegisterApp(app *models.RegisteredApp) error {
-	panic("implement me")
+	if r.apps == nil {
+		r.apps = make(map[uuid.UUID]*models.RegisteredApp)
+	}
+
+	stored := *app
+	stored.AppSecret = ""
+	r.apps[app.ID] = &stored
+
+	return nil
 }
 
 func (r *AuthRepository) DeleteApp(appUUID uuid.UUID) error {
-	panic("implement me")
+	delete(r.apps, appUUID)
+	return nil
 }
 
 func (r *AuthRepository) GetApp(appUUID uuid.UUID) (*models.RegisteredApp, error) {
-	panic("implement me")
+	app, ok := r.apps[appUUID]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	a := *app
+	return &a, nil
+}
+
+func (r *AuthRepository) UpdateAppFingerprintPolicy(appUUID uuid.UUID, policy string) error {
+	app, ok := r.apps[appUUID]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	app.FingerprintPolicy = policy
+	return nil
 }
 
 func (r *AuthRepository) GetAppByName(name string) (*models.RegisteredApp, error) {
-	panic("implement me")
+	for _, app := range r.apps {
+		if app.AppName == name {
+			a := *app
+			return &a, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
 }
 
 func (r *AuthRepository) AddAppToken(t *models.AppToken) error {
-	panic("implement me")
+	token := *t
+	r.appTokens = append(r.appTokens, &token)
+	return nil
 }
 
 func (r *AuthRepository) RemoveAppTokens(appUUID uuid.UUID) error {
-	panic("implement me")
+	tokens := r.appTokens[:0]
+	for _, t := range r.appTokens {
+		if t.AppID != appUUID {
+			tokens = append(tokens, t)
+		}
+	}
+	r.appTokens = tokens
+
+	return nil
 }
 
 func (r *AuthRepository) GetAppTokenInfo(token string) (*models.AppToken, error) {
-	panic("implement me")
+	for _, t := range r.appTokens {
+		if t.AppToken == token {
+			appToken := *t
+			return &appToken, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
 }
 
 func (r *AuthRepository) GetAppTokenByAppUUID(appUUID uuid.UUID) (*models.AppToken, error) {
-	panic("implement me")
+	var last *models.AppToken
+	for _, t := range r.appTokens {
+		if t.AppID == appUUID && (last == nil || t.IssueTokenTimestamp.After(last.IssueTokenTimestamp)) {
+			last = t
+		}
+	}
+	if last == nil {
+		return &models.AppToken{}, store.ErrRecordNotFound
+	}
+
+	appToken := *last
+	return &appToken, nil
 }
 
 func (r *AuthRepository) AddUserToken(t *models.UserToken) error {
-	panic("implement me")
+	t.ID = len(r.userTokens) + 1
+	r.userTokens = append(r.userTokens, t)
+
+	return nil
 }
 
 func (r *AuthRepository) GetUserToken(userID int) (*models.UserToken, error) {
-	panic("implement me")
+	for i := len(r.userTokens) - 1; i >= 0; i-- {
+		if r.userTokens[i].UserID == userID {
+			return r.userTokens[i], nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
 }
 
-func (r *AuthRepository) RemoveUserTokens(userID int) (int, error) {
-	panic("implement me")
+func (r *AuthRepository) GetUserTokenByRefreshToken(refreshToken string) (*models.UserToken, error) {
+	for _, t := range r.userTokens {
+		if t.RefreshToken == refreshToken {
+			return t, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
 }
 
-func (r *AuthRepository) ClearUserTokens() error {
+func (r *AuthRepository) GetSessionToken(sessionID int) (*models.UserToken, error) {
+	for i := len(r.userTokens) - 1; i >= 0; i-- {
+		if r.userTokens[i].SessionID == sessionID {
+			return r.userTokens[i], nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
+}
+
+func (r *AuthRepository) GetUserTokenFamily(familyID uuid.UUID) ([]models.UserToken, error) {
+	var tokens []models.UserToken
+	for _, t := range r.userTokens {
+		if t.FamilyID == familyID {
+			tokens = append(tokens, *t)
+		}
+	}
+
+	return tokens, nil
+}
+
+func (r *AuthRepository) GetUserTokensIssuedAfter(userID int, issuedAfter time.Time) ([]models.UserToken, error) {
+	var tokens []models.UserToken
+	for _, t := range r.userTokens {
+		if t.UserID == userID && t.IssueTokenTimestamp.After(issuedAfter) {
+			tokens = append(tokens, *t)
+		}
+	}
+
+	return tokens, nil
+}
+
+func (r *AuthRepository) MarkUserTokenUsed(tokenID int) error {
+	for _, t := range r.userTokens {
+		if t.ID == tokenID && t.UsedAt == nil {
+			now := time.Now()
+			t.UsedAt = &now
+			return nil
+		}
+	}
+
+	return store.ErrRecordNotFound
+}
+
+func (r *AuthRepository) SetUserTokenInvalidByFamily(familyID uuid.UUID) error {
+	now := time.Now()
+	for _, t := range r.userTokens {
+		if t.FamilyID == familyID && t.ExpirationTimestamp.After(now) {
+			t.ExpirationTimestamp = now
+			t.LogoutTimestamp = now
+		}
+	}
+
+	return nil
+}
+
+func (r *AuthRepository) RemoveUserTokens(userID int) (int, error) {
 	panic("implement me")
 }
 
 func (r *AuthRepository) SetUserTokenInvalidByToken(token string) error {
-	panic("implement me")
+	now := time.Now()
+	for _, t := range r.userTokens {
+		if t.RefreshToken == token {
+			t.ExpirationTimestamp = now
+			t.LogoutTimestamp = now
+		}
+	}
+
+	return nil
 }
 
 func (r *AuthRepository) SetUserTokenInvalidByUserID(userID int) error {
-	panic("implement me")
+	now := time.Now()
+	for _, t := range r.userTokens {
+		if t.UserID == userID && t.ExpirationTimestamp.After(now) {
+			t.ExpirationTimestamp = now
+			t.LogoutTimestamp = now
+		}
+	}
+
+	return nil
+}
+
+func (r *AuthRepository) SetUserTokenInvalidBySessionID(sessionID int) error {
+	now := time.Now()
+	for _, t := range r.userTokens {
+		if t.SessionID == sessionID && t.ExpirationTimestamp.After(now) {
+			t.ExpirationTimestamp = now
+			t.LogoutTimestamp = now
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,182 @@
+package teststore
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/store"
+	"fmt"
+	"sort"
+	"time"
+)
+
+type FacultyRepository struct {
+	store            *Store
+	faculties        map[int]*models.Faculty
+	departments      map[int]*models.Department
+	lastFacultyID    int
+	lastDepartmentID int
+}
+
+func (r *FacultyRepository) Create(faculty *models.Faculty) error {
+	r.lastFacultyID++
+	faculty.ID = r.lastFacultyID
+	faculty.CreatedAt = time.Now()
+
+	f := *faculty
+	r.faculties[f.ID] = &f
+
+	return nil
+}
+
+func (r *FacultyRepository) Find(facultyID int) (*models.Faculty, error) {
+	f, ok := r.faculties[facultyID]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	faculty := *f
+	return &faculty, nil
+}
+
+func (r *FacultyRepository) Update(faculty *models.Faculty) error {
+	f, ok := r.faculties[faculty.ID]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	f.Name = faculty.Name
+	return nil
+}
+
+func (r *FacultyRepository) Delete(facultyID int) error {
+	if _, ok := r.faculties[facultyID]; !ok {
+		return store.ErrRecordNotFound
+	}
+
+	for id, d := range r.departments {
+		if d.FacultyID == facultyID {
+			if err := r.DeleteDepartment(id); err != nil {
+				return err
+			}
+		}
+	}
+	roles := r.store.Role().(*RoleRepository)
+	for key := range roles.facultyRoles {
+		if key[1] == facultyID {
+			delete(roles.facultyRoles, key)
+		}
+	}
+	delete(r.faculties, facultyID)
+
+	return nil
+}
+
+func (r *FacultyRepository) GetFaculties(universityID int) ([]models.Faculty, error) {
+	var faculties []models.Faculty
+	for _, f := range r.faculties {
+		if f.UniversityID == universityID {
+			faculties = append(faculties, *f)
+		}
+	}
+	sort.Slice(faculties, func(i, j int) bool {
+		return faculties[i].Name < faculties[j].Name ||
+			faculties[i].Name == faculties[j].Name && faculties[i].ID < faculties[j].ID
+	})
+
+	return faculties, nil
+}
+
+func (r *FacultyRepository) CreateDepartment(department *models.Department) error {
+	if _, ok := r.faculties[department.FacultyID]; !ok {
+		return fmt.Errorf("the faculty %d doesn't exist", department.FacultyID)
+	}
+
+	r.lastDepartmentID++
+	department.ID = r.lastDepartmentID
+	department.CreatedAt = time.Now()
+
+	d := *department
+	r.departments[d.ID] = &d
+
+	return nil
+}
+
+func (r *FacultyRepository) FindDepartment(departmentID int) (*models.Department, error) {
+	d, ok := r.departments[departmentID]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	department := *d
+	return &department, nil
+}
+
+func (r *FacultyRepository) UpdateDepartment(department *models.Department) error {
+	d, ok := r.departments[department.ID]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	d.Name = department.Name
+	return nil
+}
+
+func (r *FacultyRepository) DeleteDepartment(departmentID int) error {
+	if _, ok := r.departments[departmentID]; !ok {
+		return store.ErrRecordNotFound
+	}
+
+	groups := r.store.Group().(*GroupRepository)
+	for _, g := range groups.groups {
+		if g.DepartmentID != nil && *g.DepartmentID == departmentID {
+			g.DepartmentID = nil
+		}
+	}
+	subjects := r.store.Subject().(*SubjectRepository)
+	for _, s := range subjects.subjects {
+		if s.DepartmentID != nil && *s.DepartmentID == departmentID {
+			s.DepartmentID = nil
+		}
+	}
+	delete(r.departments, departmentID)
+
+	return nil
+}
+
+func (r *FacultyRepository) GetDepartments(facultyID int) ([]models.Department, error) {
+	var departments []models.Department
+	for _, d := range r.departments {
+		if d.FacultyID == facultyID {
+			departments = append(departments, *d)
+		}
+	}
+	sort.Slice(departments, func(i, j int) bool {
+		return departments[i].Name < departments[j].Name ||
+			departments[i].Name == departments[j].Name && departments[i].ID < departments[j].ID
+	})
+
+	return departments, nil
+}
+
+// isInOrgNode reports whether the department is in the node of the org tree
+func (r *FacultyRepository) isInOrgNode(departmentID *int, node models.OrgNode) (bool, error) {
+	if departmentID == nil {
+		return false, nil
+	}
+
+	d, ok := r.departments[*departmentID]
+	if !ok {
+		return false, nil
+	}
+
+	switch node.Type {
+	case models.OrgNodeUniversity:
+		f, ok := r.faculties[d.FacultyID]
+		return ok && f.UniversityID == node.ID, nil
+	case models.OrgNodeFaculty:
+		return d.FacultyID == node.ID, nil
+	case models.OrgNodeDepartment:
+		return d.ID == node.ID, nil
+	default:
+		return false, fmt.Errorf("unknown node of the org tree %q", node.Type)
+	}
+}
@@ -2,92 +2,582 @@ package teststore
 
 import (
 	"backend/internal/api/v1/models"
+	"backend/internal/store"
+	"errors"
+	"sort"
+	"time"
 )
 
 type GroupRepository struct {
-	store *Store
+	store     *Store
+	groups    map[int]*models.Group
+	members   []models.GroupMember
+	invites   []models.GroupInvite
+	joins     []models.GroupJoinRequest
+	rollovers []models.GroupRollover
+	names     []models.GroupNameHistory
+	lastID    int
 }
 
 func (r *GroupRepository) Create(group *models.Group) error {
-	panic("implement me")
+	r.lastID++
+	group.ID = r.lastID
+	group.CreatedAt = time.Now()
+	group.CompileFullGroupNameAndCompareCustom()
+
+	g := *group
+	r.groups[g.ID] = &g
+
+	return nil
 }
 
 func (r *GroupRepository) GetAllGroups(limit, offset int) ([]models.Group, error) {
 	panic("implement me")
 }
 
-func (r *GroupRepository) Find(i int) (*models.Group, error) {
-	panic("implement me")
+func (r *GroupRepository) Find(id int) (*models.Group, error) {
+	g, ok := r.groups[id]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	group := *g
+	group.CompileFullGroupNameAndCompareCustom()
+	return &group, nil
 }
 
-func (r *GroupRepository) FindByName(s string) (*models.Group, error) {
-	panic("implement me")
+func (r *GroupRepository) FindByName(name string) (*models.Group, error) {
+	for _, g := range r.groups {
+		if g.CustomName == name {
+			group := *g
+			group.CompileFullGroupNameAndCompareCustom()
+			return &group, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
 }
 
-func (r *GroupRepository) Update(i int, group *models.UpdateGroup) error {
-	panic("implement me")
+func (r *GroupRepository) Update(groupID int, up *models.UpdateGroup) error {
+	g, ok := r.groups[groupID]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	up.Apply(g)
+	return nil
 }
 
-func (r *GroupRepository) Delete(i int) error {
-	panic("implement me")
+func (r *GroupRepository) Delete(groupID int) error {
+	if _, ok := r.groups[groupID]; !ok {
+		return store.ErrRecordNotFound
+	}
+	delete(r.groups, groupID)
+
+	members := r.members[:0]
+	for _, m := range r.members {
+		if m.GroupID == groupID {
+			delete(r.store.Role().(*RoleRepository).memberRoles, [2]int{m.UserID, groupID})
+			continue
+		}
+		members = append(members, m)
+	}
+	r.members = members
+
+	for i := range r.invites {
+		if r.invites[i].GroupID == groupID && r.invites[i].RevokedAt == nil {
+			revokedAt := time.Now()
+			r.invites[i].RevokedAt = &revokedAt
+		}
+	}
+
+	return nil
+}
+
+func (r *GroupRepository) SoftDelete(groupID int, deletedAt time.Time) error {
+	g, ok := r.groups[groupID]
+	if !ok || g.DeletedAt != nil {
+		return store.ErrRecordNotFound
+	}
+
+	g.DeletedAt = &deletedAt
+	return nil
+}
+
+func (r *GroupRepository) Restore(groupID int) error {
+	g, ok := r.groups[groupID]
+	if !ok || g.DeletedAt == nil {
+		return store.ErrRecordNotFound
+	}
+
+	g.DeletedAt = nil
+	return nil
+}
+
+func (r *GroupRepository) SetArchived(groupID int, archivedAt *time.Time) error {
+	g, ok := r.groups[groupID]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	g.ArchivedAt = archivedAt
+	return nil
+}
+
+func (r *GroupRepository) SetDepartment(groupID int, departmentID *int) error {
+	g, ok := r.groups[groupID]
+	if !ok || g.DeletedAt != nil {
+		return store.ErrRecordNotFound
+	}
+
+	g.DepartmentID = departmentID
+	return nil
+}
+
+func (r *GroupRepository) GetGroupsOfOrgNode(node models.OrgNode) ([]models.Group, error) {
+	faculties := r.store.Faculty().(*FacultyRepository)
+
+	var groups []models.Group
+	for _, id := range r.groupIDs() {
+		g := r.groups[id]
+		if g.DeletedAt != nil {
+			continue
+		}
+		if in, err := faculties.isInOrgNode(g.DepartmentID, node); err != nil {
+			return nil, err
+		} else if in {
+			group := *g
+			group.CompileFullGroupNameAndCompareCustom()
+			groups = append(groups, group)
+		}
+	}
+
+	return groups, nil
+}
+
+func (r *GroupRepository) GetDeletedGroups() ([]models.Group, error) {
+	var groups []models.Group
+	for _, id := range r.groupIDs() {
+		if g := r.groups[id]; g.DeletedAt != nil {
+			group := *g
+			group.CompileFullGroupNameAndCompareCustom()
+			groups = append(groups, group)
+		}
+	}
+
+	return groups, nil
+}
+
+func (r *GroupRepository) DeleteGroupsDeletedBefore(t time.Time) (int, error) {
+	n := 0
+	for _, id := range r.groupIDs() {
+		if g := r.groups[id]; g.DeletedAt != nil && g.DeletedAt.Before(t) {
+			if err := r.Delete(id); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// groupIDs returns IDs of the groups in the order of creation
+func (r *GroupRepository) groupIDs() []int {
+	ids := make([]int, 0, len(r.groups))
+	for id := range r.groups {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	return ids
 }
 
 func (r *GroupRepository) IsGroupExist(groupID int) (bool, error) {
-	panic("implement me")
+	_, ok := r.groups[groupID]
+	return ok, nil
 }
 
 func (r *GroupRepository) AddGroupMember(userID, groupID int, inviterID int) error {
-	panic("implement me")
+	isMember, err := r.IsUserGroupMember(userID, groupID)
+	if err != nil {
+		return err
+	}
+	if isMember {
+		return errors.New("the user is already a member of the group")
+	}
+
+	r.members = append(r.members, models.GroupMember{
+		ID:      len(r.members) + 1,
+		UserID:  userID,
+		GroupID: groupID,
+	})
+
+	return nil
+}
+
+func (r *GroupRepository) ImportGroupMembers(groupID, inviterID, roleID int, newUsers []*models.User, userIDs []int) error {
+	ids := append([]int{}, userIDs...)
+	for _, u := range newUsers {
+		if err := r.store.User().Create(u); err != nil {
+			return err
+		}
+		ids = append(ids, u.ID)
+	}
+
+	for _, id := range ids {
+		if err := r.AddGroupMember(id, groupID, inviterID); err != nil {
+			return err
+		}
+		if err := r.store.Role().SetMemberRole(id, groupID, roleID, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *GroupRepository) RemoveGroupMember(userID, groupID, keptRoleID int) error {
+	for i, m := range r.members {
+		if m.UserID == userID && m.GroupID == groupID {
+			if r.isLastMemberWithRole(userID, groupID, keptRoleID) {
+				return store.ErrLastMemberWithRole
+			}
+			r.members = append(r.members[:i], r.members[i+1:]...)
+			delete(r.store.Role().(*RoleRepository).memberRoles, [2]int{userID, groupID})
+			r.store.Task().(*TaskRepository).removeUserFromGroupTasks(userID, groupID)
+			return nil
+		}
+	}
+
+	return store.ErrRecordNotFound
+}
+
+// isLastMemberWithRole reports whether the member is the only one with the role in the group
+func (r *GroupRepository) isLastMemberWithRole(userID, groupID, roleID int) bool {
+	if roleID == 0 || !containsID(r.store.Role().(*RoleRepository).memberRoles[[2]int{userID, groupID}], roleID) {
+		return false
+	}
+
+	count, _ := r.CountMembersWithRole(groupID, roleID)
+	return count <= 1
 }
 
 func (r *GroupRepository) IsUserGroupMember(userID, groupID int) (bool, error) {
-	panic("implement me")
+	for _, m := range r.members {
+		if m.UserID == userID && m.GroupID == groupID {
+			return true, nil
+		}
+	}
+
+	return false, nil
 }
 
-func (r *GroupRepository) GetGroupsUserMemberOf(userID int) ([]models.Group, error) {
-	panic("implement me")
+func (r *GroupRepository) GetGroupsUserMemberOf(userID int, includeArchived bool) ([]models.Group, error) {
+	var groups []models.Group
+	for _, m := range r.members {
+		if m.UserID == userID {
+			group, err := r.Find(m.GroupID)
+			if err != nil {
+				return nil, err
+			}
+			if group.DeletedAt != nil || (group.ArchivedAt != nil && !includeArchived) {
+				continue
+			}
+			groups = append(groups, *group)
+		}
+	}
+
+	if len(groups) == 0 {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return groups, nil
 }
 
 func (r *GroupRepository) GetGroupMembers(groupID int) ([]models.User, error) {
-	panic("implement me")
+	var users []models.User
+	for _, m := range r.members {
+		if m.GroupID == groupID {
+			u, err := r.store.User().Find(m.UserID)
+			if err != nil {
+				return nil, err
+			}
+			users = append(users, *u)
+		}
+	}
+
+	if len(users) == 0 {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return users, nil
 }
 
 func (r *GroupRepository) GetMembersCount(groupID int) (int, error) {
-	panic("implement me")
+	count := 0
+	for _, m := range r.members {
+		if m.GroupID == groupID {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *GroupRepository) CountMembersWithRole(groupID, roleID int) (int, error) {
+	count := 0
+	for _, m := range r.members {
+		if m.GroupID != groupID {
+			continue
+		}
+		for _, id := range r.store.Role().(*RoleRepository).memberRoles[[2]int{m.UserID, groupID}] {
+			if id == roleID {
+				count++
+			}
+		}
+	}
+
+	return count, nil
 }
 
 func (r *GroupRepository) GetMemberRoles(userID, groupID int) ([]models.Role, error) {
-	panic("implement me")
+	roles := r.store.Role().(*RoleRepository)
+	return roles.getRoles(roles.memberRoles[[2]int{userID, groupID}]), nil
 }
 
 func (r *GroupRepository) GetRolePermissions(roleID int) ([]models.Permission, error) {
-	panic("implement me")
+	role, ok := r.store.Role().(*RoleRepository).roles[roleID]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	var permissions []models.Permission
+	for _, rp := range role.Permissions {
+		if rp.State {
+			permissions = append(permissions, rp.Permission)
+		}
+	}
+
+	return permissions, nil
 }
 
 func (r *GroupRepository) GetRole(roleID int) (*models.Role, error) {
-	panic("implement me")
+	role, ok := r.store.Role().(*RoleRepository).roles[roleID]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	result := *role
+	return &result, nil
 }
 
 func (r *GroupRepository) GetRoleByName(roleName string) (*models.Role, error) {
-	panic("implement me")
+	role := r.store.Role().(*RoleRepository).findRoleByName(roleName)
+	if role == nil {
+		return nil, store.ErrRecordNotFound
+	}
+
+	result := *role
+	return &result, nil
 }
 
-func (r *GroupRepository) GetGroupInvite(groupID int) (*models.GroupInvite, error) {
-	panic("implement me")
+func (r *GroupRepository) AddGroupInvite(invite *models.GroupInvite) error {
+	invite.ID = len(r.invites) + 1
+	r.invites = append(r.invites, *invite)
+	return nil
+}
+
+func (r *GroupRepository) GetGroupInvites(groupID int) ([]models.GroupInvite, error) {
+	var invites []models.GroupInvite
+	for _, invite := range r.invites {
+		if invite.GroupID == groupID && invite.RevokedAt == nil {
+			invites = append(invites, invite)
+		}
+	}
+
+	if len(invites) == 0 {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return invites, nil
 }
 
 func (r *GroupRepository) GetGroupInviteByHash(inviteHash string) (*models.GroupInvite, error) {
-	panic("implement me")
+	for _, invite := range r.invites {
+		if invite.InviteHash == inviteHash {
+			result := invite
+			return &result, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
 }
 
-func (r *GroupRepository) AddGroupInviteHash(invite *models.GroupInvite) error {
-	panic("implement me")
+func (r *GroupRepository) JoinGroupByInvite(userID, inviteID, roleID int) error {
+	if inviteID < 1 || inviteID > len(r.invites) || !r.invites[inviteID-1].Valid(time.Now()) {
+		return store.ErrRecordNotFound
+	}
+	invite := &r.invites[inviteID-1]
+
+	if err := r.AddGroupMember(userID, invite.GroupID, invite.InviterID); err != nil {
+		return err
+	}
+	invite.Uses++
+
+	return r.store.Role().SetMemberRole(userID, invite.GroupID, roleID, 0)
 }
 
-func (r *GroupRepository) DeleteGroupInvites(groupID int) error {
-	panic("implement me")
+func (r *GroupRepository) RevokeGroupInvite(groupID, inviteID int, revokedAt time.Time) error {
+	for i := range r.invites {
+		if r.invites[i].ID == inviteID && r.invites[i].GroupID == groupID && r.invites[i].RevokedAt == nil {
+			r.invites[i].RevokedAt = &revokedAt
+			return nil
+		}
+	}
+
+	return store.ErrRecordNotFound
 }
 
-func (r *GroupRepository) DeleteGroupInviteByHash(hash string) error {
-	panic("implement me")
+func (r *GroupRepository) CreateJoinRequest(request *models.GroupJoinRequest) error {
+	request.ID = len(r.joins) + 1
+	r.joins = append(r.joins, *request)
+	return nil
+}
+
+func (r *GroupRepository) FindJoinRequest(requestID int) (*models.GroupJoinRequest, error) {
+	if requestID < 1 || requestID > len(r.joins) {
+		return nil, store.ErrRecordNotFound
+	}
+
+	request := r.joins[requestID-1]
+	return &request, nil
+}
+
+func (r *GroupRepository) GetJoinRequests(groupID int, status string) ([]models.GroupJoinRequest, error) {
+	var requests []models.GroupJoinRequest
+	for _, request := range r.joins {
+		if request.GroupID == groupID && (status == "" || request.Status == status) {
+			requests = append(requests, request)
+		}
+	}
+
+	if len(requests) == 0 {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return requests, nil
+}
+
+func (r *GroupRepository) GetUserJoinRequests(userID int) ([]models.GroupJoinRequest, error) {
+	var requests []models.GroupJoinRequest
+	for i := len(r.joins) - 1; i >= 0; i-- {
+		if r.joins[i].UserID == userID {
+			requests = append(requests, r.joins[i])
+		}
+	}
+
+	if len(requests) == 0 {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return requests, nil
+}
+
+func (r *GroupRepository) ApproveJoinRequest(requestID, deciderID, roleID int, decidedAt time.Time) error {
+	request, err := r.decideJoinRequest(requestID, deciderID, models.JoinRequestStatusApproved, decidedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := r.AddGroupMember(request.UserID, request.GroupID, deciderID); err != nil {
+		return err
+	}
+
+	return r.store.Role().SetMemberRole(request.UserID, request.GroupID, roleID, 0)
+}
+
+func (r *GroupRepository) RejectJoinRequest(requestID, deciderID int, decidedAt time.Time) error {
+	_, err := r.decideJoinRequest(requestID, deciderID, models.JoinRequestStatusRejected, decidedAt)
+	return err
+}
+
+func (r *GroupRepository) decideJoinRequest(requestID, deciderID int, status string, decidedAt time.Time) (*models.GroupJoinRequest, error) {
+	if requestID < 1 || requestID > len(r.joins) || r.joins[requestID-1].Status != models.JoinRequestStatusPending {
+		return nil, store.ErrRecordNotFound
+	}
+
+	request := &r.joins[requestID-1]
+	request.Status = status
+	request.DecidedByID = &deciderID
+	request.DecidedAt = &decidedAt
+
+	return request, nil
+}
+
+func (r *GroupRepository) RolloverGroups(rollover *models.GroupRollover, yearStart time.Time, finalCourse int) (bool, error) {
+	for _, ro := range r.rollovers {
+		if ro.UniversityID == rollover.UniversityID && ro.AcademicYear == rollover.AcademicYear {
+			return false, nil
+		}
+	}
+
+	for _, id := range r.groupIDs() {
+		g := r.groups[id]
+		if g.UniversityID != rollover.UniversityID || g.ArchivedAt != nil || g.DeletedAt != nil ||
+			!g.CreatedAt.Before(yearStart) {
+			continue
+		}
+
+		g.CompileFullGroupNameAndCompareCustom()
+		r.names = append(r.names, models.GroupNameHistory{
+			ID:           len(r.names) + 1,
+			GroupID:      g.ID,
+			FullName:     g.FullName,
+			CustomName:   g.CustomName,
+			CourseNumber: g.CourseNumber,
+			AcademicYear: rollover.AcademicYear,
+			ChangedAt:    rollover.CreatedAt,
+		})
+
+		if g.CourseNumber >= finalCourse {
+			archivedAt := rollover.CreatedAt
+			g.ArchivedAt = &archivedAt
+			rollover.Archived++
+		} else {
+			g.CourseNumber++
+			rollover.Advanced++
+		}
+	}
+
+	rollover.ID = len(r.rollovers) + 1
+	r.rollovers = append(r.rollovers, *rollover)
+
+	return true, nil
+}
+
+func (r *GroupRepository) GetGroupRollovers(universityID int) ([]models.GroupRollover, error) {
+	var rollovers []models.GroupRollover
+	for _, ro := range r.rollovers {
+		if ro.UniversityID == universityID {
+			rollovers = append(rollovers, ro)
+		}
+	}
+
+	sort.Slice(rollovers, func(i, j int) bool {
+		return rollovers[i].AcademicYear > rollovers[j].AcademicYear
+	})
+
+	return rollovers, nil
+}
+
+func (r *GroupRepository) GetGroupNameHistory(groupID int) ([]models.GroupNameHistory, error) {
+	var names []models.GroupNameHistory
+	for _, n := range r.names {
+		if n.GroupID == groupID {
+			names = append(names, n)
+		}
+	}
+
+	return names, nil
 }
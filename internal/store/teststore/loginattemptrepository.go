@@ -0,0 +1,65 @@
+package teststore
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/store"
+	"time"
+)
+
+type LoginAttemptRepository struct {
+	store    *Store
+	attempts map[string]*models.LoginAttempts
+}
+
+func (r *LoginAttemptRepository) Find(key string) (*models.LoginAttempts, error) {
+	a, ok := r.attempts[key]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	attempts := *a
+	return &attempts, nil
+}
+
+func (r *LoginAttemptRepository) AddFailure(key string, at, resetBefore time.Time) (*models.LoginAttempts, error) {
+	a, ok := r.attempts[key]
+	if !ok {
+		a = &models.LoginAttempts{Key: key}
+		r.attempts[key] = a
+	}
+
+	if a.LastFailureAt.Before(resetBefore) {
+		a.Failures = 0
+	}
+	a.Failures++
+	a.LastFailureAt = at
+
+	attempts := *a
+	return &attempts, nil
+}
+
+func (r *LoginAttemptRepository) Lock(key string, until time.Time) error {
+	if a, ok := r.attempts[key]; ok {
+		a.LockedUntil = &until
+		a.Failures = 0
+	}
+
+	return nil
+}
+
+func (r *LoginAttemptRepository) Delete(key string) error {
+	delete(r.attempts, key)
+	return nil
+}
+
+func (r *LoginAttemptRepository) DeleteStale(failedBefore, now time.Time) (int64, error) {
+	var deleted int64
+	for key, a := range r.attempts {
+		if a.LastFailureAt.Before(failedBefore) && (a.LockedUntil == nil || a.LockedUntil.Before(now)) {
+			delete(r.attempts, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
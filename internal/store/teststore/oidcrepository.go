@@ -0,0 +1,86 @@
+package teststore
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/store"
+	"time"
+)
+
+type OIDCRepository struct {
+	store      *Store
+	identities map[int]*models.ExternalIdentity
+	states     map[int]*models.OIDCLoginState
+	// lastStateID keeps ids unique after expired states are deleted
+	lastStateID int
+}
+
+func (r *OIDCRepository) CreateIdentity(i *models.ExternalIdentity) error {
+	i.ID = len(r.identities) + 1
+	identity := *i
+	r.identities[i.ID] = &identity
+
+	return nil
+}
+
+func (r *OIDCRepository) FindIdentity(issuer, subject string) (*models.ExternalIdentity, error) {
+	for _, i := range r.identities {
+		if i.Issuer == issuer && i.Subject == subject {
+			identity := *i
+			return &identity, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
+}
+
+func (r *OIDCRepository) FindIdentitiesByUser(userID int) ([]models.ExternalIdentity, error) {
+	identities := make([]models.ExternalIdentity, 0)
+	for id := 1; id <= len(r.identities); id++ {
+		if i, ok := r.identities[id]; ok && i.UserID == userID {
+			identities = append(identities, *i)
+		}
+	}
+
+	return identities, nil
+}
+
+func (r *OIDCRepository) UpdateIdentityLastLogin(identityID int, lastLoginAt time.Time) error {
+	i, ok := r.identities[identityID]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	i.LastLoginAt = &lastLoginAt
+	return nil
+}
+
+func (r *OIDCRepository) CreateLoginState(s *models.OIDCLoginState) error {
+	r.lastStateID++
+	s.ID = r.lastStateID
+	state := *s
+	r.states[s.ID] = &state
+
+	return nil
+}
+
+func (r *OIDCRepository) UseLoginState(stateHash string, usedAt time.Time) (*models.OIDCLoginState, error) {
+	for _, s := range r.states {
+		if s.StateHash == stateHash && s.Valid(usedAt) {
+			s.UsedAt = &usedAt
+			state := *s
+			return &state, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
+}
+
+func (r *OIDCRepository) DeleteExpiredLoginStates(now time.Time) error {
+	for id, s := range r.states {
+		if !now.Before(s.ExpiresAt) {
+			delete(r.states, id)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package teststore
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/store"
+	"sort"
+	"time"
+)
+
+type PersonalTokenRepository struct {
+	store  *Store
+	tokens map[int]*models.PersonalToken
+}
+
+func (r *PersonalTokenRepository) Create(t *models.PersonalToken) error {
+	t.ID = len(r.tokens) + 1
+	token := *t
+	r.tokens[t.ID] = &token
+
+	return nil
+}
+
+func (r *PersonalTokenRepository) FindByHash(tokenHash string) (*models.PersonalToken, error) {
+	for _, t := range r.tokens {
+		if t.TokenHash == tokenHash {
+			token := *t
+			return &token, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
+}
+
+func (r *PersonalTokenRepository) FindByUser(userID int) ([]models.PersonalToken, error) {
+	var tokens []models.PersonalToken
+	for _, t := range r.tokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			tokens = append(tokens, *t)
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].ID > tokens[j].ID
+	})
+	return tokens, nil
+}
+
+func (r *PersonalTokenRepository) Revoke(userID, tokenID int, revokedAt time.Time) error {
+	t, ok := r.tokens[tokenID]
+	if !ok || t.UserID != userID || t.RevokedAt != nil {
+		return store.ErrRecordNotFound
+	}
+
+	t.RevokedAt = &revokedAt
+	return nil
+}
+
+func (r *PersonalTokenRepository) RevokeByUser(userID int, revokedAt time.Time) error {
+	for _, t := range r.tokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			revoked := revokedAt
+			t.RevokedAt = &revoked
+		}
+	}
+
+	return nil
+}
+
+func (r *PersonalTokenRepository) UpdateLastUsed(tokenID int, lastUsedAt time.Time) error {
+	t, ok := r.tokens[tokenID]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	t.LastUsedAt = &lastUsedAt
+	return nil
+}
@@ -0,0 +1,171 @@
+package teststore
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/store"
+)
+
+type RoleRepository struct {
+	store        *Store
+	permissions  map[string]*models.Permission
+	roles        map[int]*models.Role
+	userRoles    map[int][]int    //Map[userID][]roleID
+	memberRoles  map[[2]int][]int //Map[{userID, groupID}][]roleID
+	facultyRoles map[[2]int][]int //Map[{userID, facultyID}][]roleID
+}
+
+func (r *RoleRepository) Seed(permissions []models.Permission, roles []models.Role) error {
+	for i := range permissions {
+		p, ok := r.permissions[permissions[i].Name]
+		if !ok {
+			p = &models.Permission{ID: len(r.permissions) + 1, Name: permissions[i].Name}
+			r.permissions[p.Name] = p
+		}
+		p.Type = permissions[i].Type
+		permissions[i].ID = p.ID
+	}
+
+	for i := range roles {
+		role := r.findRoleByName(roles[i].Name)
+		if role == nil {
+			role = &models.Role{ID: len(r.roles) + 1, Name: roles[i].Name}
+			r.roles[role.ID] = role
+		}
+		role.Description = roles[i].Description
+		role.Scope = roles[i].Scope
+		role.Permissions = nil
+
+		for _, rp := range roles[i].Permissions {
+			p, ok := r.permissions[rp.Permission.Name]
+			if !ok {
+				return store.ErrRecordNotFound
+			}
+			role.Permissions = append(role.Permissions, models.RolePermissions{Permission: *p, State: rp.State})
+		}
+		roles[i].ID = role.ID
+	}
+
+	return nil
+}
+
+func (r *RoleRepository) FindPermission(name string) (*models.Permission, error) {
+	p, ok := r.permissions[name]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	permission := *p
+	return &permission, nil
+}
+
+func (r *RoleRepository) AddUserRole(userID, roleID int) error {
+	for _, id := range r.userRoles[userID] {
+		if id == roleID {
+			return nil
+		}
+	}
+
+	r.userRoles[userID] = append(r.userRoles[userID], roleID)
+	return nil
+}
+
+func (r *RoleRepository) RemoveUserRole(userID, roleID int) error {
+	r.userRoles[userID] = removeID(r.userRoles[userID], roleID)
+	return nil
+}
+
+func (r *RoleRepository) AddFacultyRole(userID, facultyID, roleID int) error {
+	key := [2]int{userID, facultyID}
+	for _, id := range r.facultyRoles[key] {
+		if id == roleID {
+			return nil
+		}
+	}
+
+	r.facultyRoles[key] = append(r.facultyRoles[key], roleID)
+	return nil
+}
+
+func (r *RoleRepository) RemoveFacultyRole(userID, facultyID, roleID int) error {
+	key := [2]int{userID, facultyID}
+	r.facultyRoles[key] = removeID(r.facultyRoles[key], roleID)
+	return nil
+}
+
+func (r *RoleRepository) GetFacultyRoles(userID, facultyID int) ([]models.Role, error) {
+	return r.getRoles(r.facultyRoles[[2]int{userID, facultyID}]), nil
+}
+
+func (r *RoleRepository) SetMemberRole(userID, groupID, roleID, keptRoleID int) error {
+	isMember, err := r.store.Group().IsUserGroupMember(userID, groupID)
+	if err != nil {
+		return err
+	} else if !isMember {
+		return store.ErrRecordNotFound
+	}
+
+	if keptRoleID != roleID && r.store.Group().(*GroupRepository).isLastMemberWithRole(userID, groupID, keptRoleID) {
+		return store.ErrLastMemberWithRole
+	}
+
+	r.memberRoles[[2]int{userID, groupID}] = []int{roleID}
+	return nil
+}
+
+func (r *RoleRepository) TransferMemberRole(groupID, fromUserID, toUserID, roleID, replacementRoleID int) error {
+	for _, userID := range []int{fromUserID, toUserID} {
+		isMember, err := r.store.Group().IsUserGroupMember(userID, groupID)
+		if err != nil {
+			return err
+		} else if !isMember {
+			return store.ErrRecordNotFound
+		}
+	}
+
+	r.memberRoles[[2]int{toUserID, groupID}] = []int{roleID}
+	r.memberRoles[[2]int{fromUserID, groupID}] = []int{replacementRoleID}
+	return nil
+}
+
+func (r *RoleRepository) findRoleByName(name string) *models.Role {
+	for _, role := range r.roles {
+		if role.Name == name {
+			return role
+		}
+	}
+
+	return nil
+}
+
+// getRoles returns copies of the roles with the IDs
+func (r *RoleRepository) getRoles(roleIDs []int) []models.Role {
+	roles := make([]models.Role, 0, len(roleIDs))
+	for _, id := range roleIDs {
+		if role, ok := r.roles[id]; ok {
+			roles = append(roles, *role)
+		}
+	}
+
+	return roles
+}
+
+func removeID(ids []int, id int) []int {
+	result := ids[:0]
+	for _, i := range ids {
+		if i != id {
+			result = append(result, i)
+		}
+	}
+
+	return result
+}
+
+func containsID(ids []int, id int) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package teststore
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/store"
+	"sort"
+	"time"
+)
+
+type SessionRepository struct {
+	store    *Store
+	sessions map[int]*models.UserSession
+}
+
+func (r *SessionRepository) Create(s *models.UserSession) error {
+	s.ID = len(r.sessions) + 1
+	s.CreatedAt = time.Now()
+	s.LastSeenAt = s.CreatedAt
+	r.sessions[s.ID] = s
+
+	return nil
+}
+
+func (r *SessionRepository) Find(sessionID int) (*models.UserSession, error) {
+	s, ok := r.sessions[sessionID]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return s, nil
+}
+
+func (r *SessionRepository) FindByUser(userID int) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	now := time.Now()
+	for _, s := range r.sessions {
+		if s.UserID == userID && s.IsActive(now) {
+			sessions = append(sessions, *s)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+func (r *SessionRepository) UpdateLastSeen(sessionID int, lastSeenAt time.Time) error {
+	s, ok := r.sessions[sessionID]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	s.LastSeenAt = lastSeenAt
+	return nil
+}
+
+func (r *SessionRepository) Revoke(sessionID int) error {
+	s, ok := r.sessions[sessionID]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	if s.RevokedAt == nil {
+		now := time.Now()
+		s.RevokedAt = &now
+	}
+	return nil
+}
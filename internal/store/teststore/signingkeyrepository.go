@@ -0,0 +1,70 @@
+package teststore
+
+import (
+	"backend/internal/api/v1/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+type SigningKeyRepository struct {
+	sync.RWMutex
+	store *Store
+	keys  map[string]*models.SigningKey
+}
+
+func (r *SigningKeyRepository) Create(k *models.SigningKey) error {
+	r.Lock()
+	defer r.Unlock()
+
+	key := *k
+	r.keys[k.ID] = &key
+
+	return nil
+}
+
+func (r *SigningKeyRepository) FindValid(now time.Time) ([]models.SigningKey, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	keys := make([]models.SigningKey, 0, len(r.keys))
+	for _, k := range r.keys {
+		if k.CanVerify(now) {
+			keys = append(keys, *k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+func (r *SigningKeyRepository) RetireActive(exceptKeyID string, retiredAt time.Time) error {
+	r.Lock()
+	defer r.Unlock()
+
+	for id, k := range r.keys {
+		if id != exceptKeyID && k.IsActive() {
+			t := retiredAt
+			k.RetiredAt = &t
+		}
+	}
+
+	return nil
+}
+
+func (r *SigningKeyRepository) DeleteRetired(before time.Time) (int64, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	var deleted int64
+	for id, k := range r.keys {
+		if k.RetiredAt != nil && !k.RetiredAt.After(before) {
+			delete(r.keys, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
@@ -3,15 +3,27 @@ package teststore
 import (
 	"backend/internal/api/v1/models"
 	"backend/internal/store"
+	"time"
 )
 
 type Store struct {
-	authRepository       *AuthRepository
-	userRepository       *UserRepository
-	taskRepository       *TaskRepository
-	universityRepository *UniversityRepository
-	groupRepository      *GroupRepository
-	subjectRepository    *SubjectRepository
+	authRepository              *AuthRepository
+	sessionRepository           *SessionRepository
+	tokenBlacklistRepository    *TokenBlacklistRepository
+	signingKeyRepository        *SigningKeyRepository
+	verificationTokenRepository *VerificationTokenRepository
+	roleRepository              *RoleRepository
+	loginAttemptRepository      *LoginAttemptRepository
+	personalTokenRepository     *PersonalTokenRepository
+	oidcRepository              *OIDCRepository
+	auditRepository             *AuditRepository
+	twoFactorRepository         *TwoFactorRepository
+	userRepository              *UserRepository
+	taskRepository              *TaskRepository
+	universityRepository        *UniversityRepository
+	facultyRepository           *FacultyRepository
+	groupRepository             *GroupRepository
+	subjectRepository           *SubjectRepository
 }
 
 func New() *Store {
@@ -28,6 +40,111 @@ func (s *Store) Auth() store.AuthRepository {
 	return s.authRepository
 }
 
+func (s *Store) Session() store.SessionRepository {
+	if s.sessionRepository == nil {
+		s.sessionRepository = &SessionRepository{
+			store:    s,
+			sessions: make(map[int]*models.UserSession),
+		}
+	}
+
+	return s.sessionRepository
+}
+
+func (s *Store) TokenBlacklist() store.TokenBlacklistRepository {
+	if s.tokenBlacklistRepository == nil {
+		s.tokenBlacklistRepository = &TokenBlacklistRepository{
+			store:  s,
+			hashes: make(map[string]time.Time),
+		}
+	}
+
+	return s.tokenBlacklistRepository
+}
+
+func (s *Store) SigningKey() store.SigningKeyRepository {
+	if s.signingKeyRepository == nil {
+		s.signingKeyRepository = &SigningKeyRepository{
+			store: s,
+			keys:  make(map[string]*models.SigningKey),
+		}
+	}
+
+	return s.signingKeyRepository
+}
+
+func (s *Store) VerificationToken() store.VerificationTokenRepository {
+	if s.verificationTokenRepository == nil {
+		s.verificationTokenRepository = &VerificationTokenRepository{
+			store:  s,
+			tokens: make(map[int]*models.VerificationToken),
+		}
+	}
+
+	return s.verificationTokenRepository
+}
+
+func (s *Store) Role() store.RoleRepository {
+	if s.roleRepository == nil {
+		s.roleRepository = &RoleRepository{
+			store:        s,
+			permissions:  make(map[string]*models.Permission),
+			roles:        make(map[int]*models.Role),
+			userRoles:    make(map[int][]int),
+			memberRoles:  make(map[[2]int][]int),
+			facultyRoles: make(map[[2]int][]int),
+		}
+	}
+
+	return s.roleRepository
+}
+
+func (s *Store) PersonalToken() store.PersonalTokenRepository {
+	if s.personalTokenRepository == nil {
+		s.personalTokenRepository = &PersonalTokenRepository{
+			store:  s,
+			tokens: make(map[int]*models.PersonalToken),
+		}
+	}
+
+	return s.personalTokenRepository
+}
+
+func (s *Store) OIDC() store.OIDCRepository {
+	if s.oidcRepository == nil {
+		s.oidcRepository = &OIDCRepository{
+			store:      s,
+			identities: make(map[int]*models.ExternalIdentity),
+			states:     make(map[int]*models.OIDCLoginState),
+		}
+	}
+
+	return s.oidcRepository
+}
+
+func (s *Store) LoginAttempt() store.LoginAttemptRepository {
+	if s.loginAttemptRepository == nil {
+		s.loginAttemptRepository = &LoginAttemptRepository{
+			store:    s,
+			attempts: make(map[string]*models.LoginAttempts),
+		}
+	}
+
+	return s.loginAttemptRepository
+}
+
+func (s *Store) TwoFactor() store.TwoFactorRepository {
+	if s.twoFactorRepository == nil {
+		s.twoFactorRepository = &TwoFactorRepository{
+			store:         s,
+			secrets:       make(map[int]*models.UserTOTP),
+			recoveryCodes: make(map[int]map[string]*time.Time),
+		}
+	}
+
+	return s.twoFactorRepository
+}
+
 func (s *Store) User() store.UserRepository {
 	if s.userRepository == nil {
 		s.userRepository = &UserRepository{
@@ -42,7 +159,9 @@ func (s *Store) User() store.UserRepository {
 func (s *Store) Task() store.TaskRepository {
 	if s.taskRepository == nil {
 		s.taskRepository = &TaskRepository{
-			store: s,
+			store:        s,
+			groupsOnTask: make(map[int][]int),
+			usersOnTask:  make(map[int][]int),
 		}
 	}
 	//TODO: implement methods
@@ -52,16 +171,39 @@ func (s *Store) Task() store.TaskRepository {
 func (s *Store) University() store.UniversityRepository {
 	if s.universityRepository == nil {
 		s.universityRepository = &UniversityRepository{
-			store: s,
+			store:        s,
+			universities: make(map[int]*models.University),
 		}
 	}
 	return s.universityRepository
 }
 
+func (s *Store) Faculty() store.FacultyRepository {
+	if s.facultyRepository == nil {
+		s.facultyRepository = &FacultyRepository{
+			store:       s,
+			faculties:   make(map[int]*models.Faculty),
+			departments: make(map[int]*models.Department),
+		}
+	}
+	return s.facultyRepository
+}
+
+func (s *Store) Audit() store.AuditRepository {
+	if s.auditRepository == nil {
+		s.auditRepository = &AuditRepository{
+			store: s,
+		}
+	}
+
+	return s.auditRepository
+}
+
 func (s *Store) Group() store.GroupRepository {
 	if s.groupRepository == nil {
 		s.groupRepository = &GroupRepository{
-			store: s,
+			store:  s,
+			groups: make(map[int]*models.Group),
 		}
 	}
 
@@ -71,9 +213,9 @@ func (s *Store) Group() store.GroupRepository {
 func (s *Store) Subject() store.SubjectRepository {
 	if s.subjectRepository == nil {
 		s.subjectRepository = &SubjectRepository{
-			store: s,
+			store:    s,
+			subjects: make(map[int]*models.Subject),
 		}
 	}
-	//TODO: implement methods
 	return s.subjectRepository
 }
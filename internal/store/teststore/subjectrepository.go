@@ -2,24 +2,74 @@ package teststore
 
 import (
 	"backend/internal/api/v1/models"
+	"backend/internal/store"
 )
 
 type SubjectRepository struct {
-	store *Store
+	store    *Store
+	subjects map[int]*models.Subject
+	lastID   int
 }
 
 func (r *SubjectRepository) Create(subject *models.Subject) error {
-	panic("implement me")
+	r.lastID++
+	subject.ID = r.lastID
+
+	s := *subject
+	r.subjects[s.ID] = &s
+
+	return nil
 }
 
 func (r *SubjectRepository) GetAll(limit, offset int) ([]models.Subject, error) {
 	panic("implement me")
 }
 
-func (r *SubjectRepository) Find(i int) (*models.Subject, error) {
-	panic("implement me")
+func (r *SubjectRepository) Find(subjectID int) (*models.Subject, error) {
+	s, ok := r.subjects[subjectID]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	subject := *s
+	return &subject, nil
 }
 
-func (r *SubjectRepository) Delete(i int) (*models.Subject, error) {
-	panic("implement me")
+func (r *SubjectRepository) Delete(subjectID int) (*models.Subject, error) {
+	subject, err := r.Find(subjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(r.subjects, subjectID)
+	return subject, nil
+}
+
+func (r *SubjectRepository) SetDepartment(subjectID int, departmentID *int) error {
+	s, ok := r.subjects[subjectID]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	s.DepartmentID = departmentID
+	return nil
+}
+
+func (r *SubjectRepository) GetSubjectsOfOrgNode(node models.OrgNode) ([]models.Subject, error) {
+	faculties := r.store.Faculty().(*FacultyRepository)
+
+	var subjects []models.Subject
+	for id := 1; id <= r.lastID; id++ {
+		s, ok := r.subjects[id]
+		if !ok {
+			continue
+		}
+		if in, err := faculties.isInOrgNode(s.DepartmentID, node); err != nil {
+			return nil, err
+		} else if in {
+			subjects = append(subjects, *s)
+		}
+	}
+
+	return subjects, nil
 }
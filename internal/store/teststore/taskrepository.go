@@ -2,10 +2,13 @@ package teststore
 
 import (
 	"backend/internal/api/v1/models"
+	"sort"
 )
 
 type TaskRepository struct {
-	store *Store
+	store        *Store
+	groupsOnTask map[int][]int //Map[task.id][]group.id
+	usersOnTask  map[int][]int //Map[task.id][]user.id
 }
 
 func (r *TaskRepository) CreateGroupTask(task *models.Task) error {
@@ -37,11 +40,13 @@ func (r *TaskRepository) AssignTaskSequence(taskID, nextTaskID int) error {
 }
 
 func (r *TaskRepository) AssignTaskToGroup(taskID, groupID int) error {
-	panic("implement me")
+	r.groupsOnTask[taskID] = append(r.groupsOnTask[taskID], groupID)
+	return nil
 }
 
 func (r *TaskRepository) AssignTaskToUser(taskID, userID int) error {
-	panic("implement me")
+	r.usersOnTask[taskID] = append(r.usersOnTask[taskID], userID)
+	return nil
 }
 
 func (r *TaskRepository) FindPrevTasks(taskID int) ([]int, error) {
@@ -73,19 +78,50 @@ func (r *TaskRepository) FindUserLocalTasks(userID int) ([]models.Task, error) {
 }
 
 func (r *TaskRepository) FindGroupsOnTask(taskID int) ([]int, error) {
-	panic("implement me")
+	return sortedIDs(r.groupsOnTask[taskID]), nil
 }
 
 func (r *TaskRepository) FindUsersOnTask(taskID int) ([]int, error) {
-	panic("implement me")
+	return sortedIDs(r.usersOnTask[taskID]), nil
 }
 
 func (r *TaskRepository) RemoveGroupFromTask(taskID, groupID int) error {
-	panic("implement me")
+	r.groupsOnTask[taskID] = removeID(r.groupsOnTask[taskID], groupID)
+	return nil
 }
 
 func (r *TaskRepository) RemoveUserFromTask(taskID, userID int) error {
-	panic("implement me")
+	r.usersOnTask[taskID] = removeID(r.usersOnTask[taskID], userID)
+	return nil
+}
+
+// removeUserFromGroupTasks removes the assignments of the user to the tasks of the group not shared with its other groups
+func (r *TaskRepository) removeUserFromGroupTasks(userID, groupID int) {
+	for taskID, groupIDs := range r.groupsOnTask {
+		if !containsID(groupIDs, groupID) {
+			continue
+		}
+
+		shared := false
+		for _, id := range groupIDs {
+			if id == groupID {
+				continue
+			}
+			if isMember, _ := r.store.Group().IsUserGroupMember(userID, id); isMember {
+				shared = true
+				break
+			}
+		}
+		if !shared {
+			r.usersOnTask[taskID] = removeID(r.usersOnTask[taskID], userID)
+		}
+	}
+}
+
+func sortedIDs(ids []int) []int {
+	sorted := append([]int(nil), ids...)
+	sort.Ints(sorted)
+	return sorted
 }
 
 func (r *TaskRepository) DeleteTask(id int) error {
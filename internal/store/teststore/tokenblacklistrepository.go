@@ -0,0 +1,46 @@
+package teststore
+
+import (
+	"sync"
+	"time"
+)
+
+type TokenBlacklistRepository struct {
+	sync.RWMutex
+	store  *Store
+	hashes map[string]time.Time
+}
+
+func (r *TokenBlacklistRepository) Add(tokenHash string, expiresAt time.Time) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if exp, ok := r.hashes[tokenHash]; !ok || exp.Before(expiresAt) {
+		r.hashes[tokenHash] = expiresAt
+	}
+
+	return nil
+}
+
+func (r *TokenBlacklistRepository) Exists(tokenHash string, now time.Time) (bool, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	exp, ok := r.hashes[tokenHash]
+	return ok && exp.After(now), nil
+}
+
+func (r *TokenBlacklistRepository) DeleteExpired(now time.Time) (int64, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	var deleted int64
+	for hash, exp := range r.hashes {
+		if !exp.After(now) {
+			delete(r.hashes, hash)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
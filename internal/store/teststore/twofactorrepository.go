@@ -0,0 +1,80 @@
+package teststore
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/store"
+	"time"
+)
+
+type TwoFactorRepository struct {
+	store         *Store
+	secrets       map[int]*models.UserTOTP
+	recoveryCodes map[int]map[string]*time.Time
+}
+
+func (r *TwoFactorRepository) SaveTOTP(t *models.UserTOTP) error {
+	totp := *t
+	r.secrets[t.UserID] = &totp
+
+	return nil
+}
+
+func (r *TwoFactorRepository) FindTOTP(userID int) (*models.UserTOTP, error) {
+	t, ok := r.secrets[userID]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	totp := *t
+	return &totp, nil
+}
+
+func (r *TwoFactorRepository) ConfirmTOTP(userID int, confirmedAt time.Time) error {
+	t, ok := r.secrets[userID]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	t.ConfirmedAt = &confirmedAt
+	return nil
+}
+
+func (r *TwoFactorRepository) UseTOTPStep(userID int, step int64) (bool, error) {
+	t, ok := r.secrets[userID]
+	if !ok || t.LastUsedStep >= step {
+		return false, nil
+	}
+
+	t.LastUsedStep = step
+	return true, nil
+}
+
+func (r *TwoFactorRepository) DeleteTOTP(userID int) error {
+	delete(r.secrets, userID)
+	return nil
+}
+
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	codes := make(map[string]*time.Time, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes[hash] = nil
+	}
+	r.recoveryCodes[userID] = codes
+
+	return nil
+}
+
+func (r *TwoFactorRepository) UseRecoveryCode(userID int, codeHash string, usedAt time.Time) (bool, error) {
+	usedAtOfCode, ok := r.recoveryCodes[userID][codeHash]
+	if !ok || usedAtOfCode != nil {
+		return false, nil
+	}
+
+	r.recoveryCodes[userID][codeHash] = &usedAt
+	return true, nil
+}
+
+func (r *TwoFactorRepository) DeleteRecoveryCodes(userID int) error {
+	delete(r.recoveryCodes, userID)
+	return nil
+}
@@ -2,16 +2,42 @@ package teststore
 
 import (
 	"backend/internal/api/v1/models"
+	"backend/internal/store"
+	"time"
 )
 
 type UniversityRepository struct {
-	store *Store
+	store        *Store
+	universities map[int]*models.University
 }
 
 func (r *UniversityRepository) Create(university *models.University) error {
-	panic("implement me")
+	university.ID = len(r.universities) + 1
+	university.AddedAt = time.Now()
+
+	u := *university
+	r.universities[u.ID] = &u
+
+	return nil
 }
 
 func (r *UniversityRepository) Find(universityID int) (*models.University, error) {
-	panic("implement me")
+	u, ok := r.universities[universityID]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	university := *u
+	return &university, nil
+}
+
+func (r *UniversityRepository) GetAll() ([]models.University, error) {
+	universities := make([]models.University, 0, len(r.universities))
+	for id := 1; id <= len(r.universities); id++ {
+		if u, ok := r.universities[id]; ok {
+			universities = append(universities, *u)
+		}
+	}
+
+	return universities, nil
 }
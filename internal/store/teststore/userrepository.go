@@ -3,6 +3,7 @@ package teststore
 import (
 	"backend/internal/api/v1/models"
 	"backend/internal/store"
+	"time"
 )
 
 type UserRepository struct {
@@ -54,6 +55,26 @@ func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
 	return nil, store.ErrRecordNotFound
 }
 
+func (r *UserRepository) ConfirmEmail(userID int, confirmedAt time.Time) error {
+	u, ok := r.users[userID]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	u.EmailConfirmedAt = &confirmedAt
+	return nil
+}
+
+func (r *UserRepository) UpdatePassword(userID int, encryptedPassword string) error {
+	u, ok := r.users[userID]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	u.EncryptedPassword = encryptedPassword
+	return nil
+}
+
 func (r *UserRepository) CreateTester() (*models.User, error) {
 	u := &models.User{
 		Login:    "tester",
@@ -84,10 +105,12 @@ func (UserRepository) GetAll(limit, offset int) ([]models.User, error) {
 	panic("implement me")
 }
 
-func (UserRepository) IsUserExist(userID int) (bool, error) {
-	panic("implement me")
+func (r *UserRepository) IsUserExist(userID int) (bool, error) {
+	_, ok := r.users[userID]
+	return ok, nil
 }
 
-func (UserRepository) GetUserRoles(userID int) ([]models.Role, error) {
-	panic("implement me")
+func (r *UserRepository) GetUserRoles(userID int) ([]models.Role, error) {
+	roles := r.store.Role().(*RoleRepository)
+	return roles.getRoles(roles.userRoles[userID]), nil
 }
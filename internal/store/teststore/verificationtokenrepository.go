@@ -0,0 +1,62 @@
+package teststore
+
+import (
+	"backend/internal/api/v1/models"
+	"backend/internal/store"
+	"time"
+)
+
+type VerificationTokenRepository struct {
+	store  *Store
+	tokens map[int]*models.VerificationToken
+}
+
+func (r *VerificationTokenRepository) Create(t *models.VerificationToken) error {
+	t.ID = len(r.tokens) + 1
+	token := *t
+	r.tokens[t.ID] = &token
+
+	return nil
+}
+
+func (r *VerificationTokenRepository) FindByHash(purpose, tokenHash string) (*models.VerificationToken, error) {
+	for _, t := range r.tokens {
+		if t.Purpose == purpose && t.TokenHash == tokenHash {
+			token := *t
+			return &token, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
+}
+
+func (r *VerificationTokenRepository) MarkUsed(tokenID int, usedAt time.Time) (bool, error) {
+	t, ok := r.tokens[tokenID]
+	if !ok || t.UsedAt != nil {
+		return false, nil
+	}
+
+	t.UsedAt = &usedAt
+	return true, nil
+}
+
+func (r *VerificationTokenRepository) InvalidateUserTokens(userID int, purpose string, usedAt time.Time) error {
+	for _, t := range r.tokens {
+		if t.UserID == userID && t.Purpose == purpose && t.UsedAt == nil {
+			usedAt := usedAt
+			t.UsedAt = &usedAt
+		}
+	}
+
+	return nil
+}
+
+func (r *VerificationTokenRepository) AddAttempt(tokenID int) (int, error) {
+	t, ok := r.tokens[tokenID]
+	if !ok {
+		return 0, store.ErrRecordNotFound
+	}
+
+	t.Attempts++
+	return t.Attempts, nil
+}
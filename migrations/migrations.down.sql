@@ -19,6 +19,24 @@ DROP TABLE IF EXISTS userroles CASCADE;
 DROP TABLE IF EXISTS role CASCADE;
 
 DROP TABLE IF EXISTS usertoken CASCADE;
+DROP TABLE IF EXISTS usersession CASCADE;
+DROP TABLE IF EXISTS accesstokenblacklist CASCADE;
+DROP TABLE IF EXISTS signingkey CASCADE;
+DROP TABLE IF EXISTS verificationtoken CASCADE;
+DROP TABLE IF EXISTS usertotp CASCADE;
+DROP TABLE IF EXISTS recoverycode CASCADE;
+DROP TABLE IF EXISTS loginattempt CASCADE;
+DROP TABLE IF EXISTS personaltoken CASCADE;
+DROP TABLE IF EXISTS externalidentity CASCADE;
+DROP TABLE IF EXISTS oidcloginstate CASCADE;
+DROP TABLE IF EXISTS auditlog CASCADE;
+DROP FUNCTION IF EXISTS auditlog_no_update;
+DROP TABLE IF EXISTS groupjoinrequest CASCADE;
+DROP TABLE IF EXISTS grouprollover CASCADE;
+DROP TABLE IF EXISTS groupnamehistory CASCADE;
+DROP TABLE IF EXISTS facultyroles CASCADE;
+DROP TABLE IF EXISTS department CASCADE;
+DROP TABLE IF EXISTS faculty CASCADE;
 
 DROP TABLE IF EXISTS usertask CASCADE;
 
@@ -118,20 +118,17 @@ create table UserTask
     notes            varchar
 );
 
---Not used yet
 create table Permission
 (
     id   serial PRIMARY KEY,
     name varchar
 );
---Not used yet
 create table Role
 (
     id          serial PRIMARY KEY,
     name        varchar,
     description varchar
 );
---Not used yet
 create table RolePermissions
 (
     permission_id serial,
@@ -140,7 +137,6 @@ create table RolePermissions
     PRIMARY KEY (permission_id, role_id)
 );
 
---Not used yet
 create table GroupMemberRoles
 (
     group_member_id int REFERENCES GroupMember (id),
@@ -213,3 +209,327 @@ alter type status add value  'one';
 alter type status add value  'two';
 alter type status add value  'three';
 drop type status;
+
+-- 18.10.2026   --
+
+create table UserSession
+(
+    id           serial PRIMARY KEY,
+    user_id      int REFERENCES "user" (id),
+    device_name  varchar,
+    user_agent   varchar,
+    ip           varchar,
+    created_at   timestamptz not null default now(),
+    last_seen_at timestamptz not null default now(),
+    revoked_at   timestamptz
+);
+
+alter table UserToken
+    add column session_id int REFERENCES UserSession (id);
+create index usertoken_session_id_idx on UserToken (session_id);
+
+alter table UserToken
+    add column family_id uuid,
+    add column parent_id int REFERENCES UserToken (id) ON DELETE SET NULL,
+    add column used_at   timestamptz;
+create index usertoken_family_id_idx on UserToken (family_id);
+-- The tokens issued before the sessions can't be refreshed, the users sign in again
+update UserToken set expiration_timestamp = now() where session_id is null and expiration_timestamp > now();
+create unique index usertoken_refresh_token_idx on UserToken (refresh_token);
+
+create table AccessTokenBlacklist
+(
+    token_hash varchar PRIMARY KEY,
+    expires_at timestamptz not null
+);
+create index accesstokenblacklist_expires_at_idx on AccessTokenBlacklist (expires_at);
+
+create table SigningKey
+(
+    kid         varchar PRIMARY KEY,
+    algorithm   varchar     not null,
+    private_key text        not null,
+    created_at  timestamptz not null default now(),
+    retired_at  timestamptz
+);
+
+alter table "user"
+    add column email_confirmed_at timestamptz;
+-- Accounts registered before the email confirmation are considered confirmed
+update "user" set email_confirmed_at = coalesce(created_at, now());
+
+create table VerificationToken
+(
+    id         serial PRIMARY KEY,
+    user_id    int REFERENCES "user" (id) ON DELETE CASCADE,
+    purpose    varchar     not null,
+    token_hash varchar     not null,
+    email      varchar     not null,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+    used_at    timestamptz
+);
+create unique index verificationtoken_purpose_token_hash_idx on VerificationToken (purpose, token_hash);
+create index verificationtoken_user_id_idx on VerificationToken (user_id);
+
+alter table VerificationToken
+    add column attempts int not null default 0;
+
+create table UserTOTP
+(
+    user_id        int PRIMARY KEY REFERENCES "user" (id) ON DELETE CASCADE,
+    secret         varchar     not null,
+    created_at     timestamptz not null default now(),
+    confirmed_at   timestamptz,
+    last_used_step bigint      not null default 0
+);
+
+create table RecoveryCode
+(
+    id        serial PRIMARY KEY,
+    user_id   int REFERENCES "user" (id) ON DELETE CASCADE,
+    code_hash varchar not null,
+    used_at   timestamptz
+);
+create index recoverycode_user_id_idx on RecoveryCode (user_id);
+
+create table LoginAttempt
+(
+    key             varchar PRIMARY KEY,
+    failures        int         not null default 0,
+    last_failure_at timestamptz not null,
+    locked_until    timestamptz
+);
+
+-- Roles and permissions are seeded from the service data file (configs/service_roles.json) on start
+alter table Role
+    add column scope varchar not null default 'group';
+create unique index role_name_uindex on Role (name);
+
+alter table Permission
+    add column type varchar not null default 'group';
+create unique index permission_name_uindex on Permission (name);
+
+alter table RolePermissions
+    add foreign key (permission_id) references Permission (id) on delete cascade;
+
+-- Groups created before the roles get the owner: the member who wasn't invited (the creator), else the earliest one.
+-- The owner role is created here, the seeding on start updates it and grants its permissions
+insert into Role (name, description, scope)
+values ('owner', 'Owner of the group. Manages the group, its members and tasks', 'group')
+on conflict (name) do nothing;
+insert into GroupMemberRoles (group_member_id, role_id)
+select distinct on (gm.group_id) gm.id, owner.id
+from GroupMember gm,
+     Role owner
+where owner.name = 'owner'
+  and not exists(select
+                 from GroupMemberRoles gmr
+                          join GroupMember m on m.id = gmr.group_member_id
+                 where m.group_id = gm.group_id
+                   and gmr.role_id = owner.id)
+order by gm.group_id, gm.invited_by_id is not null, gm.id;
+
+-- App secrets are stored as SHA-256 hashes. Apps registered before scopes get all of them
+alter table RegisteredApp
+    add column app_secret_hash varchar,
+    add column scope           varchar not null default '';
+update RegisteredApp set app_secret_hash = encode(sha256(app_secret::bytea), 'hex'), scope = 'auth api';
+alter table RegisteredApp
+    drop column app_secret;
+
+alter table AppToken
+    add column scope varchar not null default '';
+update AppToken set scope = 'auth api';
+
+create table PersonalToken
+(
+    id           serial PRIMARY KEY,
+    user_id      int REFERENCES "user" (id) ON DELETE CASCADE,
+    name         varchar     not null,
+    token_hash   varchar     not null,
+    scope        varchar     not null,
+    created_at   timestamptz not null default now(),
+    expires_at   timestamptz not null,
+    last_used_at timestamptz,
+    revoked_at   timestamptz
+);
+create unique index personaltoken_token_hash_idx on PersonalToken (token_hash);
+create index personaltoken_user_id_idx on PersonalToken (user_id);
+
+-- Users signed in with the OpenID Connect provider
+create table ExternalIdentity
+(
+    id            serial PRIMARY KEY,
+    user_id       int REFERENCES "user" (id) ON DELETE CASCADE,
+    issuer        varchar     not null,
+    subject       varchar     not null,
+    email         varchar     not null default '',
+    created_at    timestamptz not null default now(),
+    last_login_at timestamptz
+);
+create unique index externalidentity_issuer_subject_uindex on ExternalIdentity (issuer, subject);
+create index externalidentity_user_id_idx on ExternalIdentity (user_id);
+
+create table OIDCLoginState
+(
+    id            serial PRIMARY KEY,
+    state_hash    varchar     not null,
+    code_verifier varchar     not null,
+    nonce         varchar     not null,
+    created_at    timestamptz not null default now(),
+    expires_at    timestamptz not null,
+    used_at       timestamptz
+);
+create unique index oidcloginstate_state_hash_uindex on OIDCLoginState (state_hash);
+
+-- Sessions opened by the administrator on behalf of the user
+alter table UserSession
+    add column impersonator_id int REFERENCES "user" (id);
+-- The impersonation sessions end together with their access token
+alter table UserSession
+    add column expires_at timestamptz;
+
+-- Append-only audit log
+create table AuditLog
+(
+    id            bigserial PRIMARY KEY,
+    actor_user_id int REFERENCES "user" (id) ON DELETE SET NULL,
+    action        varchar     not null,
+    target_type   varchar     not null default '',
+    target_id     varchar     not null default '',
+    ip            varchar     not null default '',
+    details       jsonb,
+    created_at    timestamptz not null default now()
+);
+create index auditlog_created_at_idx on AuditLog (created_at);
+create index auditlog_actor_user_id_idx on AuditLog (actor_user_id);
+
+alter table AuditLog
+    add column actor_app_id    uuid,
+    add column impersonator_id int REFERENCES "user" (id) ON DELETE SET NULL,
+    add column request_id      varchar not null default '',
+    add column before          jsonb,
+    add column after           jsonb;
+create index auditlog_action_idx on AuditLog (action);
+create index auditlog_target_idx on AuditLog (target_type, target_id);
+create index auditlog_request_id_idx on AuditLog (request_id);
+-- The entries are never changed, only the retention deletes them.
+-- The actors are only cleared by ON DELETE SET NULL when the user is deleted
+create function auditlog_no_update() returns trigger as
+$$
+begin
+    if (to_jsonb(new) - 'actor_user_id' - 'impersonator_id') = (to_jsonb(old) - 'actor_user_id' - 'impersonator_id')
+        and (new.actor_user_id is null or new.actor_user_id = old.actor_user_id)
+        and (new.impersonator_id is null or new.impersonator_id = old.impersonator_id) then
+        return new;
+    end if;
+    raise exception 'auditlog is append-only';
+end;
+$$ language plpgsql;
+create trigger auditlog_no_update
+    before update on AuditLog
+    for each row execute procedure auditlog_no_update();
+
+-- User access tokens used from another device are accepted by the apps registered before the fingerprints
+alter table RegisteredApp
+    add column fingerprint_policy varchar(16) not null default 'off';
+
+-- Named invites with usage limits. The invites created before are unlimited
+alter table GroupInviteHashes
+    add column name         varchar     not null default '',
+    add column max_uses     int         not null default 0,
+    add column uses         int         not null default 0,
+    add column default_role varchar     not null default '',
+    add column created_at   timestamptz not null default now(),
+    add column revoked_at   timestamptz;
+create unique index groupinvitehashes_hash_uindex on GroupInviteHashes (hash);
+create index groupinvitehashes_group_id_idx on GroupInviteHashes (group_id);
+
+-- Groups are joined with invites (open), join requests (approval) or aren't joined at all (closed)
+alter table "group"
+    add column join_mode varchar(16) not null default 'open';
+
+create table GroupJoinRequest
+(
+    id            serial PRIMARY KEY,
+    group_id      int         not null REFERENCES "group" (id),
+    user_id       int         not null REFERENCES "user" (id),
+    message       varchar     not null default '',
+    status        varchar(16) not null default 'pending',
+    created_at    timestamptz not null default now(),
+    decided_by_id int REFERENCES "user" (id),
+    decided_at    timestamptz
+);
+create index groupjoinrequest_group_id_status_idx on GroupJoinRequest (group_id, status);
+create index groupjoinrequest_user_id_idx on GroupJoinRequest (user_id);
+-- Only one pending request of the user to the group
+create unique index groupjoinrequest_pending_uindex on GroupJoinRequest (group_id, user_id) where status = 'pending';
+
+-- Groups move to the next course once per academic year, the graduated groups are archived
+alter table "group"
+    add column archived_at timestamptz;
+
+create table GroupRollover
+(
+    id            serial PRIMARY KEY,
+    university_id int         not null REFERENCES university (id),
+    academic_year int         not null,
+    rolled_by_id  int REFERENCES "user" (id),
+    advanced      int         not null default 0,
+    archived      int         not null default 0,
+    created_at    timestamptz not null default now()
+);
+create unique index grouprollover_university_id_academic_year_uindex on GroupRollover (university_id, academic_year);
+
+create table GroupNameHistory
+(
+    id            serial PRIMARY KEY,
+    group_id      int         not null REFERENCES "group" (id) ON DELETE CASCADE,
+    full_name     varchar     not null,
+    custom_name   varchar     not null default '',
+    course_number int         not null,
+    academic_year int         not null,
+    changed_at    timestamptz not null default now()
+);
+create index groupnamehistory_group_id_idx on GroupNameHistory (group_id);
+
+-- Deleted groups are kept for the retention and restored by an administrator, then they are purged
+alter table "group"
+    add column deleted_at timestamptz;
+create index group_deleted_at_idx on "group" (deleted_at) where deleted_at is not null;
+
+-- The org tree of the university: faculties and their departments. The groups and the subjects are attached to the departments
+create table Faculty
+(
+    id            serial PRIMARY KEY,
+    university_id int         not null REFERENCES university (id),
+    name          varchar     not null,
+    created_at    timestamptz not null default now()
+);
+create index faculty_university_id_idx on Faculty (university_id);
+
+create table Department
+(
+    id         serial PRIMARY KEY,
+    faculty_id int         not null REFERENCES Faculty (id) ON DELETE CASCADE,
+    name       varchar     not null,
+    created_at timestamptz not null default now()
+);
+create index department_faculty_id_idx on Department (faculty_id);
+
+alter table "group"
+    add column department_id int REFERENCES Department (id) ON DELETE SET NULL;
+create index group_department_id_idx on "group" (department_id);
+alter table subject
+    add column department_id int REFERENCES Department (id) ON DELETE SET NULL;
+create index subject_department_id_idx on subject (department_id);
+
+-- Faculty roles are delegated to the users per faculty
+create table FacultyRoles
+(
+    user_id    int REFERENCES "user" (id) ON DELETE CASCADE,
+    faculty_id int REFERENCES Faculty (id) ON DELETE CASCADE,
+    role_id    int REFERENCES Role (id),
+    PRIMARY KEY (user_id, faculty_id, role_id)
+);
@@ -14,6 +14,8 @@ type (
 	DataRole struct {
 		Name        string `json:"name"`
 		Description string `json:"description"`
+		// Scope is "global" for roles of the service and "group" for roles of group members
+		Scope string `json:"scope"`
 	}
 
 	DataPermissionsItem struct {
@@ -37,8 +37,6 @@ type TokenManager struct {
 	rTokenLength int
 	rTokenFormat []rune
 	signingKey   string
-
-	aTokenBlacklistManager AccessTokenBlacklistManager
 }
 
 type TokenManagerConfig struct {
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+)
+
+var ErrEdDSAVerification = errors.New("crypto/ed25519: verification error")
+
+// SigningMethodEdDSA implements the EdDSA (Ed25519) signing method from RFC 8037.
+//	jwt-go v3 doesn't support it out of the box.
+//	Expects ed25519.PrivateKey for signing and ed25519.PublicKey for validation.
+type SigningMethodEdDSA struct{}
+
+var SigningMethodEd25519 *SigningMethodEdDSA
+
+func init() {
+	SigningMethodEd25519 = &SigningMethodEdDSA{}
+	jwt.RegisterSigningMethod(SigningMethodEd25519.Alg(), func() jwt.SigningMethod {
+		return SigningMethodEd25519
+	})
+}
+
+func (m *SigningMethodEdDSA) Alg() string {
+	return "EdDSA"
+}
+
+func (m *SigningMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok || len(publicKey) != ed25519.PublicKeySize {
+		return jwt.ErrInvalidKeyType
+	}
+
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return ErrEdDSAVerification
+	}
+
+	return nil
+}
+
+func (m *SigningMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok || len(privateKey) != ed25519.PrivateKeySize {
+		return "", jwt.ErrInvalidKeyType
+	}
+
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
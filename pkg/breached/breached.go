@@ -0,0 +1,130 @@
+// Package breached checks passwords against the local list of known-breached passwords.
+// The list is the file of SHA-1 hashes sorted by the hash, one "HASH[:COUNT]" per line,
+// as the Pwned Passwords "ordered by hash" download. The file is indexed by the hash prefix on open,
+// and only the lines of the prefix are read on the check, so neither the password nor the list leaves the server.
+package breached
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PrefixLength is the length of the hash prefix the list is indexed by
+const PrefixLength = 5
+
+const prefixCount = 1 << (4 * PrefixLength)
+
+var ErrNotSorted = errors.New("breached: the hashes aren't sorted")
+
+// List of the breached passwords. It's safe for concurrent use
+type List struct {
+	file *os.File
+	// offsets[p] is the offset of the first line with the prefix p, the lines of p end at offsets[p+1]
+	offsets []int64
+}
+
+// Open indexes the list file. The file is kept open until Close
+func Open(path string) (*List, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets, err := index(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &List{file: file, offsets: offsets}, nil
+}
+
+func index(r io.Reader) ([]int64, error) {
+	offsets := make([]int64, prefixCount+1)
+	next := 0 // prefixes before next have the offset set
+	var offset int64
+
+	reader := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		if hash := lineHash(line); hash != "" {
+			prefix, parseErr := parsePrefix(hash)
+			if parseErr != nil {
+				return nil, fmt.Errorf("breached: line %d: invalid hash", n)
+			}
+			if prefix < next-1 {
+				return nil, ErrNotSorted
+			}
+			for ; next <= prefix; next++ {
+				offsets[next] = offset
+			}
+		}
+		offset += int64(len(line))
+
+		if err == io.EOF {
+			break
+		}
+	}
+	for ; next <= prefixCount; next++ {
+		offsets[next] = offset
+	}
+
+	return offsets, nil
+}
+
+// Contains checks if the password is in the list
+func (l *List) Contains(password string) (bool, error) {
+	hash := Hash(password)
+	prefix, err := parsePrefix(hash)
+	if err != nil {
+		return false, err
+	}
+
+	start, end := l.offsets[prefix], l.offsets[prefix+1]
+	scanner := bufio.NewScanner(io.NewSectionReader(l.file, start, end-start))
+	for scanner.Scan() {
+		if strings.EqualFold(lineHash(scanner.Text()), hash) {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+// Hash is the hash of the password in the list
+func Hash(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func (l *List) Close() error {
+	return l.file.Close()
+}
+
+// lineHash returns the hash of the line without the count
+func lineHash(line string) string {
+	if i := strings.IndexByte(line, ':'); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimSpace(line)
+}
+
+func parsePrefix(hash string) (int, error) {
+	if len(hash) < PrefixLength {
+		return 0, strconv.ErrSyntax
+	}
+
+	prefix, err := strconv.ParseUint(hash[:PrefixLength], 16, 32)
+	return int(prefix), err
+}
@@ -0,0 +1,67 @@
+package breached_test
+
+import (
+	"backend/pkg/breached"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeList(t *testing.T, lines ...string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestList_Contains(t *testing.T) {
+	hashes := []string{
+		breached.Hash("123456") + ":37359195",
+		strings.ToLower(breached.Hash("qwerty")) + ":10556095",
+		breached.Hash("password"),
+		"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF:1",
+		"00000000000000000000000000000000000000AB:2\r",
+	}
+	sort.Slice(hashes, func(i, j int) bool { return strings.ToUpper(hashes[i]) < strings.ToUpper(hashes[j]) })
+
+	list, err := breached.Open(writeList(t, hashes...))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer list.Close()
+
+	for _, password := range []string{"123456", "qwerty", "password"} {
+		contains, err := list.Contains(password)
+		assert.NoError(t, err)
+		assert.True(t, contains, password)
+	}
+
+	contains, err := list.Contains("correct horse battery staple")
+	assert.NoError(t, err)
+	assert.False(t, contains)
+}
+
+func TestOpen(t *testing.T) {
+	_, err := breached.Open(writeList(t, "FFFFF00000000000000000000000000000000000:1", "00000000000000000000000000000000000000AB:2"))
+	assert.Equal(t, breached.ErrNotSorted, err)
+
+	_, err = breached.Open(writeList(t, "not a hash"))
+	assert.Error(t, err)
+
+	_, err = breached.Open(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+
+	list, err := breached.Open(writeList(t))
+	if assert.NoError(t, err) {
+		contains, err := list.Contains("password")
+		assert.NoError(t, err)
+		assert.False(t, contains)
+		assert.NoError(t, list.Close())
+	}
+}
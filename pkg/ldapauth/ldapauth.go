@@ -0,0 +1,176 @@
+// Package ldapauth authenticates users with the search and bind against the LDAP directory
+// and maps the attributes of the directory entry.
+package ldapauth
+
+import (
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/go-ldap/ldap/v3"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// UsernamePlaceholder in the user filter is replaced with the escaped username
+const UsernamePlaceholder = "{username}"
+
+var ErrInvalidCredentials = errors.New("ldap: invalid credentials")
+
+// Config of the directory. BindDN and BindPassword are of the service account that searches users,
+//	the search is anonymous if BindDN is empty. IDAttribute is the stable id of the entry, the DN is used if it's empty.
+type Config struct {
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	UserFilter   string
+
+	IDAttribute       string
+	LoginAttribute    string
+	FullNameAttribute string
+	EmailAttribute    string
+	GroupAttribute    string
+}
+
+// Entry is the authenticated user of the directory
+type Entry struct {
+	DN       string
+	ID       string
+	Login    string
+	FullName string
+	Email    string
+	Groups   []string
+}
+
+type Client struct {
+	config Config
+}
+
+func New(config Config) *Client {
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	return &Client{
+		config: config,
+	}
+}
+
+// Authenticate finds the user entry by the username and binds with its DN and the password.
+//	Returns ErrInvalidCredentials, if the user isn't found, isn't unique or the password is wrong.
+func (c *Client) Authenticate(username, password string) (*Entry, error) {
+	// The bind with the empty password is the unauthenticated bind, that succeeds on many servers
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if c.config.BindDN != "" {
+		if err := conn.Bind(c.config.BindDN, c.config.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap: service account bind: %w", err)
+		}
+	}
+
+	filter := strings.ReplaceAll(c.config.UserFilter, UsernamePlaceholder, ldap.EscapeFilter(username))
+	res, err := conn.Search(ldap.NewSearchRequest(
+		c.config.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2,
+		int(c.config.Timeout.Seconds()),
+		false,
+		filter,
+		c.attributes(),
+		nil,
+	))
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, ErrInvalidCredentials
+	} else if err != nil {
+		return nil, fmt.Errorf("ldap: search: %w", err)
+	}
+	if len(res.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	entry := res.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		return nil, ErrInvalidCredentials
+	} else if err != nil {
+		return nil, fmt.Errorf("ldap: user bind: %w", err)
+	}
+
+	return c.mapEntry(entry), nil
+}
+
+func (c *Client) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.config.InsecureSkipVerify}
+
+	conn, err := ldap.DialURL(c.config.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: c.config.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(c.config.Timeout)
+
+	if c.config.StartTLS {
+		if u, err := url.Parse(c.config.URL); err == nil {
+			tlsConfig.ServerName = u.Hostname()
+		}
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *Client) attributes() []string {
+	attributes := make([]string, 0, 5)
+	for _, a := range []string{c.config.IDAttribute, c.config.LoginAttribute, c.config.FullNameAttribute,
+		c.config.EmailAttribute, c.config.GroupAttribute} {
+		if a != "" {
+			attributes = append(attributes, a)
+		}
+	}
+	return attributes
+}
+
+func (c *Client) mapEntry(entry *ldap.Entry) *Entry {
+	e := &Entry{
+		DN:       entry.DN,
+		ID:       entry.DN,
+		Login:    entry.GetAttributeValue(c.config.LoginAttribute),
+		FullName: entry.GetAttributeValue(c.config.FullNameAttribute),
+		Email:    entry.GetAttributeValue(c.config.EmailAttribute),
+	}
+	if c.config.IDAttribute != "" {
+		// Binary ids, such as objectGUID of Active Directory, are hex encoded
+		if id := entry.GetRawAttributeValue(c.config.IDAttribute); len(id) > 0 {
+			if utf8.Valid(id) {
+				e.ID = string(id)
+			} else {
+				e.ID = hex.EncodeToString(id)
+			}
+		}
+	}
+	if c.config.GroupAttribute != "" {
+		e.Groups = entry.GetAttributeValues(c.config.GroupAttribute)
+	}
+
+	return e
+}
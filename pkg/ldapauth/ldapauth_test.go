@@ -0,0 +1,92 @@
+package ldapauth_test
+
+import (
+	"backend/pkg/ldapauth"
+	"backend/pkg/ldapauth/ldaptest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const (
+	testBaseDN    = "ou=people,dc=university,dc=example"
+	testServiceDN = "cn=unitask,dc=university,dc=example"
+)
+
+func newTestClient(t *testing.T) *ldapauth.Client {
+	t.Helper()
+
+	srv, err := ldaptest.NewServer(
+		ldaptest.Entry{DN: testServiceDN, Password: "service"},
+		ldaptest.Entry{
+			DN:       "uid=student,ou=people,dc=university,dc=example",
+			Password: "password",
+			Attributes: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"student"},
+				"cn":          {"Student Name"},
+				"mail":        {"student@university.example"},
+				"entryUUID":   {"4a1f3c2e-0001"},
+				"memberOf":    {"cn=group-1,ou=groups,dc=university,dc=example"},
+			},
+		},
+		// Duplicates aren't accepted
+		ldaptest.Entry{DN: "uid=twin,ou=people,dc=university,dc=example", Password: "password", Attributes: map[string][]string{"uid": {"twin"}}},
+		ldaptest.Entry{DN: "uid=twin,ou=staff,ou=people,dc=university,dc=example", Password: "password", Attributes: map[string][]string{"uid": {"twin"}}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+
+	return ldapauth.New(ldapauth.Config{
+		URL:               srv.URL,
+		BindDN:            testServiceDN,
+		BindPassword:      "service",
+		BaseDN:            testBaseDN,
+		UserFilter:        "(|(uid={username})(mail={username}))",
+		IDAttribute:       "entryUUID",
+		LoginAttribute:    "uid",
+		FullNameAttribute: "cn",
+		EmailAttribute:    "mail",
+		GroupAttribute:    "memberOf",
+	})
+}
+
+func TestClient_Authenticate(t *testing.T) {
+	c := newTestClient(t)
+
+	for _, username := range []string{"student", "student@university.example"} {
+		e, err := c.Authenticate(username, "password")
+		assert.NoError(t, err)
+		if assert.NotNil(t, e) {
+			assert.Equal(t, "4a1f3c2e-0001", e.ID)
+			assert.Equal(t, "student", e.Login)
+			assert.Equal(t, "Student Name", e.FullName)
+			assert.Equal(t, "student@university.example", e.Email)
+			assert.Equal(t, []string{"cn=group-1,ou=groups,dc=university,dc=example"}, e.Groups)
+		}
+	}
+}
+
+func TestClient_Authenticate_Invalid(t *testing.T) {
+	c := newTestClient(t)
+
+	testCases := []struct {
+		name     string
+		username string
+		password string
+	}{
+		{name: "wrong password", username: "student", password: "wrong"},
+		{name: "empty password", username: "student", password: ""},
+		{name: "unknown user", username: "nobody", password: "password"},
+		{name: "not unique", username: "twin", password: "password"},
+		{name: "filter injection", username: "*", password: "password"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := c.Authenticate(tc.username, tc.password)
+			assert.Equal(t, ldapauth.ErrInvalidCredentials, err)
+		})
+	}
+}
@@ -0,0 +1,303 @@
+// Package ldaptest provides the in-process LDAP server for tests.
+//	The server supports the simple bind and the search with and, or, not, equality and presence filters.
+package ldaptest
+
+import (
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"net"
+	"strings"
+	"sync"
+)
+
+// LDAP protocol operations and result codes used by the server
+const (
+	opBindRequest       = 0
+	opBindResponse      = 1
+	opUnbindRequest     = 2
+	opSearchRequest     = 3
+	opSearchResultEntry = 4
+	opSearchResultDone  = 5
+
+	filterAnd      = 0
+	filterOr       = 1
+	filterNot      = 2
+	filterEquality = 3
+	filterPresent  = 7
+
+	resultSuccess            = 0
+	resultSizeLimitExceeded  = 4
+	resultInvalidCredentials = 49
+	resultInsufficientAccess = 50
+	resultUnwillingToPerform = 53
+	resultProtocolError      = 2
+)
+
+// Entry of the directory. Attribute names are case-insensitive
+type Entry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+func (e *Entry) values(attribute string) []string {
+	for name, values := range e.Attributes {
+		if strings.EqualFold(name, attribute) {
+			return values
+		}
+	}
+	return nil
+}
+
+type Server struct {
+	// URL is the ldap:// URL the server listens on
+	URL string
+	// AllowAnonymousSearch allows the search without the bind
+	AllowAnonymousSearch bool
+
+	listener net.Listener
+	mu       sync.Mutex
+	entries  []Entry
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+}
+
+// NewServer starts the server on the random local port
+func NewServer(entries ...Entry) (*Server, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		URL:      "ldap://" + l.Addr().String(),
+		listener: l,
+		entries:  entries,
+		conns:    make(map[net.Conn]struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+func (s *Server) AddEntry(e Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, e)
+}
+
+// Close stops the server and closes open connections
+func (s *Server) Close() {
+	_ = s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	bound := false
+
+	for {
+		p, err := ber.ReadPacket(conn)
+		if err != nil || len(p.Children) < 2 {
+			return
+		}
+		messageID, ok := p.Children[0].Value.(int64)
+		if !ok {
+			return
+		}
+
+		op := p.Children[1]
+		switch op.Tag {
+		case opBindRequest:
+			code := s.bind(op)
+			bound = code == resultSuccess
+			s.write(conn, messageID, result(opBindResponse, code, ""))
+		case opSearchRequest:
+			if !bound && !s.AllowAnonymousSearch {
+				s.write(conn, messageID, result(opSearchResultDone, resultInsufficientAccess, "bind required"))
+				continue
+			}
+			s.search(conn, messageID, op)
+		case opUnbindRequest:
+			return
+		default:
+			s.write(conn, messageID, result(op.Tag+1, resultUnwillingToPerform, "unsupported operation"))
+		}
+	}
+}
+
+// bind checks the simple bind. The bind with the empty DN and password is anonymous
+func (s *Server) bind(op *ber.Packet) int {
+	if len(op.Children) < 3 {
+		return resultProtocolError
+	}
+	dn := op.Children[1].Data.String()
+	password := op.Children[2].Data.String()
+	if dn == "" && password == "" {
+		return resultSuccess
+	}
+	if password == "" {
+		return resultUnwillingToPerform
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if strings.EqualFold(e.DN, dn) && e.Password != "" && e.Password == password {
+			return resultSuccess
+		}
+	}
+	return resultInvalidCredentials
+}
+
+func (s *Server) search(conn net.Conn, messageID int64, op *ber.Packet) {
+	if len(op.Children) < 8 {
+		s.write(conn, messageID, result(opSearchResultDone, resultProtocolError, ""))
+		return
+	}
+	baseDN := strings.ToLower(op.Children[0].Data.String())
+	sizeLimit, _ := op.Children[3].Value.(int64)
+	filter := op.Children[6]
+	attributes := make([]string, 0, len(op.Children[7].Children))
+	for _, a := range op.Children[7].Children {
+		attributes = append(attributes, a.Data.String())
+	}
+
+	s.mu.Lock()
+	found := make([]Entry, 0)
+	for _, e := range s.entries {
+		if strings.HasSuffix(strings.ToLower(e.DN), baseDN) && matches(filter, &e) {
+			found = append(found, e)
+		}
+	}
+	s.mu.Unlock()
+
+	for i := range found {
+		if sizeLimit > 0 && int64(i) >= sizeLimit {
+			s.write(conn, messageID, result(opSearchResultDone, resultSizeLimitExceeded, ""))
+			return
+		}
+		s.write(conn, messageID, searchEntry(&found[i], attributes))
+	}
+	s.write(conn, messageID, result(opSearchResultDone, resultSuccess, ""))
+}
+
+func matches(filter *ber.Packet, e *Entry) bool {
+	switch filter.Tag {
+	case filterAnd:
+		for _, f := range filter.Children {
+			if !matches(f, e) {
+				return false
+			}
+		}
+		return true
+	case filterOr:
+		for _, f := range filter.Children {
+			if matches(f, e) {
+				return true
+			}
+		}
+		return false
+	case filterNot:
+		return len(filter.Children) == 1 && !matches(filter.Children[0], e)
+	case filterEquality:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		value := filter.Children[1].Data.String()
+		for _, v := range e.values(filter.Children[0].Data.String()) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case filterPresent:
+		attribute := filter.Data.String()
+		return strings.EqualFold(attribute, "objectClass") || len(e.values(attribute)) > 0
+	default:
+		return false
+	}
+}
+
+func (s *Server) write(conn net.Conn, messageID int64, op *ber.Packet) {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+	p.AppendChild(op)
+	_, _ = conn.Write(p.Bytes())
+}
+
+func result(tag ber.Tag, code int, message string) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "LDAP Result")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "resultCode"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, message, "diagnosticMessage"))
+	return p
+}
+
+// searchEntry encodes the entry with the requested attributes, all attributes are returned if none are requested
+func searchEntry(e *Entry, attributes []string) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, opSearchResultEntry, nil, "Search Result Entry")
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "objectName"))
+
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for name, values := range e.Attributes {
+		if len(attributes) > 0 && !containsFold(attributes, name) {
+			continue
+		}
+
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "value"))
+		}
+		attribute.AppendChild(set)
+		list.AppendChild(attribute)
+	}
+	p.AppendChild(list)
+
+	return p
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
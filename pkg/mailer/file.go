@@ -0,0 +1,55 @@
+package mailer
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes emails to .eml files instead of sending them. Used for local development
+type FileMailer struct {
+	dir string
+}
+
+func NewFileMailer(dir string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &FileMailer{dir: dir}, nil
+}
+
+func (m *FileMailer) Send(msg *Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102T150405"), uuid.New().String())
+	return os.WriteFile(filepath.Join(m.dir, name), msg.Bytes(), 0o644)
+}
+
+// LogMailer writes emails to the log instead of sending them. Used for local development
+type LogMailer struct {
+	logger *logrus.Logger
+}
+
+func NewLogMailer(logger *logrus.Logger) *LogMailer {
+	return &LogMailer{logger: logger}
+}
+
+func (m *LogMailer) Send(msg *Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"from":    msg.From,
+		"to":      msg.To,
+		"subject": msg.Subject,
+	}).Info("Email:\n", msg.Body)
+
+	return nil
+}
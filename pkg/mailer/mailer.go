@@ -0,0 +1,54 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/mail"
+	"time"
+)
+
+// Message is the plain text email
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// Bytes encodes the message in the RFC 5322 format
+func (m *Message) Bytes() []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "From: %s\r\n", m.From)
+	for _, to := range m.To {
+		fmt.Fprintf(&b, "To: %s\r\n", to)
+	}
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(m.Body)
+
+	return b.Bytes()
+}
+
+func (m *Message) validate() error {
+	if len(m.To) == 0 {
+		return fmt.Errorf("the message has no recipients")
+	}
+	for _, to := range append([]string{m.From}, m.To...) {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("invalid address %q: %w", to, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"net"
+	"net/smtp"
+	"strconv"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+// SMTPMailer sends emails through the SMTP server.
+//	The connection is upgraded with STARTTLS, if the server supports it.
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+}
+
+func NewSMTPMailer(config SMTPConfig) *SMTPMailer {
+	m := &SMTPMailer{
+		addr: net.JoinHostPort(config.Host, strconv.Itoa(config.Port)),
+	}
+	if config.Username != "" {
+		m.auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+
+	return m
+}
+
+func (m *SMTPMailer) Send(msg *Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	return smtp.SendMail(m.addr, m.auth, msg.From, msg.To, msg.Bytes())
+}
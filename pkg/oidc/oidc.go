@@ -0,0 +1,360 @@
+// Package oidc implements the relying party of the OpenID Connect
+// authorization code flow with PKCE (RFC 7636).
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+
+	codeVerifierBytes = 32
+	// keysRefreshRate limits JWKS reloads caused by ID tokens with an unknown kid
+	keysRefreshRate = 1 * time.Minute
+	// leeway is the allowed clock skew between the provider and the server
+	leeway = 1 * time.Minute
+)
+
+var (
+	ErrInvalidIDToken = errors.New("oidc: invalid id token")
+	ErrExchange       = errors.New("oidc: code exchange failed")
+)
+
+// Config of the client registered at the provider
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Provider is the part of the provider metadata used by the client
+type Provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Token is the response of the token endpoint
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+}
+
+// Claims of the ID token
+type Claims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	AuthorizedParty   string   `json:"azp"`
+	ExpiresAt         int64    `json:"exp"`
+	IssuedAt          int64    `json:"iat"`
+	NotBefore         int64    `json:"nbf"`
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+}
+
+// Valid checks the time claims, the rest is checked by Client.VerifyIDToken
+func (c *Claims) Valid() error {
+	now := time.Now()
+	if c.ExpiresAt == 0 || now.After(time.Unix(c.ExpiresAt, 0).Add(leeway)) {
+		return errors.New("the token is expired")
+	}
+	if c.NotBefore != 0 && now.Add(leeway).Before(time.Unix(c.NotBefore, 0)) {
+		return errors.New("the token isn't valid yet")
+	}
+	if c.IssuedAt != 0 && now.Add(leeway).Before(time.Unix(c.IssuedAt, 0)) {
+		return errors.New("the token is issued in the future")
+	}
+	return nil
+}
+
+// audience is the "aud" claim, it's either a string or an array of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+func (a audience) contains(clientID string) bool {
+	for _, aud := range a {
+		if aud == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// Client of the provider. The provider metadata and keys are loaded on the first use
+type Client struct {
+	config Config
+	http   *http.Client
+
+	mu            sync.Mutex
+	provider      *Provider
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+func New(config Config, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid"}
+	}
+
+	return &Client{
+		config: config,
+		http:   httpClient,
+	}
+}
+
+// Issuer returns the configured issuer. Subjects are unique only within the issuer
+func (c *Client) Issuer() string {
+	return c.config.Issuer
+}
+
+// AuthCodeURL returns the URL of the provider the user is redirected to.
+//	The state and the nonce are returned back in the redirect and in the ID token.
+func (c *Client) AuthCodeURL(state, nonce, codeVerifier string) (string, error) {
+	p, err := c.discover()
+	if err != nil {
+		return "", err
+	}
+
+	scopes := c.config.Scopes
+	if !containsString(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", c.config.ClientID)
+	query.Set("redirect_uri", c.config.RedirectURL)
+	query.Set("scope", strings.Join(scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", CodeChallenge(codeVerifier))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(p.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return p.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange exchanges the authorization code for tokens
+func (c *Client) Exchange(code, codeVerifier string) (*Token, error) {
+	p, err := c.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.config.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	form.Set("client_id", c.config.ClientID)
+
+	req, err := http.NewRequest(http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var e struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		_ = json.NewDecoder(res.Body).Decode(&e)
+		return nil, fmt.Errorf("%w: %d %s %s", ErrExchange, res.StatusCode, e.Error, e.ErrorDescription)
+	}
+
+	t := &Token{}
+	if err := json.NewDecoder(res.Body).Decode(t); err != nil {
+		return nil, err
+	}
+	if t.IDToken == "" {
+		return nil, fmt.Errorf("%w: the response has no id_token", ErrExchange)
+	}
+
+	return t, nil
+}
+
+// VerifyIDToken checks the signature, the issuer, the audience and the nonce of the ID token
+func (c *Client) VerifyIDToken(rawIDToken, nonce string) (*Claims, error) {
+	parser := &jwt.Parser{ValidMethods: []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}}
+
+	claims := &Claims{}
+	if _, err := parser.ParseWithClaims(rawIDToken, claims, c.keyFunc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if claims.Issuer != c.config.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, claims.Issuer)
+	}
+	if !claims.Audience.contains(c.config.ClientID) {
+		return nil, fmt.Errorf("%w: the token is issued for another client", ErrInvalidIDToken)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != c.config.ClientID {
+		return nil, fmt.Errorf("%w: unexpected authorized party %q", ErrInvalidIDToken, claims.AuthorizedParty)
+	}
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: the nonce doesn't match", ErrInvalidIDToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: the token has no subject", ErrInvalidIDToken)
+	}
+
+	return claims, nil
+}
+
+// NewCodeVerifier returns the random PKCE code verifier
+func NewCodeVerifier() (string, error) {
+	b := make([]byte, codeVerifierBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge returns the S256 PKCE code challenge of the verifier
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (c *Client) discover() (*Provider, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.provider != nil {
+		return c.provider, nil
+	}
+
+	p := &Provider{}
+	if err := c.getJSON(strings.TrimSuffix(c.config.Issuer, "/")+discoveryPath, p); err != nil {
+		return nil, fmt.Errorf("oidc: discovery: %w", err)
+	}
+	if p.Issuer != c.config.Issuer {
+		return nil, fmt.Errorf("oidc: discovery: the issuer %q doesn't match the configured one", p.Issuer)
+	}
+	if p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" || p.JWKSURI == "" {
+		return nil, errors.New("oidc: discovery: the provider metadata is incomplete")
+	}
+
+	c.provider = p
+	return p, nil
+}
+
+func (c *Client) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p, err := c.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key := c.findKey(kid); key != nil {
+		return key, nil
+	}
+	// The provider may have rotated its keys
+	if time.Since(c.keysFetchedAt) < keysRefreshRate {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	keys, err := c.fetchKeys(p.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	c.keys = keys
+	c.keysFetchedAt = time.Now()
+
+	if key := c.findKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// findKey returns the key with the kid. Tokens without the kid are accepted only if the provider has one key
+func (c *Client) findKey(kid string) interface{} {
+	if kid == "" {
+		if len(c.keys) == 1 {
+			for _, key := range c.keys {
+				return key
+			}
+		}
+		return nil
+	}
+	return c.keys[kid]
+}
+
+func (c *Client) getJSON(u string, v interface{}) error {
+	res, err := c.http.Get(u)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %d", u, res.StatusCode)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package oidc_test
+
+import (
+	"backend/pkg/oidc"
+	"backend/pkg/oidc/oidctest"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T) (*oidc.Client, *oidctest.Provider) {
+	t.Helper()
+
+	p, err := oidctest.NewProvider("unitask", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+
+	c := oidc.New(oidc.Config{
+		Issuer:       p.Issuer(),
+		ClientID:     "unitask",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:3000/login/oidc",
+		Scopes:       []string{"profile", "email"},
+	}, p.Client())
+	return c, p
+}
+
+func TestClient_AuthorizationCodeFlow(t *testing.T) {
+	c, p := newTestClient(t)
+
+	verifier, err := oidc.NewCodeVerifier()
+	assert.NoError(t, err)
+
+	authURL, err := c.AuthCodeURL("state", "nonce", verifier)
+	assert.NoError(t, err)
+	u, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	assert.Equal(t, "openid profile email", u.Query().Get("scope"))
+	assert.Equal(t, oidc.CodeChallenge(verifier), u.Query().Get("code_challenge"))
+
+	code, state, err := p.Authorize(authURL, oidctest.Identity{Subject: "s1", Email: "student@university.example", EmailVerified: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "state", state)
+
+	token, err := c.Exchange(code, verifier)
+	assert.NoError(t, err)
+
+	claims, err := c.VerifyIDToken(token.IDToken, "nonce")
+	assert.NoError(t, err)
+	assert.Equal(t, "s1", claims.Subject)
+	assert.Equal(t, "student@university.example", claims.Email)
+	assert.True(t, claims.EmailVerified)
+
+	// The code is single-use
+	_, err = c.Exchange(code, verifier)
+	assert.True(t, errors.Is(err, oidc.ErrExchange))
+}
+
+func TestClient_Exchange_InvalidVerifier(t *testing.T) {
+	c, p := newTestClient(t)
+
+	verifier, err := oidc.NewCodeVerifier()
+	assert.NoError(t, err)
+	authURL, err := c.AuthCodeURL("state", "nonce", verifier)
+	assert.NoError(t, err)
+	code, _, err := p.Authorize(authURL, oidctest.Identity{Subject: "s1"})
+	assert.NoError(t, err)
+
+	_, err = c.Exchange(code, "another verifier")
+	assert.True(t, errors.Is(err, oidc.ErrExchange))
+}
+
+func TestClient_VerifyIDToken(t *testing.T) {
+	c, p := newTestClient(t)
+	now := time.Now()
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   p.Issuer(),
+			"sub":   "s1",
+			"aud":   []string{"unitask", "other"},
+			"azp":   "unitask",
+			"exp":   now.Add(time.Minute).Unix(),
+			"iat":   now.Unix(),
+			"nonce": "nonce",
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		claims func() jwt.MapClaims
+		valid  bool
+	}{
+		{
+			name:   "valid",
+			claims: valid,
+			valid:  true,
+		},
+		{
+			name: "another issuer",
+			claims: func() jwt.MapClaims {
+				c := valid()
+				c["iss"] = "https://evil.example"
+				return c
+			},
+		},
+		{
+			name: "another audience",
+			claims: func() jwt.MapClaims {
+				c := valid()
+				c["aud"] = "other"
+				return c
+			},
+		},
+		{
+			name: "another nonce",
+			claims: func() jwt.MapClaims {
+				c := valid()
+				c["nonce"] = "replayed"
+				return c
+			},
+		},
+		{
+			name: "expired",
+			claims: func() jwt.MapClaims {
+				c := valid()
+				c["exp"] = now.Add(-time.Hour).Unix()
+				return c
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := p.IDToken(tc.claims())
+			assert.NoError(t, err)
+
+			_, err = c.VerifyIDToken(token, "nonce")
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, oidc.ErrInvalidIDToken))
+			}
+		})
+	}
+
+	// Tokens signed with HS256 by anyone who knows the public parameters are rejected
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, valid()).SignedString([]byte("secret"))
+	assert.NoError(t, err)
+	_, err = c.VerifyIDToken(token, "nonce")
+	assert.True(t, errors.Is(err, oidc.ErrInvalidIDToken))
+}
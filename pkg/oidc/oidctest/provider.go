@@ -0,0 +1,240 @@
+// Package oidctest provides the stub OpenID Connect provider for tests
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const keyID = "test-key"
+
+// Identity is the user signed in at the provider
+type Identity struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+type authRequest struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	identity      Identity
+}
+
+// Provider serves the discovery document, the JWKS and the token endpoint.
+//	The user sign-in at the provider is emulated by Authorize.
+type Provider struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]authRequest
+}
+
+func NewProvider(clientID, clientSecret string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Provider{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		codes:        make(map[string]authRequest),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("/jwks", p.handleJWKS)
+	mux.HandleFunc("/token", p.handleToken)
+	p.Server = httptest.NewServer(mux)
+
+	return p, nil
+}
+
+// Issuer returns the issuer identifier of the provider
+func (p *Provider) Issuer() string {
+	return p.URL
+}
+
+// Authorize emulates the sign-in of the user at the provider.
+//	It checks the authorization request and returns the code and the state the provider redirects back with.
+func (p *Provider) Authorize(authURL string, identity Identity) (code, state string, err error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	q := u.Query()
+
+	if q.Get("response_type") != "code" {
+		return "", "", errors.New("unsupported response_type")
+	}
+	if q.Get("client_id") != p.ClientID {
+		return "", "", errors.New("unknown client_id")
+	}
+	if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		return "", "", errors.New("PKCE with S256 is required")
+	}
+
+	code, err = randomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	p.mu.Lock()
+	p.codes[code] = authRequest{
+		clientID:      q.Get("client_id"),
+		redirectURI:   q.Get("redirect_uri"),
+		codeChallenge: q.Get("code_challenge"),
+		nonce:         q.Get("nonce"),
+		identity:      identity,
+	}
+	p.mu.Unlock()
+
+	return code, q.Get("state"), nil
+}
+
+// IDToken signs the ID token with the provider key. For tests of the token verification
+func (p *Provider) IDToken(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	return token.SignedString(p.key)
+}
+
+func (p *Provider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 p.URL,
+		"authorization_endpoint": p.URL + "/authorize",
+		"token_endpoint":         p.URL + "/token",
+		"jwks_uri":               p.URL + "/jwks",
+	})
+}
+
+func (p *Provider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *Provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+
+	if p.ClientSecret != "" {
+		id, secret, ok := r.BasicAuth()
+		if ok {
+			id, _ = url.QueryUnescape(id)
+			secret, _ = url.QueryUnescape(secret)
+		}
+		if !ok || id != p.ClientID || secret != p.ClientSecret {
+			tokenError(w, http.StatusUnauthorized, "invalid_client")
+			return
+		}
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	// Codes are single-use
+	p.mu.Lock()
+	req, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	if !ok || req.clientID != r.PostForm.Get("client_id") || req.redirectURI != r.PostForm.Get("redirect_uri") {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+	if codeChallenge(r.PostForm.Get("code_verifier")) != req.codeChallenge {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	idToken, err := p.IDToken(jwt.MapClaims{
+		"iss":                p.URL,
+		"sub":                req.identity.Subject,
+		"aud":                p.ClientID,
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"iat":                now.Unix(),
+		"nonce":              req.nonce,
+		"email":              req.identity.Email,
+		"email_verified":     req.identity.EmailVerified,
+		"name":               req.identity.Name,
+		"preferred_username": req.identity.PreferredUsername,
+	})
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	accessToken, err := randomString()
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code int, e string) {
+	writeJSON(w, code, map[string]string{"error": e, "error_description": fmt.Sprintf("stub provider: %s", e)})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func codeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
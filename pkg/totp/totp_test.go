@@ -0,0 +1,57 @@
+package totp
+
+import (
+	"encoding/base32"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238 appendix B for SHA1, truncated to 6 digits
+func TestCode_RFC6238(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	testCases := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+	}
+
+	for _, tc := range testCases {
+		code, err := Code(secret, Step(time.Unix(tc.unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.code, code)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NoError(t, err)
+
+	now := time.Now()
+	code, err := Code(secret, Step(now))
+	assert.NoError(t, err)
+
+	step, ok := Verify(secret, code, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	// The previous step is accepted to tolerate clock drift, the older ones aren't
+	_, ok = Verify(secret, code, now.Add(Period), 1)
+	assert.True(t, ok)
+	_, ok = Verify(secret, code, now.Add(2*Period), 1)
+	assert.False(t, ok)
+
+	_, ok = Verify(secret, "12345", now, 1)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Unitask", "user@example.org", "JBSWY3DPEHPK3PXP")
+	assert.Equal(t, "otpauth://totp/Unitask:user@example.org?algorithm=SHA1&digits=6&issuer=Unitask&period=30&secret=JBSWY3DPEHPK3PXP", uri)
+}